@@ -14,9 +14,11 @@ import (
 	"gorm.io/gorm"
 
 	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/container"
 	"smart-ledger-server/internal/middleware"
 	"smart-ledger-server/internal/pkg/database"
 	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/storage"
 )
 
 // mustLoadConfig 加载配置，失败则退出
@@ -55,6 +57,16 @@ func mustMigrateDatabase(log *zap.Logger) {
 	log.Info("数据库迁移完成")
 }
 
+// mustInitStorage 初始化文件存储，失败则退出
+func mustInitStorage(cfg *config.StorageConfig, log *zap.Logger) storage.Storage {
+	store, err := storage.NewStorage(cfg)
+	if err != nil {
+		log.Fatal("初始化文件存储失败", zap.Error(err))
+	}
+	log.Info("文件存储初始化完成", zap.String("type", cfg.Type))
+	return store
+}
+
 // initRedisIfConfigured 如果配置了 Redis 则初始化
 // 返回 true 表示 Redis 初始化成功，调用者需要在程序退出时调用 database.CloseRedis()
 func initRedisIfConfigured(cfg *config.RedisConfig, log *zap.Logger) bool {
@@ -68,6 +80,17 @@ func initRedisIfConfigured(cfg *config.RedisConfig, log *zap.Logger) bool {
 	return false
 }
 
+// startBackgroundJobs 启动后台任务
+func startBackgroundJobs(ctx context.Context, ctn *container.Container, log *zap.Logger) {
+	if jobService := ctn.AIJobService(); jobService != nil {
+		jobService.Start(ctx)
+		log.Info("异步识别任务调度器已启动")
+	}
+	if ctn.InsightService().Start(ctx) {
+		log.Info("月度消费洞察任务已启动")
+	}
+}
+
 // applyGlobalMiddleware 应用全局中间件
 func applyGlobalMiddleware(r *gin.Engine, log *zap.Logger, limiter *middleware.IPRateLimiter) {
 	r.Use(middleware.Recovery(log))
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 
 	"golang.org/x/time/rate"
@@ -36,8 +37,11 @@ func main() {
 		defer database.CloseRedis()
 	}
 
+	// 初始化文件存储
+	store := mustInitStorage(&cfg.Storage, log)
+
 	// 3. 创建依赖容器（核心简化点）
-	ctn := container.NewContainer(cfg, db, log)
+	ctn := container.NewContainer(cfg, db, store, log)
 
 	// 4. 初始化限流器
 	ipLimiter := middleware.NewIPRateLimiter(rate.Limit(10), 20)
@@ -48,6 +52,11 @@ func main() {
 	// 6. 注册路由
 	registerAllRoutes(r, cfg, ctn)
 
-	// 7. 启动服务器
+	// 7. 启动后台任务（异步识别等），服务器关闭后停止
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	startBackgroundJobs(bgCtx, ctn, log)
+
+	// 8. 启动服务器
 	runServer(cfg, r, log)
 }
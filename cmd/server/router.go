@@ -48,6 +48,7 @@ func registerAuthenticatedRoutes(v1 *gin.RouterGroup, cfg *config.Config, ctn *c
 		registerUserProtectedRoutes(auth, ctn)
 		registerCategoryRoutes(auth, ctn)
 		registerBillRoutes(auth, ctn)
+		registerImportProfileRoutes(auth, ctn)
 		registerStatsRoutes(auth, ctn)
 		registerAIRoutes(auth, ctn)
 	}
@@ -79,14 +80,37 @@ func registerBillRoutes(auth *gin.RouterGroup, ctn *container.Container) {
 	h := ctn.BillHandler()
 	{
 		bills.GET("", h.List)
+		// 待复核账单
+		bills.GET("/review", h.ListReview)
+		bills.POST("/review", h.Review)
+		// 一句话记账
+		bills.POST("/quick", h.QuickEntry)
+		// 支付通知/短信记账
+		bills.POST("/notifications", h.IngestNotification)
 		bills.GET("/:id", h.Get)
+		bills.GET("/:id/image", h.GetImage)
 		bills.POST("", h.Create)
 		bills.POST("/import", h.Import)
+		bills.GET("/import/parsers", h.ListImportParsers)
 		bills.PUT("/:id", h.Update)
 		bills.DELETE("/:id", h.Delete)
 	}
 }
 
+// registerImportProfileRoutes 注册账单导入配置路由
+func registerImportProfileRoutes(auth *gin.RouterGroup, ctn *container.Container) {
+	profiles := auth.Group("/import-profiles")
+	h := ctn.ImportProfileHandler()
+	{
+		profiles.GET("", h.List)
+		profiles.POST("", h.Create)
+		profiles.POST("/preview", h.Preview)
+		profiles.PUT("/:id", h.Update)
+		profiles.DELETE("/:id", h.Delete)
+		profiles.POST("/:id/import", h.Import)
+	}
+}
+
 // registerStatsRoutes 注册统计路由
 func registerStatsRoutes(auth *gin.RouterGroup, ctn *container.Container) {
 	stats := auth.Group("/stats")
@@ -95,6 +119,8 @@ func registerStatsRoutes(auth *gin.RouterGroup, ctn *container.Container) {
 		stats.GET("/summary", h.GetSummary)
 		stats.GET("/category", h.GetCategoryStats)
 		stats.GET("/secondary-category", h.GetSecondaryCategoryStats)
+		stats.POST("/ask", h.Ask)
+		stats.GET("/insights", h.GetInsight)
 	}
 }
 
@@ -106,6 +132,22 @@ func registerAIRoutes(auth *gin.RouterGroup, ctn *container.Container) {
 			// 单张识别
 			ai.POST("/recognize", h.Recognize)
 			ai.POST("/recognize-and-save", h.RecognizeAndSave)
+			// 批量识别
+			ai.POST("/batch-recognize", h.BatchRecognize)
+			ai.POST("/batch-recognize-and-save", h.BatchRecognizeAndSave)
+			// 多笔交易截图：先预览，再保存勾选的交易
+			ai.POST("/recognize-multi", h.RecognizeTransactions)
+			ai.POST("/recognize-multi/save", h.SaveTransactions)
+			// 使用已保存截图重新识别
+			ai.POST("/bills/:id/re-recognize", h.ReRecognize)
+			// 提供方健康状态
+			ai.GET("/health", h.Health)
+			// 用户调用配额
+			ai.GET("/quota", h.Quota)
+			// 异步识别任务
+			ai.POST("/jobs", h.SubmitJob)
+			ai.GET("/jobs/:id", h.GetJob)
+			ai.GET("/jobs/:id/events", h.JobEvents)
 		}
 	}
 }
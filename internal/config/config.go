@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,6 +15,7 @@ type Config struct {
 	Redis    RedisConfig    `mapstructure:"redis"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	AI       AIConfig       `mapstructure:"ai"`
+	Storage  StorageConfig  `mapstructure:"storage"`
 	Log      LogConfig      `mapstructure:"log"`
 }
 
@@ -72,11 +74,110 @@ type JWTConfig struct {
 
 // AIConfig AI服务配置
 type AIConfig struct {
-	APIKey       string      `mapstructure:"api_key"`        // API 密钥
-	BaseURL      string      `mapstructure:"base_url"`       // 基础 URL（可选）
-	Model        string      `mapstructure:"model"`          // 模型名称
-	MaxImageSize int64       `mapstructure:"max_image_size"` // 最大图片大小(字节)
-	Batch        BatchConfig `mapstructure:"batch"`          // 批量处理配置
+	Provider       string                 `mapstructure:"provider"`        // 使用的提供方：openai, qwen, ollama
+	APIKey         string                 `mapstructure:"api_key"`         // API 密钥（兼容旧配置，等同于 openai.api_key）
+	BaseURL        string                 `mapstructure:"base_url"`        // 基础 URL（兼容旧配置，等同于 openai.base_url）
+	Model          string                 `mapstructure:"model"`           // 模型名称（兼容旧配置，等同于 openai.model）
+	MaxImageSize   int64                  `mapstructure:"max_image_size"`  // 最大图片大小(字节)
+	OpenAI         ProviderConfig         `mapstructure:"openai"`          // OpenAI 配置
+	Qwen           ProviderConfig         `mapstructure:"qwen"`            // 通义千问（DashScope）配置
+	Ollama         ProviderConfig         `mapstructure:"ollama"`          // 本地 Ollama 配置
+	Fallback       []string               `mapstructure:"fallback"`        // 备用提供方（按顺序故障转移）
+	Retry          RetryConfig            `mapstructure:"retry"`           // 重试配置
+	CircuitBreaker CircuitBreakerConfig   `mapstructure:"circuit_breaker"` // 熔断配置
+	Batch          BatchConfig            `mapstructure:"batch"`           // 批量处理配置
+	Cache          RecognitionCacheConfig `mapstructure:"cache"`           // 识别结果缓存配置
+	Quota          QuotaConfig            `mapstructure:"quota"`           // 单用户调用配额
+	PDF            PDFConfig              `mapstructure:"pdf"`             // PDF 电子发票/回单识别配置
+	Preprocess     PreprocessConfig       `mapstructure:"preprocess"`      // 图片上传前预处理配置
+	Prompt         PromptConfig           `mapstructure:"prompt"`          // 识别提示词模板配置
+	Insights       InsightsConfig         `mapstructure:"insights"`        // 月度消费洞察配置
+}
+
+// InsightsConfig 月度消费洞察配置
+// 后台任务按 Interval 检查，为上个月有账单且尚未生成洞察的用户生成报告
+type InsightsConfig struct {
+	Disabled bool          `mapstructure:"disabled"` // 是否关闭自动生成
+	Interval time.Duration `mapstructure:"interval"` // 检查间隔
+	Timezone string        `mapstructure:"timezone"` // 判断“上个月”使用的时区
+}
+
+// PromptConfig 识别提示词模板配置
+// 覆盖目录中与 internal/pkg/ai/prompts 下同名的 .tmpl 文件覆盖内置模板，common.tmpl 为各模板共用的片段
+type PromptConfig struct {
+	Dir            string        `mapstructure:"dir"`             // 模板覆盖目录，为空时只使用内置模板
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // 检查覆盖目录变更的间隔，变更后自动重新加载
+}
+
+// PreprocessConfig 图片预处理配置
+// 上传给AI前按 EXIF 方向旋转、去除 EXIF/GPS 等元数据、缩小尺寸，HEIC 图片转换为 JPEG
+type PreprocessConfig struct {
+	Disabled     bool   `mapstructure:"disabled"`      // 是否关闭旋转、去元数据和缩放（仍然校验图片格式）
+	MaxDimension int    `mapstructure:"max_dimension"` // 长边最大像素，超过时等比缩小
+	JPEGQuality  int    `mapstructure:"jpeg_quality"`  // 重新编码 JPEG 的质量（1-100）
+	HEICCommand  string `mapstructure:"heic_command"`  // HEIC 转 JPEG 命令，{input} 替换为文件路径，从标准输出读取 JPEG，为空时使用内置解码器
+}
+
+// PDFConfig PDF 识别配置
+// 优先从 PDF 中提取文本解析发票字段，提取不到时取页面内嵌图片，仍然没有时使用渲染命令将首页转为图片
+type PDFConfig struct {
+	RenderCommand string `mapstructure:"render_command"` // 渲染命令，{input} 替换为 PDF 文件路径，从标准输出读取 PNG/JPEG，为空时不渲染
+}
+
+// QuotaConfig 单用户AI调用配额，0 表示不限制
+// 命中识别结果缓存的调用不计入配额
+type QuotaConfig struct {
+	Daily   int `mapstructure:"daily"`   // 每日最大调用次数
+	Monthly int `mapstructure:"monthly"` // 每月最大调用次数
+}
+
+// RecognitionCacheConfig 识别结果缓存配置
+// 配置了 Redis 时使用 Redis，否则使用进程内 LRU
+type RecognitionCacheConfig struct {
+	Disabled bool          `mapstructure:"disabled"` // 是否关闭缓存
+	TTL      time.Duration `mapstructure:"ttl"`      // 缓存有效期
+	Size     int           `mapstructure:"size"`     // 进程内 LRU 最大条目数
+}
+
+// RetryConfig 瞬时错误重试配置
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"` // 单个提供方最大尝试次数（含首次）
+	BaseDelay   time.Duration `mapstructure:"base_delay"`   // 退避基础时间
+	MaxDelay    time.Duration `mapstructure:"max_delay"`    // 退避最大时间
+}
+
+// CircuitBreakerConfig 熔断配置
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 连续失败多少次后熔断
+	OpenTimeout      time.Duration `mapstructure:"open_timeout"`      // 熔断持续时间，之后放行试探请求
+}
+
+// ProviderConfig AI提供方配置
+type ProviderConfig struct {
+	APIKey  string        `mapstructure:"api_key"`  // API 密钥
+	BaseURL string        `mapstructure:"base_url"` // 基础 URL（可选，为空时使用提供方默认地址）
+	Model   string        `mapstructure:"model"`    // 模型名称（可选，为空时使用提供方默认模型）
+	Timeout time.Duration `mapstructure:"timeout"`  // 单次请求超时（可选）
+	// 结构化输出模式：json_schema, json_object, none（可选，为空时使用提供方默认值）
+	StructuredOutput string `mapstructure:"structured_output"`
+	// 图片细节级别（OpenAI 兼容接口）：low, high, auto（可选，为空时按图片尺寸选择）
+	ImageDetail string `mapstructure:"image_detail"`
+	// Transport 自定义 HTTP 传输层（可选，不从配置文件读取），测试中用于录制和回放提供方请求
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// ProviderConfig 根据名称获取提供方配置
+func (c *AIConfig) ProviderConfig(name string) (*ProviderConfig, error) {
+	switch name {
+	case "openai":
+		return &c.OpenAI, nil
+	case "qwen":
+		return &c.Qwen, nil
+	case "ollama":
+		return &c.Ollama, nil
+	default:
+		return nil, fmt.Errorf("未配置的AI提供方: %s", name)
+	}
 }
 
 // BatchConfig 批量处理配置
@@ -87,6 +188,28 @@ type BatchConfig struct {
 	TaskTimeout int `mapstructure:"task_timeout"` // 单个任务超时时间(秒)
 }
 
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Type  string             `mapstructure:"type"`  // local, s3
+	Local LocalStorageConfig `mapstructure:"local"` // 本地存储配置
+	S3    S3Config           `mapstructure:"s3"`    // S3 兼容存储配置
+}
+
+// LocalStorageConfig 本地存储配置
+type LocalStorageConfig struct {
+	Root string `mapstructure:"root"` // 存储根目录
+}
+
+// S3Config S3 兼容存储配置（AWS S3 / MinIO 等）
+type S3Config struct {
+	Endpoint     string `mapstructure:"endpoint"`       // 服务地址，如 http://localhost:9000
+	Region       string `mapstructure:"region"`         // 区域
+	Bucket       string `mapstructure:"bucket"`         // 存储桶
+	AccessKey    string `mapstructure:"access_key"`     // 访问密钥ID
+	SecretKey    string `mapstructure:"secret_key"`     // 访问密钥
+	UsePathStyle bool   `mapstructure:"use_path_style"` // 是否使用路径风格访问（MinIO 需开启）
+}
+
 // LogConfig 日志配置
 type LogConfig struct {
 	Level      string          `mapstructure:"level"`       // debug, info, warn, error
@@ -173,12 +296,43 @@ func setDefaults(cfg *Config) {
 	}
 
 	// AI defaults
-	if cfg.AI.Model == "" {
-		cfg.AI.Model = "gpt-4o"
+	if cfg.AI.Provider == "" {
+		cfg.AI.Provider = "openai"
+	}
+	// 兼容旧配置：ai.api_key/base_url/model 视为 openai 配置
+	if cfg.AI.OpenAI.APIKey == "" && cfg.AI.APIKey != "" {
+		cfg.AI.OpenAI = ProviderConfig{
+			APIKey:  cfg.AI.APIKey,
+			BaseURL: cfg.AI.BaseURL,
+			Model:   cfg.AI.Model,
+		}
 	}
 	if cfg.AI.MaxImageSize == 0 {
 		cfg.AI.MaxImageSize = 10 * 1024 * 1024 // 10MB
 	}
+	// AI retry / circuit breaker defaults
+	if cfg.AI.Retry.MaxAttempts == 0 {
+		cfg.AI.Retry.MaxAttempts = 3
+	}
+	if cfg.AI.Retry.BaseDelay == 0 {
+		cfg.AI.Retry.BaseDelay = 500 * time.Millisecond
+	}
+	if cfg.AI.Retry.MaxDelay == 0 {
+		cfg.AI.Retry.MaxDelay = 5 * time.Second
+	}
+	if cfg.AI.CircuitBreaker.FailureThreshold == 0 {
+		cfg.AI.CircuitBreaker.FailureThreshold = 5
+	}
+	if cfg.AI.CircuitBreaker.OpenTimeout == 0 {
+		cfg.AI.CircuitBreaker.OpenTimeout = 30 * time.Second
+	}
+	// AI recognition cache defaults
+	if cfg.AI.Cache.TTL == 0 {
+		cfg.AI.Cache.TTL = 24 * time.Hour
+	}
+	if cfg.AI.Cache.Size == 0 {
+		cfg.AI.Cache.Size = 1000
+	}
 	// AI batch defaults
 	if cfg.AI.Batch.MaxImages == 0 {
 		cfg.AI.Batch.MaxImages = 20
@@ -192,6 +346,32 @@ func setDefaults(cfg *Config) {
 	if cfg.AI.Batch.TaskTimeout == 0 {
 		cfg.AI.Batch.TaskTimeout = 60
 	}
+	// AI prompt defaults
+	if cfg.AI.Prompt.ReloadInterval == 0 {
+		cfg.AI.Prompt.ReloadInterval = time.Minute
+	}
+	// AI insights defaults
+	if cfg.AI.Insights.Interval == 0 {
+		cfg.AI.Insights.Interval = time.Hour
+	}
+	if cfg.AI.Insights.Timezone == "" {
+		cfg.AI.Insights.Timezone = "Asia/Shanghai"
+	}
+	// AI image preprocess defaults
+	if cfg.AI.Preprocess.MaxDimension == 0 {
+		cfg.AI.Preprocess.MaxDimension = 2048
+	}
+	if cfg.AI.Preprocess.JPEGQuality == 0 {
+		cfg.AI.Preprocess.JPEGQuality = 85
+	}
+
+	// Storage defaults
+	if cfg.Storage.Type == "" {
+		cfg.Storage.Type = "local"
+	}
+	if cfg.Storage.Local.Root == "" {
+		cfg.Storage.Local.Root = "./data/uploads"
+	}
 
 	// Log defaults
 	if cfg.Log.Level == "" {
@@ -6,6 +6,10 @@ import (
 
 	"smart-ledger-server/internal/config"
 	"smart-ledger-server/internal/handler"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/internal/pkg/database"
+	"smart-ledger-server/internal/pkg/notification"
+	"smart-ledger-server/internal/pkg/storage"
 	"smart-ledger-server/internal/repository"
 	"smart-ledger-server/internal/service"
 )
@@ -13,37 +17,51 @@ import (
 // Container 依赖注入容器，封装和管理所有依赖
 type Container struct {
 	// 基础设施
-	cfg    *config.Config
-	db     *gorm.DB
-	logger *zap.Logger
+	cfg     *config.Config
+	db      *gorm.DB
+	storage storage.Storage
+	logger  *zap.Logger
 
 	// Repositories
 	userRepo             *repository.UserRepository
 	categoryRepo         *repository.CategoryRepository
 	billRepo             *repository.BillRepository
 	categoryTemplateRepo *repository.CategoryTemplateRepository
+	correctionRepo       *repository.CategoryCorrectionRepository
+	aiUsageRepo          *repository.AIUsageRepository
+	recognitionJobRepo   *repository.RecognitionJobRepository
+	monthlyInsightRepo   *repository.MonthlyInsightRepository
+	importProfileRepo    *repository.ImportProfileRepository
 
 	// Services
-	userService     *service.UserService
-	categoryService *service.CategoryService
-	billService     *service.BillService
-	statsService    *service.StatsService
-	aiService       *service.AIService
+	userService          *service.UserService
+	categoryService      *service.CategoryService
+	billService          *service.BillService
+	statsService         *service.StatsService
+	askService           *service.AskService
+	insightService       *service.InsightService
+	aiService            *service.AIService
+	aiJobService         *service.AIJobService
+	quickEntryService    *service.QuickEntryService
+	notificationService  *service.NotificationService
+	importProfileService *service.ImportProfileService
 
 	// Handlers
-	userHandler     *handler.UserHandler
-	categoryHandler *handler.CategoryHandler
-	billHandler     *handler.BillHandler
-	statsHandler    *handler.StatsHandler
-	aiHandler       *handler.AIHandler
+	userHandler          *handler.UserHandler
+	categoryHandler      *handler.CategoryHandler
+	billHandler          *handler.BillHandler
+	statsHandler         *handler.StatsHandler
+	aiHandler            *handler.AIHandler
+	importProfileHandler *handler.ImportProfileHandler
 }
 
 // NewContainer 创建容器实例
-func NewContainer(cfg *config.Config, db *gorm.DB, logger *zap.Logger) *Container {
+func NewContainer(cfg *config.Config, db *gorm.DB, store storage.Storage, logger *zap.Logger) *Container {
 	ctn := &Container{
-		cfg:    cfg,
-		db:     db,
-		logger: logger,
+		cfg:     cfg,
+		db:      db,
+		storage: store,
+		logger:  logger,
 	}
 	ctn.initRepositories()
 	ctn.initServices()
@@ -57,31 +75,60 @@ func (c *Container) initRepositories() {
 	c.categoryRepo = repository.NewCategoryRepository(c.db)
 	c.billRepo = repository.NewBillRepository(c.db)
 	c.categoryTemplateRepo = repository.NewCategoryTemplateRepository(c.db)
+	c.correctionRepo = repository.NewCategoryCorrectionRepository(c.db)
+	c.aiUsageRepo = repository.NewAIUsageRepository(c.db)
+	c.recognitionJobRepo = repository.NewRecognitionJobRepository(c.db)
+	c.monthlyInsightRepo = repository.NewMonthlyInsightRepository(c.db)
+	c.importProfileRepo = repository.NewImportProfileRepository(c.db)
 }
 
 // initServices 初始化所有 Services
 func (c *Container) initServices() {
 	c.categoryService = service.NewCategoryService(c.categoryRepo, c.categoryTemplateRepo)
 	c.userService = service.NewUserService(c.userRepo, c.categoryService, c.cfg)
-	c.billService = service.NewBillService(c.billRepo, c.categoryRepo)
+	c.billService = service.NewBillService(c.billRepo, c.categoryRepo, c.correctionRepo, c.userRepo, c.storage)
 	c.statsService = service.NewStatsService(c.billRepo)
+	c.importProfileService = service.NewImportProfileService(c.importProfileRepo, c.billService)
+
+	// 识别结果缓存：配置了 Redis 时使用 Redis，否则使用进程内 LRU
+	var recognitionCache ai.Cache
+	if !c.cfg.AI.Cache.Disabled {
+		recognitionCache = ai.NewCache(database.GetRedis(), c.cfg.AI.Cache.Size)
+	}
 
 	// AI Service 可能失败
-	aiService, err := service.NewAIService(&c.cfg.AI, c.billService, c.categoryService)
+	aiService, err := service.NewAIService(&c.cfg.AI, c.billService, c.categoryService, c.userRepo, c.aiUsageRepo, c.storage, recognitionCache)
 	if err != nil {
 		c.logger.Warn("初始化AI服务失败", zap.Error(err))
 	}
 	c.aiService = aiService
+	// 一句话记账和通知记账在AI未启用时仅使用规则解析，账本问答和洞察生成不可用，避免传入 nil 指针的接口值
+	var quickEntryRecognizer service.QuickEntryRecognizer
+	var notificationRecognizer service.NotificationRecognizer
+	var queryPlanner service.LedgerQueryPlanner
+	var insightWriter service.InsightWriter
+	if aiService != nil {
+		c.aiJobService = service.NewAIJobService(aiService, c.recognitionJobRepo, c.storage)
+		quickEntryRecognizer = aiService
+		notificationRecognizer = aiService
+		queryPlanner = aiService
+		insightWriter = aiService
+	}
+	c.quickEntryService = service.NewQuickEntryService(quickEntryRecognizer, c.billService, c.categoryService)
+	c.notificationService = service.NewNotificationService(notification.NewDefaultRegistry(), notificationRecognizer, c.billService, c.categoryService)
+	c.askService = service.NewAskService(queryPlanner, c.billRepo, c.categoryRepo)
+	c.insightService = service.NewInsightService(insightWriter, c.statsService, c.billRepo, c.monthlyInsightRepo, c.cfg.AI.Insights)
 }
 
 // initHandlers 初始化所有 Handlers
 func (c *Container) initHandlers() {
 	c.userHandler = handler.NewUserHandler(c.userService)
 	c.categoryHandler = handler.NewCategoryHandler(c.categoryService)
-	c.billHandler = handler.NewBillHandler(c.billService)
-	c.statsHandler = handler.NewStatsHandler(c.statsService)
+	c.billHandler = handler.NewBillHandler(c.billService, c.quickEntryService, c.notificationService)
+	c.statsHandler = handler.NewStatsHandler(c.statsService, c.askService, c.insightService)
+	c.importProfileHandler = handler.NewImportProfileHandler(c.importProfileService)
 	if c.aiService != nil {
-		c.aiHandler = handler.NewAIHandler(c.aiService)
+		c.aiHandler = handler.NewAIHandler(c.aiService, c.aiJobService)
 	}
 }
 
@@ -93,11 +140,19 @@ func (c *Container) BillRepo() *repository.BillRepository         { return c.bil
 
 // Service 访问器
 
-func (c *Container) UserService() *service.UserService         { return c.userService }
-func (c *Container) CategoryService() *service.CategoryService { return c.categoryService }
-func (c *Container) BillService() *service.BillService         { return c.billService }
-func (c *Container) StatsService() *service.StatsService       { return c.statsService }
-func (c *Container) AIService() *service.AIService             { return c.aiService }
+func (c *Container) UserService() *service.UserService                 { return c.userService }
+func (c *Container) CategoryService() *service.CategoryService         { return c.categoryService }
+func (c *Container) BillService() *service.BillService                 { return c.billService }
+func (c *Container) StatsService() *service.StatsService               { return c.statsService }
+func (c *Container) AskService() *service.AskService                   { return c.askService }
+func (c *Container) InsightService() *service.InsightService           { return c.insightService }
+func (c *Container) AIService() *service.AIService                     { return c.aiService }
+func (c *Container) AIJobService() *service.AIJobService               { return c.aiJobService }
+func (c *Container) QuickEntryService() *service.QuickEntryService     { return c.quickEntryService }
+func (c *Container) NotificationService() *service.NotificationService { return c.notificationService }
+func (c *Container) ImportProfileService() *service.ImportProfileService {
+	return c.importProfileService
+}
 
 // Handler 访问器
 
@@ -106,3 +161,6 @@ func (c *Container) CategoryHandler() *handler.CategoryHandler { return c.catego
 func (c *Container) BillHandler() *handler.BillHandler         { return c.billHandler }
 func (c *Container) StatsHandler() *handler.StatsHandler       { return c.statsHandler }
 func (c *Container) AIHandler() *handler.AIHandler             { return c.aiHandler }
+func (c *Container) ImportProfileHandler() *handler.ImportProfileHandler {
+	return c.importProfileHandler
+}
@@ -1,22 +1,33 @@
 package handler
 
 import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"smart-ledger-server/internal/model/dto"
 	"smart-ledger-server/internal/pkg/response"
 	"smart-ledger-server/internal/service"
 	"smart-ledger-server/pkg/errcode"
 )
 
+// sseHeartbeatInterval SSE 心跳间隔，避免代理因空闲断开连接
+const sseHeartbeatInterval = 15 * time.Second
+
 // AIHandler AI处理器
 type AIHandler struct {
-	aiService service.AIServiceInterface
+	aiService  service.AIServiceInterface
+	jobService service.AIJobServiceInterface
 }
 
 // NewAIHandler 创建AI处理器
-func NewAIHandler(aiService service.AIServiceInterface) *AIHandler {
+func NewAIHandler(aiService service.AIServiceInterface, jobService service.AIJobServiceInterface) *AIHandler {
 	return &AIHandler{
-		aiService: aiService,
+		aiService:  aiService,
+		jobService: jobService,
 	}
 }
 
@@ -80,3 +91,339 @@ func (h *AIHandler) RecognizeAndSave(c *gin.Context) {
 
 	response.Success(c, resp)
 }
+
+// BatchRecognize 批量识别支付截图
+// @Summary 批量识别支付截图
+// @Tags AI
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param images formData file true "支付截图（可多张）"
+// @Success 200 {object} response.Response{data=dto.BatchRecognizeResponse}
+// @Router /ai/batch-recognize [post]
+func (h *AIHandler) BatchRecognize(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		response.ParamError(c, "请上传图片")
+		return
+	}
+
+	resp, err := h.aiService.BatchRecognize(c.Request.Context(), userID, form.File["images"])
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// BatchRecognizeAndSave 批量识别支付截图并保存
+// @Summary 批量识别支付截图并保存为账单
+// @Tags AI
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param images formData file true "支付截图（可多张）"
+// @Success 200 {object} response.Response{data=dto.BatchRecognizeResponse}
+// @Router /ai/batch-recognize-and-save [post]
+func (h *AIHandler) BatchRecognizeAndSave(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		response.ParamError(c, "请上传图片")
+		return
+	}
+
+	resp, err := h.aiService.BatchRecognizeAndCreateBill(c.Request.Context(), userID, form.File["images"])
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// RecognizeTransactions 识别多笔交易截图
+// @Summary 识别包含多笔交易的截图（银行APP、支付宝账单列表等），返回预览不保存
+// @Tags AI
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param image formData file true "账单列表截图"
+// @Success 200 {object} response.Response{data=dto.AIRecognizeListResponse}
+// @Router /ai/recognize-multi [post]
+func (h *AIHandler) RecognizeTransactions(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		response.ParamError(c, "请上传图片")
+		return
+	}
+
+	resp, err := h.aiService.RecognizeTransactions(c.Request.Context(), userID, file)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// SaveTransactions 保存多笔识别交易
+// @Summary 将预览中勾选的交易在同一事务中保存为账单，与已有账单重复的交易不再创建
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body dto.SaveAITransactionsRequest true "勾选的交易"
+// @Success 200 {object} response.Response{data=dto.SaveAITransactionsResponse}
+// @Router /ai/recognize-multi/save [post]
+func (h *AIHandler) SaveTransactions(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.SaveAITransactionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.aiService.SaveTransactions(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// ReRecognize 重新识别账单截图
+// @Summary 使用已保存的截图重新识别账单
+// @Tags AI
+// @Produce json
+// @Security Bearer
+// @Param id path int true "账单ID"
+// @Param apply query bool false "是否将识别结果写回账单"
+// @Success 200 {object} response.Response{data=dto.ReRecognizeResponse}
+// @Router /ai/bills/{id}/re-recognize [post]
+func (h *AIHandler) ReRecognize(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的账单ID")
+		return
+	}
+
+	var req dto.ReRecognizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.aiService.ReRecognizeBill(c.Request.Context(), userID, id, req.Apply)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Health 获取AI服务健康状态
+// @Summary 获取AI提供方健康状态（熔断、连续失败次数等）
+// @Tags AI
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=dto.AIHealthResponse}
+// @Router /ai/health [get]
+func (h *AIHandler) Health(c *gin.Context) {
+	response.Success(c, h.aiService.Health(c.Request.Context()))
+}
+
+// Quota 获取AI调用配额
+// @Summary 获取当前用户的AI调用配额与用量
+// @Tags AI
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=dto.AIQuotaResponse}
+// @Router /ai/quota [get]
+func (h *AIHandler) Quota(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	resp, err := h.aiService.Quota(c.Request.Context(), userID)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// SubmitJob 提交异步识别任务
+// @Summary 提交异步识别任务，立即返回任务ID
+// @Tags AI
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param images formData file true "支付截图（可多张）"
+// @Param save formData bool false "识别成功后是否自动创建账单"
+// @Success 200 {object} response.Response{data=dto.AIJobResponse}
+// @Router /ai/jobs [post]
+func (h *AIHandler) SubmitJob(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		response.ParamError(c, "请上传图片")
+		return
+	}
+
+	var req dto.SubmitAIJobRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.jobService.Submit(c.Request.Context(), userID, form.File["images"], req.Save)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetJob 查询异步识别任务
+// @Summary 查询异步识别任务状态及各图片结果
+// @Tags AI
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Success 200 {object} response.Response{data=dto.AIJobResponse}
+// @Router /ai/jobs/{id} [get]
+func (h *AIHandler) GetJob(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	resp, err := h.jobService.Get(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// JobEvents 订阅异步识别任务进度
+// @Summary 通过 SSE 订阅异步识别任务进度
+// @Description 首先推送 snapshot 事件（任务当前状态），之后每张图片完成推送 item 事件，任务结束推送 done 事件后关闭连接
+// @Description 任务由其他实例处理时，进度在每次心跳时从数据库同步
+// @Tags AI
+// @Produce text/event-stream
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Router /ai/jobs/{id}/events [get]
+func (h *AIHandler) JobEvents(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	snapshot, events, cancel, err := h.jobService.Subscribe(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+	defer cancel()
+
+	// 长连接不受服务器 WriteTimeout 限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("snapshot", snapshot)
+	if snapshot.Finished {
+		c.SSEvent("done", snapshot)
+		return
+	}
+	c.Writer.Flush()
+
+	// 记录已推送的图片状态，避免本实例广播和心跳轮询重复推送
+	seen := make(map[int]string, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		seen[item.Index] = item.Status
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			// 任务可能由其他实例处理，进度不会广播到本实例，需从数据库同步
+			polled, err := h.jobService.Poll(c.Request.Context(), userID, snapshot.ID, seen)
+			if err != nil {
+				c.SSEvent("ping", time.Now().Unix())
+				return true
+			}
+			for _, event := range polled {
+				if event.Type == "done" {
+					c.SSEvent("done", event.Job)
+					return false
+				}
+				c.SSEvent("item", event.Item)
+			}
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case event := <-events:
+			if event.Type == "item" {
+				if seen[event.Item.Index] == event.Item.Status {
+					return true
+				}
+				seen[event.Item.Index] = event.Item.Status
+				c.SSEvent("item", event.Item)
+				return true
+			}
+			c.SSEvent(event.Type, event.Job)
+			return false
+		}
+	})
+}
@@ -1,8 +1,7 @@
 package handler
 
 import (
-	"os"
-	"path/filepath"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -17,13 +16,17 @@ import (
 
 // BillHandler 账单处理器
 type BillHandler struct {
-	billService service.BillServiceInterface
+	billService         service.BillServiceInterface
+	quickEntryService   service.QuickEntryServiceInterface
+	notificationService service.NotificationServiceInterface
 }
 
 // NewBillHandler 创建账单处理器
-func NewBillHandler(billService service.BillServiceInterface) *BillHandler {
+func NewBillHandler(billService service.BillServiceInterface, quickEntryService service.QuickEntryServiceInterface, notificationService service.NotificationServiceInterface) *BillHandler {
 	return &BillHandler{
-		billService: billService,
+		billService:         billService,
+		quickEntryService:   quickEntryService,
+		notificationService: notificationService,
 	}
 }
 
@@ -102,6 +105,9 @@ func (h *BillHandler) Get(c *gin.Context) {
 // @Param category_id query int false "分类ID"
 // @Param bill_type query int false "账单类型 (1:支出 2:收入)"
 // @Param keyword query string false "关键词"
+// @Param is_confirmed query bool false "是否已确认"
+// @Param min_confidence query number false "最低置信度 (0-1)"
+// @Param max_confidence query number false "最高置信度 (0-1)"
 // @Success 200 {object} response.Response{data=dto.BillListResponse}
 // @Router /bills [get]
 func (h *BillHandler) List(c *gin.Context) {
@@ -126,6 +132,135 @@ func (h *BillHandler) List(c *gin.Context) {
 	response.Success(c, resp)
 }
 
+// ListReview 获取待复核账单
+// @Summary 获取未确认的账单，按置信度从低到高排列
+// @Tags 账单
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param start_date query string false "开始日期 (2006-01-02)"
+// @Param end_date query string false "结束日期 (2006-01-02)"
+// @Param max_confidence query number false "最高置信度 (0-1)"
+// @Success 200 {object} response.Response{data=dto.BillListResponse}
+// @Router /bills/review [get]
+func (h *BillHandler) ListReview(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.BillListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.billService.ListReview(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Review 批量复核账单
+// @Summary 批量确认或驳回未确认的账单，驳回会删除账单及其截图
+// @Tags 账单
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body dto.ReviewBillsRequest true "复核信息"
+// @Success 200 {object} response.Response{data=dto.ReviewBillsResponse}
+// @Router /bills/review [post]
+func (h *BillHandler) Review(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.ReviewBillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.billService.Review(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// QuickEntry 一句话记账
+// @Summary 解析“午饭 35 微信”“昨天打车 28.5”等一句话记账文本，返回账单草稿，可选直接保存
+// @Description AI未启用或解析失败时使用规则解析，响应中的 source 说明解析方式
+// @Tags 账单
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body dto.QuickEntryRequest true "记账文本"
+// @Success 200 {object} response.Response{data=dto.QuickEntryResponse}
+// @Router /bills/quick [post]
+func (h *BillHandler) QuickEntry(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.QuickEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.quickEntryService.Parse(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// IngestNotification 支付通知/短信记账
+// @Summary 解析银行动账短信或微信、支付宝支付通知，创建待复核账单
+// @Description 优先使用内置的银行、微信、支付宝规则解析，无法解析时使用AI；同一条通知重复推送时返回已有账单（bill.duplicate=true）
+// @Tags 账单
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body dto.IngestNotificationRequest true "通知内容"
+// @Success 200 {object} response.Response{data=dto.IngestNotificationResponse}
+// @Router /bills/notifications [post]
+func (h *BillHandler) IngestNotification(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.IngestNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.notificationService.Ingest(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
 // Update 更新账单
 // @Summary 更新账单
 // @Tags 账单
@@ -194,6 +329,38 @@ func (h *BillHandler) Delete(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+// GetImage 获取账单截图
+// @Summary 获取账单截图
+// @Tags 账单
+// @Produce octet-stream
+// @Security Bearer
+// @Param id path int true "账单ID"
+// @Success 200 {file} file
+// @Router /bills/{id}/image [get]
+func (h *BillHandler) GetImage(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的账单ID")
+		return
+	}
+
+	reader, contentType, err := h.billService.GetImage(c.Request.Context(), userID, id)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, nil)
+}
+
+// Import 导入账单文件，parser_type 为 auto 时根据表头自动识别账单来源
 func (h *BillHandler) Import(c *gin.Context) {
 	parseType := c.PostForm("parser_type")
 	if parseType == "" {
@@ -201,34 +368,40 @@ func (h *BillHandler) Import(c *gin.Context) {
 		return
 	}
 
-	// 2. 获取上传的文件
 	file, err := c.FormFile("file")
 	if err != nil {
 		response.ParamError(c, "请上传文件")
 		return
 	}
-	userID := c.GetUint64("user_id")
-	// 3. 保存临时文件
-	tempDir := filepath.Join(os.TempDir(), "smart-ledger-upload")
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		logger.Log.Error("创建临时目录失败", zap.Error(err))
-		response.Error(c, errcode.ErrServer.WithMessage(err.Error()))
-		return
-	}
-	tempPath := filepath.Join(tempDir, file.Filename)
-	if err := c.SaveUploadedFile(file, tempPath); err != nil {
-		logger.Log.Error("保存上传的文件失败", zap.Error(err))
-		response.Error(c, errcode.ErrServer.WithMessage(err.Error()))
+	src, err := file.Open()
+	if err != nil {
+		logger.Log.Error("打开上传的文件失败", zap.Error(err))
+		response.ServerError(c)
 		return
 	}
-	defer os.Remove(tempPath) // 处理完成后删除
+	defer src.Close()
 
-	// 4. 调用 Service 导入
-	result, err := h.billService.ImportFromExcel(c.Request.Context(), userID, tempPath, parseType)
+	userID := c.GetUint64("user_id")
+	result, err := h.billService.Import(c.Request.Context(), userID, src, file.Filename, parseType)
 	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
 		logger.Log.Error("handler调用billService导入账单失败", zap.Error(err))
-		response.Error(c, errcode.ErrServer.WithMessage(err.Error()))
+		response.ServerError(c)
 		return
 	}
 	response.Success(c, result)
 }
+
+// ListImportParsers 获取支持的账单导入来源，用于客户端展示导入选项
+// @Summary 获取支持的账单导入来源
+// @Tags 账单导入
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=[]dto.ImportParserResponse}
+// @Router /bills/import/parsers [get]
+func (h *BillHandler) ListImportParsers(c *gin.Context) {
+	response.Success(c, h.billService.ListImportParsers())
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/response"
+	"smart-ledger-server/internal/service"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// ImportProfileHandler 账单导入配置处理器
+type ImportProfileHandler struct {
+	profileService service.ImportProfileServiceInterface
+}
+
+// NewImportProfileHandler 创建账单导入配置处理器
+func NewImportProfileHandler(profileService service.ImportProfileServiceInterface) *ImportProfileHandler {
+	return &ImportProfileHandler{
+		profileService: profileService,
+	}
+}
+
+// List 获取导入配置列表
+// @Summary 获取导入配置列表
+// @Tags 账单导入
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} response.Response{data=[]dto.ImportProfileResponse}
+// @Router /import-profiles [get]
+func (h *ImportProfileHandler) List(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+	resp, err := h.profileService.List(c.Request.Context(), userID)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Create 创建导入配置
+// @Summary 创建导入配置
+// @Tags 账单导入
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param body body dto.CreateImportProfileRequest true "导入配置"
+// @Success 200 {object} response.Response{data=dto.ImportProfileResponse}
+// @Router /import-profiles [post]
+func (h *ImportProfileHandler) Create(c *gin.Context) {
+	var req dto.CreateImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	userID := c.GetUint64("user_id")
+	resp, err := h.profileService.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Update 更新导入配置
+// @Summary 更新导入配置
+// @Tags 账单导入
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "导入配置ID"
+// @Param body body dto.UpdateImportProfileRequest true "更新信息"
+// @Success 200 {object} response.Response{data=dto.ImportProfileResponse}
+// @Router /import-profiles/{id} [put]
+func (h *ImportProfileHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的导入配置ID")
+		return
+	}
+
+	var req dto.UpdateImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	userID := c.GetUint64("user_id")
+	resp, err := h.profileService.Update(c.Request.Context(), userID, id, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Delete 删除导入配置
+// @Summary 删除导入配置
+// @Tags 账单导入
+// @Produce json
+// @Security Bearer
+// @Param id path int true "导入配置ID"
+// @Success 200 {object} response.Response
+// @Router /import-profiles/{id} [delete]
+func (h *ImportProfileHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的导入配置ID")
+		return
+	}
+
+	userID := c.GetUint64("user_id")
+	if err := h.profileService.Delete(c.Request.Context(), userID, id); err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Preview 按列映射预览上传文件的前 N 行，未设置列映射时只返回表头列名
+// @Summary 预览导入
+// @Tags 账单导入
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "账单文件（xlsx/csv）"
+// @Param mapping formData string false "列映射（JSON）"
+// @Param limit formData int false "预览行数，默认 10，最多 50"
+// @Success 200 {object} response.Response{data=dto.ImportPreviewResponse}
+// @Router /import-profiles/preview [post]
+func (h *ImportProfileHandler) Preview(c *gin.Context) {
+	var req dto.ImportPreviewRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	var mapping dto.ImportMapping
+	if req.Mapping != "" {
+		if err := json.Unmarshal([]byte(req.Mapping), &mapping); err != nil {
+			response.ParamError(c, "列映射格式错误")
+			return
+		}
+		if err := binding.Validator.ValidateStruct(&mapping); err != nil {
+			response.ParamError(c, err.Error())
+			return
+		}
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.ParamError(c, "请上传文件")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		logger.Log.Error("打开上传的文件失败", zap.Error(err))
+		response.ServerError(c)
+		return
+	}
+	defer src.Close()
+
+	resp, err := h.profileService.Preview(c.Request.Context(), src, file.Filename, &mapping, req.Limit)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Import 按导入配置导入账单文件
+// @Summary 按导入配置导入账单
+// @Tags 账单导入
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param id path int true "导入配置ID"
+// @Param file formData file true "账单文件（xlsx/csv）"
+// @Success 200 {object} response.Response{data=dto.BillImportResponse}
+// @Router /import-profiles/{id}/import [post]
+func (h *ImportProfileHandler) Import(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ParamError(c, "无效的导入配置ID")
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.ParamError(c, "请上传文件")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		logger.Log.Error("打开上传的文件失败", zap.Error(err))
+		response.ServerError(c)
+		return
+	}
+	defer src.Close()
+
+	userID := c.GetUint64("user_id")
+	resp, err := h.profileService.Import(c.Request.Context(), userID, id, src, file.Filename)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		logger.Log.Error("按导入配置导入账单失败", zap.Error(err))
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
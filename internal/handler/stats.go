@@ -11,13 +11,17 @@ import (
 
 // StatsHandler 统计处理器
 type StatsHandler struct {
-	statsService service.StatsServiceInterface
+	statsService   service.StatsServiceInterface
+	askService     service.AskServiceInterface
+	insightService service.InsightServiceInterface
 }
 
 // NewStatsHandler 创建统计处理器
-func NewStatsHandler(statsService service.StatsServiceInterface) *StatsHandler {
+func NewStatsHandler(statsService service.StatsServiceInterface, askService service.AskServiceInterface, insightService service.InsightServiceInterface) *StatsHandler {
 	return &StatsHandler{
-		statsService: statsService,
+		statsService:   statsService,
+		askService:     askService,
+		insightService: insightService,
 	}
 }
 
@@ -29,6 +33,7 @@ func NewStatsHandler(statsService service.StatsServiceInterface) *StatsHandler {
 // @Security Bearer
 // @Param period query string true "统计周期 (day/week/month/year)"
 // @Param date query string true "日期 (day:2006-01-02, week:2006-01-02, month:2006-01, year:2006)"
+// @Param confirmed_only query bool false "仅统计已确认账单"
 // @Success 200 {object} response.Response{data=dto.StatsSummaryResponse}
 // @Router /stats/summary [get]
 func (h *StatsHandler) GetSummary(c *gin.Context) {
@@ -81,6 +86,7 @@ func (h *StatsHandler) GetSecondaryCategoryStats(c *gin.Context) {
 // @Security Bearer
 // @Param period query string true "统计周期 (day/week/month/year)"
 // @Param date query string true "日期"
+// @Param confirmed_only query bool false "仅统计已确认账单"
 // @Success 200 {object} response.Response{data=dto.CategoryStatsResponse}
 // @Router /stats/category [get]
 func (h *StatsHandler) GetCategoryStats(c *gin.Context) {
@@ -104,3 +110,66 @@ func (h *StatsHandler) GetCategoryStats(c *gin.Context) {
 
 	response.Success(c, resp)
 }
+
+// Ask 用自然语言查询账本
+// @Summary 用自然语言查询账本
+// @Description AI将问题转换为查询计划，经校验后使用统计接口计算结果，返回金额、回答和统计口径
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.AskLedgerRequest true "问题"
+// @Success 200 {object} response.Response{data=dto.AskLedgerResponse}
+// @Router /stats/ask [post]
+func (h *StatsHandler) Ask(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.AskLedgerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.askService.Ask(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetInsight 获取月度消费洞察
+// @Summary 获取月度消费洞察
+// @Description 后台任务每月初为上个月有账单的用户生成，包含服务端计算的统计数据和AI撰写的变化分析、商户提醒与节省建议
+// @Tags 统计
+// @Produce json
+// @Security Bearer
+// @Param month query string false "月份（2006-01），默认上个月"
+// @Success 200 {object} response.Response{data=dto.MonthlyInsightResponse}
+// @Router /stats/insights [get]
+func (h *StatsHandler) GetInsight(c *gin.Context) {
+	userID := c.GetUint64("user_id")
+
+	var req dto.MonthlyInsightRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	resp, err := h.insightService.Get(c.Request.Context(), userID, &req)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrCode); ok {
+			response.Error(c, e)
+			return
+		}
+		response.ServerError(c)
+		return
+	}
+
+	response.Success(c, resp)
+}
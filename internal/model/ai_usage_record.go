@@ -0,0 +1,39 @@
+package model
+
+// UsageStatus AI调用状态
+type UsageStatus string
+
+const (
+	UsageStatusReserved  UsageStatus = "reserved"  // 已预留配额，调用进行中
+	UsageStatusSucceeded UsageStatus = "succeeded" // 调用成功
+	UsageStatusFailed    UsageStatus = "failed"    // 调用失败，不计入配额
+)
+
+// UsageSource AI调用来源
+type UsageSource string
+
+const (
+	UsageSourceUser   UsageSource = "user"   // 用户发起的调用，计入用户配额
+	UsageSourceSystem UsageSource = "system" // 后台任务（如月度消费洞察）发起的调用，只用于统计费用，不计入配额
+)
+
+// AIUsageRecord AI调用用量记录
+// 调用前先写入 reserved 记录占用配额，调用结束后更新为成功或失败
+type AIUsageRecord struct {
+	BaseModel
+	UserID           uint64      `gorm:"not null;index:idx_user_created,priority:1" json:"user_id"` // 所属用户ID
+	Provider         string      `gorm:"type:varchar(50)" json:"provider"`                          // 提供方名称
+	Model            string      `gorm:"type:varchar(100)" json:"model"`                            // 模型名称
+	PromptTokens     int64       `gorm:"default:0" json:"prompt_tokens"`                            // 输入 token 数
+	CompletionTokens int64       `gorm:"default:0" json:"completion_tokens"`                        // 输出 token 数
+	LatencyMs        int64       `gorm:"default:0" json:"latency_ms"`                               // 调用耗时(毫秒)
+	Status           UsageStatus `gorm:"type:varchar(16);not null;default:reserved" json:"status"`  // 调用状态
+	Source           UsageSource `gorm:"type:varchar(16);not null;default:user" json:"source"`      // 调用来源
+	Cached           bool        `gorm:"default:false" json:"cached"`                               // 是否命中识别结果缓存
+	ErrorMessage     string      `gorm:"type:varchar(500)" json:"error_message"`                    // 失败原因
+}
+
+// TableName 指定表名
+func (AIUsageRecord) TableName() string {
+	return "ai_usage_records"
+}
@@ -27,15 +27,20 @@ type Bill struct {
 	PayTime       time.Time       `gorm:"type:datetime;not null;index" json:"pay_time"`      // 支付时间
 	PayMethod     string          `gorm:"type:varchar(50)" json:"pay_method"`                // 支付方式（如：余额、银行卡）
 	OrderNo       string          `gorm:"type:varchar(100)" json:"order_no"`                 // 订单号
+	InvoiceNo     string          `gorm:"type:varchar(32)" json:"invoice_no"`                // 电子发票号码
 	Remark        string          `gorm:"type:varchar(500)" json:"remark"`                   // 备注信息
 	ImagePath     string          `gorm:"type:varchar(255)" json:"image_path"`               // 支付截图路径
+	ImageHash     string          `gorm:"type:varchar(64)" json:"-"`                         // 支付截图内容摘要（用于识别重复上传）
+	SourceHash    string          `gorm:"type:varchar(64)" json:"-"`                         // 支付通知/短信原文摘要（用于识别重复推送）
 	AIRawResponse string          `gorm:"type:text" json:"-"`                                // AI识别原始响应（不输出到JSON）
 	Confidence    float64         `gorm:"type:decimal(3,2)" json:"confidence"`               // AI识别置信度（0-1）
+	PromptVersion string          `gorm:"type:varchar(32)" json:"prompt_version"`            // AI识别使用的提示词版本
 	IsConfirmed   bool            `gorm:"default:false" json:"is_confirmed"`                 // 是否已确认（用户确认AI识别结果）
 
 	// 关联
-	User     *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`         // 所属用户
-	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"` // 所属分类
+	User     *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`         // 所属用户
+	Category *Category  `gorm:"foreignKey:CategoryID" json:"category,omitempty"` // 所属分类
+	Items    []BillItem `gorm:"foreignKey:BillID" json:"items,omitempty"`        // 账单明细
 }
 
 // TableName 指定表名
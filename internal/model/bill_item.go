@@ -0,0 +1,18 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// BillItem 账单明细（商品行）
+type BillItem struct {
+	BaseModel
+	BillID    uint64          `gorm:"index;not null" json:"bill_id"`               // 所属账单ID
+	Name      string          `gorm:"type:varchar(255);not null" json:"name"`      // 商品名称
+	Price     decimal.Decimal `gorm:"type:decimal(10,2);not null" json:"price"`    // 单价
+	Quantity  decimal.Decimal `gorm:"type:decimal(10,3);not null" json:"quantity"` // 数量（支持称重商品的小数）
+	SortOrder int             `gorm:"default:0" json:"sort_order"`                 // 排序（保持小票上的顺序）
+}
+
+// TableName 指定表名
+func (BillItem) TableName() string {
+	return "bill_items"
+}
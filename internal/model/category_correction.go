@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// CategoryCorrection 用户对AI识别分类的修正记录
+// 同一用户、商户、分类只保留一条，重复修正时累加命中次数
+type CategoryCorrection struct {
+	BaseModel
+	UserID          uint64    `gorm:"not null;uniqueIndex:uk_user_merchant_category,priority:1" json:"user_id"`                    // 所属用户ID
+	Merchant        string    `gorm:"type:varchar(255);not null;uniqueIndex:uk_user_merchant_category,priority:2" json:"merchant"` // 商户名称（已规范化）
+	CategoryID      uint64    `gorm:"not null;uniqueIndex:uk_user_merchant_category,priority:3" json:"category_id"`                // 修正后的分类ID
+	Platform        string    `gorm:"type:varchar(50)" json:"platform"`                                                            // 支付平台
+	BillType        BillType  `gorm:"default:1" json:"bill_type"`                                                                  // 修正后的账单类型
+	HitCount        int       `gorm:"default:1" json:"hit_count"`                                                                  // 修正次数
+	LastCorrectedAt time.Time `gorm:"type:datetime" json:"last_corrected_at"`                                                      // 最近一次修正时间
+
+	// 关联
+	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"` // 修正后的分类
+}
+
+// TableName 指定表名
+func (CategoryCorrection) TableName() string {
+	return "category_corrections"
+}
@@ -23,37 +23,49 @@ type RegisterRequest struct {
 
 // UpdateProfileRequest 更新用户信息请求
 type UpdateProfileRequest struct {
-	Nickname  string `json:"nickname" binding:"max=50"`
-	AvatarURL string `json:"avatar_url" binding:"max=255"`
+	Nickname             string   `json:"nickname" binding:"max=50"`
+	AvatarURL            string   `json:"avatar_url" binding:"max=255"`
+	AutoConfirmThreshold *float64 `json:"auto_confirm_threshold" binding:"omitempty,min=0,max=1"` // AI账单自动确认阈值，0 表示关闭
+	// RecognitionInstructions AI识别自定义说明，如“打车账单标注可报销”，传空字符串清除
+	RecognitionInstructions *string `json:"recognition_instructions" binding:"omitempty,max=500"`
 }
 
 // =============== 账单相关 ===============
 
 // CreateBillRequest 创建账单请求
 type CreateBillRequest struct {
-	Amount     decimal.Decimal `json:"amount" binding:"required"`
-	BillType   int             `json:"bill_type" binding:"required,oneof=1 2"`
-	Platform   string          `json:"platform" binding:"max=50"`
-	Merchant   string          `json:"merchant" binding:"max=255"`
-	CategoryID *uint64         `json:"category_id"`
-	PayTime    time.Time       `json:"pay_time" binding:"required"`
-	PayMethod  string          `json:"pay_method" binding:"max=50"`
-	OrderNo    string          `json:"order_no" binding:"max=100"`
-	Remark     string          `json:"remark" binding:"max=500"`
+	Amount     decimal.Decimal   `json:"amount" binding:"required"`
+	BillType   int               `json:"bill_type" binding:"required,oneof=1 2"`
+	Platform   string            `json:"platform" binding:"max=50"`
+	Merchant   string            `json:"merchant" binding:"max=255"`
+	CategoryID *uint64           `json:"category_id"`
+	PayTime    time.Time         `json:"pay_time" binding:"required"`
+	PayMethod  string            `json:"pay_method" binding:"max=50"`
+	OrderNo    string            `json:"order_no" binding:"max=100"`
+	Remark     string            `json:"remark" binding:"max=500"`
+	Items      []BillItemRequest `json:"items" binding:"omitempty,max=200,dive"`
+}
+
+// BillItemRequest 账单明细请求
+type BillItemRequest struct {
+	Name     string          `json:"name" binding:"required,max=255"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"` // 为空时默认为 1
 }
 
 // UpdateBillRequest 更新账单请求
 type UpdateBillRequest struct {
-	Amount      decimal.Decimal `json:"amount"`
-	BillType    int             `json:"bill_type" binding:"omitempty,oneof=1 2"`
-	Platform    string          `json:"platform" binding:"max=50"`
-	Merchant    string          `json:"merchant" binding:"max=255"`
-	CategoryID  *uint64         `json:"category_id"`
-	PayTime     *time.Time      `json:"pay_time"`
-	PayMethod   string          `json:"pay_method" binding:"max=50"`
-	OrderNo     string          `json:"order_no" binding:"max=100"`
-	Remark      string          `json:"remark" binding:"max=500"`
-	IsConfirmed *bool           `json:"is_confirmed"`
+	Amount      decimal.Decimal    `json:"amount"`
+	BillType    int                `json:"bill_type" binding:"omitempty,oneof=1 2"`
+	Platform    string             `json:"platform" binding:"max=50"`
+	Merchant    string             `json:"merchant" binding:"max=255"`
+	CategoryID  *uint64            `json:"category_id"`
+	PayTime     *time.Time         `json:"pay_time"`
+	PayMethod   string             `json:"pay_method" binding:"max=50"`
+	OrderNo     string             `json:"order_no" binding:"max=100"`
+	Remark      string             `json:"remark" binding:"max=500"`
+	IsConfirmed *bool              `json:"is_confirmed"`
+	Items       *[]BillItemRequest `json:"items" binding:"omitempty,max=200,dive"` // 为空不修改，传空数组清空明细
 }
 
 // BillListRequest 账单列表请求
@@ -65,6 +77,62 @@ type BillListRequest struct {
 	CategoryID uint64 `form:"category_id"`
 	BillType   int    `form:"bill_type" binding:"omitempty,oneof=1 2"`
 	Keyword    string `form:"keyword" binding:"max=100"`
+	// 复核相关筛选
+	IsConfirmed   *bool    `form:"is_confirmed"`
+	MinConfidence *float64 `form:"min_confidence" binding:"omitempty,min=0,max=1"`
+	MaxConfidence *float64 `form:"max_confidence" binding:"omitempty,min=0,max=1"`
+}
+
+// ReviewBillsRequest 批量复核账单请求
+type ReviewBillsRequest struct {
+	IDs    []uint64 `json:"ids" binding:"required,min=1,max=100"`
+	Action string   `json:"action" binding:"required,oneof=confirm reject"` // confirm=确认，reject=驳回（删除账单）
+}
+
+// QuickEntryRequest 一句话记账请求
+type QuickEntryRequest struct {
+	Text     string `json:"text" binding:"required,max=200"` // 如“午饭 35 微信”“昨天打车 28.5”
+	Timezone string `json:"timezone" binding:"max=64"`       // IANA 时区名，用于换算“昨天”等相对日期，默认 Asia/Shanghai
+	Save     bool   `json:"save"`                            // 是否直接保存为账单
+}
+
+// IngestNotificationRequest 支付通知/短信记账请求
+type IngestNotificationRequest struct {
+	Text       string     `json:"text" binding:"required,max=1000"` // 通知或短信原文
+	Sender     string     `json:"sender" binding:"max=100"`         // 短信发送号码（如 95588）或通知来源应用包名（如 com.tencent.mm）
+	ReceivedAt *time.Time `json:"received_at"`                      // 收到通知的时间，用于补全正文中缺少的日期并识别重复推送，默认为当前时间
+}
+
+// ReRecognizeRequest 重新识别请求
+type ReRecognizeRequest struct {
+	Apply bool `form:"apply"` // 是否将识别结果写回账单，默认只返回差异
+}
+
+// SaveAITransactionsRequest 保存多笔识别交易请求，仅包含用户在预览中勾选的交易
+type SaveAITransactionsRequest struct {
+	Transactions []AITransactionRequest `json:"transactions" binding:"required,min=1,max=100,dive"`
+}
+
+// AITransactionRequest 待保存的识别交易，字段与识别结果一致，用户可在预览时修改
+type AITransactionRequest struct {
+	Platform    string          `json:"platform" binding:"max=50"`
+	Amount      decimal.Decimal `json:"amount" binding:"required"`
+	Merchant    string          `json:"merchant" binding:"max=255"`
+	Category    string          `json:"category" binding:"max=50"`
+	SubCategory string          `json:"sub_category" binding:"max=50"`
+	PayTime     string          `json:"pay_time"`
+	PayMethod   string          `json:"pay_method" binding:"max=50"`
+	OrderNo     string          `json:"order_no" binding:"max=100"`
+	BillType    int             `json:"bill_type" binding:"required,oneof=1 2"`
+	Confidence  float64         `json:"confidence" binding:"min=0,max=1"`
+	Remark      string          `json:"remark" binding:"max=500"`
+	// PromptVersion 识别结果中的提示词版本，原样传回
+	PromptVersion string `json:"prompt_version" binding:"max=32"`
+}
+
+// SubmitAIJobRequest 提交异步识别任务请求
+type SubmitAIJobRequest struct {
+	Save bool `form:"save"` // 识别成功后是否自动创建账单
 }
 
 // ImportBillRequest 导入账单请求
@@ -72,6 +140,37 @@ type ImportBillRequest struct {
 	parserType string `form:"parser_type" binding:"required"`
 }
 
+// ImportMapping 账单导入的列映射
+type ImportMapping struct {
+	Sheet         string            `json:"sheet" binding:"max=100"`                                                 // xlsx 工作表名称，为空时使用第一个工作表
+	HeaderRow     int               `json:"header_row" binding:"min=0,max=50"`                                       // 表头所在行号（从 1 开始），为 0 时使用第 1 行
+	Columns       map[string]string `json:"columns"`                                                                 // 字段 → 表头中的列名，字段见 README
+	DateFormat    string            `json:"date_format" binding:"max=50"`                                            // 日期格式，如 yyyy-MM-dd HH:mm:ss，为空时自动识别
+	TypeRule      string            `json:"type_rule" binding:"omitempty,oneof=sign column split"`                   // 收支判断规则：sign=金额正负，column=收支列，split=收入支出分列
+	AmountSign    string            `json:"amount_sign" binding:"omitempty,oneof=expense_negative expense_positive"` // 金额符号约定，type_rule 为 sign 时使用
+	IncomeValues  []string          `json:"income_values" binding:"max=20"`                                          // 收支列中表示收入的值
+	ExpenseValues []string          `json:"expense_values" binding:"max=20"`                                         // 收支列中表示支出的值
+	Platform      string            `json:"platform" binding:"max=50"`                                               // 导入账单的支付平台，如招商银行
+}
+
+// CreateImportProfileRequest 创建导入配置请求
+type CreateImportProfileRequest struct {
+	Name    string        `json:"name" binding:"required,max=50"`
+	Mapping ImportMapping `json:"mapping"`
+}
+
+// UpdateImportProfileRequest 更新导入配置请求
+type UpdateImportProfileRequest struct {
+	Name    string         `json:"name" binding:"max=50"`
+	Mapping *ImportMapping `json:"mapping"` // 为空不修改
+}
+
+// ImportPreviewRequest 导入预览请求（multipart 表单，文件字段为 file）
+type ImportPreviewRequest struct {
+	Mapping string `form:"mapping"`                                // 列映射（JSON），未设置列映射时只返回表头列名
+	Limit   int    `form:"limit" binding:"omitempty,min=1,max=50"` // 预览的行数，默认 10
+}
+
 // SetDefaults 设置默认值
 func (r *BillListRequest) SetDefaults() {
 	if r.Page <= 0 {
@@ -86,21 +185,36 @@ func (r *BillListRequest) SetDefaults() {
 
 // StatsSummaryRequest 统计摘要请求
 type StatsSummaryRequest struct {
-	Period string `form:"period" binding:"required,oneof=day week month year"`
-	Date   string `form:"date" binding:"required"`
+	Period        string `form:"period" binding:"required,oneof=day week month year"`
+	Date          string `form:"date" binding:"required"`
+	ConfirmedOnly bool   `form:"confirmed_only"` // 仅统计已确认账单
 }
 
 // StatsCategoryRequest 分类统计请求
 type StatsCategoryRequest struct {
-	Period string `form:"period" binding:"required,oneof=day week month year"`
-	Date   string `form:"date" binding:"required"`
+	Period        string `form:"period" binding:"required,oneof=day week month year"`
+	Date          string `form:"date" binding:"required"`
+	ConfirmedOnly bool   `form:"confirmed_only"` // 仅统计已确认账单
 }
 
 // StatsSecondaryCategoryRequest 二级分类统计请求
 type StatsSecondaryCategoryRequest struct {
-	Period     string `form:"period" binding:"required,oneof=day week month year"`
-	Date       string `form:"date" binding:"required"`
-	CategoryID uint64 `form:"category_id" binding:"required"`
+	Period        string `form:"period" binding:"required,oneof=day week month year"`
+	Date          string `form:"date" binding:"required"`
+	CategoryID    uint64 `form:"category_id" binding:"required"`
+	ConfirmedOnly bool   `form:"confirmed_only"` // 仅统计已确认账单
+}
+
+// MonthlyInsightRequest 月度消费洞察请求
+type MonthlyInsightRequest struct {
+	Month string `form:"month"` // 月份（2006-01），默认上个月
+}
+
+// AskLedgerRequest 自然语言查询账本请求
+type AskLedgerRequest struct {
+	Question      string `json:"question" binding:"required,max=200"` // 如“上个月外卖花了多少？”“今年哪个月交通最贵？”
+	Timezone      string `json:"timezone" binding:"max=64"`           // IANA 时区名，用于换算“上个月”等相对日期，默认 Asia/Shanghai
+	ConfirmedOnly bool   `json:"confirmed_only"`                      // 仅统计已确认账单
 }
 
 // =============== 分类相关 ===============
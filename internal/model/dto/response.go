@@ -23,26 +23,43 @@ type UserResponse struct {
 	AvatarURL   string     `json:"avatar_url"`
 	LastLoginAt *time.Time `json:"last_login_at"`
 	CreatedAt   time.Time  `json:"created_at"`
+	// AutoConfirmThreshold AI账单自动确认阈值，0 表示关闭
+	AutoConfirmThreshold float64 `json:"auto_confirm_threshold"`
+	// RecognitionInstructions AI识别自定义说明
+	RecognitionInstructions string `json:"recognition_instructions"`
 }
 
 // =============== 账单相关 ===============
 
 // BillResponse 账单响应
 type BillResponse struct {
-	ID          uint64            `json:"id"`
-	UUID        string            `json:"uuid"`
-	Amount      decimal.Decimal   `json:"amount"`
-	BillType    int               `json:"bill_type"`
-	Platform    string            `json:"platform"`
-	Merchant    string            `json:"merchant"`
-	Category    *CategoryResponse `json:"category"`
-	PayTime     time.Time         `json:"pay_time"`
-	PayMethod   string            `json:"pay_method"`
-	OrderNo     string            `json:"order_no"`
-	Remark      string            `json:"remark"`
-	Confidence  float64           `json:"confidence"`
-	IsConfirmed bool              `json:"is_confirmed"`
-	CreatedAt   time.Time         `json:"created_at"`
+	ID            uint64             `json:"id"`
+	UUID          string             `json:"uuid"`
+	Amount        decimal.Decimal    `json:"amount"`
+	BillType      int                `json:"bill_type"`
+	Platform      string             `json:"platform"`
+	Merchant      string             `json:"merchant"`
+	Category      *CategoryResponse  `json:"category"`
+	PayTime       time.Time          `json:"pay_time"`
+	PayMethod     string             `json:"pay_method"`
+	OrderNo       string             `json:"order_no"`
+	InvoiceNo     string             `json:"invoice_no"` // 电子发票号码
+	Remark        string             `json:"remark"`
+	HasImage      bool               `json:"has_image"` // 是否有截图，可通过 /bills/{id}/image 下载
+	Confidence    float64            `json:"confidence"`
+	PromptVersion string             `json:"prompt_version"` // AI识别使用的提示词版本
+	IsConfirmed   bool               `json:"is_confirmed"`
+	Items         []BillItemResponse `json:"items"`
+	CreatedAt     time.Time          `json:"created_at"`
+	Duplicate     bool               `json:"duplicate,omitempty"` // 为 true 时表示该截图或通知已生成过账单，返回的是已有账单
+}
+
+// BillItemResponse 账单明细响应
+type BillItemResponse struct {
+	ID       uint64          `json:"id"`
+	Name     string          `json:"name"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
 }
 
 // BillListResponse 账单列表响应
@@ -53,11 +70,53 @@ type BillListResponse struct {
 	List     []BillResponse `json:"list"`
 }
 
+// ReviewBillsResponse 批量复核账单响应
+type ReviewBillsResponse struct {
+	Affected int64    `json:"affected"` // 实际确认或驳回的账单数量
+	Skipped  []uint64 `json:"skipped"`  // 不存在、无权限或已确认而被跳过的账单ID
+}
+
+// QuickEntryResponse 一句话记账响应
+type QuickEntryResponse struct {
+	Source string          `json:"source"` // 解析方式：ai=AI解析，rule=规则解析（AI未启用或解析失败时）
+	Draft  QuickEntryDraft `json:"draft"`
+	Bill   *BillResponse   `json:"bill,omitempty"` // 请求 save=true 时返回已保存的账单
+}
+
+// QuickEntryDraft 一句话记账解析得到的账单草稿，可直接作为创建账单的参数
+type QuickEntryDraft struct {
+	Amount    decimal.Decimal   `json:"amount"`
+	BillType  int               `json:"bill_type"`
+	Platform  string            `json:"platform"`
+	Merchant  string            `json:"merchant"`
+	Category  *CategoryResponse `json:"category"` // 未匹配到分类时为 null
+	PayTime   time.Time         `json:"pay_time"`
+	PayMethod string            `json:"pay_method"`
+	Remark    string            `json:"remark"`
+}
+
+// IngestNotificationResponse 支付通知/短信记账响应
+type IngestNotificationResponse struct {
+	Source string        `json:"source"`           // 解析方式：rule=规则解析，ai=AI解析（规则无法解析时）
+	Parser string        `json:"parser,omitempty"` // 规则解析时使用的解析器，如 bank_sms、wechat、alipay
+	Bill   *BillResponse `json:"bill"`             // bill.duplicate 为 true 时表示重复通知，返回的是已有账单
+}
+
 // BillImportResponse 账单导入响应
 type BillImportResponse struct {
-	Total  int           `json:"total"`
-	Failed int           `json:"failed"`
-	Errors []ImportError `json:"errors"`
+	ParserType string        `json:"parser_type"` // 使用的解析器类型，自动识别时为识别出的类型
+	Total      int           `json:"total"`
+	Failed     int           `json:"failed"`
+	Skipped    int           `json:"skipped"`    // 不计入收支而跳过的行数（中性交易、退款等）
+	Duplicated int           `json:"duplicated"` // 交易单号已导入过而跳过的行数
+	Errors     []ImportError `json:"errors"`
+}
+
+// ImportParserResponse 账单导入来源
+type ImportParserResponse struct {
+	Type    string   `json:"type"`    // 解析器类型，作为导入接口的 parser_type
+	Name    string   `json:"name"`    // 展示名称
+	Formats []string `json:"formats"` // 支持的文件格式
 }
 
 // ImportError 导入错误详情
@@ -68,20 +127,190 @@ type ImportError struct {
 	Message string            `json:"message"`
 }
 
+// ImportProfileResponse 导入配置响应
+type ImportProfileResponse struct {
+	ID        uint64        `json:"id"`
+	Name      string        `json:"name"`
+	Mapping   ImportMapping `json:"mapping"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ImportPreviewResponse 导入预览响应
+type ImportPreviewResponse struct {
+	Columns []string           `json:"columns"` // 表头行的列名，用于设置列映射
+	Rows    []ImportPreviewRow `json:"rows"`    // 按列映射解析的前 N 行
+	Errors  []ImportError      `json:"errors"`  // 前 N 行中解析失败的行
+	Skipped int                `json:"skipped"` // 不计收支而跳过的行数（全部行）
+	Total   int                `json:"total"`   // 可导入的行数（全部行）
+}
+
+// ImportPreviewRow 导入预览中的一行账单
+type ImportPreviewRow struct {
+	Row       int       `json:"row"`
+	PayTime   time.Time `json:"pay_time"`
+	Amount    string    `json:"amount"`
+	BillType  int       `json:"bill_type"`
+	Merchant  string    `json:"merchant"`
+	Category  string    `json:"category"`
+	Remark    string    `json:"remark"`
+	OrderNo   string    `json:"order_no"`
+	PayMethod string    `json:"pay_method"`
+}
+
 // =============== AI 识别相关 ===============
 
 // AIRecognizeResponse AI识别响应
 type AIRecognizeResponse struct {
-	Platform    string          `json:"platform"`
-	Amount      decimal.Decimal `json:"amount"`
-	Merchant    string          `json:"merchant"`
-	Category    string          `json:"category"`
-	SubCategory string          `json:"sub_category"`
-	PayTime     string          `json:"pay_time"`
-	PayMethod   string          `json:"pay_method"`
-	OrderNo     string          `json:"order_no"`
-	BillType    int             `json:"bill_type"` // 1=支出, 2=收入
-	Confidence  float64         `json:"confidence"`
+	Platform      string            `json:"platform"`
+	Amount        decimal.Decimal   `json:"amount"`
+	Merchant      string            `json:"merchant"`
+	Category      string            `json:"category"`
+	SubCategory   string            `json:"sub_category"`
+	PayTime       string            `json:"pay_time"`
+	PayMethod     string            `json:"pay_method"`
+	OrderNo       string            `json:"order_no"`
+	InvoiceNo     string            `json:"invoice_no,omitempty"` // 电子发票号码，仅识别PDF发票时返回
+	BillType      int               `json:"bill_type"`            // 1=支出, 2=收入
+	Confidence    float64           `json:"confidence"`
+	Items         []AIRecognizeItem `json:"items"`
+	Remark        string            `json:"remark"`                   // 按用户自定义说明标注的内容
+	PromptVersion string            `json:"prompt_version,omitempty"` // 识别使用的提示词版本，本地解析时为空
+	RawContent    string            `json:"-"`                        // 模型原始返回内容，随账单保存
+	Usage         *AIUsage          `json:"-"`                        // 本次调用的用量，用于用量统计
+	// DuplicateBillID 已存在的重复账单ID（同一截图，或多笔识别时订单号/时间金额相同），不为空时说明是重复上传
+	DuplicateBillID *uint64 `json:"duplicate_bill_id,omitempty"`
+}
+
+// AIRecognizeListResponse 多笔交易识别结果（账单列表类截图）
+type AIRecognizeListResponse struct {
+	Transactions []AIRecognizeResponse `json:"transactions"`
+	RawContent   string                `json:"-"` // 模型原始返回内容
+	Usage        *AIUsage              `json:"-"` // 本次调用的用量，用于用量统计
+}
+
+// SaveAITransactionsResponse 保存多笔识别交易响应
+type SaveAITransactionsResponse struct {
+	Created    int            `json:"created"`
+	Duplicated int            `json:"duplicated"` // 与已有账单重复而未创建的数量
+	Bills      []BillResponse `json:"bills"`      // 与请求顺序一致，重复的交易返回已有账单并标记 duplicate
+}
+
+// AIUsage AI调用用量
+type AIUsage struct {
+	Provider         string // 提供方名称
+	Model            string // 模型名称
+	PromptTokens     int64  // 输入 token 数
+	CompletionTokens int64  // 输出 token 数
+	Cached           bool   // 是否命中识别结果缓存（未调用模型）
+}
+
+// AIRecognizeItem AI识别的商品明细
+type AIRecognizeItem struct {
+	Name     string          `json:"name"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// FieldChange 字段变更
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ReRecognizeResponse 重新识别响应
+type ReRecognizeResponse struct {
+	Bill        *BillResponse        `json:"bill"`        // 账单（applied 为 true 时为更新后的账单）
+	Recognition *AIRecognizeResponse `json:"recognition"` // 本次识别结果
+	Changes     []FieldChange        `json:"changes"`     // 识别结果与账单的差异
+	Applied     bool                 `json:"applied"`     // 是否已写回账单
+}
+
+// BatchRecognizeItem 批量识别单张图片结果
+type BatchRecognizeItem struct {
+	Index    int                  `json:"index"`
+	FileName string               `json:"file_name"`
+	Success  bool                 `json:"success"`
+	Data     *AIRecognizeResponse `json:"data,omitempty"`
+	Bill     *BillResponse        `json:"bill,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Duration int64                `json:"duration"` // 处理耗时(毫秒)
+}
+
+// BatchRecognizeResponse 批量识别响应
+type BatchRecognizeResponse struct {
+	Total     int                  `json:"total"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Duration  int64                `json:"duration"` // 总耗时(毫秒)
+	Results   []BatchRecognizeItem `json:"results"`
+}
+
+// AIHealthResponse AI服务健康状态响应
+type AIHealthResponse struct {
+	Available bool               `json:"available"` // 是否至少有一个提供方可用
+	Providers []AIProviderHealth `json:"providers"`
+}
+
+// AIProviderHealth AI提供方健康状态
+type AIProviderHealth struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"` // closed, open, half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"` // 熔断恢复试探时间
+}
+
+// AIJobResponse 异步识别任务响应
+type AIJobResponse struct {
+	ID         string              `json:"id"`       // 任务ID（UUID）
+	Status     string              `json:"status"`   // pending, running, completed, failed
+	Finished   bool                `json:"finished"` // 任务是否已结束
+	Save       bool                `json:"save"`     // 识别成功后是否创建账单
+	Total      int                 `json:"total"`
+	Succeeded  int                 `json:"succeeded"`
+	Failed     int                 `json:"failed"`
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Items      []AIJobItemResponse `json:"items,omitempty"`
+}
+
+// AIJobItemResponse 异步识别任务中单张图片的结果
+type AIJobItemResponse struct {
+	Index    int                  `json:"index"`
+	FileName string               `json:"file_name"`
+	Status   string               `json:"status"` // pending, succeeded, failed
+	Data     *AIRecognizeResponse `json:"data,omitempty"`
+	BillID   *uint64              `json:"bill_id,omitempty"` // 创建（或重复上传时已有）的账单ID
+	Error    string               `json:"error,omitempty"`
+	Duration int64                `json:"duration"` // 处理耗时(毫秒)
+}
+
+// AIJobEvent 异步识别任务进度事件
+type AIJobEvent struct {
+	Type string             // item: 单张图片处理完成，done: 任务结束
+	Item *AIJobItemResponse // Type 为 item 时有值
+	Job  *AIJobResponse     // 任务当前状态（不含图片明细）
+}
+
+// AIQuotaResponse AI调用配额响应
+type AIQuotaResponse struct {
+	Daily   AIQuotaPeriod `json:"daily"`
+	Monthly AIQuotaPeriod `json:"monthly"`
+}
+
+// AIQuotaPeriod 单个周期的配额使用情况
+type AIQuotaPeriod struct {
+	Limit            int       `json:"limit"`     // 调用次数上限，0 表示不限制
+	Used             int64     `json:"used"`      // 已调用次数（不含命中缓存的调用）
+	Remaining        int64     `json:"remaining"` // 剩余次数，不限制时为 -1
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	ResetAt          time.Time `json:"reset_at"` // 配额重置时间
 }
 
 // =============== 统计相关 ===============
@@ -118,6 +347,91 @@ type CategoryStatsResponse struct {
 	Categories []CategoryStatsItem `json:"categories"`
 }
 
+// MonthlyInsightResponse 月度消费洞察响应
+type MonthlyInsightResponse struct {
+	Month         string       `json:"month"`
+	Facts         InsightFacts `json:"facts"`       // 服务端计算的统计数据，洞察中的数字均取自这里
+	Summary       string       `json:"summary"`     // 收支概况
+	Changes       []string     `json:"changes"`     // 变化最大的分类
+	Merchants     []string     `json:"merchants"`   // 值得注意的商户
+	Suggestions   []string     `json:"suggestions"` // 节省开支的建议
+	PromptVersion string       `json:"prompt_version"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+}
+
+// InsightFacts 生成月度消费洞察的统计数据，由服务端计算后发送给模型
+type InsightFacts struct {
+	Month                string                  `json:"month"`
+	PreviousMonth        string                  `json:"previous_month"`
+	TotalExpense         decimal.Decimal         `json:"total_expense"`
+	PreviousExpense      decimal.Decimal         `json:"previous_expense"`
+	ExpenseChangePercent *decimal.Decimal        `json:"expense_change_percent,omitempty"` // 上月没有支出时为空
+	TotalIncome          decimal.Decimal         `json:"total_income"`
+	PreviousIncome       decimal.Decimal         `json:"previous_income"`
+	BillCount            int64                   `json:"bill_count"`
+	PreviousBillCount    int64                   `json:"previous_bill_count"`
+	DailyAverage         decimal.Decimal         `json:"daily_average"`
+	CategoryChanges      []InsightCategoryChange `json:"category_changes"`
+	NewMerchants         []InsightMerchant       `json:"new_merchants"`
+	TopMerchants         []InsightMerchant       `json:"top_merchants"`
+}
+
+// InsightCategoryChange 分类支出环比变化
+type InsightCategoryChange struct {
+	Category       string           `json:"category"`
+	Amount         decimal.Decimal  `json:"amount"`
+	PreviousAmount decimal.Decimal  `json:"previous_amount"`
+	Change         decimal.Decimal  `json:"change"`
+	ChangePercent  *decimal.Decimal `json:"change_percent,omitempty"` // 上月没有支出时为空
+}
+
+// InsightMerchant 商户支出
+type InsightMerchant struct {
+	Merchant       string          `json:"merchant"`
+	Amount         decimal.Decimal `json:"amount"`
+	Count          int64           `json:"count"`
+	PreviousAmount decimal.Decimal `json:"previous_amount"`
+}
+
+// AIInsightResponse 模型撰写的月度消费洞察
+type AIInsightResponse struct {
+	Summary       string   `json:"summary"`
+	Changes       []string `json:"changes"`
+	Merchants     []string `json:"merchants"`
+	Suggestions   []string `json:"suggestions"`
+	PromptVersion string   `json:"-"` // 使用的提示词版本
+	RawContent    string   `json:"-"` // 模型原始返回内容
+	Usage         *AIUsage `json:"-"` // 本次调用的用量，用于用量统计
+}
+
+// AskLedgerResponse 自然语言查询账本响应
+type AskLedgerResponse struct {
+	Question    string          `json:"question"`
+	Answer      string          `json:"answer"`          // 一句话回答，如“2025-02 餐饮支出共 1234.50 元”
+	Value       decimal.Decimal `json:"value"`           // 回答中的金额
+	Label       string          `json:"label,omitempty"` // 取最大、最小值时对应的日期、月份或分类
+	Explanation string          `json:"explanation"`     // 按查询计划说明统计口径
+	Plan        AIQueryPlan     `json:"plan"`            // 校验后实际执行的查询计划
+	Rows        []AskLedgerRow  `json:"rows"`            // 按分类、日期或月份的明细，查询总额时为空
+}
+
+// AskLedgerRow 自然语言查询的明细行
+type AskLedgerRow struct {
+	Label  string          `json:"label"` // 分类名、日期（2006-01-02）或月份（2006-01）
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// AIQueryPlan 模型将问题转换得到的查询计划，只能描述对已有统计方法的调用，由服务端校验后执行
+type AIQueryPlan struct {
+	Metric    string   `json:"metric"`     // total=总额，by_category=按分类，by_day=按日，by_month=按月，unsupported=无法回答
+	BillType  int      `json:"bill_type"`  // 1=支出，2=收入
+	StartDate string   `json:"start_date"` // 2006-01-02，含当天
+	EndDate   string   `json:"end_date"`   // 2006-01-02，含当天
+	Category  string   `json:"category"`   // 一级或二级分类名称，为空时不限分类
+	Pick      string   `json:"pick"`       // all=全部明细，max=最大，min=最小，avg=平均
+	Usage     *AIUsage `json:"-"`          // 本次调用的用量，用于用量统计
+}
+
 // =============== 分类相关 ===============
 
 // CategoryResponse 分类响应
@@ -0,0 +1,14 @@
+package model
+
+// ImportProfile 用户保存的账单导入配置，按自定义列映射解析银行流水等没有内置解析器的表格
+type ImportProfile struct {
+	BaseModel
+	UserID  uint64 `gorm:"index;not null" json:"user_id"`         // 所属用户ID
+	Name    string `gorm:"type:varchar(50);not null" json:"name"` // 配置名称（如：招商银行储蓄卡）
+	Mapping string `gorm:"type:text;not null" json:"-"`           // 列映射（JSON）
+}
+
+// TableName 指定表名
+func (ImportProfile) TableName() string {
+	return "import_profiles"
+}
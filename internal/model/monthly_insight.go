@@ -0,0 +1,16 @@
+package model
+
+// MonthlyInsight 用户的月度消费洞察，每个用户每月一条
+type MonthlyInsight struct {
+	BaseModel
+	UserID        uint64 `gorm:"not null;uniqueIndex:uk_user_month,priority:1" json:"user_id"`               // 所属用户ID
+	Month         string `gorm:"type:varchar(7);not null;uniqueIndex:uk_user_month,priority:2" json:"month"` // 统计月份（2006-01）
+	Facts         string `gorm:"type:text" json:"-"`                                                         // 服务端计算的统计数据（JSON）
+	Narrative     string `gorm:"type:text" json:"-"`                                                         // 模型撰写的洞察（JSON），已去除引用统计数据以外数字的句子
+	PromptVersion string `gorm:"type:varchar(32)" json:"prompt_version"`                                     // 使用的提示词版本
+}
+
+// TableName 指定表名
+func (MonthlyInsight) TableName() string {
+	return "monthly_insights"
+}
@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// JobStatus 异步识别任务状态
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"   // 等待处理
+	JobStatusRunning   JobStatus = "running"   // 处理中
+	JobStatusCompleted JobStatus = "completed" // 已完成（单张图片可能失败）
+	JobStatusFailed    JobStatus = "failed"    // 全部图片识别失败
+)
+
+// JobItemStatus 异步识别任务中单张图片的状态
+type JobItemStatus string
+
+const (
+	JobItemStatusPending   JobItemStatus = "pending"   // 等待处理
+	JobItemStatusSucceeded JobItemStatus = "succeeded" // 识别成功
+	JobItemStatusFailed    JobItemStatus = "failed"    // 识别失败
+)
+
+// RecognitionJob 异步识别任务
+type RecognitionJob struct {
+	BaseModel
+	UUID        string     `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`             // 任务唯一标识
+	UserID      uint64     `gorm:"index;not null" json:"user_id"`                                 // 所属用户ID
+	Status      JobStatus  `gorm:"type:varchar(20);not null;index;default:pending" json:"status"` // 任务状态
+	Save        bool       `gorm:"default:false" json:"save"`                                     // 识别成功后是否创建账单
+	Total       int        `gorm:"default:0" json:"total"`                                        // 图片总数
+	Succeeded   int        `gorm:"default:0" json:"succeeded"`                                    // 成功数量
+	Failed      int        `gorm:"default:0" json:"failed"`                                       // 失败数量
+	Error       string     `gorm:"type:varchar(500)" json:"error"`                                // 任务整体失败原因
+	ClaimedBy   string     `gorm:"type:varchar(64)" json:"-"`                                     // 处理实例标识
+	HeartbeatAt *time.Time `gorm:"type:datetime" json:"-"`                                        // 处理实例最近一次心跳时间
+	StartedAt   *time.Time `gorm:"type:datetime" json:"started_at"`                               // 开始处理时间
+	FinishedAt  *time.Time `gorm:"type:datetime" json:"finished_at"`                              // 完成时间
+
+	// 关联
+	Items []RecognitionJobItem `gorm:"foreignKey:JobID" json:"items,omitempty"` // 任务图片
+}
+
+// TableName 指定表名
+func (RecognitionJob) TableName() string {
+	return "recognition_jobs"
+}
+
+// IsFinished 任务是否已结束
+func (j *RecognitionJob) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed
+}
+
+// RecognitionJobItem 异步识别任务中的单张图片
+type RecognitionJobItem struct {
+	BaseModel
+	JobID     uint64        `gorm:"index;not null" json:"job_id"`                            // 所属任务ID
+	Index     int           `gorm:"column:item_index;not null" json:"index"`                 // 上传顺序
+	FileName  string        `gorm:"type:varchar(255)" json:"file_name"`                      // 原始文件名
+	ImagePath string        `gorm:"type:varchar(255)" json:"image_path"`                     // 待识别图片的存储路径
+	MimeType  string        `gorm:"type:varchar(50)" json:"mime_type"`                       // 图片类型
+	Status    JobItemStatus `gorm:"type:varchar(20);not null;default:pending" json:"status"` // 处理状态
+	Result    string        `gorm:"type:text" json:"-"`                                      // 识别结果（JSON）
+	BillID    *uint64       `json:"bill_id"`                                                 // 创建的账单ID
+	Error     string        `gorm:"type:varchar(500)" json:"error"`                          // 失败原因
+	Duration  int64         `gorm:"default:0" json:"duration"`                               // 处理耗时(毫秒)
+}
+
+// TableName 指定表名
+func (RecognitionJobItem) TableName() string {
+	return "recognition_job_items"
+}
@@ -12,6 +12,10 @@ type User struct {
 	Nickname    string     `gorm:"type:varchar(50)" json:"nickname"`
 	AvatarURL   string     `gorm:"type:varchar(255)" json:"avatar_url"`
 	LastLoginAt *time.Time `gorm:"type:datetime" json:"last_login_at"`
+	// AutoConfirmThreshold AI识别置信度不低于该值时自动确认账单，0 表示不自动确认
+	AutoConfirmThreshold float64 `gorm:"type:decimal(3,2);not null;default:0" json:"auto_confirm_threshold"`
+	// RecognitionInstructions 用户自定义识别说明，追加在AI识别提示词末尾
+	RecognitionInstructions string `gorm:"type:varchar(500);not null;default:''" json:"recognition_instructions"`
 }
 
 // TableName 指定表名
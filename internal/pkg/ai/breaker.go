@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常
+	BreakerOpen     BreakerState = "open"      // 熔断中，拒绝请求
+	BreakerHalfOpen BreakerState = "half_open" // 试探中，仅放行一个请求
+)
+
+// CircuitBreaker 熔断器
+// 连续失败达到阈值后熔断，冷却时间过后放行一个试探请求，成功则恢复
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	state               BreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probing             bool // 半开状态下是否已有试探请求在进行
+	lastError           string
+	lastFailureAt       time.Time
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+		state:            BreakerClosed,
+	}
+}
+
+// Allow 判断是否允许请求通过
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		// 冷却结束，进入半开状态放行一个试探请求
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess 记录成功，恢复为关闭状态
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.probing = false
+}
+
+// RecordFailure 记录失败，达到阈值或试探失败时熔断
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures++
+	b.lastFailureAt = b.now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
+
+// Release 释放半开状态下的试探名额（请求未产生可判定结果时调用）
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// ProviderHealth 提供方健康状态
+type ProviderHealth struct {
+	Name                string       // 提供方名称
+	State               BreakerState // 熔断器状态
+	ConsecutiveFailures int          // 连续失败次数
+	LastError           string       // 最近一次错误
+	LastFailureAt       *time.Time   // 最近一次失败时间
+	RetryAt             *time.Time   // 熔断恢复试探时间
+}
+
+// snapshot 获取熔断器当前状态快照
+func (b *CircuitBreaker) snapshot(name string) ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	health := ProviderHealth{
+		Name:                name,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailureAt.IsZero() {
+		lastFailureAt := b.lastFailureAt
+		health.LastFailureAt = &lastFailureAt
+	}
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(b.openTimeout)
+		health.RetryAt = &retryAt
+	}
+	return health
+}
@@ -0,0 +1,278 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"smart-ledger-server/internal/model/dto"
+)
+
+const (
+	// cacheKeyPrefix 识别结果缓存 key 前缀，解析逻辑变化时递增版本号使旧缓存失效
+	cacheKeyPrefix = "ai:recognize:v2:"
+)
+
+// cacheScopeKey 识别结果缓存作用域在 context 中的 key
+type cacheScopeKey struct{}
+
+// WithCacheScope 设置识别结果缓存的作用域（Prompt.CacheScope），未设置时按完整提示词区分缓存
+func WithCacheScope(ctx context.Context, scope string) context.Context {
+	if scope == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheScopeKey{}, scope)
+}
+
+// Cache 识别结果缓存
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// NewCache 创建识别结果缓存，rdb 不为空时使用 Redis，否则使用进程内 LRU
+func NewCache(rdb *redis.Client, size int) Cache {
+	if rdb != nil {
+		return NewRedisCache(rdb)
+	}
+	return NewLRUCache(size)
+}
+
+// ImageHash 计算图片内容的 SHA-256 摘要
+func ImageHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecognitionCacheKey 识别结果缓存 key，scope 为提示词类型、模板版本、用户和分类集合版本
+// 分类或模板变化后落到新的 key 上；历史修正不参与，修正记录变化不会使缓存失效
+func RecognitionCacheKey(imageHash, scope string) string {
+	scopeSum := sha256.Sum256([]byte(scope))
+	return cacheKeyPrefix + imageHash + ":" + hex.EncodeToString(scopeSum[:8])
+}
+
+// recognitionCacheKey 获取本次识别的缓存 key，context 中没有作用域时使用完整提示词
+func recognitionCacheKey(ctx context.Context, imageData []byte, prompt string) string {
+	scope, ok := ctx.Value(cacheScopeKey{}).(string)
+	if !ok {
+		scope = "prompt:" + prompt
+	}
+	return RecognitionCacheKey(ImageHash(imageData), scope)
+}
+
+// cacheEntry 缓存内容，RawContent 不参与 dto 序列化，需单独保存
+type cacheEntry struct {
+	Result     *dto.AIRecognizeResponse `json:"result"`
+	RawContent string                   `json:"raw_content"`
+}
+
+// listCacheEntry 多笔交易识别结果的缓存内容
+type listCacheEntry struct {
+	Result     *dto.AIRecognizeListResponse `json:"result"`
+	RawContent string                       `json:"raw_content"`
+}
+
+// CachedClient 带识别结果缓存的客户端
+// 只缓存识别成功的结果，缓存读写失败时直接调用下游客户端
+type CachedClient struct {
+	client Client
+	cache  Cache
+	ttl    time.Duration
+}
+
+// NewCachedClient 创建带缓存的客户端
+func NewCachedClient(client Client, cache Cache, ttl time.Duration) *CachedClient {
+	return &CachedClient{client: client, cache: cache, ttl: ttl}
+}
+
+// RecognizePayment 识别支付截图，命中缓存时不调用模型
+func (c *CachedClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	key := recognitionCacheKey(ctx, imageData, prompt)
+
+	if data, ok := c.cache.Get(ctx, key); ok {
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && entry.Result != nil {
+			entry.Result.RawContent = entry.RawContent
+			entry.Result.Usage = &dto.AIUsage{Cached: true}
+			return entry.Result, nil
+		}
+	}
+
+	result, err := c.client.RecognizePayment(ctx, imageData, mimeType, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(cacheEntry{Result: result, RawContent: result.RawContent}); err == nil {
+		c.cache.Set(ctx, key, data, c.ttl)
+	}
+	return result, nil
+}
+
+// RecognizeTransactions 识别截图中的多笔交易，命中缓存时不调用模型
+// 多笔识别的提示词类型与单笔不同，两者的缓存 key 不会冲突
+func (c *CachedClient) RecognizeTransactions(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeListResponse, error) {
+	recognizer, ok := c.client.(TransactionRecognizer)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	key := recognitionCacheKey(ctx, imageData, prompt)
+
+	if data, ok := c.cache.Get(ctx, key); ok {
+		var entry listCacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && entry.Result != nil {
+			entry.Result.RawContent = entry.RawContent
+			entry.Result.Usage = &dto.AIUsage{Cached: true}
+			return entry.Result, nil
+		}
+	}
+
+	result, err := recognizer.RecognizeTransactions(ctx, imageData, mimeType, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(listCacheEntry{Result: result, RawContent: result.RawContent}); err == nil {
+		c.cache.Set(ctx, key, data, c.ttl)
+	}
+	return result, nil
+}
+
+// RecognizeText 识别一句话记账文本，文本识别开销小且提示词包含当前时间，不做缓存
+func (c *CachedClient) RecognizeText(ctx context.Context, text string, prompt string) (*dto.AIRecognizeResponse, error) {
+	recognizer, ok := c.client.(TextRecognizer)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return recognizer.RecognizeText(ctx, text, prompt)
+}
+
+// PlanQuery 将自然语言问题转换为账本查询计划，提示词包含当前日期，不做缓存
+func (c *CachedClient) PlanQuery(ctx context.Context, question string, prompt string) (*dto.AIQueryPlan, error) {
+	planner, ok := c.client.(QueryPlanner)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return planner.PlanQuery(ctx, question, prompt)
+}
+
+// WriteInsight 撰写月度消费洞察，每个用户每月只生成一次，不做缓存
+func (c *CachedClient) WriteInsight(ctx context.Context, facts string, prompt string) (*dto.AIInsightResponse, error) {
+	writer, ok := c.client.(InsightWriter)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return writer.WriteInsight(ctx, facts, prompt)
+}
+
+// Health 透传下游客户端的健康状态
+func (c *CachedClient) Health() []ProviderHealth {
+	if reporter, ok := c.client.(HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
+
+// RedisCache 基于 Redis 的缓存，多实例部署时共享
+type RedisCache struct {
+	rdb *redis.Client
+}
+
+// NewRedisCache 创建 Redis 缓存
+func NewRedisCache(rdb *redis.Client) *RedisCache {
+	return &RedisCache{rdb: rdb}
+}
+
+// Get 读取缓存，Redis 不可用时视为未命中
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set 写入缓存，失败时忽略
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	c.rdb.Set(ctx, key, value, ttl)
+}
+
+// lruItem LRU 缓存条目
+type lruItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRUCache 进程内 LRU 缓存
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// NewLRUCache 创建进程内 LRU 缓存
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Get 读取缓存，过期条目视为未命中并移除
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*lruItem)
+	if !item.expiresAt.IsZero() && c.now().After(item.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return item.value, true
+}
+
+// Set 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		item := elem.Value.(*lruItem)
+		item.value = value
+		item.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedClient_RecognizePayment(t *testing.T) {
+	inner := &sequenceClient{errs: []error{errBadRequest}}
+	client := NewCachedClient(inner, NewLRUCache(10), time.Hour)
+	ctx := context.Background()
+
+	// 失败结果不缓存
+	_, err := client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt")
+	require.Error(t, err)
+
+	result, err := client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Merchant)
+	assert.Equal(t, 2, inner.calls)
+
+	// 相同图片和提示词命中缓存
+	result, err = client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Merchant)
+	assert.Equal(t, 2, inner.calls)
+	require.NotNil(t, result.Usage)
+	assert.True(t, result.Usage.Cached)
+
+	// 提示词（分类）变化后重新识别
+	_, err = client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "new prompt")
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestCachedClient_CacheScope(t *testing.T) {
+	inner := &sequenceClient{}
+	client := NewCachedClient(inner, NewLRUCache(10), time.Hour)
+	ctx := WithCacheScope(context.Background(), "recognition:recognition-v2:categories")
+
+	_, err := client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt with hints")
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+
+	// 作用域相同时，提示词中的历史修正变化仍命中缓存
+	result, err := client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt with new hints")
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+	assert.True(t, result.Usage.Cached)
+
+	// 作用域变化（分类变化）后重新识别
+	ctx = WithCacheScope(context.Background(), "recognition:recognition-v2:new-categories")
+	_, err = client.RecognizePayment(ctx, []byte("image"), "image/jpeg", "prompt with new hints")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewLRUCache(2)
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+	_, ok := cache.Get(ctx, "a")
+	require.True(t, ok)
+
+	// 容量满时淘汰最久未使用的 b
+	cache.Set(ctx, "c", []byte("3"), time.Minute)
+	_, ok = cache.Get(ctx, "b")
+	assert.False(t, ok)
+	value, ok := cache.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	// 过期后未命中
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get(ctx, "c")
+	assert.False(t, ok)
+}
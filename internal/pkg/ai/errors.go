@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrAllProvidersUnavailable 所有AI提供方均不可用（熔断或连续失败）
+var ErrAllProvidersUnavailable = errors.New("所有AI提供方均不可用")
+
+// ErrUnsupported AI提供方不支持该识别方式（多笔交易、文本记账等）
+var ErrUnsupported = errors.New("当前AI提供方不支持该识别方式")
+
+// StatusError 提供方返回的非预期 HTTP 状态
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+// Error 实现 error 接口
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API 调用失败: 状态码 %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// IsRetryable 判断错误是否为可重试的瞬时错误（429、5xx、超时、网络错误）
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		// 调用方主动取消，不再重试
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus 429 和 5xx 视为瞬时错误
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"smart-ledger-server/internal/model/dto"
+)
+
+// ErrFakeNoReply FakeClient 没有可用的预设结果
+var ErrFakeNoReply = errors.New("FakeClient 没有预设的返回结果")
+
+// FakeCall FakeClient 收到的一次调用
+type FakeCall struct {
+	Method    string // RecognizePayment、RecognizeText、RecognizeTransactions、PlanQuery 或 WriteInsight
+	ImageData []byte
+	MimeType  string
+	Text      string // 文本记账的内容、查询的问题或消费洞察的统计数据
+	Prompt    string
+}
+
+// FakeReply FakeClient 的预设结果
+type FakeReply struct {
+	Result       *dto.AIRecognizeResponse     // RecognizePayment、RecognizeText 的结果
+	Transactions *dto.AIRecognizeListResponse // RecognizeTransactions 的结果
+	Plan         *dto.AIQueryPlan             // PlanQuery 的结果
+	Insight      *dto.AIInsightResponse       // WriteInsight 的结果
+	Err          error
+	Delay        time.Duration // 返回前等待的时间，期间 ctx 结束时返回 ctx.Err()
+}
+
+// FakeClient 可编排的AI客户端，用于不依赖网络的测试
+// 按调用顺序依次返回预设结果，设置 Handler 时由 Handler 决定每次调用的结果；
+// 返回的识别结果是预设结果的副本，调用方修改不会影响后续调用
+type FakeClient struct {
+	// Handler 不为空时处理全部调用，忽略预设结果
+	Handler func(ctx context.Context, call FakeCall) FakeReply
+
+	mu      sync.Mutex
+	replies []FakeReply
+	calls   []FakeCall
+}
+
+// NewFakeClient 创建按顺序返回 replies 的客户端
+func NewFakeClient(replies ...FakeReply) *FakeClient {
+	return &FakeClient{replies: replies}
+}
+
+// Enqueue 追加预设结果
+func (c *FakeClient) Enqueue(replies ...FakeReply) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies = append(c.replies, replies...)
+}
+
+// Calls 获取已收到的调用
+func (c *FakeClient) Calls() []FakeCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FakeCall(nil), c.calls...)
+}
+
+// RecognizePayment 识别支付截图
+func (c *FakeClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	reply, err := c.reply(ctx, FakeCall{Method: "RecognizePayment", ImageData: imageData, MimeType: mimeType, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	return copyRecognition(reply.Result), nil
+}
+
+// RecognizeText 识别一句话记账文本
+func (c *FakeClient) RecognizeText(ctx context.Context, text string, prompt string) (*dto.AIRecognizeResponse, error) {
+	reply, err := c.reply(ctx, FakeCall{Method: "RecognizeText", Text: text, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	return copyRecognition(reply.Result), nil
+}
+
+// RecognizeTransactions 识别多笔交易截图
+func (c *FakeClient) RecognizeTransactions(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeListResponse, error) {
+	reply, err := c.reply(ctx, FakeCall{Method: "RecognizeTransactions", ImageData: imageData, MimeType: mimeType, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Transactions == nil {
+		return &dto.AIRecognizeListResponse{Transactions: []dto.AIRecognizeResponse{}}, nil
+	}
+	list := *reply.Transactions
+	list.Transactions = append([]dto.AIRecognizeResponse(nil), list.Transactions...)
+	return &list, nil
+}
+
+// PlanQuery 将问题转换为账本查询计划
+func (c *FakeClient) PlanQuery(ctx context.Context, question string, prompt string) (*dto.AIQueryPlan, error) {
+	reply, err := c.reply(ctx, FakeCall{Method: "PlanQuery", Text: question, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Plan == nil {
+		return &dto.AIQueryPlan{Metric: "unsupported"}, nil
+	}
+	plan := *reply.Plan
+	return &plan, nil
+}
+
+// WriteInsight 撰写月度消费洞察
+func (c *FakeClient) WriteInsight(ctx context.Context, facts string, prompt string) (*dto.AIInsightResponse, error) {
+	reply, err := c.reply(ctx, FakeCall{Method: "WriteInsight", Text: facts, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Insight == nil {
+		return &dto.AIInsightResponse{Changes: []string{}, Merchants: []string{}, Suggestions: []string{}}, nil
+	}
+	insight := *reply.Insight
+	return &insight, nil
+}
+
+// reply 记录调用并取出本次的结果，等待 Delay 后返回
+func (c *FakeClient) reply(ctx context.Context, call FakeCall) (FakeReply, error) {
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	handler := c.Handler
+	var reply FakeReply
+	hasReply := len(c.replies) > 0
+	if handler == nil && hasReply {
+		reply = c.replies[0]
+		c.replies = c.replies[1:]
+	}
+	c.mu.Unlock()
+
+	switch {
+	case handler != nil:
+		reply = handler(ctx, call)
+	case !hasReply:
+		return reply, ErrFakeNoReply
+	}
+
+	if reply.Delay > 0 {
+		timer := time.NewTimer(reply.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return reply, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return reply, reply.Err
+}
+
+// copyRecognition 复制识别结果，结果为空时返回空的识别结果
+func copyRecognition(result *dto.AIRecognizeResponse) *dto.AIRecognizeResponse {
+	if result == nil {
+		return &dto.AIRecognizeResponse{}
+	}
+	copied := *result
+	return &copied
+}
@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model/dto"
+)
+
+// HealthReporter 可输出提供方健康状态的客户端
+type HealthReporter interface {
+	Health() []ProviderHealth
+}
+
+// namedProvider 带熔断器的提供方
+type namedProvider struct {
+	name    string
+	client  Client
+	breaker *CircuitBreaker
+}
+
+// FallbackClient 组合客户端
+// 对瞬时错误按抖动退避重试，重试耗尽后切换到下一个提供方，每个提供方独立熔断
+type FallbackClient struct {
+	providers   []namedProvider
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// NewFallbackClient 创建组合客户端，providers 的顺序即为优先级
+func NewFallbackClient(names []string, clients []Client, retry config.RetryConfig, breaker config.CircuitBreakerConfig) *FallbackClient {
+	providers := make([]namedProvider, len(clients))
+	for i, client := range clients {
+		providers[i] = namedProvider{
+			name:    names[i],
+			client:  client,
+			breaker: NewCircuitBreaker(breaker.FailureThreshold, breaker.OpenTimeout),
+		}
+	}
+	maxAttempts := retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &FallbackClient{
+		providers:   providers,
+		maxAttempts: maxAttempts,
+		baseDelay:   retry.BaseDelay,
+		maxDelay:    retry.MaxDelay,
+		sleep:       sleepContext,
+	}
+}
+
+// RecognizePayment 识别支付截图
+func (c *FallbackClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	var result *dto.AIRecognizeResponse
+	name, err := c.do(ctx, nil, func(client Client) error {
+		var err error
+		result, err = client.RecognizePayment(ctx, imageData, mimeType, prompt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = withProvider(result.Usage, name)
+	return result, nil
+}
+
+// RecognizeTransactions 识别截图中的多笔交易，跳过不支持多笔识别的提供方
+func (c *FallbackClient) RecognizeTransactions(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeListResponse, error) {
+	supported := func(client Client) bool {
+		_, ok := client.(TransactionRecognizer)
+		return ok
+	}
+	var result *dto.AIRecognizeListResponse
+	name, err := c.do(ctx, supported, func(client Client) error {
+		var err error
+		result, err = client.(TransactionRecognizer).RecognizeTransactions(ctx, imageData, mimeType, prompt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = withProvider(result.Usage, name)
+	return result, nil
+}
+
+// RecognizeText 识别一句话记账文本，跳过不支持文本识别的提供方
+func (c *FallbackClient) RecognizeText(ctx context.Context, text string, prompt string) (*dto.AIRecognizeResponse, error) {
+	supported := func(client Client) bool {
+		_, ok := client.(TextRecognizer)
+		return ok
+	}
+	var result *dto.AIRecognizeResponse
+	name, err := c.do(ctx, supported, func(client Client) error {
+		var err error
+		result, err = client.(TextRecognizer).RecognizeText(ctx, text, prompt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = withProvider(result.Usage, name)
+	return result, nil
+}
+
+// PlanQuery 将自然语言问题转换为账本查询计划，跳过不支持的提供方
+func (c *FallbackClient) PlanQuery(ctx context.Context, question string, prompt string) (*dto.AIQueryPlan, error) {
+	supported := func(client Client) bool {
+		_, ok := client.(QueryPlanner)
+		return ok
+	}
+	var plan *dto.AIQueryPlan
+	name, err := c.do(ctx, supported, func(client Client) error {
+		var err error
+		plan, err = client.(QueryPlanner).PlanQuery(ctx, question, prompt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan.Usage = withProvider(plan.Usage, name)
+	return plan, nil
+}
+
+// WriteInsight 撰写月度消费洞察，跳过不支持的提供方
+func (c *FallbackClient) WriteInsight(ctx context.Context, facts string, prompt string) (*dto.AIInsightResponse, error) {
+	supported := func(client Client) bool {
+		_, ok := client.(InsightWriter)
+		return ok
+	}
+	var result *dto.AIInsightResponse
+	name, err := c.do(ctx, supported, func(client Client) error {
+		var err error
+		result, err = client.(InsightWriter).WriteInsight(ctx, facts, prompt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = withProvider(result.Usage, name)
+	return result, nil
+}
+
+// withProvider 标记用量所属的提供方
+// 兼容客户端（如 qwen 复用 OpenAIClient）不知道自己的提供方名称，统一在此标记
+func withProvider(usage *dto.AIUsage, name string) *dto.AIUsage {
+	if usage == nil {
+		usage = &dto.AIUsage{}
+	}
+	usage.Provider = name
+	return usage
+}
+
+// do 按优先级在各提供方上执行 call，返回成功的提供方名称
+// supported 不为空时跳过不满足条件的提供方
+func (c *FallbackClient) do(ctx context.Context, supported func(Client) bool, call func(Client) error) (string, error) {
+	var lastErr error
+	candidates := 0
+	for _, p := range c.providers {
+		if supported != nil && !supported(p.client) {
+			continue
+		}
+		candidates++
+		if !p.breaker.Allow() {
+			continue
+		}
+
+		err := c.callWithRetry(ctx, p, call)
+		if err == nil {
+			return p.name, nil
+		}
+		if !IsRetryable(err) {
+			// 非瞬时错误（参数错误、解析失败等）切换提供方也无济于事
+			return "", err
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		lastErr = fmt.Errorf("%s: %w", p.name, err)
+	}
+
+	if supported != nil && candidates == 0 {
+		return "", ErrUnsupported
+	}
+	if lastErr == nil {
+		return "", ErrAllProvidersUnavailable
+	}
+	return "", fmt.Errorf("%w: %v", ErrAllProvidersUnavailable, lastErr)
+}
+
+// callWithRetry 在单个提供方上执行带退避的重试
+func (c *FallbackClient) callWithRetry(ctx context.Context, p namedProvider, call func(Client) error) error {
+	var err error
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+		err = call(p.client)
+		if err == nil {
+			p.breaker.RecordSuccess()
+			return nil
+		}
+		if !IsRetryable(err) {
+			// 提供方可达，只是本次请求无法处理，不计入熔断
+			p.breaker.Release()
+			return err
+		}
+		if attempt == c.maxAttempts {
+			break
+		}
+		if sleepErr := c.sleep(ctx, c.backoff(attempt)); sleepErr != nil {
+			p.breaker.Release()
+			return err
+		}
+	}
+	p.breaker.RecordFailure(err)
+	return err
+}
+
+// backoff 计算第 attempt 次失败后的等待时间（指数退避 + 全抖动）
+func (c *FallbackClient) backoff(attempt int) time.Duration {
+	if c.baseDelay <= 0 {
+		return 0
+	}
+	delay := c.baseDelay << (attempt - 1)
+	if c.maxDelay > 0 && (delay > c.maxDelay || delay <= 0) {
+		delay = c.maxDelay
+	}
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
+
+// Health 获取各提供方健康状态
+func (c *FallbackClient) Health() []ProviderHealth {
+	health := make([]ProviderHealth, len(c.providers))
+	for i, p := range c.providers {
+		health[i] = p.breaker.snapshot(p.name)
+	}
+	return health
+}
+
+// sleepContext 可被 context 取消的等待
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model/dto"
+)
+
+// sequenceClient 按顺序返回预设错误，用尽后返回成功
+type sequenceClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *sequenceClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	c.calls++
+	if c.calls <= len(c.errs) {
+		return nil, c.errs[c.calls-1]
+	}
+	return &dto.AIRecognizeResponse{Merchant: "ok"}, nil
+}
+
+var (
+	errTooManyRequests = &StatusError{Provider: "test", StatusCode: http.StatusTooManyRequests}
+	errBadGateway      = &StatusError{Provider: "test", StatusCode: http.StatusBadGateway}
+	errBadRequest      = &StatusError{Provider: "test", StatusCode: http.StatusBadRequest}
+)
+
+func newTestFallbackClient(clients ...Client) *FallbackClient {
+	names := make([]string, len(clients))
+	for i := range clients {
+		names[i] = []string{"primary", "secondary", "tertiary"}[i]
+	}
+	c := NewFallbackClient(names, clients,
+		config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		config.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+	)
+	c.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	return c
+}
+
+func TestFallbackClient_RetryThenSuccess(t *testing.T) {
+	primary := &sequenceClient{errs: []error{errTooManyRequests, errBadGateway}}
+	client := newTestFallbackClient(primary)
+
+	result, err := client.RecognizePayment(context.Background(), nil, "image/jpeg", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Merchant)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, BreakerClosed, client.Health()[0].State)
+}
+
+func TestFallbackClient_FailoverAfterRetriesExhausted(t *testing.T) {
+	primary := &sequenceClient{errs: []error{errBadGateway, errBadGateway, context.DeadlineExceeded}}
+	secondary := &sequenceClient{}
+	client := newTestFallbackClient(primary, secondary)
+
+	result, err := client.RecognizePayment(context.Background(), nil, "image/jpeg", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Merchant)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, 1, secondary.calls)
+	assert.Equal(t, 1, client.Health()[0].ConsecutiveFailures)
+}
+
+func TestFallbackClient_NonRetryableErrorStops(t *testing.T) {
+	primary := &sequenceClient{errs: []error{errBadRequest}}
+	secondary := &sequenceClient{}
+	client := newTestFallbackClient(primary, secondary)
+
+	_, err := client.RecognizePayment(context.Background(), nil, "image/jpeg", "")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrAllProvidersUnavailable))
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 0, secondary.calls)
+	assert.Equal(t, 0, client.Health()[0].ConsecutiveFailures)
+}
+
+func TestFallbackClient_AllProvidersOpen(t *testing.T) {
+	alwaysDown := make([]error, 100)
+	for i := range alwaysDown {
+		alwaysDown[i] = errBadGateway
+	}
+	primary := &sequenceClient{errs: alwaysDown}
+	client := newTestFallbackClient(primary)
+
+	// 连续两次失败后熔断
+	for i := 0; i < 2; i++ {
+		_, err := client.RecognizePayment(context.Background(), nil, "image/jpeg", "")
+		assert.ErrorIs(t, err, ErrAllProvidersUnavailable)
+	}
+	health := client.Health()
+	assert.Equal(t, BreakerOpen, health[0].State)
+	assert.NotNil(t, health[0].RetryAt)
+
+	// 熔断期间不再调用提供方
+	calls := primary.calls
+	_, err := client.RecognizePayment(context.Background(), nil, "image/jpeg", "")
+	assert.ErrorIs(t, err, ErrAllProvidersUnavailable)
+	assert.Equal(t, calls, primary.calls)
+}
+
+func TestCircuitBreaker_HalfOpenRecovery(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	require.True(t, breaker.Allow())
+	breaker.RecordFailure(errBadGateway)
+	assert.False(t, breaker.Allow())
+
+	// 冷却结束后只放行一个试探请求
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	assert.False(t, breaker.Allow())
+
+	breaker.RecordSuccess()
+	assert.True(t, breaker.Allow())
+	assert.Equal(t, BreakerClosed, breaker.snapshot("test").State)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(errTooManyRequests))
+	assert.True(t, IsRetryable(errBadGateway))
+	assert.True(t, IsRetryable(context.DeadlineExceeded))
+	assert.False(t, IsRetryable(errBadRequest))
+	assert.False(t, IsRetryable(context.Canceled))
+	assert.False(t, IsRetryable(errors.New("解析 AI 返回结果失败")))
+}
+
+func TestFallbackClient_Unsupported(t *testing.T) {
+	client := newTestFallbackClient(&sequenceClient{})
+
+	_, err := client.RecognizeTransactions(context.Background(), nil, "image/jpeg", "")
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = client.RecognizeText(context.Background(), "午饭 35", "")
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.Equal(t, BreakerClosed, client.Health()[0].State)
+}
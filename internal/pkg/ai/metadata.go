@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"strings"
+)
+
+// heicBrands HEIC/HEIF 文件 ftyp 盒中的主品牌
+var heicBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "hevm": true, "hevs": true,
+	"mif1": true, "msf1": true,
+}
+
+// DetectContentType 根据文件内容判断类型，不依赖客户端上传的 Content-Type
+// 在 http.DetectContentType 的基础上识别 iPhone 拍摄的 HEIC 图片，返回值不含参数
+func DetectContentType(data []byte) string {
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" && heicBrands[string(data[8:12])] {
+		return "image/heic"
+	}
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// jpegSegments 遍历 JPEG 扫描数据之前的标记段，fn 返回 false 时停止
+// 回调参数为标记、段内容（不含长度）和整个段在 data 中的起止位置
+func jpegSegments(data []byte, fn func(marker byte, payload []byte, start, end int) bool) {
+	pos := 2 // SOI
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// 填充字节
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// SOS 之后是压缩数据
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return
+		}
+		if !fn(marker, data[pos+4:end], pos, end) {
+			return
+		}
+		pos = end
+	}
+}
+
+// jpegOrientation 读取 JPEG 的 EXIF 方向（1-8），没有 EXIF 或无法解析时返回 1
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, payload []byte, _, _ int) bool {
+		if marker != 0xE1 || !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return true
+		}
+		if o := tiffOrientation(payload[6:]); o >= 1 && o <= 8 {
+			orientation = o
+		}
+		return false
+	})
+	return orientation
+}
+
+// tiffOrientation 从 EXIF 的 TIFF 结构中读取 IFD0 的 Orientation（0x0112）
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// stripJPEGMetadata 无损去除 JPEG 中的 EXIF/XMP（含 GPS）、IPTC 和注释段
+// 保留 JFIF（APP0）、ICC 颜色配置（APP2）和 Adobe 颜色变换（APP14），其余数据原样复制
+func stripJPEGMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	pos := 2
+	jpegSegments(data, func(marker byte, _ []byte, start, end int) bool {
+		isApp := marker >= 0xE0 && marker <= 0xEF
+		keep := !isApp || marker == 0xE0 || marker == 0xE2 || marker == 0xEE
+		if marker == 0xFE {
+			keep = false
+		}
+		if keep {
+			out = append(out, data[start:end]...)
+		}
+		pos = end
+		return true
+	})
+	return append(out, data[pos:]...)
+}
+
+// pngMetadataChunks 可能包含拍摄信息、位置或编辑软件信息的 PNG 辅助块
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true,
+}
+
+// stripPNGMetadata 无损去除 PNG 中的 EXIF 和文本块，数据无法解析时原样返回
+func stripPNGMetadata(data []byte) []byte {
+	const signatureLen = 8
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:signatureLen]...)
+	pos := signatureLen
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return data
+		}
+		if !pngMetadataChunks[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return append(out, data[pos:]...)
+}
@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model/dto"
+)
+
+const (
+	// ollamaDefaultBaseURL Ollama 默认服务地址
+	ollamaDefaultBaseURL = "http://localhost:11434"
+	// ollamaDefaultModel 默认视觉模型
+	ollamaDefaultModel = "qwen2.5vl"
+	// ollamaDefaultTimeout 本地推理较慢，默认超时适当放宽
+	ollamaDefaultTimeout = 120 * time.Second
+)
+
+func init() {
+	RegisterProvider("ollama", func(cfg *config.ProviderConfig) (Client, error) {
+		return NewOllamaClient(cfg)
+	})
+}
+
+// OllamaClient 本地 Ollama 视觉模型客户端
+type OllamaClient struct {
+	baseURL          string
+	model            string
+	structuredOutput string
+	httpClient       *http.Client
+}
+
+// NewOllamaClient 创建 Ollama 客户端
+func NewOllamaClient(cfg *config.ProviderConfig) (*OllamaClient, error) {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+	model := cfg.Model
+	if model == "" {
+		model = ollamaDefaultModel
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = ollamaDefaultTimeout
+	}
+	return &OllamaClient{
+		baseURL:          baseURL,
+		model:            model,
+		structuredOutput: cfg.StructuredOutput,
+		httpClient:       &http.Client{Timeout: timeout, Transport: cfg.Transport},
+	}, nil
+}
+
+// ollamaFormat 根据结构化输出模式构建 format 参数，默认按 JSON Schema 约束
+func ollamaFormat(structuredOutput string, schema map[string]interface{}) interface{} {
+	switch structuredOutput {
+	case StructuredOutputJSONObject:
+		return "json"
+	case StructuredOutputNone:
+		return nil
+	default:
+		return schema
+	}
+}
+
+// ollamaMessage Ollama 对话消息
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // base64 编码的图片
+}
+
+// ollamaChatRequest Ollama /api/chat 请求
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   interface{}            `json:"format,omitempty"` // "json" 或 JSON Schema 对象
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse Ollama /api/chat 响应
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// RecognizePayment 识别支付截图
+func (c *OllamaClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	content, usage, err := c.chat(ctx, imageMessage(imageData, prompt), PaymentSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseAiResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// RecognizeTransactions 识别截图中的多笔交易
+func (c *OllamaClient) RecognizeTransactions(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeListResponse, error) {
+	content, usage, err := c.chat(ctx, imageMessage(imageData, prompt), TransactionsSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseTransactionsResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// RecognizeText 识别一句话记账文本，提示词作为系统消息，用户输入作为用户消息
+func (c *OllamaClient) RecognizeText(ctx context.Context, text string, prompt string) (*dto.AIRecognizeResponse, error) {
+	messages := []ollamaMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: text},
+	}
+	content, usage, err := c.chat(ctx, messages, PaymentSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseAiResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// PlanQuery 将自然语言问题转换为账本查询计划，提示词作为系统消息，问题作为用户消息
+func (c *OllamaClient) PlanQuery(ctx context.Context, question string, prompt string) (*dto.AIQueryPlan, error) {
+	messages := []ollamaMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: question},
+	}
+	content, usage, err := c.chat(ctx, messages, QueryPlanSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := ParseQueryPlan(content)
+	plan.Usage = usage
+	return plan, err
+}
+
+// WriteInsight 撰写月度消费洞察，提示词作为系统消息，统计数据作为用户消息
+func (c *OllamaClient) WriteInsight(ctx context.Context, facts string, prompt string) (*dto.AIInsightResponse, error) {
+	messages := []ollamaMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: facts},
+	}
+	content, usage, err := c.chat(ctx, messages, InsightSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseInsight(content)
+	result.Usage = usage
+	return result, err
+}
+
+// imageMessage 构建包含提示词和图片的用户消息
+func imageMessage(imageData []byte, prompt string) []ollamaMessage {
+	return []ollamaMessage{{
+		Role:    "user",
+		Content: prompt,
+		Images:  []string{ImageToBase64(imageData)},
+	}}
+}
+
+// chat 调用 /api/chat，返回模型输出内容及用量
+func (c *OllamaClient) chat(ctx context.Context, messages []ollamaMessage, schema map[string]interface{}) (string, *dto.AIUsage, error) {
+	reqBody, err := json.Marshal(ollamaChatRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   false,
+		Format:   ollamaFormat(c.structuredOutput, schema),
+		Options: map[string]interface{}{
+			"temperature": 0.1,
+		},
+	})
+	if err != nil {
+		return "", nil, pkgerrors.Wrap(err, "构建 Ollama 请求失败")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", nil, pkgerrors.Wrap(err, "构建 Ollama 请求失败")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("Ollama API 调用失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("读取 Ollama 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, &StatusError{Provider: "Ollama", StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", nil, pkgerrors.Wrap(err, "解析 Ollama 响应失败")
+	}
+	if chatResp.Error != "" {
+		return "", nil, fmt.Errorf("Ollama API 调用失败: %s", chatResp.Error)
+	}
+	if chatResp.Message.Content == "" {
+		return "", nil, fmt.Errorf("Ollama 返回空结果")
+	}
+
+	return chatResp.Message.Content, &dto.AIUsage{
+		Model:            c.model,
+		PromptTokens:     chatResp.PromptEvalCount,
+		CompletionTokens: chatResp.EvalCount,
+	}, nil
+}
@@ -1,27 +1,46 @@
 package ai
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"image"
+	"net/http"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/shared"
-	pkgerrors "github.com/pkg/errors"
 
 	"smart-ledger-server/internal/config"
 	"smart-ledger-server/internal/model/dto"
 )
 
+func init() {
+	RegisterProvider("openai", func(cfg *config.ProviderConfig) (Client, error) {
+		return NewOpenAIClient(cfg)
+	})
+}
+
+// 图片细节级别，low 固定按 512x512 计费，high 按 512 像素分块计费
+const (
+	ImageDetailLow  = "low"
+	ImageDetailHigh = "high"
+	ImageDetailAuto = "auto"
+)
+
+// lowDetailMaxSide low 级别下模型看到的最大边长，不超过该尺寸的图片使用 high 也不会更清晰
+const lowDetailMaxSide = 512
+
 // OpenAIClient OpenAI 兼容客户端
 type OpenAIClient struct {
-	client openai.Client
-	model  shared.ChatModel
+	client           openai.Client
+	model            shared.ChatModel
+	structuredOutput string
+	imageDetail      string
 }
 
 // NewOpenAIClient 创建 OpenAI 兼容客户端
-func NewOpenAIClient(cfg *config.AIConfig) (*OpenAIClient, error) {
+func NewOpenAIClient(cfg *config.ProviderConfig) (*OpenAIClient, error) {
 	// 准备选项
 	opts := []option.RequestOption{
 		option.WithAPIKey(cfg.APIKey),
@@ -32,6 +51,18 @@ func NewOpenAIClient(cfg *config.AIConfig) (*OpenAIClient, error) {
 		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
 	}
 
+	if cfg.Transport != nil {
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: cfg.Transport}))
+	}
+
+	// 重试由 FallbackClient 统一处理，关闭 SDK 自带重试
+	opts = append(opts, option.WithMaxRetries(0))
+
+	// 单次请求超时
+	if cfg.Timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.Timeout))
+	}
+
 	// 创建客户端
 	client := openai.NewClient(opts...)
 
@@ -41,14 +72,100 @@ func NewOpenAIClient(cfg *config.AIConfig) (*OpenAIClient, error) {
 		model = openai.ChatModelGPT4o
 	}
 
+	// 结构化输出模式，默认按 JSON Schema 约束
+	structuredOutput := cfg.StructuredOutput
+	if structuredOutput == "" {
+		structuredOutput = StructuredOutputJSONSchema
+	}
+
+	switch cfg.ImageDetail {
+	case "", ImageDetailLow, ImageDetailHigh, ImageDetailAuto:
+	default:
+		return nil, fmt.Errorf("不支持的图片细节级别: %s", cfg.ImageDetail)
+	}
+
 	return &OpenAIClient{
-		client: client,
-		model:  model,
+		client:           client,
+		model:            model,
+		structuredOutput: structuredOutput,
+		imageDetail:      cfg.ImageDetail,
 	}, nil
 }
 
 // RecognizePayment 识别支付截图
 func (c *OpenAIClient) RecognizePayment(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeResponse, error) {
+	content, usage, err := c.complete(ctx, c.imageMessages(imageData, mimeType, prompt), paymentSchemaName, PaymentSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseAiResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// RecognizeTransactions 识别截图中的多笔交易
+func (c *OpenAIClient) RecognizeTransactions(ctx context.Context, imageData []byte, mimeType string, prompt string) (*dto.AIRecognizeListResponse, error) {
+	content, usage, err := c.complete(ctx, c.imageMessages(imageData, mimeType, prompt), transactionsSchemaName, TransactionsSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseTransactionsResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// RecognizeText 识别一句话记账文本，提示词作为系统消息，用户输入作为用户消息
+func (c *OpenAIClient) RecognizeText(ctx context.Context, text string, prompt string) (*dto.AIRecognizeResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(prompt),
+		openai.UserMessage(text),
+	}
+	content, usage, err := c.complete(ctx, messages, paymentSchemaName, PaymentSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseAiResponse(content)
+	result.Usage = usage
+	return result, err
+}
+
+// PlanQuery 将自然语言问题转换为账本查询计划，提示词作为系统消息，问题作为用户消息
+func (c *OpenAIClient) PlanQuery(ctx context.Context, question string, prompt string) (*dto.AIQueryPlan, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(prompt),
+		openai.UserMessage(question),
+	}
+	content, usage, err := c.complete(ctx, messages, queryPlanSchemaName, QueryPlanSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := ParseQueryPlan(content)
+	plan.Usage = usage
+	return plan, err
+}
+
+// WriteInsight 撰写月度消费洞察，提示词作为系统消息，统计数据作为用户消息
+func (c *OpenAIClient) WriteInsight(ctx context.Context, facts string, prompt string) (*dto.AIInsightResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(prompt),
+		openai.UserMessage(facts),
+	}
+	content, usage, err := c.complete(ctx, messages, insightSchemaName, InsightSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseInsight(content)
+	result.Usage = usage
+	return result, err
+}
+
+// imageMessages 构建包含提示词和图片的用户消息
+func (c *OpenAIClient) imageMessages(imageData []byte, mimeType, prompt string) []openai.ChatCompletionMessageParamUnion {
 	// 构建图片 data URL
 	base64Image := ImageToBase64(imageData)
 	imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Image)
@@ -58,36 +175,77 @@ func (c *OpenAIClient) RecognizePayment(ctx context.Context, imageData []byte, m
 		openai.TextContentPart(prompt),
 		openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
 			URL:    imageURL,
-			Detail: "high",
+			Detail: c.detail(imageData),
 		}),
 	}
+	return []openai.ChatCompletionMessageParamUnion{openai.UserMessage(contentParts)}
+}
 
+// detail 获取图片细节级别：优先使用配置，未配置时按预处理后的图片尺寸选择
+// 长边不超过 512 时使用 low 节省 token，无法读取尺寸时使用 high 保证识别准确
+func (c *OpenAIClient) detail(imageData []byte) string {
+	if c.imageDetail != "" {
+		return c.imageDetail
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err == nil && max(cfg.Width, cfg.Height) <= lowDetailMaxSide {
+		return ImageDetailLow
+	}
+	return ImageDetailHigh
+}
+
+// complete 发送消息，返回模型输出内容及用量
+func (c *OpenAIClient) complete(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, schemaName string, schema map[string]interface{}) (string, *dto.AIUsage, error) {
 	// 调用 API
 	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages:    []openai.ChatCompletionMessageParamUnion{openai.UserMessage(contentParts)},
-		Model:       c.model,
-		MaxTokens:   openai.Int(1000),
-		Temperature: openai.Float(0.1),
+		Messages:       messages,
+		Model:          c.model,
+		MaxTokens:      openai.Int(maxTokens(schemaName)),
+		Temperature:    openai.Float(0.1),
+		ResponseFormat: c.responseFormat(schemaName, schema),
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API 调用失败: %w", err)
+		return "", nil, fmt.Errorf("OpenAI API 调用失败: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("OpenAI 返回空结果")
+		return "", nil, fmt.Errorf("OpenAI 返回空结果")
 	}
 
-	content := resp.Choices[0].Message.Content
+	return resp.Choices[0].Message.Content, &dto.AIUsage{
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
 
-	return ParseAiResponse(content)
+// maxTokens 单次输出的 token 上限，多笔交易的输出明显更长
+func maxTokens(schemaName string) int64 {
+	if schemaName == transactionsSchemaName {
+		return 4000
+	}
+	return 1000
 }
 
-func ParseAiResponse(content string) (result *dto.AIRecognizeResponse, err error) {
-	result = &dto.AIRecognizeResponse{}
-	err = json.Unmarshal([]byte(content), result)
-	if err != nil {
-		err = pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+// responseFormat 根据结构化输出模式构建 response_format 参数
+func (c *OpenAIClient) responseFormat(schemaName string, schema map[string]interface{}) openai.ChatCompletionNewParamsResponseFormatUnion {
+	switch c.structuredOutput {
+	case StructuredOutputJSONSchema:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   schemaName,
+					Strict: openai.Bool(true),
+					Schema: schema,
+				},
+			},
+		}
+	case StructuredOutputJSONObject:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+		}
+	default:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{}
 	}
-	return
 }
@@ -0,0 +1,385 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"smart-ledger-server/internal/model/dto"
+)
+
+// ErrInvalidRecognition 识别结果未通过校验
+var ErrInvalidRecognition = errors.New("识别结果无效")
+
+// codeFenceRe 匹配 markdown 代码块
+var codeFenceRe = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)```")
+
+// numberCleaner 去除金额中的货币符号、千分位等非数字字符
+var numberCleaner = strings.NewReplacer("¥", "", "￥", "", "元", "", "RMB", "", "CNY", "", ",", "", "，", "", " ", "", "+", "")
+
+// payTimeLayouts 模型常见的时间格式
+var payTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006年01月02日 15:04:05",
+	"2006年01月02日 15:04",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"2006年01月02日",
+	"2006年1月2日",
+}
+
+// ParseAiResponse 解析模型返回内容
+// 兼容 markdown 代码块、JSON 前后的说明文字、字符串形式的数字和多种时间格式，并校验结果
+func ParseAiResponse(content string) (result *dto.AIRecognizeResponse, err error) {
+	result = &dto.AIRecognizeResponse{RawContent: content}
+
+	raw, err := extractJSONObject(content)
+	if err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err = decoder.Decode(&fields); err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	if err = decodeRecognition(fields, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ParseTransactionsResponse 解析多笔交易识别结果
+// 兼容模型未按要求包装、直接返回单笔交易对象的情况；未通过校验的交易会被丢弃，全部无效时返回错误
+func ParseTransactionsResponse(content string) (*dto.AIRecognizeListResponse, error) {
+	result := &dto.AIRecognizeListResponse{RawContent: content, Transactions: []dto.AIRecognizeResponse{}}
+
+	raw, err := extractJSONObject(content)
+	if err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var root map[string]interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	entries, ok := root["transactions"].([]interface{})
+	if !ok {
+		entries = []interface{}{root}
+	}
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var transaction dto.AIRecognizeResponse
+		if err := decodeRecognition(fields, &transaction); err != nil {
+			continue
+		}
+		result.Transactions = append(result.Transactions, transaction)
+	}
+
+	if len(result.Transactions) == 0 {
+		return result, pkgerrors.Wrap(ErrInvalidRecognition, "没有识别到有效的交易")
+	}
+	return result, nil
+}
+
+// ParseQueryPlan 解析账本查询计划
+// 只修正字段格式，指标、日期范围和分类由调用方校验
+func ParseQueryPlan(content string) (*dto.AIQueryPlan, error) {
+	plan := &dto.AIQueryPlan{}
+
+	raw, err := extractJSONObject(content)
+	if err != nil {
+		return plan, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return plan, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	plan.Metric = strings.ToLower(toString(fields["metric"]))
+	plan.Pick = strings.ToLower(toString(fields["pick"]))
+	plan.Category = toString(fields["category"])
+	plan.StartDate = normalizePlanDate(toString(fields["start_date"]))
+	plan.EndDate = normalizePlanDate(toString(fields["end_date"]))
+	billType, _ := strconv.Atoi(normalizeBillType(fields["bill_type"]).String())
+	plan.BillType = billType
+	if plan.Metric == "" {
+		return plan, pkgerrors.Wrap(ErrInvalidRecognition, "查询计划缺少 metric")
+	}
+	return plan, nil
+}
+
+// ParseInsight 解析月度消费洞察，缺失的列表字段为空列表，概况为空时返回错误
+func ParseInsight(content string) (*dto.AIInsightResponse, error) {
+	result := &dto.AIInsightResponse{RawContent: content}
+
+	raw, err := extractJSONObject(content)
+	if err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return result, pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+
+	result.Summary = toString(fields["summary"])
+	result.Changes = toStringList(fields["changes"])
+	result.Merchants = toStringList(fields["merchants"])
+	result.Suggestions = toStringList(fields["suggestions"])
+	if result.Summary == "" {
+		return result, pkgerrors.Wrap(ErrInvalidRecognition, "消费洞察缺少概况")
+	}
+	return result, nil
+}
+
+// toStringList 将字符串数组转换为去除空白项的字符串列表，单个字符串视为只有一项
+func toStringList(value interface{}) []string {
+	list := []string{}
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	for _, item := range items {
+		if s := toString(item); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// normalizePlanDate 将查询计划中的日期统一为 2006-01-02，无法解析时原样返回
+func normalizePlanDate(date string) string {
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006年01月02日", "2006年1月2日", time.RFC3339} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
+
+// decodeRecognition 修正字段格式后解码为识别结果并校验
+func decodeRecognition(fields map[string]interface{}, result *dto.AIRecognizeResponse) error {
+	normalizeFields(fields)
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+	if err := json.Unmarshal(normalized, result); err != nil {
+		return pkgerrors.Wrap(err, "解析 AI 返回结果失败")
+	}
+	return validateRecognition(result)
+}
+
+// extractJSONObject 从模型输出中提取第一个完整的 JSON 对象
+func extractJSONObject(content string) ([]byte, error) {
+	content = strings.TrimSpace(content)
+	if match := codeFenceRe.FindStringSubmatch(content); match != nil {
+		content = strings.TrimSpace(match[1])
+	}
+
+	start := strings.IndexByte(content, '{')
+	if start < 0 {
+		return nil, errors.New("返回内容中没有 JSON 对象")
+	}
+
+	// 跳过字符串内的括号，找到与起始括号配对的位置
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(content); i++ {
+		ch := content[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return []byte(content[start : i+1]), nil
+			}
+		}
+	}
+	return nil, errors.New("返回内容中的 JSON 对象不完整")
+}
+
+// normalizeFields 修正模型输出中常见的字段格式问题
+func normalizeFields(fields map[string]interface{}) {
+	// 字符串字段：null 统一为空字符串
+	for _, key := range []string{"platform", "merchant", "category", "sub_category", "pay_method", "order_no", "remark"} {
+		fields[key] = toString(fields[key])
+	}
+
+	// 金额：去除货币符号，负数表示支出
+	amount, hasAmount := toNumber(fields["amount"])
+	if hasAmount && amount.IsNegative() {
+		amount = amount.Abs()
+		if fields["bill_type"] == nil {
+			fields["bill_type"] = json.Number("1")
+		}
+	}
+	if hasAmount {
+		fields["amount"] = json.Number(amount.String())
+	} else {
+		delete(fields, "amount")
+	}
+
+	fields["bill_type"] = normalizeBillType(fields["bill_type"])
+	fields["pay_time"] = normalizePayTime(toString(fields["pay_time"]))
+
+	// 置信度：兼容百分数
+	if confidence, ok := toNumber(fields["confidence"]); ok {
+		if confidence.GreaterThan(decimal.NewFromInt(1)) {
+			confidence = confidence.Div(decimal.NewFromInt(100))
+		}
+		fields["confidence"] = json.Number(confidence.String())
+	} else {
+		delete(fields, "confidence")
+	}
+
+	// 商品明细
+	items, _ := fields["items"].([]interface{})
+	normalizedItems := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemFields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		itemFields["name"] = toString(itemFields["name"])
+		for _, key := range []string{"price", "quantity"} {
+			if number, ok := toNumber(itemFields[key]); ok {
+				itemFields[key] = json.Number(number.String())
+			} else {
+				delete(itemFields, key)
+			}
+		}
+		normalizedItems = append(normalizedItems, itemFields)
+	}
+	fields["items"] = normalizedItems
+}
+
+// toString 将任意值转换为字符串，null 转换为空字符串
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "null" || s == "None" {
+			return ""
+		}
+		return s
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toNumber 将数字或数字字符串转换为 decimal
+func toNumber(value interface{}) (decimal.Decimal, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		d, err := decimal.NewFromString(v.String())
+		return d, err == nil
+	case float64:
+		return decimal.NewFromFloat(v), true
+	case string:
+		cleaned := numberCleaner.Replace(strings.TrimSpace(v))
+		if strings.HasSuffix(cleaned, "%") {
+			cleaned = strings.TrimSuffix(cleaned, "%")
+			d, err := decimal.NewFromString(cleaned)
+			return d.Div(decimal.NewFromInt(100)), err == nil
+		}
+		d, err := decimal.NewFromString(cleaned)
+		return d, err == nil
+	default:
+		return decimal.Zero, false
+	}
+}
+
+// normalizeBillType 将账单类型统一为 1（支出）或 2（收入），无法识别时默认支出
+func normalizeBillType(value interface{}) json.Number {
+	switch toString(value) {
+	case "2", "收入", "income":
+		return json.Number("2")
+	default:
+		return json.Number("1")
+	}
+}
+
+// normalizePayTime 将常见时间格式统一为 RFC3339（Asia/Shanghai），无法解析时返回空字符串
+func normalizePayTime(payTime string) string {
+	if payTime == "" {
+		return ""
+	}
+	location, _ := time.LoadLocation("Asia/Shanghai")
+	for _, layout := range payTimeLayouts {
+		if t, err := time.ParseInLocation(layout, payTime, location); err == nil {
+			return t.In(location).Format(time.RFC3339)
+		}
+	}
+	// 秒/毫秒级时间戳
+	if ts, err := strconv.ParseInt(payTime, 10, 64); err == nil {
+		if ts > 1e12 {
+			ts /= 1000
+		}
+		return time.Unix(ts, 0).In(location).Format(time.RFC3339)
+	}
+	return ""
+}
+
+// validateRecognition 校验识别结果
+func validateRecognition(result *dto.AIRecognizeResponse) error {
+	if !result.Amount.IsPositive() {
+		return pkgerrors.Wrap(ErrInvalidRecognition, "金额缺失或不大于0")
+	}
+	if result.Confidence < 0 {
+		result.Confidence = 0
+	}
+	if result.Confidence > 1 {
+		result.Confidence = 1
+	}
+	return nil
+}
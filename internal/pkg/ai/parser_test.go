@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAIResponse_Repair(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		wantAmount     string
+		wantBillType   int
+		wantPayTime    string
+		wantConfidence float64
+	}{
+		{
+			name:           "markdown code fence",
+			content:        "```json\n{\"amount\": 12.5, \"bill_type\": 1, \"pay_time\": \"2024-01-15 10:30:00\", \"confidence\": 0.9}\n```",
+			wantAmount:     "12.5",
+			wantBillType:   1,
+			wantPayTime:    "2024-01-15T10:30:00+08:00",
+			wantConfidence: 0.9,
+		},
+		{
+			name:           "surrounding text",
+			content:        "识别结果如下：{\"amount\": 8, \"merchant\": \"便利店{24h}\", \"confidence\": 0.8} 以上。",
+			wantAmount:     "8",
+			wantBillType:   1,
+			wantConfidence: 0.8,
+		},
+		{
+			name:           "string amount with currency",
+			content:        `{"amount": "¥1,234.50", "bill_type": "收入", "pay_time": "2024年01月15日 10:30", "confidence": "95%"}`,
+			wantAmount:     "1234.5",
+			wantBillType:   2,
+			wantPayTime:    "2024-01-15T10:30:00+08:00",
+			wantConfidence: 0.95,
+		},
+		{
+			name:           "negative amount and percentage confidence",
+			content:        `{"amount": -25.5, "bill_type": null, "pay_time": "2024/01/15 10:30", "confidence": 88}`,
+			wantAmount:     "25.5",
+			wantBillType:   1,
+			wantPayTime:    "2024-01-15T10:30:00+08:00",
+			wantConfidence: 0.88,
+		},
+		{
+			name:           "unix timestamp",
+			content:        `{"amount": 3, "bill_type": 2, "pay_time": "1705285800", "confidence": 1}`,
+			wantAmount:     "3",
+			wantBillType:   2,
+			wantPayTime:    "2024-01-15T10:30:00+08:00",
+			wantConfidence: 1,
+		},
+		{
+			name:         "unparseable pay time",
+			content:      `{"amount": 3, "pay_time": "昨天下午"}`,
+			wantAmount:   "3",
+			wantBillType: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseAiResponse(tt.content)
+			require.NoError(t, err)
+			assert.True(t, result.Amount.Equal(decimal.RequireFromString(tt.wantAmount)), result.Amount.String())
+			assert.Equal(t, tt.wantBillType, result.BillType)
+			assert.Equal(t, tt.wantPayTime, result.PayTime)
+			assert.InDelta(t, tt.wantConfidence, result.Confidence, 1e-9)
+			assert.Equal(t, tt.content, result.RawContent)
+		})
+	}
+}
+
+func TestParseAIResponse_Items(t *testing.T) {
+	result, err := ParseAiResponse(`{"amount": 30, "merchant": null, "items": [{"name": "咖啡", "price": "¥15", "quantity": "2"}, "invalid"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "", result.Merchant)
+	require.Len(t, result.Items, 1)
+	assert.True(t, result.Items[0].Price.Equal(decimal.NewFromInt(15)))
+	assert.True(t, result.Items[0].Quantity.Equal(decimal.NewFromInt(2)))
+}
+
+func TestParseAIResponse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no json", content: "无法识别该图片"},
+		{name: "truncated json", content: `{"amount": 12.5, "merchant": "星巴`},
+		{name: "missing amount", content: `{"merchant": "星巴克"}`},
+		{name: "zero amount", content: `{"amount": "0.00"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseAiResponse(tt.content)
+			assert.Error(t, err)
+			require.NotNil(t, result)
+			assert.Equal(t, tt.content, result.RawContent)
+		})
+	}
+}
+
+func TestParseTransactionsResponse(t *testing.T) {
+	content := "```json\n" + `{"transactions": [
+		{"merchant": "瑞幸咖啡", "amount": "-19.9", "bill_type": null, "pay_time": "2024-01-15 08:30", "order_no": "A001", "confidence": 0.9},
+		{"merchant": "月度汇总", "amount": 0, "confidence": 0.5},
+		{"merchant": "工资", "amount": "+8000", "bill_type": 2, "pay_time": "2024-01-10", "confidence": 95}
+	]}` + "\n```"
+
+	result, err := ParseTransactionsResponse(content)
+	require.NoError(t, err)
+	assert.Equal(t, content, result.RawContent)
+	require.Len(t, result.Transactions, 2)
+
+	assert.Equal(t, "瑞幸咖啡", result.Transactions[0].Merchant)
+	assert.True(t, result.Transactions[0].Amount.Equal(decimal.RequireFromString("19.9")))
+	assert.Equal(t, 1, result.Transactions[0].BillType)
+	assert.Equal(t, "2024-01-15T08:30:00+08:00", result.Transactions[0].PayTime)
+	assert.Equal(t, "A001", result.Transactions[0].OrderNo)
+
+	assert.Equal(t, 2, result.Transactions[1].BillType)
+	assert.True(t, result.Transactions[1].Amount.Equal(decimal.NewFromInt(8000)))
+	assert.Equal(t, 0.95, result.Transactions[1].Confidence)
+}
+
+func TestParseTransactionsResponse_SingleObject(t *testing.T) {
+	result, err := ParseTransactionsResponse(`{"merchant": "便利店", "amount": 8, "confidence": 0.8}`)
+	require.NoError(t, err)
+	require.Len(t, result.Transactions, 1)
+	assert.Equal(t, "便利店", result.Transactions[0].Merchant)
+}
+
+func TestParseTransactionsResponse_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"transactions": []}`,
+		`{"transactions": [{"merchant": "余额", "amount": null}]}`,
+		"没有识别到交易",
+	} {
+		result, err := ParseTransactionsResponse(content)
+		assert.Error(t, err, content)
+		require.NotNil(t, result)
+		assert.Equal(t, content, result.RawContent)
+	}
+}
+
+func TestParseQueryPlan(t *testing.T) {
+	plan, err := ParseQueryPlan("查询计划如下：\n```json\n" + `{"metric": "By_Month", "bill_type": "收入", "start_date": "2025/01/01", "end_date": "2025年3月31日", "category": null, "pick": "MAX"}` + "\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "by_month", plan.Metric)
+	assert.Equal(t, 2, plan.BillType)
+	assert.Equal(t, "2025-01-01", plan.StartDate)
+	assert.Equal(t, "2025-03-31", plan.EndDate)
+	assert.Empty(t, plan.Category)
+	assert.Equal(t, "max", plan.Pick)
+
+	// 无法解析的日期原样返回，由调用方校验
+	plan, err = ParseQueryPlan(`{"metric": "total", "start_date": "上个月", "end_date": "", "category": "餐饮"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, plan.BillType)
+	assert.Equal(t, "上个月", plan.StartDate)
+	assert.Equal(t, "餐饮", plan.Category)
+
+	for _, content := range []string{`{"pick": "all"}`, "SELECT SUM(amount) FROM bills"} {
+		_, err := ParseQueryPlan(content)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestParseInsight(t *testing.T) {
+	insight, err := ParseInsight("```json\n" + `{"summary": " 本月支出 73.00 元。 ", "changes": ["交通减少 25.00 元", " "], "merchants": "新商户瑞幸咖啡", "suggestions": null}` + "\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "本月支出 73.00 元。", insight.Summary)
+	assert.Equal(t, []string{"交通减少 25.00 元"}, insight.Changes)
+	assert.Equal(t, []string{"新商户瑞幸咖啡"}, insight.Merchants)
+	assert.Equal(t, []string{}, insight.Suggestions)
+
+	for _, content := range []string{`{"changes": ["交通减少"]}`, "本月支出较多"} {
+		_, err := ParseInsight(content)
+		assert.Error(t, err, content)
+	}
+}
@@ -0,0 +1,283 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gen2brain/heic"
+
+	"smart-ledger-server/internal/config"
+)
+
+// ErrUnsupportedImage 文件内容不是支持的图片格式
+var ErrUnsupportedImage = errors.New("不支持的图片格式")
+
+// maxDecodePixels 允许解码的最大像素数，防止解压炸弹
+const maxDecodePixels = 80_000_000
+
+// Preprocessor 图片预处理：在上传给AI之前缩小图片并去除隐私元数据，降低耗时和费用
+//   - 按 EXIF 方向旋转 JPEG，去除 EXIF/GPS 等元数据
+//   - 长边超过 MaxDimension 时等比缩小后重新编码（JPEG 按配置质量，PNG 保持无损）
+//   - HEIC 在进程内解码（libheif 编译的 WASM，无需 cgo 和系统库）后编码为 JPEG，配置了外部命令时使用外部命令转换
+//
+// 不需要旋转和缩放的 JPEG/PNG 只删除元数据段，不重新编码；GIF/WebP 原样返回
+type Preprocessor struct {
+	disabled     bool
+	maxDimension int
+	jpegQuality  int
+	heicCommand  []string
+}
+
+// NewPreprocessor 创建图片预处理器
+func NewPreprocessor(cfg config.PreprocessConfig) *Preprocessor {
+	return &Preprocessor{
+		disabled:     cfg.Disabled,
+		maxDimension: cfg.MaxDimension,
+		jpegQuality:  cfg.JPEGQuality,
+		heicCommand:  strings.Fields(cfg.HEICCommand),
+	}
+}
+
+// Process 按文件内容识别图片格式并预处理，返回处理后的图片和 MIME 类型
+func (p *Preprocessor) Process(ctx context.Context, data []byte) ([]byte, string, error) {
+	mimeType := DetectContentType(data)
+	if mimeType == "image/heic" {
+		converted, err := p.convertHEIC(ctx, data)
+		if err != nil {
+			return nil, "", err
+		}
+		data, mimeType = converted, "image/jpeg"
+	}
+	if !isValidImageType(mimeType) {
+		return nil, "", ErrUnsupportedImage
+	}
+	if p.disabled {
+		return data, mimeType, nil
+	}
+
+	switch mimeType {
+	case "image/jpeg":
+		return p.processJPEG(data)
+	case "image/png":
+		return p.processPNG(data)
+	}
+	return data, mimeType, nil
+}
+
+// processJPEG 旋转、缩放并去除元数据，不需要旋转和缩放时无损处理
+func (p *Preprocessor) processJPEG(data []byte) ([]byte, string, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	orientation := jpegOrientation(data)
+	if orientation == 1 && !p.needsResize(cfg.Width, cfg.Height) {
+		return stripJPEGMetadata(data), "image/jpeg", nil
+	}
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	rgba := p.resize(orient(toRGBA(img), orientation))
+
+	// 重新编码不会写入任何元数据
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: p.jpegQuality}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// processPNG 缩放并去除元数据，截图中的文字对压缩失真敏感，因此保持 PNG 格式
+func (p *Preprocessor) processPNG(data []byte) ([]byte, string, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	if !p.needsResize(cfg.Width, cfg.Height) {
+		return stripPNGMetadata(data), "image/png", nil
+	}
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, p.resize(toRGBA(img))); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+func (p *Preprocessor) needsResize(width, height int) bool {
+	return p.maxDimension > 0 && max(width, height) > p.maxDimension
+}
+
+// resize 长边超过 maxDimension 时按区域平均等比缩小，文字边缘比最近邻采样清晰
+func (p *Preprocessor) resize(src *image.RGBA) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if !p.needsResize(sw, sh) {
+		return src
+	}
+	dw, dh := p.maxDimension, p.maxDimension
+	if sw >= sh {
+		dh = max(1, sh*p.maxDimension/sw)
+	} else {
+		dw = max(1, sw*p.maxDimension/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0 := dy * sh / dh
+		y1 := max(y0+1, (dy+1)*sh/dh)
+		for dx := 0; dx < dw; dx++ {
+			x0 := dx * sw / dw
+			x1 := max(x0+1, (dx+1)*sw/dw)
+			var r, g, b, a, n int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					r += int(row[4*x])
+					g += int(row[4*x+1])
+					b += int(row[4*x+2])
+					a += int(row[4*x+3])
+					n++
+				}
+			}
+			i := dy*dst.Stride + 4*dx
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// toRGBA 转换为原点在 (0,0) 的 RGBA 图片
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// orient 按 EXIF 方向变换图片，使其以正常方向显示
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	// 目标坐标 (x, y) 对应的原图坐标
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2: // 水平翻转
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // 旋转 180°
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // 垂直翻转
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // 沿主对角线翻转
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // 顺时针旋转 90°
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // 沿副对角线翻转
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // 逆时针旋转 90°
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[y*dst.Stride+4*x:y*dst.Stride+4*x+4], src.Pix[sy*src.Stride+4*sx:])
+		}
+	}
+	return dst
+}
+
+// convertHEIC 将 HEIC 转换为 JPEG，配置了外部命令时调用外部命令，否则在进程内解码
+func (p *Preprocessor) convertHEIC(ctx context.Context, data []byte) ([]byte, error) {
+	if len(p.heicCommand) == 0 {
+		return p.decodeHEIC(data)
+	}
+	file, err := os.CreateTemp("", "upload-*.heic")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(p.heicCommand)-1)
+	for i, arg := range p.heicCommand[1:] {
+		args[i] = strings.ReplaceAll(arg, "{input}", file.Name())
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.heicCommand[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("HEIC 转换失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if http.DetectContentType(stdout.Bytes()) != "image/jpeg" {
+		return nil, errors.New("HEIC 转换结果不是 JPEG")
+	}
+	return stdout.Bytes(), nil
+}
+
+// decodeHEIC 在进程内解码 HEIC 并编码为 JPEG，解码时已按 irot/imir 旋转，结果不含元数据
+// 长边超过 maxDimension 时先缩小，避免后续按 JPEG 处理时再次重新编码
+func (p *Preprocessor) decodeHEIC(data []byte) ([]byte, error) {
+	cfg, err := heic.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, ErrUnsupportedImage
+	}
+	img, err := heic.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	rgba := toRGBA(img)
+	if !p.disabled {
+		rgba = p.resize(rgba)
+	}
+	quality := p.jpegQuality
+	if quality <= 0 {
+		quality = jpeg.DefaultQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
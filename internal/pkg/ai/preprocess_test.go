@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+)
+
+// exifSegment 生成只包含 Orientation 和一个 GPS 标记的 APP1 段
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	// Orientation，SHORT
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	// GPSInfo IFD 指针，内容不重要
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// testJPEG 生成左半边黑、右半边白的 JPEG，orientation 不为 0 时插入 EXIF
+func testJPEG(t *testing.T, width, height int, orientation uint16) []byte {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := width / 2; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0xFF})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+	return append(append(append([]byte{}, data[:2]...), exifSegment(orientation)...), data[2:]...)
+}
+
+func newTestPreprocessor(maxDimension int) *Preprocessor {
+	return NewPreprocessor(config.PreprocessConfig{MaxDimension: maxDimension, JPEGQuality: 90})
+}
+
+func TestDetectContentType(t *testing.T) {
+	heic := append([]byte{0, 0, 0, 0x18}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+	assert.Equal(t, "image/heic", DetectContentType(heic))
+	assert.Equal(t, "image/jpeg", DetectContentType(testJPEG(t, 8, 8, 0)))
+	assert.Equal(t, "application/pdf", DetectContentType([]byte("%PDF-1.7\n")))
+	assert.Equal(t, "text/plain", DetectContentType([]byte("hello")))
+}
+
+func TestPreprocessor_JPEGStripsMetadataWithoutReencoding(t *testing.T) {
+	data := testJPEG(t, 64, 32, 1)
+	require.Equal(t, 1, jpegOrientation(data))
+
+	out, mimeType, err := newTestPreprocessor(2048).Process(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mimeType)
+	assert.NotContains(t, string(out), "Exif")
+	assert.Equal(t, testJPEG(t, 64, 32, 0), out)
+}
+
+func TestPreprocessor_JPEGRotatesByOrientation(t *testing.T) {
+	out, _, err := newTestPreprocessor(2048).Process(context.Background(), testJPEG(t, 64, 32, 6))
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "Exif")
+
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 32, 64), img.Bounds())
+	// 顺时针旋转 90° 后，原来的左半边（黑）位于上方
+	top, _, _, _ := img.At(16, 8).RGBA()
+	bottom, _, _, _ := img.At(16, 56).RGBA()
+	assert.Less(t, top, uint32(0x4000))
+	assert.Greater(t, bottom, uint32(0xC000))
+}
+
+func TestPreprocessor_Downscale(t *testing.T) {
+	out, _, err := newTestPreprocessor(100).Process(context.Background(), testJPEG(t, 400, 200, 0))
+	require.NoError(t, err)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.Width)
+	assert.Equal(t, 50, cfg.Height)
+
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 150, 300))))
+	out, mimeType, err := newTestPreprocessor(100).Process(context.Background(), pngData.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	pngCfg, err := png.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 50, pngCfg.Width)
+	assert.Equal(t, 100, pngCfg.Height)
+}
+
+func TestPreprocessor_PNGStripsTextChunks(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))))
+	data := buf.Bytes()
+
+	// 在 IHDR 之后插入 tEXt 块
+	text := []byte("Comment\x00GPS 31.23,121.47")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte("tEXt"), text...)))
+	const ihdrEnd = 8 + 12 + 13
+	withText := append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+
+	out, mimeType, err := newTestPreprocessor(2048).Process(context.Background(), withText)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, data, out)
+}
+
+func TestPreprocessor_Rejects(t *testing.T) {
+	p := newTestPreprocessor(2048)
+
+	_, _, err := p.Process(context.Background(), []byte("%PDF-1.7\n"))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+
+	// 只有文件头的 HEIC 无法解码
+	heic := append([]byte{0, 0, 0, 0x18}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+	_, _, err = p.Process(context.Background(), heic)
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestPreprocessor_HEIC(t *testing.T) {
+	// iPhone 照片格式的样例（512x512，HEVC 8 位）
+	data, err := os.ReadFile("test_img/sample.heic")
+	require.NoError(t, err)
+	require.Equal(t, "image/heic", DetectContentType(data))
+
+	// 未配置外部命令时在进程内解码并转为 JPEG，长边按配置缩小
+	out, mimeType, err := newTestPreprocessor(256).Process(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mimeType)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 256, cfg.Width)
+	assert.Equal(t, 256, cfg.Height)
+
+	out, _, err = newTestPreprocessor(2048).Process(context.Background(), data)
+	require.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 512, img.Bounds().Dx())
+
+	// 关闭预处理时仍需转换格式，但不缩小
+	disabled := NewPreprocessor(config.PreprocessConfig{Disabled: true, MaxDimension: 256})
+	out, mimeType, err = disabled.Process(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mimeType)
+	cfg, err = jpeg.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 512, cfg.Width)
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1：左红右绿
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	green := color.RGBA{G: 0xFF, A: 0xFF}
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, green)
+
+	tests := []struct {
+		orientation int
+		width       int
+		first       color.RGBA // 左上角像素
+	}{
+		{1, 2, red},
+		{2, 2, green},
+		{3, 2, green},
+		{4, 2, red},
+		{5, 1, red},
+		{6, 1, red},
+		{7, 1, green},
+		{8, 1, green},
+	}
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+		assert.Equal(t, tt.width, dst.Rect.Dx(), "orientation %d", tt.orientation)
+		assert.Equal(t, tt.first, dst.RGBAAt(0, 0), "orientation %d", tt.orientation)
+	}
+}
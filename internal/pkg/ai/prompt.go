@@ -0,0 +1,314 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/pkg/logger"
+)
+
+// PromptKind 提示词类型，对应同名的模板文件
+type PromptKind string
+
+const (
+	PromptRecognition  PromptKind = "recognition"  // 单笔支付截图识别
+	PromptTransactions PromptKind = "transactions" // 多笔交易截图识别
+	PromptText         PromptKind = "text"         // 一句话记账
+	PromptNotification PromptKind = "notification" // 支付通知/短信
+	PromptQuery        PromptKind = "query"        // 自然语言查询账本
+	PromptInsight      PromptKind = "insight"      // 月度消费洞察
+)
+
+// promptKinds 全部提示词类型
+var promptKinds = []PromptKind{PromptRecognition, PromptTransactions, PromptText, PromptNotification, PromptQuery, PromptInsight}
+
+// commonPromptFile 各提示词共用片段所在的模板文件
+const commonPromptFile = "common.tmpl"
+
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// promptVersionRe 模板首行声明的版本，如 {{- /* version: recognition-v2 */ -}}
+var promptVersionRe = regexp.MustCompile(`\{\{-?\s*/\*\s*version:\s*([\w.-]+)\s*\*/`)
+
+// PromptData 渲染提示词的数据
+type PromptData struct {
+	UserID       uint64           // 用户ID，只用于区分缓存作用域，不出现在提示词中
+	Categories   []model.Category // 用户分类（含 Children），为空时使用默认分类
+	Hints        []CorrectionHint // 用户历史修正
+	Instructions string           // 用户自定义说明，追加在提示词末尾
+	Now          time.Time        // 当前时间（一句话记账、多笔交易、查询账本）或收到通知的时间
+}
+
+// Prompt 渲染后的提示词
+type Prompt struct {
+	Text    string
+	Version string // 模板版本，随识别结果保存到账单；覆盖了 common.tmpl 时附加其摘要，如 recognition-v2+1a2b3c4d
+	// CacheScope 识别结果缓存的作用域，由提示词类型、模板版本、用户、分类集合版本和自定义说明组成
+	// 识别结果受用户的历史修正影响且包含原始回复，不在用户之间共享
+	// 不包含历史修正和当前时间：修正在命中缓存后由账单服务按商户映射应用，不应使缓存失效
+	CacheScope string
+}
+
+// promptContext 模板中可以使用的字段
+type promptContext struct {
+	PromptData
+	ExpenseCategories string // 支出分类说明
+	IncomeCategories  string // 收入分类说明
+	Weekday           string // Now 的中文星期
+}
+
+// promptTemplate 已解析的提示词模板
+type promptTemplate struct {
+	tmpl    *template.Template
+	version string
+}
+
+// PromptStore 提示词模板
+// 内置模板位于 prompts 目录并编译进程序；配置了覆盖目录时，目录中的同名文件覆盖内置模板，
+// 并按 ReloadInterval 检查文件变更，修改提示词无需重新部署
+type PromptStore struct {
+	dir            string
+	reloadInterval time.Duration
+
+	mu        sync.RWMutex
+	templates map[PromptKind]*promptTemplate
+	checkedAt time.Time // 上次检查覆盖目录的时间
+	modTime   time.Time // 覆盖目录中最新的文件修改时间
+}
+
+// NewPromptStore 加载提示词模板，覆盖目录中的模板无法解析时返回错误
+func NewPromptStore(cfg config.PromptConfig) (*PromptStore, error) {
+	s := &PromptStore{dir: cfg.Dir, reloadInterval: cfg.ReloadInterval}
+	modTime, err := s.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.templates, s.modTime, s.checkedAt = templates, modTime, time.Now()
+	return s, nil
+}
+
+// Render 渲染指定类型的提示词
+func (s *PromptStore) Render(kind PromptKind, data PromptData) (*Prompt, error) {
+	s.reloadIfChanged()
+
+	s.mu.RLock()
+	t, ok := s.templates[kind]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的提示词类型: %s", kind)
+	}
+
+	expenseDesc, incomeDesc := buildCategoryDesc(data.Categories)
+	ctx := promptContext{
+		PromptData:        data,
+		ExpenseCategories: expenseDesc,
+		IncomeCategories:  incomeDesc,
+		Weekday:           weekdayNames[data.Now.Weekday()],
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, ctx); err != nil {
+		return nil, fmt.Errorf("渲染提示词 %s 失败: %w", kind, err)
+	}
+	return &Prompt{Text: buf.String(), Version: t.version, CacheScope: cacheScope(kind, t.version, data)}, nil
+}
+
+// cacheScope 构建识别结果缓存的作用域，每个用户使用独立的缓存
+func cacheScope(kind PromptKind, version string, data PromptData) string {
+	scope := fmt.Sprintf("%s:%s:%d:%s", kind, version, data.UserID, CategorySetVersion(data.Categories))
+	if data.Instructions != "" {
+		sum := sha256.Sum256([]byte(data.Instructions))
+		scope += ":" + hex.EncodeToString(sum[:4])
+	}
+	return scope
+}
+
+// CategorySetVersion 分类集合的版本摘要，只与分类的类型和名称（含层级）有关，与顺序、ID 无关
+func CategorySetVersion(categories []model.Category) string {
+	names := make([]string, 0, len(categories))
+	for _, cat := range categories {
+		names = append(names, fmt.Sprintf("%d/%s", cat.Type, cat.Name))
+		for _, child := range cat.Children {
+			names = append(names, fmt.Sprintf("%d/%s/%s", cat.Type, cat.Name, child.Name))
+		}
+	}
+	sort.Strings(names)
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// reloadIfChanged 覆盖目录中的文件有变更时重新加载，加载失败时继续使用原有模板
+func (s *PromptStore) reloadIfChanged() {
+	if s.dir == "" || s.reloadInterval <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) < s.reloadInterval {
+		return
+	}
+	s.checkedAt = time.Now()
+
+	modTime, err := s.latestModTime()
+	if err != nil || !modTime.After(s.modTime) {
+		return
+	}
+	templates, err := s.load()
+	if err != nil {
+		logger.Log.Warn("重新加载提示词模板失败，继续使用原有模板", zap.String("dir", s.dir), zap.Error(err))
+		return
+	}
+	s.templates, s.modTime = templates, modTime
+}
+
+// load 读取内置模板和覆盖目录中的模板并解析
+func (s *PromptStore) load() (map[PromptKind]*promptTemplate, error) {
+	files := make(map[string]string)
+	builtin := make(map[string]string)
+	entries, err := fs.ReadDir(builtinPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		content, err := fs.ReadFile(builtinPrompts, "prompts/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = string(content)
+		builtin[entry.Name()] = string(content)
+	}
+	if s.dir != "" {
+		paths, err := filepath.Glob(filepath.Join(s.dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			files[filepath.Base(path)] = string(content)
+		}
+	}
+
+	// 公共片段被覆盖时，各模板声明的版本不再能代表实际内容
+	var commonDigest string
+	if files[commonPromptFile] != builtin[commonPromptFile] {
+		sum := sha256.Sum256([]byte(files[commonPromptFile]))
+		commonDigest = hex.EncodeToString(sum[:4])
+	}
+
+	templates := make(map[PromptKind]*promptTemplate, len(promptKinds))
+	for _, kind := range promptKinds {
+		name := string(kind) + ".tmpl"
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("解析提示词模板 %s 失败: %w", name, err)
+		}
+		if _, err := tmpl.New(commonPromptFile).Parse(files[commonPromptFile]); err != nil {
+			return nil, fmt.Errorf("解析提示词模板 %s 失败: %w", commonPromptFile, err)
+		}
+		templates[kind] = &promptTemplate{
+			tmpl:    tmpl,
+			version: promptVersion(files[name], files[commonPromptFile], commonDigest),
+		}
+	}
+	return templates, nil
+}
+
+// latestModTime 覆盖目录中模板文件的最新修改时间
+func (s *PromptStore) latestModTime() (time.Time, error) {
+	var latest time.Time
+	if s.dir == "" {
+		return latest, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.tmpl"))
+	if err != nil {
+		return latest, err
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// promptVersion 获取模板声明的版本，未声明时使用模板内容摘要
+// commonDigest 为覆盖后的 common.tmpl 摘要，非空时附加到声明的版本后
+func promptVersion(content, common, commonDigest string) string {
+	if m := promptVersionRe.FindStringSubmatch(content); m != nil {
+		if commonDigest != "" {
+			return m[1] + "+" + commonDigest
+		}
+		return m[1]
+	}
+	sum := sha256.Sum256([]byte(content + "\n" + common))
+	return "sha-" + hex.EncodeToString(sum[:4])
+}
+
+// weekdayNames 星期的中文名称
+var weekdayNames = [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// buildCategoryDesc 构建支出、收入分类说明
+func buildCategoryDesc(categories []model.Category) (string, string) {
+	// 按类型分组分类
+	var expenseCategories, incomeCategories []model.Category
+	for _, cat := range categories {
+		if cat.Type == model.CategoryTypeExpense {
+			expenseCategories = append(expenseCategories, cat)
+		} else if cat.Type == model.CategoryTypeIncome {
+			incomeCategories = append(incomeCategories, cat)
+		}
+	}
+	return describeCategories("【支出分类】（bill_type=1）：\n", expenseCategories),
+		describeCategories("【收入分类】（bill_type=2）：\n", incomeCategories)
+}
+
+// describeCategories 按“一级分类：二级分类、二级分类”的格式列出分类
+func describeCategories(title string, categories []model.Category) string {
+	if len(categories) == 0 {
+		return ""
+	}
+
+	var desc strings.Builder
+	desc.WriteString(title)
+	for _, cat := range categories {
+		var childNames []string
+		for _, child := range cat.Children {
+			childNames = append(childNames, child.Name)
+		}
+		desc.WriteString("- ")
+		desc.WriteString(cat.Name)
+		if len(childNames) > 0 {
+			desc.WriteString("：")
+			desc.WriteString(strings.Join(childNames, "、"))
+		}
+		desc.WriteString("\n")
+	}
+	return desc.String()
+}
@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+)
+
+func TestPromptStore_Recognition(t *testing.T) {
+	store, err := NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+	categories := []model.Category{
+		{Name: "餐饮", Type: model.CategoryTypeExpense, Children: []model.Category{{Name: "咖啡饮品"}}},
+		{Name: "薪资", Type: model.CategoryTypeIncome},
+	}
+
+	prompt, err := store.Render(PromptRecognition, PromptData{Categories: categories})
+	require.NoError(t, err)
+	assert.Equal(t, "recognition-v2", prompt.Version)
+	assert.Contains(t, prompt.Text, "【支出分类】（bill_type=1）：\n- 餐饮：咖啡饮品\n\n【收入分类】（bill_type=2）：\n- 薪资\n\n注意事项：")
+	assert.NotContains(t, prompt.Text, "用户历史修正")
+	assert.NotContains(t, prompt.Text, "【用户自定义说明】")
+	assert.NotContains(t, prompt.Text, "{{")
+
+	prompt, err = store.Render(PromptRecognition, PromptData{
+		Categories: categories,
+		Hints: []CorrectionHint{
+			{Merchant: "瑞幸咖啡", Platform: "微信支付", BillType: 1, Category: "餐饮", SubCategory: "咖啡饮品"},
+			{Merchant: "公司", BillType: 2, Category: "薪资"},
+		},
+		Instructions: "公司报销打车费用，打车账单请标注“可报销”",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Text, "- 薪资\n\n【用户历史修正】")
+	assert.Contains(t, prompt.Text, "- 瑞幸咖啡（微信支付） → bill_type=1，category=餐饮，sub_category=咖啡饮品\n")
+	assert.Contains(t, prompt.Text, "- 公司 → bill_type=2，category=薪资\n\n注意事项：")
+	assert.Contains(t, prompt.Text, "中选择\n\n【用户自定义说明】")
+	assert.Contains(t, prompt.Text, "公司报销打车费用，打车账单请标注“可报销”")
+}
+
+func TestPromptStore_CacheScope(t *testing.T) {
+	store, err := NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+	categories := []model.Category{
+		{BaseModel: model.BaseModel{ID: 1}, Name: "餐饮", Type: model.CategoryTypeExpense, Children: []model.Category{{Name: "咖啡饮品"}}},
+		{BaseModel: model.BaseModel{ID: 2}, Name: "薪资", Type: model.CategoryTypeIncome},
+	}
+	render := func(kind PromptKind, data PromptData) string {
+		prompt, err := store.Render(kind, data)
+		require.NoError(t, err)
+		return prompt.CacheScope
+	}
+	base := render(PromptRecognition, PromptData{UserID: 1, Categories: categories})
+
+	// 历史修正和当前时间变化不影响缓存作用域
+	assert.Equal(t, base, render(PromptRecognition, PromptData{
+		UserID:     1,
+		Categories: categories,
+		Hints:      []CorrectionHint{{Merchant: "瑞幸咖啡", BillType: 1, Category: "餐饮"}},
+		Now:        time.Now(),
+	}))
+
+	// 分类名称相同（ID、顺序不同）时作用域相同
+	reordered := []model.Category{
+		{BaseModel: model.BaseModel{ID: 12}, Name: "薪资", Type: model.CategoryTypeIncome},
+		{BaseModel: model.BaseModel{ID: 11}, Name: "餐饮", Type: model.CategoryTypeExpense, Children: []model.Category{{Name: "咖啡饮品"}}},
+	}
+	assert.Equal(t, base, render(PromptRecognition, PromptData{UserID: 1, Categories: reordered}))
+
+	// 用户、分类、自定义说明、提示词类型变化时使用新的作用域
+	renamed := []model.Category{categories[0], {Name: "工资", Type: model.CategoryTypeIncome}}
+	assert.NotEqual(t, base, render(PromptRecognition, PromptData{UserID: 2, Categories: categories}))
+	assert.NotEqual(t, base, render(PromptRecognition, PromptData{UserID: 1, Categories: renamed}))
+	assert.NotEqual(t, base, render(PromptRecognition, PromptData{UserID: 1, Categories: categories, Instructions: "打车可报销"}))
+	assert.NotEqual(t, base, render(PromptTransactions, PromptData{UserID: 1, Categories: categories}))
+}
+
+func TestPromptStore_DefaultCategories(t *testing.T) {
+	store, err := NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+
+	prompt, err := store.Render(PromptRecognition, PromptData{})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Text, "- 金融：转账、还款、理财、保险\n\n【收入分类】")
+	assert.Contains(t, prompt.Text, "- 其他收入：退款、报销、意外来财\n\n注意事项：")
+}
+
+func TestPromptStore_TimeDependentPrompts(t *testing.T) {
+	store, err := NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+	now := time.Date(2025, 3, 7, 12, 30, 0, 0, time.FixedZone("CST", 8*3600))
+
+	prompt, err := store.Render(PromptText, PromptData{Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, "text-v2", prompt.Version)
+	assert.Contains(t, prompt.Text, "当前时间是2025-03-07T12:30:00+08:00（星期五）")
+
+	prompt, err = store.Render(PromptTransactions, PromptData{Now: now})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Text, "今天是2025-03-07，")
+
+	prompt, err = store.Render(PromptNotification, PromptData{Now: now})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Text, "收到通知的时间是2025-03-07T12:30:00+08:00，")
+}
+
+func TestPromptStore_OverrideAndReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "recognition.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{- /* version: custom-1 */ -}}识别截图{{template "instructions" .}}`), 0o644))
+
+	store, err := NewPromptStore(config.PromptConfig{Dir: dir, ReloadInterval: time.Nanosecond})
+	require.NoError(t, err)
+	prompt, err := store.Render(PromptRecognition, PromptData{Instructions: "标注报销"})
+	require.NoError(t, err)
+	assert.Equal(t, "custom-1", prompt.Version)
+	assert.Equal(t, "识别截图\n\n【用户自定义说明】以下是用户的个人要求，在不违反上述输出格式的前提下遵循；需要标注的内容写入remark字段：\n标注报销", prompt.Text)
+
+	// 其他类型仍使用内置模板
+	prompt, err = store.Render(PromptText, PromptData{})
+	require.NoError(t, err)
+	assert.Equal(t, "text-v2", prompt.Version)
+
+	// 未声明版本时使用内容摘要
+	require.NoError(t, os.WriteFile(path, []byte("新的提示词"), 0o644))
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, future, future))
+	prompt, err = store.Render(PromptRecognition, PromptData{})
+	require.NoError(t, err)
+	assert.Equal(t, "新的提示词", prompt.Text)
+	assert.Regexp(t, `^sha-[0-9a-f]{8}$`, prompt.Version)
+}
+
+func TestPromptStore_OverrideCommon(t *testing.T) {
+	builtin, err := NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+	before, err := builtin.Render(PromptRecognition, PromptData{})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	common, err := builtinPrompts.ReadFile("prompts/" + commonPromptFile)
+	require.NoError(t, err)
+	path := filepath.Join(dir, commonPromptFile)
+	require.NoError(t, os.WriteFile(path, append(common, "\n"...), 0o644))
+
+	// 覆盖公共片段后，声明了版本的模板也使用新的版本和缓存作用域
+	store, err := NewPromptStore(config.PromptConfig{Dir: dir})
+	require.NoError(t, err)
+	after, err := store.Render(PromptRecognition, PromptData{})
+	require.NoError(t, err)
+	assert.Regexp(t, `^recognition-v2\+[0-9a-f]{8}$`, after.Version)
+	assert.LessOrEqual(t, len(after.Version), 32)
+	assert.NotEqual(t, before.CacheScope, after.CacheScope)
+
+	// 与内置内容相同的覆盖不改变版本
+	require.NoError(t, os.WriteFile(path, common, 0o644))
+	store, err = NewPromptStore(config.PromptConfig{Dir: dir})
+	require.NoError(t, err)
+	after, err = store.Render(PromptRecognition, PromptData{})
+	require.NoError(t, err)
+	assert.Equal(t, before.Version, after.Version)
+}
+
+func TestNewPromptStore_InvalidOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "text.tmpl"), []byte("{{.Missing"), 0o644))
+
+	_, err := NewPromptStore(config.PromptConfig{Dir: dir})
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+)
+
+// testPrompt 测试用识别提示词，提供方客户端只负责透传
+const testPrompt = "识别这张支付截图，以JSON格式返回"
+
+const stubRecognizeContent = `{"platform":"微信支付","amount":17.3,"merchant":"沙县小吃","bill_type":1,"category":"餐饮","sub_category":"正餐","pay_time":"2025-12-11T12:24:23+08:00","pay_method":"零钱","order_no":"","items":[],"confidence":0.9}`
+
+// newOpenAICompatibleStub 模拟 OpenAI 兼容的 /chat/completions 接口
+func newOpenAICompatibleStub(t *testing.T, wantModel, wantFormat string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, wantModel, req["model"])
+		responseFormat, _ := req["response_format"].(map[string]interface{})
+		assert.Equal(t, wantFormat, responseFormat["type"])
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 1700000000,
+			"model":   wantModel,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]interface{}{"role": "assistant", "content": stubRecognizeContent},
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 100, "completion_tokens": 50, "total_tokens": 150},
+		})
+	}))
+}
+
+func TestNewClient_Providers(t *testing.T) {
+	assert.Equal(t, []string{"ollama", "openai", "qwen"}, Providers())
+
+	_, err := NewClient(&config.AIConfig{Provider: "unknown"})
+	assert.Error(t, err)
+
+	client, err := NewClient(&config.AIConfig{Provider: "ollama", Fallback: []string{"qwen"}})
+	require.NoError(t, err)
+	fallback, ok := client.(*FallbackClient)
+	require.True(t, ok)
+	require.Len(t, fallback.providers, 2)
+	assert.IsType(t, &OllamaClient{}, fallback.providers[0].client)
+	assert.Equal(t, "qwen", fallback.providers[1].name)
+}
+
+func TestQwenClient_RecognizePayment(t *testing.T) {
+	server := newOpenAICompatibleStub(t, qwenDefaultModel, StructuredOutputJSONObject)
+	defer server.Close()
+
+	client, err := NewClient(&config.AIConfig{
+		Provider: "qwen",
+		Qwen:     config.ProviderConfig{APIKey: "test-key", BaseURL: server.URL},
+	})
+	require.NoError(t, err)
+
+	result, err := client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "沙县小吃", result.Merchant)
+	assert.True(t, result.Amount.Equal(decimal.NewFromFloat(17.3)))
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, "qwen", result.Usage.Provider)
+	assert.Equal(t, qwenDefaultModel, result.Usage.Model)
+	assert.Equal(t, int64(100), result.Usage.PromptTokens)
+	assert.Equal(t, int64(50), result.Usage.CompletionTokens)
+}
+
+func TestOpenAIClient_RecognizePayment_JSONSchema(t *testing.T) {
+	server := newOpenAICompatibleStub(t, "gpt-4o-mini", StructuredOutputJSONSchema)
+	defer server.Close()
+
+	client, err := NewOpenAIClient(&config.ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o-mini"})
+	require.NoError(t, err)
+
+	result, err := client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "零钱", result.PayMethod)
+}
+
+func TestOpenAIClient_ImageDetail(t *testing.T) {
+	small := testJPEG(t, 400, 300, 0)
+	large := testJPEG(t, 1024, 768, 0)
+
+	client, err := NewOpenAIClient(&config.ProviderConfig{APIKey: "test-key"})
+	require.NoError(t, err)
+	assert.Equal(t, ImageDetailLow, client.detail(small), "长边不超过 512 时 high 不会更清晰")
+	assert.Equal(t, ImageDetailHigh, client.detail(large))
+	assert.Equal(t, ImageDetailHigh, client.detail([]byte("fake-image")), "无法读取尺寸时使用 high")
+
+	client, err = NewOpenAIClient(&config.ProviderConfig{APIKey: "test-key", ImageDetail: ImageDetailAuto})
+	require.NoError(t, err)
+	assert.Equal(t, ImageDetailAuto, client.detail(small))
+
+	_, err = NewOpenAIClient(&config.ProviderConfig{APIKey: "test-key", ImageDetail: "medium"})
+	assert.Error(t, err)
+
+	// 请求中携带所选的细节级别
+	var detail string
+	stub := newOpenAICompatibleStub(t, "gpt-4o", StructuredOutputJSONSchema)
+	defer stub.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content []struct {
+					ImageURL struct {
+						Detail string `json:"detail"`
+					} `json:"image_url"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &req))
+		require.Len(t, req.Messages, 1)
+		require.Len(t, req.Messages[0].Content, 2)
+		detail = req.Messages[0].Content[1].ImageURL.Detail
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		stub.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client, err = NewOpenAIClient(&config.ProviderConfig{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	_, err = client.RecognizePayment(context.Background(), small, "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, ImageDetailLow, detail)
+}
+
+func TestOllamaClient_RecognizePayment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+
+		var req ollamaChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llava", req.Model)
+		format, ok := req.Format.(map[string]interface{})
+		require.True(t, ok, "format 应为 JSON Schema")
+		assert.Equal(t, "object", format["type"])
+		require.Len(t, req.Messages, 1)
+		assert.Equal(t, []string{ImageToBase64([]byte("fake-image"))}, req.Messages[0].Images)
+
+		json.NewEncoder(w).Encode(ollamaChatResponse{
+			Message:         ollamaMessage{Role: "assistant", Content: stubRecognizeContent},
+			PromptEvalCount: 100,
+			EvalCount:       50,
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(&config.ProviderConfig{BaseURL: server.URL, Model: "llava"})
+	require.NoError(t, err)
+
+	result, err := client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "微信支付", result.Platform)
+	assert.Equal(t, 1, result.BillType)
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, "llava", result.Usage.Model)
+	assert.Equal(t, int64(100), result.Usage.PromptTokens)
+	assert.Equal(t, int64(50), result.Usage.CompletionTokens)
+}
+
+func TestOllamaClient_RecognizePayment_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model 'llava' not found"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(&config.ProviderConfig{BaseURL: server.URL, Model: "llava"})
+	require.NoError(t, err)
+
+	_, err = client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", "prompt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestOllamaClient_RecognizeTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		format, ok := req.Format.(map[string]interface{})
+		require.True(t, ok, "format 应为 JSON Schema")
+		properties, _ := format["properties"].(map[string]interface{})
+		assert.Contains(t, properties, "transactions")
+
+		json.NewEncoder(w).Encode(ollamaChatResponse{
+			Message: ollamaMessage{Role: "assistant", Content: `{"transactions":[` +
+				stubRecognizeContent + `,{"amount":5,"merchant":"公交","bill_type":1,"confidence":0.7}]}`},
+			PromptEvalCount: 200,
+			EvalCount:       80,
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient(&config.ProviderConfig{BaseURL: server.URL, Model: "llava"})
+	require.NoError(t, err)
+
+	result, err := client.RecognizeTransactions(context.Background(), []byte("fake-image"), "image/jpeg", "prompt")
+	require.NoError(t, err)
+	require.Len(t, result.Transactions, 2)
+	assert.Equal(t, "沙县小吃", result.Transactions[0].Merchant)
+	assert.Equal(t, "公交", result.Transactions[1].Merchant)
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, int64(200), result.Usage.PromptTokens)
+}
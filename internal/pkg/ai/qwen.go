@@ -0,0 +1,33 @@
+package ai
+
+import "smart-ledger-server/internal/config"
+
+const (
+	// qwenDefaultBaseURL DashScope 的 OpenAI 兼容模式地址
+	qwenDefaultBaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+	// qwenDefaultModel 默认视觉模型
+	qwenDefaultModel = "qwen-vl-max"
+)
+
+func init() {
+	RegisterProvider("qwen", func(cfg *config.ProviderConfig) (Client, error) {
+		return NewQwenClient(cfg)
+	})
+}
+
+// NewQwenClient 创建通义千问（DashScope）客户端
+// DashScope 提供 OpenAI 兼容接口，直接复用 OpenAIClient
+func NewQwenClient(cfg *config.ProviderConfig) (*OpenAIClient, error) {
+	qwenCfg := *cfg
+	if qwenCfg.BaseURL == "" {
+		qwenCfg.BaseURL = qwenDefaultBaseURL
+	}
+	if qwenCfg.Model == "" {
+		qwenCfg.Model = qwenDefaultModel
+	}
+	// DashScope 兼容模式仅支持 json_object
+	if qwenCfg.StructuredOutput == "" {
+		qwenCfg.StructuredOutput = StructuredOutputJSONObject
+	}
+	return NewOpenAIClient(&qwenCfg)
+}
@@ -20,14 +20,8 @@ func NewRPMLimiter(rpm int) *RPMLimiter {
 	r := rate.Limit(float64(rpm) / 60.0)
 
 	// 桶大小设置为 rpm/10，允许小范围突发
-	// 但不超过 rpm/6（即10秒的量），最小为1
-	burstSize := rpm / 10
-	if burstSize < 1 {
-		burstSize = 1
-	}
-	if burstSize > rpm/6 {
-		burstSize = rpm / 6
-	}
+	// 最小为1：桶大小为 0 时 Wait 总是失败
+	burstSize := max(rpm/10, 1)
 
 	return &RPMLimiter{
 		limiter: rate.NewLimiter(r, burstSize),
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"smart-ledger-server/internal/config"
+)
+
+// ProviderFactory 根据提供方配置创建AI客户端
+type ProviderFactory func(cfg *config.ProviderConfig) (Client, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+// RegisterProvider 注册AI提供方，通常在提供方实现文件的 init 中调用
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("AI提供方重复注册: %s", name))
+	}
+	providers[name] = factory
+}
+
+// Providers 获取已注册的提供方名称
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProviderClient 根据提供方名称创建AI客户端
+func NewProviderClient(name string, cfg *config.ProviderConfig) (Client, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的AI提供方: %s", name)
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Interaction 录制的一次 HTTP 请求和响应
+// 请求只保存摘要，不保存图片和请求头（含 API 密钥）
+type Interaction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`         // 请求地址，仅供查看，回放时不比较
+	BodySHA256  string `json:"body_sha256"` // 请求体摘要
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// RecordingTransport 转发请求到真实的提供方并录制交互，Save 后作为测试夹具使用
+type RecordingTransport struct {
+	path string
+	base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecordingTransport 创建录制传输层，base 为空时使用 http.DefaultTransport
+func NewRecordingTransport(path string, base http.RoundTripper) *RecordingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RecordingTransport{path: path, base: base}
+}
+
+// RoundTrip 转发请求并记录响应
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	t.mu.Lock()
+	t.interactions = append(t.interactions, Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		BodySHA256:  bodyDigest(reqBody),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(respBody),
+	})
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// Save 将录制的交互写入夹具文件
+func (t *RecordingTransport) Save() error {
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, append(data, '\n'), 0o644)
+}
+
+// ReplayTransport 从夹具文件回放录制的交互，不访问网络
+// 按请求方法和请求体摘要匹配，每条交互只回放一次；不比较地址，回放时可使用任意 BaseURL
+type ReplayTransport struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayTransport 读取夹具文件创建回放传输层
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("解析夹具 %s 失败: %w", path, err)
+	}
+	return &ReplayTransport{interactions: interactions, used: make([]bool, len(interactions))}, nil
+}
+
+// RoundTrip 返回匹配的录制响应，没有匹配的交互时返回错误
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	digest := bodyDigest(reqBody)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, interaction := range t.interactions {
+		if t.used[i] || interaction.Method != req.Method || interaction.BodySHA256 != digest {
+			continue
+		}
+		t.used[i] = true
+		header := make(http.Header)
+		if interaction.ContentType != "" {
+			header.Set("Content-Type", interaction.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Body))),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("没有匹配的录制请求: %s %s（请求体摘要 %s），请求内容变化后需要重新录制", req.Method, req.URL, digest)
+}
+
+// readRequestBody 读取请求体并重置，使请求可以继续发送
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
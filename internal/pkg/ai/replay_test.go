@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+)
+
+func TestRecordingAndReplayTransport(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixtures", "openai.json")
+
+	// 录制：请求发送到模拟的提供方
+	server := newOpenAICompatibleStub(t, "gpt-4o-mini", StructuredOutputJSONSchema)
+	recorder := NewRecordingTransport(fixture, nil)
+	client, err := NewOpenAIClient(&config.ProviderConfig{
+		APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o-mini", Transport: recorder,
+	})
+	require.NoError(t, err)
+	recorded, err := client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save())
+	server.Close()
+
+	data, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "test-key")
+	assert.NotContains(t, string(data), testPrompt)
+
+	// 回放：提供方已关闭，地址也不同
+	replay, err := NewReplayTransport(fixture)
+	require.NoError(t, err)
+	client, err = NewOpenAIClient(&config.ProviderConfig{
+		APIKey: "other-key", BaseURL: "http://replay.invalid/v1", Model: "gpt-4o-mini", Transport: replay,
+	})
+	require.NoError(t, err)
+	replayed, err := client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, recorded.Merchant, replayed.Merchant)
+	assert.True(t, recorded.Amount.Equal(replayed.Amount))
+	assert.Equal(t, recorded.Usage, replayed.Usage)
+
+	// 每条交互只回放一次，请求内容不同时无法匹配
+	_, err = client.RecognizePayment(context.Background(), []byte("fake-image"), "image/jpeg", testPrompt)
+	assert.Error(t, err)
+	_, err = client.RecognizePayment(context.Background(), []byte("other-image"), "image/jpeg", testPrompt)
+	assert.Error(t, err)
+}
+
+func TestNewReplayTransport_MissingFixture(t *testing.T) {
+	_, err := NewReplayTransport(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
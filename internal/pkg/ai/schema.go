@@ -0,0 +1,116 @@
+package ai
+
+// 结构化输出模式
+const (
+	StructuredOutputJSONSchema = "json_schema" // 按 JSON Schema 约束输出（OpenAI / Ollama）
+	StructuredOutputJSONObject = "json_object" // 仅保证输出合法 JSON（DashScope 等）
+	StructuredOutputNone       = "none"        // 不使用结构化输出，依赖提示词约束
+)
+
+// Schema 名称
+const (
+	paymentSchemaName      = "payment_recognition"      // 单笔支付识别结果
+	transactionsSchemaName = "transactions_recognition" // 多笔交易识别结果
+	queryPlanSchemaName    = "ledger_query_plan"        // 账本查询计划
+	insightSchemaName      = "monthly_insight"          // 月度消费洞察
+)
+
+// PaymentSchema 支付识别结果的 JSON Schema
+// 为兼容 OpenAI strict 模式，所有字段均为 required，可缺失的字段使用 null 类型表达
+func PaymentSchema() map[string]interface{} {
+	nullableString := map[string]interface{}{"type": []string{"string", "null"}}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"platform":     nullableString,
+			"amount":       map[string]interface{}{"type": "number", "description": "金额，不含货币符号"},
+			"merchant":     nullableString,
+			"bill_type":    map[string]interface{}{"type": "integer", "enum": []int{1, 2}, "description": "1=支出，2=收入"},
+			"category":     nullableString,
+			"sub_category": nullableString,
+			"pay_time":     map[string]interface{}{"type": []string{"string", "null"}, "description": "ISO 8601 格式，缺失时为空字符串"},
+			"pay_method":   nullableString,
+			"order_no":     nullableString,
+			"items": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":     map[string]interface{}{"type": "string"},
+						"price":    map[string]interface{}{"type": []string{"number", "null"}},
+						"quantity": map[string]interface{}{"type": []string{"number", "null"}},
+					},
+					"required":             []string{"name", "price", "quantity"},
+					"additionalProperties": false,
+				},
+			},
+			"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+			"remark":     map[string]interface{}{"type": []string{"string", "null"}, "description": "按用户自定义说明需要标注的内容，没有时为空"},
+		},
+		"required": []string{
+			"platform", "amount", "merchant", "bill_type", "category", "sub_category",
+			"pay_time", "pay_method", "order_no", "items", "confidence", "remark",
+		},
+		"additionalProperties": false,
+	}
+}
+
+// TransactionsSchema 多笔交易识别结果的 JSON Schema
+// 根节点为对象（OpenAI strict 模式要求），transactions 中每一项与单笔支付识别结果结构一致
+func TransactionsSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"transactions": map[string]interface{}{
+				"type":  "array",
+				"items": PaymentSchema(),
+			},
+		},
+		"required":             []string{"transactions"},
+		"additionalProperties": false,
+	}
+}
+
+// QueryPlanSchema 账本查询计划的 JSON Schema
+// 指标和取值方式限定为枚举，模型只能在已有的统计方法中选择
+func QueryPlanSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"metric": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"total", "by_category", "by_day", "by_month", "unsupported"},
+				"description": "total=总额，by_category=按分类，by_day=按日，by_month=按月，unsupported=无法用账单统计回答",
+			},
+			"bill_type":  map[string]interface{}{"type": "integer", "enum": []int{1, 2}, "description": "1=支出，2=收入"},
+			"start_date": map[string]interface{}{"type": "string", "description": "开始日期，格式 2006-01-02，含当天"},
+			"end_date":   map[string]interface{}{"type": "string", "description": "结束日期，格式 2006-01-02，含当天"},
+			"category":   map[string]interface{}{"type": []string{"string", "null"}, "description": "一级或二级分类名称，不限分类时为空"},
+			"pick": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"all", "max", "min", "avg"},
+				"description": "all=全部明细，max=最大的一项，min=最小的一项，avg=平均值",
+			},
+		},
+		"required":             []string{"metric", "bill_type", "start_date", "end_date", "category", "pick"},
+		"additionalProperties": false,
+	}
+}
+
+// InsightSchema 月度消费洞察的 JSON Schema
+func InsightSchema() map[string]interface{} {
+	stringList := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": description}
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"summary":     map[string]interface{}{"type": "string", "description": "本月收支概况，一到两句话"},
+			"changes":     stringList("与上月相比变化最大的分类"),
+			"merchants":   stringList("值得注意的商户（新出现或金额明显增加）"),
+			"suggestions": stringList("节省开支的建议"),
+		},
+		"required":             []string{"summary", "changes", "merchants", "suggestions"},
+		"additionalProperties": false,
+	}
+}
@@ -10,10 +10,16 @@ import (
 )
 
 // Task 表示单个图片识别任务
+// File 为空时使用 Data/MimeType/FileName（如异步任务从存储中读取的图片）
 type Task struct {
-	Index  int                   // 图片索引（用于结果排序）
-	File   *multipart.FileHeader // 图片文件
-	Prompt string                // 识别提示词
+	Index    int                   // 图片索引（用于结果排序）
+	File     *multipart.FileHeader // 图片文件
+	Data     []byte                // 图片数据（File 为空时使用）
+	MimeType string                // 图片类型（File 为空时使用）
+	FileName string                // 文件名（File 为空时使用）
+	Prompt   string                // 识别提示词
+	// CacheScope 识别结果缓存的作用域（Prompt.CacheScope）
+	CacheScope string
 }
 
 // TaskResult 任务执行结果
@@ -24,6 +30,7 @@ type TaskResult struct {
 	Data     *dto.AIRecognizeResponse // 识别结果（成功时）
 	Error    string                   // 错误信息（失败时）
 	Duration int64                    // 处理耗时(毫秒)
+	Called   bool                     // 是否调用了AI客户端（用于用量统计）
 }
 
 // WorkerPool 任务池
@@ -49,6 +56,12 @@ func NewWorkerPool(workerCount int, limiter *RPMLimiter, taskTimeout time.Durati
 // Execute 执行批量任务
 // 返回与输入任务顺序一致的结果数组
 func (p *WorkerPool) Execute(ctx context.Context, tasks []Task) []TaskResult {
+	return p.ExecuteWithProgress(ctx, tasks, nil)
+}
+
+// ExecuteWithProgress 执行批量任务，每完成一个任务回调一次 onResult
+// onResult 在同一个协程中按完成顺序调用，返回与输入任务顺序一致的结果数组
+func (p *WorkerPool) ExecuteWithProgress(ctx context.Context, tasks []Task, onResult func(TaskResult)) []TaskResult {
 	results := make([]TaskResult, len(tasks))
 
 	// 任务通道
@@ -79,8 +92,16 @@ func (p *WorkerPool) Execute(ctx context.Context, tasks []Task) []TaskResult {
 	}()
 
 	// 收集结果
+	// 按任务在切片中的位置存放结果，Index 仅用于标识
+	positions := make(map[int]int, len(tasks))
+	for i, task := range tasks {
+		positions[task.Index] = i
+	}
 	for result := range resultChan {
-		results[result.Index] = result
+		results[positions[result.Index]] = result
+		if onResult != nil {
+			onResult(result)
+		}
 	}
 
 	return results
@@ -101,7 +122,10 @@ func (p *WorkerPool) processTask(ctx context.Context, task Task) TaskResult {
 	startTime := time.Now()
 	result := TaskResult{
 		Index:    task.Index,
-		FileName: task.File.Filename,
+		FileName: task.FileName,
+	}
+	if task.File != nil {
+		result.FileName = task.File.Filename
 	}
 
 	// 创建带超时的context
@@ -115,31 +139,16 @@ func (p *WorkerPool) processTask(ctx context.Context, task Task) TaskResult {
 		return result
 	}
 
-	// 校验文件大小
-	if task.File.Size > p.maxImageSize {
-		result.Error = "图片过大"
-		result.Duration = time.Since(startTime).Milliseconds()
-		return result
-	}
-
-	// 校验文件类型
-	contentType := task.File.Header.Get("Content-Type")
-	if !isValidImageType(contentType) {
-		result.Error = "图片格式无效"
-		result.Duration = time.Since(startTime).Milliseconds()
-		return result
-	}
-
-	// 读取图片数据
-	imageData, mimeType, err := ReadImageFromFile(task.File)
-	if err != nil {
-		result.Error = "读取图片失败"
+	imageData, mimeType, errMsg := p.loadImage(task)
+	if errMsg != "" {
+		result.Error = errMsg
 		result.Duration = time.Since(startTime).Milliseconds()
 		return result
 	}
 
 	// 调用AI识别
-	aiResult, err := p.client.RecognizePayment(taskCtx, imageData, mimeType, task.Prompt)
+	result.Called = true
+	aiResult, err := p.client.RecognizePayment(WithCacheScope(taskCtx, task.CacheScope), imageData, mimeType, task.Prompt)
 	if err != nil {
 		result.Error = err.Error()
 		result.Duration = time.Since(startTime).Milliseconds()
@@ -152,6 +161,34 @@ func (p *WorkerPool) processTask(ctx context.Context, task Task) TaskResult {
 	return result
 }
 
+// loadImage 校验并读取任务图片，失败时返回错误信息
+func (p *WorkerPool) loadImage(task Task) ([]byte, string, string) {
+	if task.File == nil {
+		if int64(len(task.Data)) > p.maxImageSize {
+			return nil, "", "图片过大"
+		}
+		if !isValidImageType(task.MimeType) {
+			return nil, "", "图片格式无效"
+		}
+		return task.Data, task.MimeType, ""
+	}
+
+	// 校验文件大小
+	if task.File.Size > p.maxImageSize {
+		return nil, "", "图片过大"
+	}
+
+	// 读取图片数据并按内容校验类型
+	imageData, mimeType, err := ReadImageFromFile(task.File)
+	if err != nil {
+		return nil, "", "读取图片失败"
+	}
+	if !isValidImageType(mimeType) {
+		return nil, "", "图片格式无效"
+	}
+	return imageData, mimeType, ""
+}
+
 // isValidImageType 检查图片类型
 func isValidImageType(contentType string) bool {
 	validTypes := map[string]bool{
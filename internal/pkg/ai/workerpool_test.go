@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/model/dto"
+)
+
+func TestWorkerPool_ExecuteWithProgress(t *testing.T) {
+	pool := NewWorkerPool(2, NewRPMLimiter(6000), time.Second, &sequenceClient{}, 1024)
+
+	// Index 不连续（如恢复任务时只处理剩余图片），结果仍按任务顺序返回
+	tasks := []Task{
+		{Index: 3, Data: []byte("image"), MimeType: "image/png", FileName: "a.png"},
+		{Index: 5, Data: []byte("image"), MimeType: "text/plain", FileName: "b.txt"},
+		{Index: 7, Data: make([]byte, 2048), MimeType: "image/jpeg", FileName: "c.jpg"},
+	}
+
+	var progress []int
+	results := pool.ExecuteWithProgress(context.Background(), tasks, func(result TaskResult) {
+		progress = append(progress, result.Index)
+	})
+
+	require.Len(t, results, 3)
+	assert.ElementsMatch(t, []int{3, 5, 7}, progress)
+
+	assert.Equal(t, 3, results[0].Index)
+	assert.Equal(t, "a.png", results[0].FileName)
+	assert.True(t, results[0].Success)
+	assert.True(t, results[0].Called)
+	assert.Equal(t, "ok", results[0].Data.Merchant)
+
+	assert.Equal(t, "图片格式无效", results[1].Error)
+	assert.False(t, results[1].Called)
+
+	assert.Equal(t, "图片过大", results[2].Error)
+	assert.False(t, results[2].Called)
+}
+
+func TestWorkerPool_ResultOrder(t *testing.T) {
+	// 靠前的任务耗时更长，完成顺序与任务顺序相反
+	client := &FakeClient{Handler: func(ctx context.Context, call FakeCall) FakeReply {
+		n := len(call.ImageData)
+		return FakeReply{
+			Result: &dto.AIRecognizeResponse{Merchant: string(call.ImageData)},
+			Delay:  time.Duration(4-n) * 20 * time.Millisecond,
+		}
+	}}
+	pool := NewWorkerPool(3, NewRPMLimiter(6000), time.Second, client, 1024)
+
+	tasks := []Task{
+		{Index: 0, Data: []byte("a"), MimeType: "image/png"},
+		{Index: 1, Data: []byte("bb"), MimeType: "image/png"},
+		{Index: 2, Data: []byte("ccc"), MimeType: "image/png"},
+	}
+	results := pool.Execute(context.Background(), tasks)
+
+	require.Len(t, results, 3)
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		require.True(t, result.Success, result.Error)
+		assert.Equal(t, string(tasks[i].Data), result.Data.Merchant)
+	}
+	assert.Len(t, client.Calls(), 3)
+}
+
+func TestWorkerPool_TaskTimeout(t *testing.T) {
+	client := NewFakeClient(
+		FakeReply{Result: &dto.AIRecognizeResponse{Merchant: "slow"}, Delay: time.Minute},
+	)
+	pool := NewWorkerPool(1, NewRPMLimiter(6000), 20*time.Millisecond, client, 1024)
+
+	start := time.Now()
+	results := pool.Execute(context.Background(), []Task{{Index: 0, Data: []byte("image"), MimeType: "image/jpeg"}})
+
+	assert.Less(t, time.Since(start), time.Second)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.True(t, results[0].Called)
+	assert.Contains(t, results[0].Error, context.DeadlineExceeded.Error())
+}
+
+func TestWorkerPool_LimiterCancellation(t *testing.T) {
+	// 6 RPM 的桶只有一个令牌，第二个任务需要等待约 10 秒
+	client := NewFakeClient(
+		FakeReply{Result: &dto.AIRecognizeResponse{Merchant: "first"}},
+		FakeReply{Result: &dto.AIRecognizeResponse{Merchant: "second"}},
+	)
+	pool := NewWorkerPool(1, NewRPMLimiter(6), time.Minute, client, 1024)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tasks := []Task{
+		{Index: 0, Data: []byte("image"), MimeType: "image/jpeg"},
+		{Index: 1, Data: []byte("image"), MimeType: "image/jpeg"},
+	}
+
+	start := time.Now()
+	results := pool.ExecuteWithProgress(ctx, tasks, func(result TaskResult) {
+		// 第一个任务完成后取消，等待令牌的任务应立即返回
+		cancel()
+	})
+
+	assert.Less(t, time.Since(start), time.Second)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.False(t, results[1].Called)
+	assert.Equal(t, "请求过于频繁，请稍后重试", results[1].Error)
+	assert.Len(t, client.Calls(), 1)
+}
+
+func TestNewRPMLimiter_LowRPM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, NewRPMLimiter(1).Wait(ctx))
+}
@@ -23,6 +23,9 @@ func InitRedis(cfg *config.RedisConfig, log *zap.Logger) error {
 	// 测试连接
 	ctx := context.Background()
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		// 连接失败时清空客户端，GetRedis 返回 nil 以便调用方降级
+		_ = rdb.Close()
+		rdb = nil
 		return fmt.Errorf("连接Redis失败: %w", err)
 	}
 
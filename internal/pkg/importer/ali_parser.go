@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+func init() {
+	Register(ParserInfo{Type: ParserTypeAli, Name: "支付宝", Formats: []string{"csv"}}, func() Parser { return NewAliParser() })
+}
+
+// AliParser 支付宝账单解析器，支持手机端和电脑端导出的 csv 文件（GBK 编码）
+// 导出文件在表头前后都有说明文字，按“收/支”所在行定位表头，遇到分隔线后结束
+type AliParser struct{}
+
+func NewAliParser() *AliParser {
+	return &AliParser{}
+}
+
+// 支付宝账单的列名，手机端和电脑端导出的列名不同时按顺序取第一个有值的列
+var (
+	aliColTime      = []string{"交易时间", "付款时间", "交易创建时间"}
+	aliColCategory  = []string{"交易分类"}
+	aliColCounter   = []string{"交易对方"}
+	aliColGoods     = []string{"商品说明", "商品名称"}
+	aliColDirection = []string{"收/支"}
+	aliColAmount    = []string{"金额", "金额（元）"}
+	aliColPayMethod = []string{"收/付款方式"}
+	aliColStatus    = []string{"交易状态"}
+	aliColFund      = []string{"资金状态"}
+	aliColRefunded  = []string{"成功退款（元）"}
+	aliColOrderNo   = []string{"交易订单号", "交易号"}
+	aliColMerchNo   = []string{"商家订单号"}
+	aliColRemark    = []string{"备注"}
+)
+
+// aliRequiredColumns 解析必需的列
+var aliRequiredColumns = [][]string{aliColTime, aliColDirection, aliColAmount, aliColStatus, aliColOrderNo}
+
+// aliSkippedStatuses 不导入的交易状态：交易关闭（未付款或已全额退款）、退款记录、未完成付款
+var aliSkippedStatuses = map[string]bool{
+	"交易关闭": true,
+	"退款成功": true,
+	"等待付款": true,
+}
+
+// Detect 表格中有支付宝账单的表头
+func (p *AliParser) Detect(rows [][]string) bool {
+	_, _, err := findAliHeader(rows)
+	return err == nil
+}
+
+func (p *AliParser) Parse(rows [][]string) (*ParseResult, error) {
+	headerIndex, header, err := findAliHeader(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ParseResult{}
+	for i, row := range rows[headerIndex+1:] {
+		if len(row) > 0 && strings.HasPrefix(strings.TrimSpace(row[0]), "---") {
+			// 表尾的汇总信息
+			break
+		}
+		rowNum := headerIndex + i + 2
+		rowData := buildRowData(header, row)
+		if len(rowData) == 0 {
+			//跳过空行
+			continue
+		}
+		get := func(names []string) string {
+			for _, name := range names {
+				if value := rowData[name]; value != "" {
+					return value
+				}
+			}
+			return ""
+		}
+
+		direction := get(aliColDirection)
+		if direction != "支出" && direction != "收入" || aliSkippedStatuses[get(aliColStatus)] || get(aliColFund) == "资金转移" {
+			// 不计收支（余额宝转入转出、还款等）、关闭和退款的交易不导入
+			result.Skipped++
+			continue
+		}
+
+		amount, err := parseAmount(get(aliColAmount))
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  "金额",
+				Message: "金额格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+		if refunded, err := parseAmount(get(aliColRefunded)); err == nil && refunded.IsPositive() {
+			// 电脑端导出的部分退款记录在原交易的“成功退款”列中
+			amount = amount.Sub(refunded)
+			if !amount.IsPositive() {
+				result.Skipped++
+				continue
+			}
+		}
+
+		payTime, err := parseExportTime(get(aliColTime))
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  "交易时间",
+				Message: "交易时间格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+
+		billType := 1
+		if direction == "收入" {
+			billType = 2
+		}
+		orderNo := get(aliColOrderNo)
+		if orderNo == "" {
+			orderNo = get(aliColMerchNo)
+		}
+		result.Records = append(result.Records, BillRecord{
+			Row:          rowNum,
+			PayTime:      payTime,
+			Amount:       amount.String(),
+			BillType:     billType,
+			Merchant:     get(aliColCounter),
+			CategoryName: get(aliColCategory),
+			Platform:     p.GetPlatform(),
+			PayMethod:    get(aliColPayMethod),
+			OrderNo:      orderNo,
+			Remark:       joinNonEmpty("；", get(aliColGoods), get(aliColRemark)),
+			RowData:      rowData,
+		})
+	}
+	return result, nil
+}
+
+func (p *AliParser) GetPlatform() string {
+	return "支付宝"
+}
+
+// findAliHeader 查找支付宝账单的表头并检查必需的列，返回表头的行下标和去除空白后的列名
+func findAliHeader(rows [][]string) (int, []string, error) {
+	headerIndex := findHeader(rows, aliColDirection[0], aliColStatus[0])
+	if headerIndex < 0 {
+		return -1, nil, pkgerrors.New("未找到支付宝账单表头，请上传支付宝导出的账单文件")
+	}
+	header := trimCells(rows[headerIndex])
+	for _, names := range aliRequiredColumns {
+		if !containsAny(header, names...) {
+			return -1, nil, pkgerrors.Errorf("支付宝账单缺少列: %s", names[0])
+		}
+	}
+	return headerIndex, header, nil
+}
+
+// joinNonEmpty 用分隔符连接非空字符串
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliParser_Parse(t *testing.T) {
+	parseResult, err := parseFile(t, "test_data/支付宝交易明细.csv", ParserTypeAli)
+	require.NoError(t, err)
+	records := parseResult.Records
+	require.Len(t, records, 3)
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+
+	// 第1条记录：GBK 编码正确解码，商品说明和备注合并为备注
+	assert.Equal(t, 18, records[0].Row)
+	assert.Equal(t, time.Date(2025, 12, 15, 12, 1, 2, 0, loc), records[0].PayTime)
+	assert.Equal(t, "45.5", records[0].Amount)
+	assert.Equal(t, 1, records[0].BillType)
+	assert.Equal(t, "肯德基", records[0].Merchant)
+	assert.Equal(t, "餐饮美食", records[0].CategoryName)
+	assert.Equal(t, "花呗", records[0].PayMethod)
+	assert.Equal(t, "2025121522001100001", records[0].OrderNo)
+	assert.Equal(t, "肯德基外卖订单；少辣", records[0].Remark)
+	assert.Equal(t, "支付宝", records[0].Platform)
+	assert.Equal(t, "T20251215001", records[0].RowData["商家订单号"])
+
+	// 第2条记录
+	assert.Equal(t, "1.5", records[1].Amount)
+	assert.Equal(t, "哈啰出行", records[1].Merchant)
+
+	// 第3条记录：收入
+	assert.Equal(t, "3000", records[2].Amount)
+	assert.Equal(t, 2, records[2].BillType)
+	assert.Equal(t, "张三", records[2].Merchant)
+	assert.Empty(t, records[2].PayMethod)
+
+	// 交易关闭、退款、余额宝转入和花呗还款不导入
+	assert.Equal(t, 4, parseResult.Skipped)
+
+	require.Len(t, parseResult.Errors, 1)
+	assert.Equal(t, 24, parseResult.Errors[0].Row)
+	assert.Equal(t, "便利店", parseResult.Errors[0].RowData["交易对方"])
+}
+
+func TestAliParser_Parse_PCExport(t *testing.T) {
+	parseResult, err := parseFile(t, "test_data/支付宝交易记录（电脑端）.csv", ParserTypeAli)
+	require.NoError(t, err)
+	records := parseResult.Records
+	require.Len(t, records, 3)
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+
+	// 第1条记录：列名和单元格带空白，优先使用付款时间
+	assert.Equal(t, 6, records[0].Row)
+	assert.Equal(t, time.Date(2025, 12, 15, 11, 0, 5, 0, loc), records[0].PayTime)
+	assert.Equal(t, "38", records[0].Amount)
+	assert.Equal(t, "星巴克", records[0].Merchant)
+	assert.Equal(t, "咖啡", records[0].Remark)
+	assert.Equal(t, "2025121522001100011", records[0].OrderNo)
+
+	// 第2条记录：扣除成功退款的金额
+	assert.Equal(t, "150", records[1].Amount)
+	assert.Equal(t, "运动鞋；退了一双袜子", records[1].Remark)
+
+	// 第3条记录：没有付款时间时使用交易创建时间
+	assert.Equal(t, 10, records[2].Row)
+	assert.Equal(t, time.Date(2025, 12, 11, 9, 30, 0, 0, loc), records[2].PayTime)
+	assert.Equal(t, "8000", records[2].Amount)
+	assert.Equal(t, 2, records[2].BillType)
+
+	// 资金转移和交易关闭不导入，表尾汇总不解析
+	assert.Equal(t, 2, parseResult.Skipped)
+	assert.Empty(t, parseResult.Errors)
+}
+
+func TestAliParser_GetPlatform(t *testing.T) {
+	parser := NewAliParser()
+	assert.Equal(t, "支付宝", parser.GetPlatform())
+}
+
+func TestAliParser_Parse_NotAliBill(t *testing.T) {
+	_, err := parseFile(t, "test_data/微信支付账单.csv", ParserTypeAli)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "未找到支付宝账单表头")
+}
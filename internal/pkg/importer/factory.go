@@ -1,20 +1,121 @@
 package importer
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
 
 type ParserType string
 
 const (
-	ParserTypeVivo ParserType = "vivo"
-	ParserTypeWX   ParserType = "wx"  //TODO: 微信账单导入
-	ParserTypeAli  ParserType = "ali" //TODO: 支付宝账单导入
+	ParserTypeAuto   ParserType = "auto"   // 根据表头自动识别
+	ParserTypeCustom ParserType = "custom" // 用户自定义列映射，不注册到解析器列表
+	ParserTypeVivo   ParserType = "vivo"
+	ParserTypeWX     ParserType = "wx"
+	ParserTypeAli    ParserType = "ali"
+)
+
+var (
+	// ErrUnsupportedParser 解析器类型未注册
+	ErrUnsupportedParser = errors.New("暂不支持的解析器类型")
+	// ErrUnknownFormat 自动识别时没有解析器支持该文件
+	ErrUnknownFormat = errors.New("无法识别账单格式，请选择账单来源后重试")
+	// ErrInvalidMapping 自定义列映射无效或与文件不符
+	ErrInvalidMapping = errors.New("列映射无效")
+)
+
+// ParserInfo 解析器信息，供客户端展示可选的导入来源
+type ParserInfo struct {
+	Type    ParserType
+	Name    string
+	Formats []string // 支持的文件格式，如 xlsx、csv
+}
+
+type registration struct {
+	info      ParserInfo
+	newParser func() Parser
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []registration
 )
 
-func NewParser(parserType ParserType) (ExcelParser, error) {
-	switch parserType {
-	case ParserTypeVivo:
-		return NewVivoParser(), nil
-	default:
-		return nil, fmt.Errorf("暂不支持的解析器类型: %s", parserType)
+// Register 注册解析器，在解析器所在文件的 init 中调用，类型重复时 panic
+func Register(info ParserInfo, newParser func() Parser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if info.Type == ParserTypeAuto || info.Type == ParserTypeCustom {
+		panic(fmt.Sprintf("importer: 解析器类型 %s 为保留类型", info.Type))
+	}
+	for _, r := range registry {
+		if r.info.Type == info.Type {
+			panic(fmt.Sprintf("importer: 解析器类型 %s 重复注册", info.Type))
+		}
+	}
+	registry = append(registry, registration{info: info, newParser: newParser})
+}
+
+// Parsers 已注册的解析器，按注册顺序排列
+func Parsers() []ParserInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	infos := make([]ParserInfo, len(registry))
+	for i, r := range registry {
+		infos[i] = r.info
+	}
+	return infos
+}
+
+func NewParser(parserType ParserType) (Parser, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, r := range registry {
+		if r.info.Type == parserType {
+			return r.newParser(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedParser, parserType)
+}
+
+// Parse 读取账单文件并按解析器类型解析，类型为 auto 时根据表头自动识别
+func Parse(r io.Reader, filename string, parserType ParserType) (*ParseResult, error) {
+	var parser Parser
+	if parserType != ParserTypeAuto {
+		var err error
+		if parser, err = NewParser(parserType); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := ReadTable(r, filename)
+	if err != nil {
+		return nil, err
+	}
+	if parser == nil {
+		if parserType, parser = detect(rows); parser == nil {
+			return nil, ErrUnknownFormat
+		}
+	}
+
+	result, err := parser.Parse(rows)
+	if err != nil {
+		return nil, err
+	}
+	result.ParserType = parserType
+	return result, nil
+}
+
+// detect 按注册顺序找到第一个支持该表格的解析器
+func detect(rows [][]string) (ParserType, Parser) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, r := range registry {
+		if parser := r.newParser(); parser.Detect(rows) {
+			return r.info.Type, parser
+		}
 	}
+	return "", nil
 }
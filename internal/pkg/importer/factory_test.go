@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseFile 读取测试文件并按解析器类型解析
+func parseFile(t *testing.T, path string, parserType ParserType) (*ParseResult, error) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	return Parse(file, filepath.Base(path), parserType)
+}
+
+func TestNewParser(t *testing.T) {
+	parser, err := NewParser(ParserTypeWX)
+	require.NoError(t, err)
+	assert.Equal(t, "微信支付", parser.GetPlatform())
+
+	parser, err = NewParser(ParserTypeAli)
+	require.NoError(t, err)
+	assert.Equal(t, "支付宝", parser.GetPlatform())
+
+	_, err = NewParser("unknown")
+	assert.True(t, errors.Is(err, ErrUnsupportedParser))
+	_, err = NewParser(ParserTypeAuto)
+	assert.True(t, errors.Is(err, ErrUnsupportedParser))
+}
+
+func TestParsers(t *testing.T) {
+	var types []ParserType
+	for _, info := range Parsers() {
+		assert.NotEmpty(t, info.Name)
+		assert.NotEmpty(t, info.Formats)
+		types = append(types, info.Type)
+	}
+	assert.ElementsMatch(t, []ParserType{ParserTypeVivo, ParserTypeWX, ParserTypeAli}, types)
+
+	assert.Panics(t, func() {
+		Register(ParserInfo{Type: ParserTypeWX}, func() Parser { return NewWXParser() })
+	})
+}
+
+func TestParse_Auto(t *testing.T) {
+	tests := []struct {
+		file     string
+		wantType ParserType
+		records  int
+	}{
+		{"test_data/vivo钱包导出.xlsx", ParserTypeVivo, 4},
+		{"test_data/微信支付账单.xlsx", ParserTypeWX, 4},
+		{"test_data/微信支付账单.csv", ParserTypeWX, 4},
+		{"test_data/支付宝交易明细.csv", ParserTypeAli, 3},
+		{"test_data/支付宝交易记录（电脑端）.csv", ParserTypeAli, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			result, err := parseFile(t, tt.file, ParserTypeAuto)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantType, result.ParserType)
+			assert.Len(t, result.Records, tt.records)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	// 无法识别的表格
+	_, err := Parse(strings.NewReader("日期,金额\n2025-12-01,10\n"), "账单.csv", ParserTypeAuto)
+	assert.True(t, errors.Is(err, ErrUnknownFormat))
+
+	// 未注册的解析器类型不读取文件
+	_, err = Parse(strings.NewReader(""), "账单.csv", "unknown")
+	assert.True(t, errors.Is(err, ErrUnsupportedParser))
+
+	_, err = Parse(strings.NewReader("旧版Excel"), "账单.xls", ParserTypeAuto)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "暂不支持 xls 格式")
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"path"
+	"smart-ledger-server/internal/pkg/logger"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// utf8BOM csv 文件开头的字节顺序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// exportTimeLayouts 账单导出文件中的时间格式，包括 Excel 另存为 csv 后的日期格式
+var exportTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006/1/2 15:04:05", "2006/1/2 15:04", "2006-01-02", "2006/1/2", "20060102"}
+
+// maxHeaderRow 查找表头的最大行数，账单说明通常只有十几行
+const maxHeaderRow = 50
+
+// zipMagic xlsx 文件（zip 格式）开头的字节
+var zipMagic = []byte("PK\x03\x04")
+
+// ReadTable 读取账单文件的所有行，按文件内容和扩展名区分 xlsx 和 csv
+// 返回的行下标与文件行号一一对应（行号 = 下标 + 1）
+func ReadTable(r io.Reader, filename string) ([][]string, error) {
+	return ReadSheet(r, filename, "")
+}
+
+// ReadSheet 读取账单文件的所有行，xlsx 文件读取指定的工作表，为空时读取第一个工作表
+func ReadSheet(r io.Reader, filename, sheet string) ([][]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "读取文件失败")
+	}
+	ext := strings.ToLower(path.Ext(filename))
+	switch {
+	case bytes.HasPrefix(content, zipMagic) || ext == ".xlsx":
+		return readExcel(content, sheet)
+	case ext == ".xls":
+		return nil, pkgerrors.New("暂不支持 xls 格式，请另存为 xlsx 或 csv 后导入")
+	default:
+		return readCSV(content)
+	}
+}
+
+// readCSV 读取 csv 文件的所有行，非 UTF-8 编码的内容按 GBK 解码
+// 账单说明行和账单行的列数不同，空行保留为 nil
+func readCSV(content []byte) ([][]string, error) {
+	var err error
+	content = bytes.TrimPrefix(content, utf8BOM)
+	if !utf8.Valid(content) {
+		if content, err = simplifiedchinese.GBK.NewDecoder().Bytes(content); err != nil {
+			return nil, pkgerrors.Wrap(err, "csv文件编码转换失败")
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "获取csv行数据失败")
+		}
+		// 空行会被跳过，补齐空行使行号与文件一致
+		line, _ := reader.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readExcel 读取 xlsx 文件指定工作表的所有行，为空时读取第一个工作表
+func readExcel(content []byte, sheet string) ([][]string, error) {
+	excelFile, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "excel解析打开文件失败")
+	}
+	defer func() {
+		if err := excelFile.Close(); err != nil {
+			logger.Log.Error("excel解析关闭文件失败", zap.Error(err))
+		}
+	}()
+	if sheet == "" {
+		sheet = excelFile.GetSheetName(0)
+	} else if index, err := excelFile.GetSheetIndex(sheet); err != nil || index < 0 {
+		return nil, pkgerrors.Errorf("工作表“%s”不存在", sheet)
+	}
+	rows, err := excelFile.GetRows(sheet)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "获取excel行数据失败")
+	}
+	return rows, nil
+}
+
+// findHeader 在文件开头查找包含所有列名的表头行，返回行下标，未找到时返回 -1
+func findHeader(rows [][]string, names ...string) int {
+	for i, row := range rows {
+		if i >= maxHeaderRow {
+			break
+		}
+		if containsAll(trimCells(row), names...) {
+			return i
+		}
+	}
+	return -1
+}
+
+// trimCells 去除每个单元格的空白
+func trimCells(row []string) []string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+// containsAll 表头是否包含所有列
+func containsAll(header []string, names ...string) bool {
+	for _, name := range names {
+		if !containsAny(header, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny 表头是否包含任意一列
+func containsAny(header []string, names ...string) bool {
+	for _, cell := range header {
+		for _, name := range names {
+			if cell == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildRowData 按表头组装行数据，去除空白后为空的单元格不保留
+func buildRowData(header, row []string) map[string]string {
+	rowData := make(map[string]string)
+	for i, value := range row {
+		value = strings.TrimSpace(value)
+		if i < len(header) && header[i] != "" && value != "" {
+			rowData[header[i]] = value
+		}
+	}
+	return rowData
+}
+
+// parseAmount 解析金额，去除货币符号和千分位
+func parseAmount(s string) (decimal.Decimal, error) {
+	s = strings.NewReplacer("¥", "", "￥", "", ",", "").Replace(strings.TrimSpace(s))
+	return decimal.NewFromString(s)
+}
+
+// parseExportTime 按北京时间解析账单导出文件中的时间
+func parseExportTime(s string) (time.Time, error) {
+	location, _ := time.LoadLocation("Asia/Shanghai")
+	var err error
+	for _, layout := range exportTimeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, strings.TrimSpace(s), location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,321 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// 列映射中的字段
+const (
+	FieldPayTime   = "pay_time"   // 交易时间（必需）
+	FieldAmount    = "amount"     // 金额，收支规则为 sign、column 时必需
+	FieldIncome    = "income"     // 收入金额，收支规则为 split 时必需
+	FieldExpense   = "expense"    // 支出金额，收支规则为 split 时必需
+	FieldDirection = "direction"  // 收支方向，收支规则为 column 时必需
+	FieldMerchant  = "merchant"   // 交易对方/摘要
+	FieldCategory  = "category"   // 分类名称
+	FieldRemark    = "remark"     // 备注
+	FieldOrderNo   = "order_no"   // 流水号（用于去重）
+	FieldPayMethod = "pay_method" // 支付方式/卡号
+)
+
+// mappingFields 可映射的字段
+var mappingFields = []string{FieldPayTime, FieldAmount, FieldIncome, FieldExpense, FieldDirection, FieldMerchant, FieldCategory, FieldRemark, FieldOrderNo, FieldPayMethod}
+
+// 收支判断规则
+const (
+	TypeRuleSign   = "sign"   // 按金额正负判断
+	TypeRuleColumn = "column" // 按收支列的取值判断
+	TypeRuleSplit  = "split"  // 收入、支出金额分为两列
+)
+
+// 金额符号约定，收支规则为 sign 时使用
+const (
+	AmountSignExpenseNegative = "expense_negative" // 负数为支出（储蓄卡流水）
+	AmountSignExpensePositive = "expense_positive" // 正数为支出（信用卡账单）
+)
+
+// dateFormatReplacer 将 yyyy-MM-dd HH:mm:ss 形式的日期格式转换为 Go 的时间格式
+var dateFormatReplacer = strings.NewReplacer(
+	"yyyy", "2006", "YYYY", "2006", "yy", "06",
+	"MM", "01", "dd", "02", "DD", "02",
+	"HH", "15", "mm", "04", "ss", "05",
+	"M", "1", "d", "2", "H", "15", "m", "4", "s", "5",
+)
+
+// Mapping 用户自定义的列映射，用于解析没有内置解析器的银行流水、信用卡账单等表格
+type Mapping struct {
+	Sheet         string            // xlsx 工作表名称，为空时使用第一个工作表
+	HeaderRow     int               // 表头所在行号（从 1 开始），为 0 时使用第 1 行
+	Columns       map[string]string // 字段 → 表头中的列名
+	DateFormat    string            // 日期格式，如 yyyy-MM-dd HH:mm:ss，为空时自动识别常见格式
+	TypeRule      string            // 收支判断规则，为空时按金额正负判断
+	AmountSign    string            // 金额符号约定，为空时负数为支出
+	IncomeValues  []string          // 收支列中表示收入的值，只设置一方时另一方为其余所有值
+	ExpenseValues []string          // 收支列中表示支出的值，两方都设置时其余的值视为不计收支
+	Platform      string            // 账单的支付平台，如招商银行
+}
+
+// MappingParser 按用户自定义的列映射解析表格
+type MappingParser struct {
+	mapping Mapping
+	layout  string // Go 时间格式，为空时自动识别
+}
+
+// NewMappingParser 校验列映射并创建解析器
+func NewMappingParser(mapping Mapping) (*MappingParser, error) {
+	if mapping.HeaderRow <= 0 {
+		mapping.HeaderRow = 1
+	}
+	if mapping.TypeRule == "" {
+		mapping.TypeRule = TypeRuleSign
+	}
+	if mapping.AmountSign == "" {
+		mapping.AmountSign = AmountSignExpenseNegative
+	}
+
+	for field, column := range mapping.Columns {
+		if !containsAny(mappingFields, field) {
+			return nil, pkgerrors.Errorf("不支持的字段: %s", field)
+		}
+		if strings.TrimSpace(column) == "" {
+			return nil, pkgerrors.Errorf("字段 %s 的列名不能为空", field)
+		}
+	}
+	required := []string{FieldPayTime}
+	switch mapping.TypeRule {
+	case TypeRuleSign:
+		if mapping.AmountSign != AmountSignExpenseNegative && mapping.AmountSign != AmountSignExpensePositive {
+			return nil, pkgerrors.Errorf("不支持的金额符号约定: %s", mapping.AmountSign)
+		}
+		required = append(required, FieldAmount)
+	case TypeRuleColumn:
+		if len(mapping.IncomeValues) == 0 && len(mapping.ExpenseValues) == 0 {
+			return nil, pkgerrors.New("按收支列判断时需要设置表示收入或支出的值")
+		}
+		required = append(required, FieldAmount, FieldDirection)
+	case TypeRuleSplit:
+		required = append(required, FieldIncome, FieldExpense)
+	default:
+		return nil, pkgerrors.Errorf("不支持的收支判断规则: %s", mapping.TypeRule)
+	}
+	for _, field := range required {
+		if mapping.Columns[field] == "" {
+			return nil, pkgerrors.Errorf("缺少字段 %s 的列映射", field)
+		}
+	}
+
+	return &MappingParser{mapping: mapping, layout: dateFormatReplacer.Replace(mapping.DateFormat)}, nil
+}
+
+// Detect 表头行包含所有映射的列
+func (p *MappingParser) Detect(rows [][]string) bool {
+	_, err := p.header(rows)
+	return err == nil
+}
+
+func (p *MappingParser) Parse(rows [][]string) (*ParseResult, error) {
+	header, err := p.header(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ParseResult{ParserType: ParserTypeCustom}
+	for i, row := range rows[p.mapping.HeaderRow:] {
+		rowNum := p.mapping.HeaderRow + i + 1
+		rowData := buildRowData(header, row)
+		if len(rowData) == 0 {
+			//跳过空行
+			continue
+		}
+		get := func(field string) string {
+			return rowData[p.mapping.Columns[field]]
+		}
+
+		payTimeValue := get(FieldPayTime)
+		if payTimeValue == "" {
+			// 没有交易时间的行通常是表尾的合计、说明
+			result.Skipped++
+			continue
+		}
+		payTime, err := p.parseTime(payTimeValue)
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  p.mapping.Columns[FieldPayTime],
+				Message: "交易时间格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+
+		amount, billType, column, err := p.amountAndType(get)
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  column,
+				Message: "金额格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+		if billType == 0 {
+			// 金额为 0 或不计收支
+			result.Skipped++
+			continue
+		}
+
+		result.Records = append(result.Records, BillRecord{
+			Row:          rowNum,
+			PayTime:      payTime,
+			Amount:       amount.String(),
+			BillType:     billType,
+			Merchant:     get(FieldMerchant),
+			CategoryName: get(FieldCategory),
+			Platform:     p.GetPlatform(),
+			PayMethod:    get(FieldPayMethod),
+			OrderNo:      get(FieldOrderNo),
+			Remark:       get(FieldRemark),
+			RowData:      rowData,
+		})
+	}
+	return result, nil
+}
+
+func (p *MappingParser) GetPlatform() string {
+	return p.mapping.Platform
+}
+
+// header 获取表头并检查映射的列都存在
+func (p *MappingParser) header(rows [][]string) ([]string, error) {
+	if p.mapping.HeaderRow > len(rows) {
+		return nil, pkgerrors.Errorf("表头行 %d 超出文件行数 %d", p.mapping.HeaderRow, len(rows))
+	}
+	header := trimCells(rows[p.mapping.HeaderRow-1])
+	for _, field := range mappingFields {
+		if column := p.mapping.Columns[field]; column != "" && !containsAny(header, column) {
+			return nil, pkgerrors.Errorf("第 %d 行表头中没有找到列: %s", p.mapping.HeaderRow, column)
+		}
+	}
+	return header, nil
+}
+
+// parseTime 按映射的日期格式解析交易时间，未设置格式时自动识别常见格式
+func (p *MappingParser) parseTime(s string) (time.Time, error) {
+	if p.layout == "" {
+		return parseExportTime(s)
+	}
+	location, _ := time.LoadLocation("Asia/Shanghai")
+	return time.ParseInLocation(p.layout, strings.TrimSpace(s), location)
+}
+
+// amountAndType 按收支判断规则计算金额（正数）和账单类型，不计收支时账单类型为 0
+// 金额格式错误时返回出错的列名
+func (p *MappingParser) amountAndType(get func(field string) string) (decimal.Decimal, int, string, error) {
+	switch p.mapping.TypeRule {
+	case TypeRuleSplit:
+		expense, err := parseOptionalAmount(get(FieldExpense))
+		if err != nil {
+			return decimal.Zero, 0, p.mapping.Columns[FieldExpense], err
+		}
+		income, err := parseOptionalAmount(get(FieldIncome))
+		if err != nil {
+			return decimal.Zero, 0, p.mapping.Columns[FieldIncome], err
+		}
+		if !expense.IsZero() {
+			return expense.Abs(), 1, "", nil
+		}
+		if !income.IsZero() {
+			return income.Abs(), 2, "", nil
+		}
+		return decimal.Zero, 0, "", nil
+	case TypeRuleColumn:
+		amount, err := parseAmount(get(FieldAmount))
+		if err != nil {
+			return decimal.Zero, 0, p.mapping.Columns[FieldAmount], err
+		}
+		if amount.IsZero() {
+			return decimal.Zero, 0, "", nil
+		}
+		return amount.Abs(), p.directionType(get(FieldDirection)), "", nil
+	default:
+		amount, err := parseAmount(get(FieldAmount))
+		if err != nil {
+			return decimal.Zero, 0, p.mapping.Columns[FieldAmount], err
+		}
+		if amount.IsZero() {
+			return decimal.Zero, 0, "", nil
+		}
+		isExpense := amount.IsNegative()
+		if p.mapping.AmountSign == AmountSignExpensePositive {
+			isExpense = !isExpense
+		}
+		if isExpense {
+			return amount.Abs(), 1, "", nil
+		}
+		return amount.Abs(), 2, "", nil
+	}
+}
+
+// directionType 按收支列的取值判断账单类型，不计收支时返回 0
+func (p *MappingParser) directionType(direction string) int {
+	switch {
+	case containsAny(p.mapping.IncomeValues, direction):
+		return 2
+	case containsAny(p.mapping.ExpenseValues, direction):
+		return 1
+	case len(p.mapping.ExpenseValues) == 0:
+		return 1
+	case len(p.mapping.IncomeValues) == 0:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// parseOptionalAmount 解析可以为空的金额，空值视为 0
+func parseOptionalAmount(s string) (decimal.Decimal, error) {
+	if strings.TrimSpace(s) == "" || strings.TrimSpace(s) == "-" {
+		return decimal.Zero, nil
+	}
+	return parseAmount(s)
+}
+
+// HeaderColumns 获取表头行的列名，供用户设置列映射，行号超出范围时返回空
+func HeaderColumns(rows [][]string, headerRow int) []string {
+	if headerRow <= 0 {
+		headerRow = 1
+	}
+	columns := []string{}
+	if headerRow > len(rows) {
+		return columns
+	}
+	for _, column := range trimCells(rows[headerRow-1]) {
+		if column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// ParseWithMapping 读取账单文件并按列映射解析
+func ParseWithMapping(r io.Reader, filename string, mapping Mapping) (*ParseResult, error) {
+	parser, err := NewMappingParser(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMapping, err.Error())
+	}
+	rows, err := ReadSheet(r, filename, mapping.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parser.Parse(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMapping, err.Error())
+	}
+	return result, nil
+}
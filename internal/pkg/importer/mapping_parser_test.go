@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cmbMapping 招商银行储蓄卡流水的列映射：负数为支出
+func cmbMapping() Mapping {
+	return Mapping{
+		HeaderRow: 3,
+		Columns: map[string]string{
+			FieldPayTime:  "交易时间",
+			FieldAmount:   "交易金额",
+			FieldMerchant: "对手信息",
+			FieldRemark:   "交易摘要",
+			FieldOrderNo:  "流水号",
+		},
+		Platform: "招商银行",
+	}
+}
+
+func TestMappingParser_Parse_Sign(t *testing.T) {
+	file, err := os.Open("test_data/招商银行交易流水.csv")
+	require.NoError(t, err)
+	defer file.Close()
+
+	parseResult, err := ParseWithMapping(file, "招商银行交易流水.csv", cmbMapping())
+	require.NoError(t, err)
+	assert.Equal(t, ParserTypeCustom, parseResult.ParserType)
+	records := parseResult.Records
+	require.Len(t, records, 2)
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+
+	// 第1条记录：负数为支出
+	assert.Equal(t, 4, records[0].Row)
+	assert.Equal(t, time.Date(2025, 12, 15, 12, 0, 0, 0, loc), records[0].PayTime)
+	assert.Equal(t, "38.5", records[0].Amount)
+	assert.Equal(t, 1, records[0].BillType)
+	assert.Equal(t, "美团", records[0].Merchant)
+	assert.Equal(t, "快捷支付", records[0].Remark)
+	assert.Equal(t, "CMB20251215001", records[0].OrderNo)
+	assert.Equal(t, "招商银行", records[0].Platform)
+
+	// 第2条记录：正数为收入
+	assert.Equal(t, "5000", records[1].Amount)
+	assert.Equal(t, 2, records[1].BillType)
+
+	// 金额为 0 的结息和没有交易时间的合计行跳过
+	assert.Equal(t, 2, parseResult.Skipped)
+	require.Len(t, parseResult.Errors, 1)
+	assert.Equal(t, 7, parseResult.Errors[0].Row)
+	assert.Equal(t, "交易金额", parseResult.Errors[0].Column)
+}
+
+func TestMappingParser_Parse_Split(t *testing.T) {
+	file, err := os.Open("test_data/工商银行账户明细.xlsx")
+	require.NoError(t, err)
+	defer file.Close()
+
+	parseResult, err := ParseWithMapping(file, "工商银行账户明细.xlsx", Mapping{
+		Sheet:      "交易明细",
+		HeaderRow:  2,
+		DateFormat: "yyyy年MM月dd日",
+		TypeRule:   TypeRuleSplit,
+		Columns: map[string]string{
+			FieldPayTime:  "交易日期",
+			FieldIncome:   "收入金额",
+			FieldExpense:  "支出金额",
+			FieldMerchant: "对方户名",
+			FieldCategory: "摘要",
+		},
+	})
+	require.NoError(t, err)
+	records := parseResult.Records
+	require.Len(t, records, 2)
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	assert.Equal(t, time.Date(2025, 12, 15, 0, 0, 0, 0, loc), records[0].PayTime)
+	assert.Equal(t, "120", records[0].Amount)
+	assert.Equal(t, 1, records[0].BillType)
+	assert.Equal(t, "盒马鲜生", records[0].Merchant)
+	assert.Equal(t, "消费", records[0].CategoryName)
+
+	assert.Equal(t, "8000", records[1].Amount)
+	assert.Equal(t, 2, records[1].BillType)
+
+	// 收入、支出都为空的行跳过，日期与格式不符时报错
+	assert.Equal(t, 1, parseResult.Skipped)
+	require.Len(t, parseResult.Errors, 1)
+	assert.Equal(t, 6, parseResult.Errors[0].Row)
+	assert.Equal(t, "交易时间格式错误", parseResult.Errors[0].Message)
+}
+
+func TestMappingParser_Parse_Column(t *testing.T) {
+	const content = "交易日,交易摘要,人民币金额,收/支\n" +
+		"2025/12/15,盒马鲜生,120.00,消费\n" +
+		"2025/12/16,还款,3000.00,存入\n" +
+		"2025/12/17,转账,50.00,转出\n"
+	columns := map[string]string{
+		FieldPayTime:   "交易日",
+		FieldAmount:    "人民币金额",
+		FieldDirection: "收/支",
+		FieldMerchant:  "交易摘要",
+	}
+
+	// 两方都设置时其余的值不计收支
+	parseResult, err := ParseWithMapping(strings.NewReader(content), "信用卡.csv", Mapping{
+		TypeRule: TypeRuleColumn, Columns: columns, IncomeValues: []string{"存入"}, ExpenseValues: []string{"消费"},
+	})
+	require.NoError(t, err)
+	require.Len(t, parseResult.Records, 2)
+	assert.Equal(t, 1, parseResult.Records[0].BillType)
+	assert.Equal(t, 2, parseResult.Records[1].BillType)
+	assert.Equal(t, 1, parseResult.Skipped)
+
+	// 只设置收入的值时其余都是支出
+	parseResult, err = ParseWithMapping(strings.NewReader(content), "信用卡.csv", Mapping{
+		TypeRule: TypeRuleColumn, Columns: columns, IncomeValues: []string{"存入"},
+	})
+	require.NoError(t, err)
+	require.Len(t, parseResult.Records, 3)
+	assert.Equal(t, 1, parseResult.Records[2].BillType)
+}
+
+func TestMappingParser_Parse_ExpensePositive(t *testing.T) {
+	const content = "交易日期,金额,描述\n2025-12-15,120.00,消费\n2025-12-16,-3000.00,还款\n"
+	parseResult, err := ParseWithMapping(strings.NewReader(content), "信用卡.csv", Mapping{
+		AmountSign: AmountSignExpensePositive,
+		Columns:    map[string]string{FieldPayTime: "交易日期", FieldAmount: "金额", FieldRemark: "描述"},
+	})
+	require.NoError(t, err)
+	require.Len(t, parseResult.Records, 2)
+	assert.Equal(t, 1, parseResult.Records[0].BillType)
+	assert.Equal(t, "120", parseResult.Records[0].Amount)
+	assert.Equal(t, 2, parseResult.Records[1].BillType)
+	assert.Equal(t, "3000", parseResult.Records[1].Amount)
+}
+
+func TestMappingParser_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		wantErr string
+	}{
+		{"缺少交易时间", Mapping{Columns: map[string]string{FieldAmount: "交易金额"}}, "缺少字段 pay_time 的列映射"},
+		{"不支持的字段", Mapping{Columns: map[string]string{FieldPayTime: "交易时间", "balance": "联机余额"}}, "不支持的字段: balance"},
+		{"不支持的收支规则", Mapping{TypeRule: "auto", Columns: map[string]string{FieldPayTime: "交易时间"}}, "不支持的收支判断规则: auto"},
+		{"不支持的金额符号", Mapping{AmountSign: "positive", Columns: map[string]string{FieldPayTime: "交易时间", FieldAmount: "交易金额"}}, "不支持的金额符号约定: positive"},
+		{"收支列缺少取值", Mapping{TypeRule: TypeRuleColumn, Columns: map[string]string{FieldPayTime: "交易时间", FieldAmount: "交易金额", FieldDirection: "收支"}}, "需要设置表示收入或支出的值"},
+		{"分列缺少支出列", Mapping{TypeRule: TypeRuleSplit, Columns: map[string]string{FieldPayTime: "交易时间", FieldIncome: "收入"}}, "缺少字段 expense 的列映射"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMappingParser(tt.mapping)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	// 列映射与文件不符
+	file, err := os.Open("test_data/招商银行交易流水.csv")
+	require.NoError(t, err)
+	defer file.Close()
+	mapping := cmbMapping()
+	mapping.HeaderRow = 1
+	_, err = ParseWithMapping(file, "招商银行交易流水.csv", mapping)
+	assert.True(t, errors.Is(err, ErrInvalidMapping))
+	assert.Contains(t, err.Error(), "第 1 行表头中没有找到列: 交易时间")
+}
+
+func TestHeaderColumns(t *testing.T) {
+	file, err := os.Open("test_data/工商银行账户明细.xlsx")
+	require.NoError(t, err)
+	defer file.Close()
+	rows, err := ReadSheet(file, "工商银行账户明细.xlsx", "交易明细")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"交易日期", "摘要", "收入金额", "支出金额", "余额", "对方户名"}, HeaderColumns(rows, 2))
+	assert.Equal(t, []string{}, HeaderColumns(rows, 100))
+
+	_, err = file.Seek(0, 0)
+	require.NoError(t, err)
+	_, err = ReadSheet(file, "工商银行账户明细.xlsx", "汇总")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "工作表“汇总”不存在")
+}
@@ -10,12 +10,17 @@ type BillRecord struct {
 	Merchant     string // 商户/备注
 	CategoryName string //分类名称
 	Platform     string //平台
+	PayMethod    string //支付方式
+	OrderNo      string //交易单号（用于去重）
+	Remark       string //备注
 	RowData      map[string]string
 }
 
 type ParseResult struct {
-	Records []BillRecord
-	Errors  []ParseError
+	ParserType ParserType //使用的解析器类型（自动识别时为识别出的类型）
+	Records    []BillRecord
+	Errors     []ParseError
+	Skipped    int //跳过的行数（中性交易、退款等）
 }
 
 type ParseError struct {
@@ -25,7 +30,10 @@ type ParseError struct {
 	RowData map[string]string
 }
 
-type ExcelParser interface {
-	Parse(filepath string) (*ParseResult, error)
+// Parser 账单解析器，解析 ReadTable 读取的表格行，行下标 + 1 即文件中的行号
+type Parser interface {
+	// Detect 根据表头判断表格是否为该解析器支持的账单，用于自动识别格式
+	Detect(rows [][]string) bool
+	Parse(rows [][]string) (*ParseResult, error)
 	GetPlatform() string
 }
@@ -1,14 +1,15 @@
 package importer
 
 import (
-	"smart-ledger-server/internal/pkg/logger"
 	"time"
 
 	pkgerrors "github.com/pkg/errors"
-	"github.com/xuri/excelize/v2"
-	"go.uber.org/zap"
 )
 
+func init() {
+	Register(ParserInfo{Type: ParserTypeVivo, Name: "vivo钱包", Formats: []string{"xlsx"}}, func() Parser { return NewVivoParser() })
+}
+
 type VivoParser struct{}
 
 func NewVivoParser() *VivoParser {
@@ -18,25 +19,17 @@ func NewVivoParser() *VivoParser {
 // vivo账单的列名
 var vivoColumns = []string{"交易时间", "交易单号", "记账分类", "收支类型", "备注", "交易金额"}
 
-func (p *VivoParser) Parse(filepath string) (*ParseResult, error) {
+// vivo钱包导出文件的表头，用于自动识别格式
+var vivoHeader = []string{"账单日期", "记账分类", "收支类型", "备注", "金额"}
+
+// Detect 第一行为vivo钱包账单的表头
+func (p *VivoParser) Detect(rows [][]string) bool {
+	return len(rows) > 0 && containsAll(trimCells(rows[0]), vivoHeader...)
+}
+
+func (p *VivoParser) Parse(rows [][]string) (*ParseResult, error) {
 	var records []BillRecord
 	var parseErrors []ParseError
-	excelFile, err := excelize.OpenFile(filepath)
-	if err != nil {
-		err = pkgerrors.Wrap(err, "excel解析打开文件失败")
-		return nil, err
-	}
-	defer func() {
-		if err = excelFile.Close(); err != nil {
-			logger.Log.Error("excel解析关闭文件失败", zap.Error(err))
-		}
-	}()
-	shellName := excelFile.GetSheetName(0)
-	rows, err := excelFile.GetRows(shellName)
-	if err != nil {
-		err = pkgerrors.Wrap(err, "获取excel行数据失败")
-		return nil, err
-	}
 	if len(rows) < 2 {
 		return nil, pkgerrors.New("文件为空或只有表头")
 	}
@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -9,11 +10,10 @@ import (
 )
 
 func TestVivoParser_Parse(t *testing.T) {
-	parser := &VivoParser{}
-	parseResult, err := parser.Parse("test_data/vivo钱包导出.xlsx")
+	parseResult, err := parseFile(t, "test_data/vivo钱包导出.xlsx", ParserTypeVivo)
+	require.NoError(t, err)
 	records := parseResult.Records
 
-	require.NoError(t, err)
 	require.Len(t, records, 4)
 
 	loc, _ := time.LoadLocation("Asia/Shanghai")
@@ -53,9 +53,8 @@ func TestVivoParser_GetPlatform(t *testing.T) {
 	assert.Equal(t, "vivo钱包", parser.GetPlatform())
 }
 
-func TestVivoParser_Parse_FileNotFound(t *testing.T) {
-	parser := &VivoParser{}
-	_, err := parser.Parse("test_data/不存在的文件.xlsx")
+func TestVivoParser_Parse_InvalidFile(t *testing.T) {
+	_, err := Parse(strings.NewReader("不是excel文件"), "vivo钱包导出.xlsx", ParserTypeVivo)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "excel解析打开文件失败")
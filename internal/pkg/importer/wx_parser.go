@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+func init() {
+	Register(ParserInfo{Type: ParserTypeWX, Name: "微信支付", Formats: []string{"xlsx", "csv"}}, func() Parser { return NewWXParser() })
+}
+
+// WXParser 微信支付账单解析器，支持官方导出的 xlsx 和 csv 文件
+// 导出文件在表头前有若干行账单说明，按“交易时间”所在行定位表头
+type WXParser struct{}
+
+func NewWXParser() *WXParser {
+	return &WXParser{}
+}
+
+// 微信支付账单的列名
+const (
+	wxColTime      = "交易时间"
+	wxColType      = "交易类型"
+	wxColCounter   = "交易对方"
+	wxColGoods     = "商品"
+	wxColDirection = "收/支"
+	wxColAmount    = "金额(元)"
+	wxColPayMethod = "支付方式"
+	wxColStatus    = "当前状态"
+	wxColOrderNo   = "交易单号"
+	wxColMerchNo   = "商户单号"
+)
+
+// wxRequiredColumns 解析必需的列
+var wxRequiredColumns = []string{wxColTime, wxColType, wxColCounter, wxColDirection, wxColAmount, wxColStatus, wxColOrderNo}
+
+// wxNeutralTypes 不计入收支的交易类型（零钱与银行卡之间的资金转移）
+var wxNeutralTypes = map[string]bool{
+	"零钱提现":  true,
+	"零钱充值":  true,
+	"零钱通转入": true,
+	"零钱通转出": true,
+}
+
+// wxRefundedRe 匹配部分退款状态中的退款金额，如“已退款(￥10.00)”
+var wxRefundedRe = regexp.MustCompile(`已退款\s*[(（]?\s*[￥¥]?\s*([\d,]+(?:\.\d+)?)`)
+
+// Detect 表格中有微信支付账单的表头
+func (p *WXParser) Detect(rows [][]string) bool {
+	return findHeader(rows, wxRequiredColumns...) >= 0
+}
+
+func (p *WXParser) Parse(rows [][]string) (*ParseResult, error) {
+	headerIndex := findHeader(rows, wxColTime, wxColDirection, wxColStatus)
+	if headerIndex < 0 {
+		return nil, pkgerrors.New("未找到微信支付账单表头，请上传微信支付导出的账单文件")
+	}
+	header := trimCells(rows[headerIndex])
+	for _, name := range wxRequiredColumns {
+		if !containsAny(header, name) {
+			return nil, pkgerrors.Errorf("微信支付账单缺少列: %s", name)
+		}
+	}
+
+	result := &ParseResult{}
+	for i, row := range rows[headerIndex+1:] {
+		rowNum := headerIndex + i + 2
+		rowData := buildRowData(header, row)
+		if len(rowData) == 0 {
+			//跳过空行
+			continue
+		}
+		get := func(column string) string {
+			return wxValue(rowData[column])
+		}
+
+		direction := get(wxColDirection)
+		txType := get(wxColType)
+		if (direction != "支出" && direction != "收入") || wxNeutralTypes[txType] {
+			// 中性交易不计入收支
+			result.Skipped++
+			continue
+		}
+		if strings.Contains(txType, "退款") {
+			// 退款到账的记录跳过，退款金额已从原支出中扣除
+			result.Skipped++
+			continue
+		}
+
+		amount, err := parseAmount(get(wxColAmount))
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  wxColAmount,
+				Message: "金额格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+		status := get(wxColStatus)
+		if direction == "支出" {
+			if strings.Contains(status, "全额退款") {
+				result.Skipped++
+				continue
+			}
+			if match := wxRefundedRe.FindStringSubmatch(status); match != nil {
+				refunded, err := parseAmount(match[1])
+				if err == nil {
+					amount = amount.Sub(refunded)
+				}
+				if !amount.IsPositive() {
+					result.Skipped++
+					continue
+				}
+			}
+		}
+
+		payTime, err := parseExportTime(get(wxColTime))
+		if err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Row:     rowNum,
+				Column:  wxColTime,
+				Message: "交易时间格式错误",
+				RowData: rowData,
+			})
+			continue
+		}
+
+		billType := 1
+		if direction == "收入" {
+			billType = 2
+		}
+		orderNo := get(wxColOrderNo)
+		if orderNo == "" {
+			orderNo = get(wxColMerchNo)
+		}
+		result.Records = append(result.Records, BillRecord{
+			Row:       rowNum,
+			PayTime:   payTime,
+			Amount:    amount.String(),
+			BillType:  billType,
+			Merchant:  get(wxColCounter),
+			Platform:  p.GetPlatform(),
+			PayMethod: get(wxColPayMethod),
+			OrderNo:   orderNo,
+			Remark:    get(wxColGoods),
+			RowData:   rowData,
+		})
+	}
+	return result, nil
+}
+
+func (p *WXParser) GetPlatform() string {
+	return "微信支付"
+}
+
+// wxValue 微信账单用“/”表示空值
+func wxValue(value string) string {
+	if value == "/" {
+		return ""
+	}
+	return value
+}
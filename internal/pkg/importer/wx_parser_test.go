@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWXParser_Parse(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+
+	// xlsx 和 csv 导出的内容相同，解析结果应一致
+	for _, file := range []string{"test_data/微信支付账单.xlsx", "test_data/微信支付账单.csv"} {
+		t.Run(file, func(t *testing.T) {
+			parseResult, err := parseFile(t, file, ParserTypeWX)
+			require.NoError(t, err)
+			assert.Equal(t, ParserTypeWX, parseResult.ParserType)
+			records := parseResult.Records
+			require.Len(t, records, 4)
+
+			// 第1条记录：普通支出
+			assert.Equal(t, 17, records[0].Row)
+			assert.Equal(t, time.Date(2025, 12, 15, 23, 27, 9, 0, loc), records[0].PayTime)
+			assert.Equal(t, "38.5", records[0].Amount)
+			assert.Equal(t, 1, records[0].BillType)
+			assert.Equal(t, "美团平台商户", records[0].Merchant)
+			assert.Equal(t, "美团订单-外卖", records[0].Remark)
+			assert.Equal(t, "零钱", records[0].PayMethod)
+			assert.Equal(t, "4200001001202512150001", records[0].OrderNo)
+			assert.Equal(t, "微信支付", records[0].Platform)
+			assert.Empty(t, records[0].CategoryName)
+			assert.Equal(t, "M20251215001", records[0].RowData["商户单号"])
+
+			// 第2条记录：部分退款，金额扣除退款部分，“/”视为空值
+			assert.Equal(t, "100", records[1].Amount)
+			assert.Equal(t, "朴朴超市", records[1].Merchant)
+			assert.Empty(t, records[1].Remark)
+			assert.Equal(t, "招商银行信用卡(1234)", records[1].PayMethod)
+
+			// 第3条记录：收入，金额带千分位
+			assert.Equal(t, time.Date(2025, 12, 5, 10, 0, 0, 0, loc), records[2].PayTime)
+			assert.Equal(t, "5000", records[2].Amount)
+			assert.Equal(t, 2, records[2].BillType)
+			assert.Equal(t, "张三", records[2].Merchant)
+			assert.Empty(t, records[2].PayMethod)
+
+			// 第4条记录
+			assert.Equal(t, "200", records[3].Amount)
+			assert.Equal(t, 2, records[3].BillType)
+			assert.Equal(t, "1000050002202512020001", records[3].OrderNo)
+
+			// 退款到账、全额退款、零钱提现和信用卡还款不导入
+			assert.Equal(t, 5, parseResult.Skipped)
+
+			require.Len(t, parseResult.Errors, 1)
+			assert.Equal(t, 25, parseResult.Errors[0].Row)
+			assert.Equal(t, "金额(元)", parseResult.Errors[0].Column)
+			assert.Equal(t, "星巴克", parseResult.Errors[0].RowData["交易对方"])
+		})
+	}
+}
+
+func TestWXParser_GetPlatform(t *testing.T) {
+	parser := NewWXParser()
+	assert.Equal(t, "微信支付", parser.GetPlatform())
+}
+
+func TestWXParser_Parse_NotWXBill(t *testing.T) {
+	_, err := parseFile(t, "test_data/vivo钱包导出.xlsx", ParserTypeWX)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "未找到微信支付账单表头")
+}
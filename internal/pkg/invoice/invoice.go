@@ -0,0 +1,142 @@
+package invoice
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Invoice 从电子发票文本中提取的字段
+type Invoice struct {
+	Number string          // 发票号码：全电发票 20 位，增值税电子普通发票 8 位
+	Code   string          // 发票代码，全电发票没有
+	Seller string          // 销售方名称
+	Buyer  string          // 购买方名称
+	Amount decimal.Decimal // 价税合计
+	Tax    decimal.Decimal // 税额
+	Date   time.Time       // 开票日期
+	Items  []string        // 项目名称，如 “*餐饮服务*餐费”
+}
+
+var (
+	numberRe = regexp.MustCompile(`发票号码[:：]?(\d{8,20})`)
+	codeRe   = regexp.MustCompile(`发票代码[:：]?(\d{10,12})`)
+	dateRe   = regexp.MustCompile(`开票日期[:：]?(\d{4})[年/-](\d{1,2})[月/-](\d{1,2})`)
+	// totalRe 价税合计的小写金额，大写金额最长约 20 个汉字
+	totalRe = regexp.MustCompile(`(?:小写|价税合计)[^¥￥\d]{0,40}[¥￥]?(\d+(?:,\d{3})*\.\d{2})`)
+	// sumRe 合计行（不含“价税合计”）：金额和税额，免税时税额为 “***”
+	sumRe = regexp.MustCompile(`(?:^|[^税])合计[^¥￥\d]{0,10}[¥￥](\d+(?:,\d{3})*\.\d{2})[^¥￥\d]{0,10}(?:[¥￥](\d+(?:,\d{3})*\.\d{2}))?`)
+	// sellerRe 全电发票版式中的 “销售方信息 名称：xxx”
+	sellerRe = regexp.MustCompile(`销售方(?:信息)?[^\n:：]{0,4}名称[:：]([^\n:：]+?)(?:统一社会信用代码|纳税人识别号|\n|$)`)
+	nameRe   = regexp.MustCompile(`名称[:：]([^\n:：]+?)(?:统一社会信用代码|纳税人识别号|\n|$)`)
+	itemRe   = regexp.MustCompile(`\*[^*\s]{1,20}\*[^\s¥￥\d]{1,30}`)
+	yuanRe   = regexp.MustCompile(`[¥￥](\d+(?:,\d{3})*\.\d{2})`)
+)
+
+// Parse 从电子发票的文本中提取发票字段，loc 为开票日期所在时区
+// 发票号码和价税合计都找到时返回 true；否则返回已提取的部分字段，调用方可以换用其他识别方式
+func Parse(text string, loc *time.Location) (*Invoice, bool) {
+	// 版式文件中的文字常被拆成单字并以空格分隔，去掉空白后再匹配，只保留换行
+	text = strings.NewReplacer(" ", "", "\t", "", "\u3000", "", "\u00a0", "", "\r", "").Replace(text)
+	inv := &Invoice{}
+
+	if m := numberRe.FindStringSubmatch(text); m != nil {
+		inv.Number = m[1]
+	}
+	if m := codeRe.FindStringSubmatch(text); m != nil {
+		inv.Code = m[1]
+	}
+	if m := dateRe.FindStringSubmatch(text); m != nil {
+		inv.Date = time.Date(atoi(m[1]), time.Month(atoi(m[2])), atoi(m[3]), 0, 0, 0, 0, loc)
+	}
+
+	if m := totalRe.FindStringSubmatch(text); m != nil {
+		inv.Amount = parseAmount(m[1])
+	}
+	if m := sumRe.FindStringSubmatch(text); m != nil {
+		net := parseAmount(m[1])
+		if m[2] != "" {
+			inv.Tax = parseAmount(m[2])
+		}
+		if inv.Amount.IsZero() {
+			inv.Amount = net.Add(inv.Tax)
+		}
+	}
+	if inv.Amount.IsZero() {
+		// 没有找到合计行时取最大的人民币金额
+		for _, m := range yuanRe.FindAllStringSubmatch(text, -1) {
+			if amount := parseAmount(m[1]); amount.GreaterThan(inv.Amount) {
+				inv.Amount = amount
+			}
+		}
+	}
+
+	if m := sellerRe.FindStringSubmatch(text); m != nil {
+		inv.Seller = m[1]
+	}
+	// 购买方信息在前，销售方信息在后
+	names := nameRe.FindAllStringSubmatch(text, -1)
+	if len(names) >= 2 {
+		inv.Buyer = names[0][1]
+		if inv.Seller == "" {
+			inv.Seller = names[1][1]
+		}
+	}
+
+	inv.Items = itemRe.FindAllString(text, -1)
+
+	return inv, inv.Number != "" && inv.Amount.IsPositive()
+}
+
+// Description 发票的简要描述，优先使用项目名称
+func (inv *Invoice) Description() string {
+	if len(inv.Items) > 0 {
+		return strings.Join(inv.Items, "、")
+	}
+	return inv.Seller
+}
+
+// knownSellers 常见销售方与平台名称的对应关系
+var knownSellers = []struct {
+	keyword  string
+	platform string
+}{
+	{"滴滴", "滴滴出行"},
+	{"京东", "京东"},
+	{"三快", "美团"},
+	{"美团", "美团"},
+	{"拉扎斯", "饿了么"},
+	{"携程", "携程"},
+	{"铁路", "12306"},
+	{"航空", "航空公司"},
+	{"中国石油", "中国石油"},
+	{"中国石化", "中国石化"},
+}
+
+// Platform 根据销售方推断消费平台，未知时返回销售方名称
+func (inv *Invoice) Platform() string {
+	for _, s := range knownSellers {
+		if strings.Contains(inv.Seller, s.keyword) {
+			return s.platform
+		}
+	}
+	return inv.Seller
+}
+
+func parseAmount(s string) decimal.Decimal {
+	amount, err := decimal.NewFromString(strings.ReplaceAll(s, ",", ""))
+	if err != nil {
+		return decimal.Zero
+	}
+	return amount
+}
+
+func atoi(s string) int {
+	n := 0
+	for _, c := range s {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package invoice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		text       string
+		wantOK     bool
+		wantNumber string
+		wantCode   string
+		wantSeller string
+		wantBuyer  string
+		wantAmount string
+		wantTax    string
+		wantDate   time.Time
+		wantItems  []string
+	}{
+		{
+			name: "fully digital invoice",
+			text: "电子发票（普通发票）\n发票号码：25312000000123456789\n开票日期：2025年03月05日\n" +
+				"购买方信息 名称：张三\n销售方信息 名称：北京三快在线科技有限公司 统一社会信用代码/纳税人识别号：91110108MA00000000\n" +
+				"项目名称 规格型号 单位 数量 单价 金额 税率/征收率 税额\n*餐饮服务*餐费 1 54.72 54.72 6% 3.28\n" +
+				"合 计 ¥54.72 ¥3.28\n价税合计（大写） 伍拾捌圆整 （小写）¥58.00",
+			wantOK:     true,
+			wantNumber: "25312000000123456789",
+			wantSeller: "北京三快在线科技有限公司",
+			wantBuyer:  "张三",
+			wantAmount: "58",
+			wantTax:    "3.28",
+			wantDate:   time.Date(2025, 3, 5, 0, 0, 0, 0, loc),
+			wantItems:  []string{"*餐饮服务*餐费"},
+		},
+		{
+			name: "vat electronic invoice with split characters",
+			text: "增 值 税 电 子 普 通 发 票\n发票代码: 044031900111 发票号码: 12345678\n开票日期: 2024年12月31日\n" +
+				"名 称: 个人\n*运输服务*客运服务费 ¥ 28.30 3% ¥ 0.85\n合 计 ¥28.30 ¥0.85\n" +
+				"价税合计(大写) 贰拾玖圆壹角伍分 (小写) ¥29.15\n名 称: 滴滴出行科技有限公司\n纳税人识别号: 911201163409833307",
+			wantOK:     true,
+			wantNumber: "12345678",
+			wantCode:   "044031900111",
+			wantSeller: "滴滴出行科技有限公司",
+			wantBuyer:  "个人",
+			wantAmount: "29.15",
+			wantTax:    "0.85",
+			wantDate:   time.Date(2024, 12, 31, 0, 0, 0, 0, loc),
+			wantItems:  []string{"*运输服务*客运服务费"},
+		},
+		{
+			name:       "total from sum line",
+			text:       "发票号码：12345678\n合计 ¥100.00 ¥6.00",
+			wantOK:     true,
+			wantNumber: "12345678",
+			wantAmount: "106",
+			wantTax:    "6",
+		},
+		{
+			name:       "missing number",
+			text:       "电子回单\n交易金额 ¥88.00",
+			wantOK:     false,
+			wantAmount: "88",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, ok := Parse(tt.text, loc)
+			require.NotNil(t, inv)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantNumber, inv.Number)
+			assert.Equal(t, tt.wantCode, inv.Code)
+			assert.Equal(t, tt.wantSeller, inv.Seller)
+			assert.Equal(t, tt.wantBuyer, inv.Buyer)
+			assert.Equal(t, tt.wantAmount, inv.Amount.String())
+			if tt.wantTax != "" {
+				assert.Equal(t, tt.wantTax, inv.Tax.String())
+			}
+			assert.True(t, tt.wantDate.Equal(inv.Date), "date = %v", inv.Date)
+			assert.Equal(t, tt.wantItems, inv.Items)
+		})
+	}
+}
+
+func TestInvoice_Platform(t *testing.T) {
+	assert.Equal(t, "美团", (&Invoice{Seller: "北京三快在线科技有限公司"}).Platform())
+	assert.Equal(t, "某某餐厅", (&Invoice{Seller: "某某餐厅"}).Platform())
+}
@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+
+	"smart-ledger-server/internal/model"
+)
+
+// alipayKeywords 支付宝通知的收支关键词
+var alipayKeywords = []keyword{
+	{"付款成功", model.BillTypeExpense},
+	{"成功付款", model.BillTypeExpense},
+	{"支付成功", model.BillTypeExpense},
+	{"已付款", model.BillTypeExpense},
+	{"支出", model.BillTypeExpense},
+	{"扣款", model.BillTypeExpense},
+	{"自动扣款", model.BillTypeExpense},
+	{"成功收款", model.BillTypeIncome},
+	{"收款到账", model.BillTypeIncome},
+	{"已收款", model.BillTypeIncome},
+	{"到账", model.BillTypeIncome},
+	{"收入", model.BillTypeIncome},
+	{"转入", model.BillTypeIncome},
+}
+
+// alipayMerchantRes 支付宝通知中的商户/对方名称
+var alipayMerchantRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?:收款方|商户名称|商家|对方)[:：]\s*([^，,。；;\n]+)`),
+	regexp.MustCompile(`(?:向|在)([^，,。；;\s\d¥￥]{2,30}?)(?:付款|支付|消费)`),
+	regexp.MustCompile(`([^，,。；;\s\d¥￥]{2,30}?)(?:向你|给你)(?:付款|转账)`),
+}
+
+// AlipayParser 支付宝通知解析器
+type AlipayParser struct{}
+
+// NewAlipayParser 创建支付宝通知解析器
+func NewAlipayParser() *AlipayParser {
+	return &AlipayParser{}
+}
+
+// Name 解析器名称
+func (p *AlipayParser) Name() string {
+	return "alipay"
+}
+
+// Parse 解析支付宝通知，如“支付宝 你有一笔58.00元的支出”“付款成功 ¥58.00 向XX付款（花呗）”
+func (p *AlipayParser) Parse(msg *Message) (*Transaction, bool) {
+	text := msg.Text
+	if !strings.Contains(msg.Sender, "AlipayGphone") && !containsAny(text, []string{"支付宝", "花呗", "余额宝"}) {
+		return nil, false
+	}
+	billType, start, end, ok := classify(text, alipayKeywords)
+	if !ok {
+		return nil, false
+	}
+	amount, ok := amountNear(text, start, end)
+	if !ok {
+		return nil, false
+	}
+
+	payMethod := ""
+	switch m := payMethodRe.FindStringSubmatch(text); {
+	case m != nil:
+		payMethod = strings.TrimSpace(m[1])
+	case strings.Contains(text, "花呗"):
+		payMethod = "花呗"
+	case strings.Contains(text, "余额宝"):
+		payMethod = "余额宝"
+	}
+	return &Transaction{
+		Amount:    amount,
+		BillType:  billType,
+		Platform:  "支付宝",
+		Merchant:  firstSubmatch(text, alipayMerchantRes),
+		PayMethod: payMethod,
+		PayTime:   parseTime(text, msg.ReceivedAt),
+	}, true
+}
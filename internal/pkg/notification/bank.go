@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+
+	"smart-ledger-server/internal/model"
+)
+
+// banks 常见银行名称、简称和短信服务号码
+var banks = []struct {
+	name     string
+	keywords []string
+	senders  []string
+}{
+	{"工商银行", []string{"工商银行", "工行"}, []string{"95588"}},
+	{"建设银行", []string{"建设银行", "建行"}, []string{"95533"}},
+	{"农业银行", []string{"农业银行", "农行"}, []string{"95599"}},
+	{"中国银行", []string{"中国银行"}, []string{"95566"}},
+	{"交通银行", []string{"交通银行", "交行"}, []string{"95559"}},
+	{"招商银行", []string{"招商银行", "招行"}, []string{"95555"}},
+	{"邮储银行", []string{"邮储银行", "邮政储蓄"}, []string{"95580"}},
+	{"浦发银行", []string{"浦发银行"}, []string{"95528"}},
+	{"中信银行", []string{"中信银行"}, []string{"95558"}},
+	{"光大银行", []string{"光大银行"}, []string{"95595"}},
+	{"民生银行", []string{"民生银行"}, []string{"95568"}},
+	{"兴业银行", []string{"兴业银行"}, []string{"95561"}},
+	{"平安银行", []string{"平安银行"}, []string{"95511"}},
+	{"广发银行", []string{"广发银行"}, []string{"95508"}},
+}
+
+// bankTagRe 短信签名中的银行名称，用于识别未内置的银行，如【宁波银行】
+var bankTagRe = regexp.MustCompile(`[【\[]([^】\]]{2,12}银行)[^】\]]*[】\]]`)
+
+// bankKeywords 银行短信的收支关键词
+var bankKeywords = []keyword{
+	{"消费", model.BillTypeExpense},
+	{"支出", model.BillTypeExpense},
+	{"支付", model.BillTypeExpense},
+	{"快捷支付", model.BillTypeExpense},
+	{"扣款", model.BillTypeExpense},
+	{"转出", model.BillTypeExpense},
+	{"取款", model.BillTypeExpense},
+	{"取现", model.BillTypeExpense},
+	{"收入", model.BillTypeIncome},
+	{"存入", model.BillTypeIncome},
+	{"转入", model.BillTypeIncome},
+	{"汇入", model.BillTypeIncome},
+	{"入账", model.BillTypeIncome},
+	{"代发", model.BillTypeIncome},
+}
+
+// bankMerchantRes 银行短信中的商户/对方名称
+var bankMerchantRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?:商户|交易对方|对方户名|对方)[名称]*[:：为]\s*([^，,。；;\s]+)`),
+	regexp.MustCompile(`在(【[^】]+】|[^，,。；;\s\d]{2,30}?)(?:快捷支付|网上支付|消费|支付|扣款)`),
+	regexp.MustCompile(`[（(]([^）)\d]{2,30})[）)]`),
+	regexp.MustCompile(`(?:向|收到|来自)([^，,。；;\s\d]{2,20}?)(?:转出|转入|转账|汇入|付款)`),
+}
+
+// BankParser 银行动账短信解析器，覆盖常见国有及股份制银行，按短信签名识别其他银行
+type BankParser struct{}
+
+// NewBankParser 创建银行短信解析器
+func NewBankParser() *BankParser {
+	return &BankParser{}
+}
+
+// Name 解析器名称
+func (p *BankParser) Name() string {
+	return "bank_sms"
+}
+
+// Parse 解析银行动账短信，如“【工商银行】您尾号1234的储蓄卡消费支出人民币58.00元”
+func (p *BankParser) Parse(msg *Message) (*Transaction, bool) {
+	text := msg.Text
+	// 动账短信都会提到卡号尾号或账户，没有时通常是营销短信
+	tail := tailRe.FindStringSubmatch(text)
+	hasCard := containsAny(text, []string{"信用卡", "储蓄卡", "借记卡"})
+	if tail == nil && !hasCard && !strings.Contains(text, "账户") {
+		return nil, false
+	}
+	bank := detectBank(msg)
+	if bank == "" {
+		// 转发时丢失了签名的短信，同时提到尾号和卡种时仍按银行短信处理
+		if tail == nil || !hasCard {
+			return nil, false
+		}
+		bank = "银行"
+	}
+	billType, start, end, ok := classify(text, bankKeywords)
+	if !ok {
+		return nil, false
+	}
+	amount, ok := amountNear(text, start, end)
+	if !ok {
+		return nil, false
+	}
+
+	payMethod := "储蓄卡"
+	if strings.Contains(text, "信用卡") {
+		payMethod = "信用卡"
+	}
+	if tail != nil {
+		payMethod += "(" + tail[1] + ")"
+	}
+	return &Transaction{
+		Amount:    amount,
+		BillType:  billType,
+		Platform:  bank,
+		Merchant:  firstSubmatch(text, bankMerchantRes),
+		PayMethod: payMethod,
+		PayTime:   parseTime(text, msg.ReceivedAt),
+	}, true
+}
+
+// detectBank 根据发送号码、银行名称或短信签名识别银行，识别不出时返回空字符串
+func detectBank(msg *Message) string {
+	sender := strings.TrimPrefix(strings.TrimSpace(msg.Sender), "+86")
+	for _, bank := range banks {
+		for _, s := range bank.senders {
+			if sender == s || strings.HasPrefix(sender, "106"+s) {
+				return bank.name
+			}
+		}
+	}
+	for _, bank := range banks {
+		if containsAny(msg.Text, bank.keywords) {
+			return bank.name
+		}
+	}
+	if m := bankTagRe.FindStringSubmatch(msg.Text); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"smart-ledger-server/internal/model"
+)
+
+var (
+	// amountRe 金额：可带“人民币/RMB/¥”前缀、“元”后缀和千分位逗号
+	amountRe = regexp.MustCompile(`(人民币|RMB|CNY|[¥￥])?\s*(\d{1,3}(?:,\d{3})+(?:\.\d{1,2})?|\d+(?:\.\d{1,2})?)\s*(元)?`)
+	// dateRe 日期：2025年3月5日、2025-03-05、3月5日
+	dateRe = regexp.MustCompile(`(\d{4})[年/.-](\d{1,2})[月/.-](\d{1,2})日?|(\d{1,2})月(\d{1,2})[日号]`)
+	// clockRe 时刻：12:30、12:30:45、12时30分
+	clockRe = regexp.MustCompile(`(\d{1,2})[:：时](\d{2})(?:[:：](\d{2}))?分?`)
+	// tailRe 卡号尾号
+	tailRe = regexp.MustCompile(`(?:尾号|尾数|末四位|账户|卡号?)[为是:：]?\s*[*＊]*(\d{4})`)
+	// payMethodRe 支付平台通知中的支付方式，如“付款方式：招商银行信用卡(1234)”
+	payMethodRe = regexp.MustCompile(`(?:支付方式|付款方式)[:：]\s*([^，,。；;\n]+)`)
+)
+
+// keyword 收支关键词
+type keyword struct {
+	word     string
+	billType model.BillType
+}
+
+// refundWords 出现即视为收入的退款类关键词，优先于“消费”等支出关键词
+var refundWords = []string{"退款", "退货", "冲正"}
+
+// classify 按最先出现的收支关键词确定交易方向，返回关键词的起止位置
+func classify(text string, keywords []keyword) (model.BillType, int, int, bool) {
+	for _, word := range refundWords {
+		if i := strings.Index(text, word); i >= 0 {
+			return model.BillTypeIncome, i, i + len(word), true
+		}
+	}
+	start, end := -1, -1
+	var billType model.BillType
+	for _, kw := range keywords {
+		i := strings.Index(text, kw.word)
+		if i < 0 {
+			continue
+		}
+		// 位置相同时取较长的关键词（如“快捷支付”优先于“支付”）
+		if start < 0 || i < start || (i == start && i+len(kw.word) > end) {
+			start, end, billType = i, i+len(kw.word), kw.billType
+		}
+	}
+	return billType, start, end, start >= 0
+}
+
+// amountNear 查找收支关键词对应的金额
+// 优先取关键词之后的第一个金额（“消费支出人民币58.00元”），没有时取关键词之前的最后一个金额（“一笔58.00元的支出”）
+// 后面的金额通常是余额，因此不会越过关键词之后的第一个金额
+func amountNear(text string, start, end int) (decimal.Decimal, bool) {
+	var before decimal.Decimal
+	var found bool
+	for _, m := range amountRe.FindAllStringSubmatchIndex(text, -1) {
+		number := text[m[4]:m[5]]
+		// 只接受带货币符号、“元”或小数的数字，避免把尾号、时间当作金额
+		if m[2] < 0 && m[6] < 0 && !strings.Contains(number, ".") {
+			continue
+		}
+		amount, err := decimal.NewFromString(strings.ReplaceAll(number, ",", ""))
+		if err != nil || !amount.IsPositive() {
+			continue
+		}
+		if m[0] >= end {
+			return amount, true
+		}
+		if m[1] <= start {
+			before, found = amount, true
+		}
+	}
+	return before, found
+}
+
+// parseTime 解析正文中的交易时间，缺少日期或时刻时使用收到通知的时间补全
+// 没有年份且日期晚于收到时间时视为去年；只有时刻且晚于收到时间时视为前一天
+func parseTime(text string, receivedAt time.Time) time.Time {
+	loc := receivedAt.Location()
+	year, month, day := receivedAt.Date()
+	hour, minute, second := receivedAt.Clock()
+
+	hasYear, hasDate := false, false
+	if m := dateRe.FindStringSubmatch(text); m != nil {
+		y, mo, d := m[1], m[2], m[3]
+		if y == "" {
+			mo, d = m[4], m[5]
+		}
+		mon, _ := strconv.Atoi(mo)
+		dd, _ := strconv.Atoi(d)
+		if mon >= 1 && mon <= 12 && dd >= 1 && dd <= 31 {
+			month, day, hasDate = time.Month(mon), dd, true
+			if y != "" {
+				year, _ = strconv.Atoi(y)
+				hasYear = true
+			}
+		}
+	}
+	hasClock := false
+	if m := clockRe.FindStringSubmatch(text); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mi, _ := strconv.Atoi(m[2])
+		s, _ := strconv.Atoi(m[3])
+		if h <= 23 && mi <= 59 && s <= 59 {
+			hour, minute, second, hasClock = h, mi, s, true
+		}
+	}
+
+	t := time.Date(year, month, day, hour, minute, second, 0, loc)
+	if t.After(receivedAt) {
+		switch {
+		case hasDate && !hasYear:
+			t = t.AddDate(-1, 0, 0)
+		case !hasDate && hasClock:
+			t = t.AddDate(0, 0, -1)
+		}
+	}
+	return t
+}
+
+// firstSubmatch 依次尝试正则，返回第一个匹配的第一个分组
+func firstSubmatch(text string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(text); m != nil {
+			return cleanMerchant(m[1])
+		}
+	}
+	return ""
+}
+
+// cleanMerchant 去掉商户名称两端的括号和空白，过长时截断
+func cleanMerchant(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), "【】[]（）()“”\"")
+	if runes := []rune(s); len(runes) > 100 {
+		s = string(runes[:100])
+	}
+	return s
+}
+
+// containsAny 判断 text 是否包含任一关键词
+func containsAny(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"smart-ledger-server/internal/model"
+)
+
+// Message 待解析的支付通知或短信
+type Message struct {
+	Text       string
+	Sender     string    // 短信发送号码（如 95588）或通知来源应用包名，可为空
+	ReceivedAt time.Time // 收到通知的时间，正文缺少日期或年份时据此补全
+}
+
+// Transaction 从通知中解析出的交易
+type Transaction struct {
+	Amount    decimal.Decimal
+	BillType  model.BillType
+	Platform  string // 银行或支付平台名称
+	Merchant  string // 商户或对方名称，未提及时为空
+	PayMethod string // 支付方式（如“储蓄卡(1234)”“花呗”），未提及时为空
+	PayTime   time.Time
+}
+
+// Parser 通知解析器
+type Parser interface {
+	// Name 解析器名称，用于记录账单由哪个解析器生成
+	Name() string
+	// Parse 解析通知，不是本解析器支持的来源或不是交易通知时返回 false
+	Parse(msg *Message) (*Transaction, bool)
+}
+
+// Registry 通知解析器注册表，按注册顺序依次尝试，第一个解析成功的结果生效
+// 解析器应在启动时注册完毕，Registry 本身不做并发保护
+type Registry struct {
+	parsers []Parser
+}
+
+// NewRegistry 创建注册表
+func NewRegistry(parsers ...Parser) *Registry {
+	return &Registry{parsers: parsers}
+}
+
+// NewDefaultRegistry 创建包含内置解析器的注册表
+// 支付平台的通知格式更固定，排在银行短信之前
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(NewWeChatParser(), NewAlipayParser(), NewBankParser())
+}
+
+// Register 注册解析器，排在已注册的解析器之后
+func (r *Registry) Register(p Parser) {
+	r.parsers = append(r.parsers, p)
+}
+
+// Names 已注册解析器的名称
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.parsers))
+	for i, p := range r.parsers {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// Parse 依次尝试已注册的解析器，返回交易和解析器名称；没有解析器能解析时返回 false
+func (r *Registry) Parse(msg *Message) (*Transaction, string, bool) {
+	for _, p := range r.parsers {
+		if tx, ok := p.Parse(msg); ok {
+			return tx, p.Name(), true
+		}
+	}
+	return nil, "", false
+}
@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/model"
+)
+
+func TestDefaultRegistry_Parse(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	receivedAt := time.Date(2025, 3, 6, 9, 0, 0, 0, loc)
+
+	tests := []struct {
+		name          string
+		sender        string
+		text          string
+		wantParser    string
+		wantAmount    string
+		wantBillType  model.BillType
+		wantPlatform  string
+		wantMerchant  string
+		wantPayMethod string
+		wantPayTime   time.Time
+	}{
+		{
+			name:          "icbc debit card expense",
+			sender:        "95588",
+			text:          "您尾号1234卡3月5日12:30快捷支付(美团)58.00元，余额1,234.56元。【工商银行】",
+			wantParser:    "bank_sms",
+			wantAmount:    "58",
+			wantBillType:  model.BillTypeExpense,
+			wantPlatform:  "工商银行",
+			wantMerchant:  "美团",
+			wantPayMethod: "储蓄卡(1234)",
+			wantPayTime:   time.Date(2025, 3, 5, 12, 30, 0, 0, loc),
+		},
+		{
+			name:          "bank text without signature",
+			text:          "您尾号1234的储蓄卡消费支出人民币58.00元",
+			wantParser:    "bank_sms",
+			wantAmount:    "58",
+			wantBillType:  model.BillTypeExpense,
+			wantPlatform:  "银行",
+			wantPayMethod: "储蓄卡(1234)",
+			wantPayTime:   receivedAt,
+		},
+		{
+			name:          "cmb credit card with merchant",
+			text:          "【招商银行】您尾号5678的信用卡03月05日21:15在【瑞幸咖啡】消费人民币19.90元",
+			wantParser:    "bank_sms",
+			wantAmount:    "19.9",
+			wantBillType:  model.BillTypeExpense,
+			wantPlatform:  "招商银行",
+			wantMerchant:  "瑞幸咖啡",
+			wantPayMethod: "信用卡(5678)",
+			wantPayTime:   time.Date(2025, 3, 5, 21, 15, 0, 0, loc),
+		},
+		{
+			name:          "salary income",
+			text:          "【建设银行】您尾号0001的储蓄卡3月5日10时02分代发工资收入人民币12,000.00元,活期余额15,000.00元。",
+			wantParser:    "bank_sms",
+			wantAmount:    "12000",
+			wantBillType:  model.BillTypeIncome,
+			wantPlatform:  "建设银行",
+			wantPayMethod: "储蓄卡(0001)",
+			wantPayTime:   time.Date(2025, 3, 5, 10, 2, 0, 0, loc),
+		},
+		{
+			name:          "unknown bank by signature with refund",
+			text:          "【宁波银行】您尾号9999的账户于12月30日消费退款入账35.50元",
+			wantParser:    "bank_sms",
+			wantAmount:    "35.5",
+			wantBillType:  model.BillTypeIncome,
+			wantPlatform:  "宁波银行",
+			wantPayMethod: "储蓄卡(9999)",
+			wantPayTime:   time.Date(2024, 12, 30, 9, 0, 0, 0, loc),
+		},
+		{
+			name:         "wechat payment",
+			sender:       "com.tencent.mm",
+			text:         "微信支付 已支付¥25.00 向便利蜂付款",
+			wantParser:   "wechat",
+			wantAmount:   "25",
+			wantBillType: model.BillTypeExpense,
+			wantPlatform: "微信支付",
+			wantMerchant: "便利蜂",
+			wantPayTime:  receivedAt,
+		},
+		{
+			name:          "wechat income",
+			text:          "微信收款助手：微信支付收款到账100.00元，已存入零钱",
+			wantParser:    "wechat",
+			wantAmount:    "100",
+			wantBillType:  model.BillTypeIncome,
+			wantPlatform:  "微信支付",
+			wantPayMethod: "零钱",
+			wantPayTime:   receivedAt,
+		},
+		{
+			name:         "alipay expense before keyword",
+			text:         "支付宝：你有一笔68.50元的支出，点击查看详情 08:45",
+			wantParser:   "alipay",
+			wantAmount:   "68.5",
+			wantBillType: model.BillTypeExpense,
+			wantPlatform: "支付宝",
+			wantPayTime:  time.Date(2025, 3, 6, 8, 45, 0, 0, loc),
+		},
+		{
+			name:          "alipay huabei",
+			sender:        "com.eg.android.AlipayGphone",
+			text:          "付款成功 ￥128.00 商家：盒马鲜生，花呗付款",
+			wantParser:    "alipay",
+			wantAmount:    "128",
+			wantBillType:  model.BillTypeExpense,
+			wantPlatform:  "支付宝",
+			wantMerchant:  "盒马鲜生",
+			wantPayMethod: "花呗",
+			wantPayTime:   receivedAt,
+		},
+		{
+			name: "verification code is ignored",
+			text: "【工商银行】您的验证码为123456，5分钟内有效，请勿泄露。",
+		},
+	}
+
+	registry := NewDefaultRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, parser, ok := registry.Parse(&Message{Text: tt.text, Sender: tt.sender, ReceivedAt: receivedAt})
+			if tt.wantParser == "" {
+				assert.False(t, ok, "parsed by %s: %+v", parser, tx)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.wantParser, parser)
+			assert.Equal(t, tt.wantAmount, tx.Amount.String())
+			assert.Equal(t, tt.wantBillType, tx.BillType)
+			assert.Equal(t, tt.wantPlatform, tx.Platform)
+			assert.Equal(t, tt.wantMerchant, tx.Merchant)
+			assert.Equal(t, tt.wantPayMethod, tx.PayMethod)
+			assert.True(t, tt.wantPayTime.Equal(tx.PayTime), "pay time: %s", tx.PayTime)
+		})
+	}
+}
+
+type stubParser struct{}
+
+func (stubParser) Name() string { return "stub" }
+
+func (stubParser) Parse(msg *Message) (*Transaction, bool) {
+	return &Transaction{PayTime: msg.ReceivedAt}, msg.Sender == "stub"
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewDefaultRegistry()
+	registry.Register(stubParser{})
+	assert.Equal(t, []string{"wechat", "alipay", "bank_sms", "stub"}, registry.Names())
+
+	_, parser, ok := registry.Parse(&Message{Text: "hello", Sender: "stub"})
+	require.True(t, ok)
+	assert.Equal(t, "stub", parser)
+}
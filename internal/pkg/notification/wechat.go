@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+
+	"smart-ledger-server/internal/model"
+)
+
+// wechatKeywords 微信支付通知的收支关键词
+var wechatKeywords = []keyword{
+	{"已支付", model.BillTypeExpense},
+	{"支付成功", model.BillTypeExpense},
+	{"付款成功", model.BillTypeExpense},
+	{"付款金额", model.BillTypeExpense},
+	{"支付金额", model.BillTypeExpense},
+	{"扣费", model.BillTypeExpense},
+	{"扣款", model.BillTypeExpense},
+	{"收款到账", model.BillTypeIncome},
+	{"收款金额", model.BillTypeIncome},
+	{"收款", model.BillTypeIncome},
+	{"已收钱", model.BillTypeIncome},
+	{"已存入零钱", model.BillTypeIncome},
+	{"收到转账", model.BillTypeIncome},
+}
+
+// wechatMerchantRes 微信支付通知中的商户/对方名称
+var wechatMerchantRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?:收款方|商户名称|商户|付款方)[:：]\s*([^，,。；;\n]+)`),
+	regexp.MustCompile(`(?:向|给)([^，,。；;\s\d¥￥]{2,30}?)(?:付款|支付|转账)`),
+	regexp.MustCompile(`([^，,。；;\s\d¥￥]{2,30}?)(?:向你|给你)(?:付款|转账)`),
+}
+
+// WeChatParser 微信支付通知解析器
+type WeChatParser struct{}
+
+// NewWeChatParser 创建微信支付通知解析器
+func NewWeChatParser() *WeChatParser {
+	return &WeChatParser{}
+}
+
+// Name 解析器名称
+func (p *WeChatParser) Name() string {
+	return "wechat"
+}
+
+// Parse 解析微信支付通知，如“微信支付 已支付¥58.00”“微信收款助手 收款到账100.00元”
+func (p *WeChatParser) Parse(msg *Message) (*Transaction, bool) {
+	text := msg.Text
+	if !strings.Contains(msg.Sender, "com.tencent.mm") && !containsAny(text, []string{"微信支付", "微信收款", "微信转账"}) {
+		return nil, false
+	}
+	billType, start, end, ok := classify(text, wechatKeywords)
+	if !ok {
+		return nil, false
+	}
+	amount, ok := amountNear(text, start, end)
+	if !ok {
+		return nil, false
+	}
+
+	payMethod := ""
+	if m := payMethodRe.FindStringSubmatch(text); m != nil {
+		payMethod = strings.TrimSpace(m[1])
+	} else if billType == model.BillTypeIncome && strings.Contains(text, "零钱") {
+		payMethod = "零钱"
+	}
+	return &Transaction{
+		Amount:    amount,
+		BillType:  billType,
+		Platform:  "微信支付",
+		Merchant:  firstSubmatch(text, wechatMerchantRes),
+		PayMethod: payMethod,
+		PayTime:   parseTime(text, msg.ReceivedAt),
+	}, true
+}
@@ -0,0 +1,253 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var (
+	// ErrNotPDF 不是 PDF 文件
+	ErrNotPDF = errors.New("pdf: 不是有效的PDF文件")
+	// ErrEncrypted 加密的 PDF 暂不支持
+	ErrEncrypted = errors.New("pdf: 暂不支持加密的PDF文件")
+	// ErrNoPages 没有找到页面
+	ErrNoPages = errors.New("pdf: 没有找到页面")
+)
+
+// maxDecodedSize 单个流解码后的最大字节数，防止压缩炸弹
+const maxDecodedSize = 32 << 20
+
+// objHeaderRe 间接对象头，如 “12 0 obj”
+var objHeaderRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// Document 已解析的 PDF 文档
+// 只实现提取文本和内嵌图片所需的子集：不依赖交叉引用表，按顺序扫描全部间接对象，支持对象流和 FlateDecode
+type Document struct {
+	objects map[int]any
+	pages   []page
+}
+
+// page 页面及其（含继承的）资源字典
+type page struct {
+	dict      dict
+	resources dict
+}
+
+// IsPDF 根据文件头判断是否为 PDF
+func IsPDF(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+// Open 解析 PDF 文档
+func Open(data []byte) (*Document, error) {
+	if !IsPDF(data) {
+		return nil, ErrNotPDF
+	}
+	// 加密字典在 trailer 或交叉引用流中，直接检查原始数据
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, ErrEncrypted
+	}
+	doc := &Document{objects: make(map[int]any)}
+	doc.scanObjects(data)
+	doc.loadObjectStreams()
+
+	// 增量更新可能留下多个目录对象，取对象号最大的
+	var catalog dict
+	catalogNum := -1
+	for num, obj := range doc.objects {
+		if d, ok := obj.(dict); ok && num > catalogNum {
+			if t, _ := d["Type"].(name); t == "Catalog" {
+				catalog, catalogNum = d, num
+			}
+		}
+	}
+	if catalog == nil {
+		return nil, ErrNoPages
+	}
+	if root, ok := doc.resolve(catalog["Pages"]).(dict); ok {
+		doc.collectPages(root, nil, 0)
+	}
+	if len(doc.pages) == 0 {
+		return nil, ErrNoPages
+	}
+	return doc, nil
+}
+
+// NumPages 页数
+func (d *Document) NumPages() int {
+	return len(d.pages)
+}
+
+// scanObjects 顺序扫描文件中的间接对象，后出现的同号对象（增量更新）覆盖先出现的
+func (d *Document) scanObjects(data []byte) {
+	pos := 0
+	for pos < len(data) {
+		loc := objHeaderRe.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			return
+		}
+		num := atoi(data[pos+loc[2] : pos+loc[3]])
+		l := &lexer{data: data, pos: pos + loc[1]}
+		obj, err := l.object()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if sd, ok := obj.(dict); ok {
+			save := l.pos
+			if kw, ok := l.token().(keyword); ok && kw == "stream" {
+				obj = &stream{dict: sd, data: l.streamData(sd)}
+			} else {
+				l.pos = save
+			}
+		}
+		d.objects[num] = obj
+		pos = l.pos
+	}
+}
+
+// loadObjectStreams 展开对象流（/Type /ObjStm）中压缩存储的对象，不覆盖直接出现的对象
+func (d *Document) loadObjectStreams() {
+	var objStreams []*stream
+	for _, obj := range d.objects {
+		if s, ok := obj.(*stream); ok {
+			if t, _ := s.dict["Type"].(name); t == "ObjStm" {
+				objStreams = append(objStreams, s)
+			}
+		}
+	}
+	for _, s := range objStreams {
+		data, err := d.decode(s)
+		if err != nil {
+			continue
+		}
+		n, _ := d.resolve(s.dict["N"]).(float64)
+		first, _ := d.resolve(s.dict["First"]).(float64)
+		header := &lexer{data: data}
+		for i := 0; i < int(n); i++ {
+			num, ok1 := header.token().(float64)
+			offset, ok2 := header.token().(float64)
+			if !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(num)]; exists {
+				continue
+			}
+			// 偏移来自文件内容，负数或越界时跳过
+			pos := first + offset
+			if first < 0 || offset < 0 || pos >= float64(len(data)) {
+				continue
+			}
+			l := &lexer{data: data, pos: int(pos)}
+			if obj, err := l.object(); err == nil {
+				d.objects[int(num)] = obj
+			}
+		}
+	}
+}
+
+// collectPages 按页面树顺序收集页面，资源字典沿父节点继承
+func (d *Document) collectPages(node dict, inherited dict, depth int) {
+	if depth > 32 {
+		return
+	}
+	resources := inherited
+	if r, ok := d.resolve(node["Resources"]).(dict); ok {
+		resources = r
+	}
+	if t, _ := node["Type"].(name); t == "Page" {
+		d.pages = append(d.pages, page{dict: node, resources: resources})
+		return
+	}
+	kids, _ := d.resolve(node["Kids"]).(array)
+	for _, kid := range kids {
+		if child, ok := d.resolve(kid).(dict); ok {
+			d.collectPages(child, resources, depth+1)
+		}
+	}
+}
+
+// resolve 解析间接引用，引用的对象为流时返回 *stream
+func (d *Document) resolve(obj any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := obj.(objRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+// decode 解码流数据，支持 FlateDecode；DCTDecode 等图片编码原样返回
+func (d *Document) decode(s *stream) ([]byte, error) {
+	var filters []name
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = []name{f}
+	case array:
+		for _, item := range f {
+			if n, ok := d.resolve(item).(name); ok {
+				filters = append(filters, n)
+			}
+		}
+	}
+
+	data := s.data
+	for _, filter := range filters {
+		switch filter {
+		case "FlateDecode", "Fl":
+			decoded, err := inflate(data)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded
+		case "DCTDecode", "DCT", "JPXDecode":
+			return data, nil
+		default:
+			return nil, fmt.Errorf("pdf: 暂不支持的编码 %s", filter)
+		}
+	}
+	if parms, ok := d.resolve(s.dict["DecodeParms"]).(dict); ok {
+		if predictor, _ := d.resolve(parms["Predictor"]).(float64); predictor > 1 {
+			return nil, fmt.Errorf("pdf: 暂不支持预测器 %v", predictor)
+		}
+	}
+	return data, nil
+}
+
+// inflate 解压 zlib 数据，部分生成器省略了 zlib 头，此时按原始 deflate 解压
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, errors.New("pdf: 流数据过大")
+	}
+	// 流末尾损坏时尽量保留已解压的内容
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func atoi(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF 按顺序拼接间接对象生成最小的 PDF 文件，objects[i] 的对象号为 i+1
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func streamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+// toUnicodeCMap 将 2 字节编码 0001-0004 映射为 “发票号码”
+const toUnicodeCMap = `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar
+<0001> <53D1>
+<0002> <7968>
+endbfchar
+1 beginbfrange
+<0003> <0004> [<53F7> <7801>]
+endbfrange
+endcmap`
+
+func TestDocument_Text(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td <00010002> Tj ET\n" +
+		"BT /F2 12 Tf 1 0 0 1 72 700 Tm [(123) -300 (456)] TJ ET\n" +
+		"BT /F1 12 Tf 1 0 0 1 72 680 Tm <00030004> Tj ET"
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R /F2 7 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		streamObject("/Filter /FlateDecode", deflate(content)),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		streamObject("", []byte(toUnicodeCMap)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	doc, err := Open(data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, doc.NumPages())
+	assert.Equal(t, "发票\n123 456\n号码", doc.Text())
+}
+
+func TestDocument_PageImage(t *testing.T) {
+	var jpg bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 40, 30)), nil))
+	gray := bytes.Repeat([]byte{0x80}, 4*3)
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im1 4 0 R /Im2 5 0 R >> >> /Contents 6 0 R >>",
+		streamObject("/Type /XObject /Subtype /Image /Width 40 /Height 30 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode", jpg.Bytes()),
+		streamObject("/Type /XObject /Subtype /Image /Width 4 /Height 3 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode", deflate(string(gray))),
+		streamObject("", []byte("q 400 0 0 300 0 0 cm /Im1 Do Q")),
+	)
+
+	doc, err := Open(data)
+	require.NoError(t, err)
+	assert.Empty(t, doc.Text())
+
+	img, ok := doc.PageImage(0)
+	require.True(t, ok)
+	assert.Equal(t, "image/jpeg", img.MimeType)
+	assert.Equal(t, jpg.Bytes(), img.Data)
+	assert.Equal(t, 40, img.Width)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	_, err := Open([]byte("not a pdf"))
+	assert.ErrorIs(t, err, ErrNotPDF)
+
+	_, err = Open(buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Filter /Standard >>"))
+	assert.ErrorIs(t, err, ErrNoPages)
+
+	encrypted := append(buildPDF("<< /Type /Catalog >>"), []byte("trailer << /Encrypt 2 0 R >>")...)
+	_, err = Open(encrypted)
+	assert.ErrorIs(t, err, ErrEncrypted)
+}
+
+func TestOpen_Malformed(t *testing.T) {
+	// 深度嵌套的数组不能导致栈溢出
+	nested := append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("["), 1<<20)...)
+	_, err := Open(nested)
+	assert.ErrorIs(t, err, ErrNoPages)
+
+	// 对象流中的负偏移和负 /First 应被忽略
+	for _, header := range []string{"/N 1 /First -100", "/N 1 /First 0"} {
+		objects := "2 -100 << /Type /Catalog >>"
+		data := buildPDF(streamObject("/Type /ObjStm "+header, []byte(objects)))
+		_, err = Open(data)
+		assert.ErrorIs(t, err, ErrNoPages, header)
+	}
+
+	// 负 /Length 回退到搜索 endstream
+	data := buildPDF("<< /Length -5 >>\nstream\nabc\nendstream")
+	_, err = Open(data)
+	assert.ErrorIs(t, err, ErrNoPages)
+}
+
+func FuzzOpen(f *testing.F) {
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		streamObject("/Filter /FlateDecode", deflate("BT /F1 12 Tf (abc) Tj ET")),
+	))
+	f.Add(buildPDF(streamObject("/Type /ObjStm /N 1 /First 4", []byte("1 0 << /Type /Catalog >>"))))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n[[[[<<[[<<"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := Open(data)
+		if err != nil {
+			return
+		}
+		doc.Text()
+		for i := 0; i < doc.NumPages(); i++ {
+			doc.PageImage(i)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Image 页面内嵌的图片
+type Image struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// PageImage 获取指定页面（从 0 开始）中面积最大的内嵌图片，扫描件转成的 PDF 通常整页就是一张图片
+// 支持 JPEG（DCTDecode）以及 8 位灰度/RGB 的 FlateDecode 图片（转换为 PNG），没有可用图片时返回 false
+func (d *Document) PageImage(index int) (*Image, bool) {
+	if index < 0 || index >= len(d.pages) {
+		return nil, false
+	}
+	xobjects, _ := d.resolve(d.pages[index].resources["XObject"]).(dict)
+
+	var best *Image
+	for _, obj := range xobjects {
+		s, ok := d.resolve(obj).(*stream)
+		if !ok {
+			continue
+		}
+		if subtype, _ := s.dict["Subtype"].(name); subtype != "Image" {
+			continue
+		}
+		img, ok := d.decodeImage(s)
+		if !ok {
+			continue
+		}
+		if best == nil || img.Width*img.Height > best.Width*best.Height {
+			best = img
+		}
+	}
+	return best, best != nil
+}
+
+// decodeImage 将图片流转换为可直接发送给视觉模型的图片
+func (d *Document) decodeImage(s *stream) (*Image, bool) {
+	width, _ := d.resolve(s.dict["Width"]).(float64)
+	height, _ := d.resolve(s.dict["Height"]).(float64)
+	w, h := int(width), int(height)
+	if w <= 0 || h <= 0 {
+		return nil, false
+	}
+
+	filter := d.resolve(s.dict["Filter"])
+	if arr, ok := filter.(array); ok && len(arr) > 0 {
+		filter = d.resolve(arr[len(arr)-1])
+	}
+	data, err := d.decode(s)
+	if err != nil {
+		return nil, false
+	}
+	if f, _ := filter.(name); f == "DCTDecode" || f == "DCT" {
+		return &Image{Data: data, MimeType: "image/jpeg", Width: w, Height: h}, true
+	}
+	if f, _ := filter.(name); f != "" && f != "FlateDecode" && f != "Fl" {
+		return nil, false
+	}
+
+	if bpc, _ := d.resolve(s.dict["BitsPerComponent"]).(float64); bpc != 8 {
+		return nil, false
+	}
+	var img image.Image
+	switch cs, _ := d.resolve(s.dict["ColorSpace"]).(name); cs {
+	case "DeviceGray":
+		if len(data) < w*h {
+			return nil, false
+		}
+		img = &image.Gray{Pix: data[:w*h], Stride: w, Rect: image.Rect(0, 0, w, h)}
+	case "DeviceRGB":
+		if len(data) < w*h*3 {
+			return nil, false
+		}
+		rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i := 0; i < w*h; i++ {
+			rgba.Set(i%w, i/w, color.RGBA{R: data[3*i], G: data[3*i+1], B: data[3*i+2], A: 0xff})
+		}
+		img = rgba
+	default:
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, false
+	}
+	return &Image{Data: buf.Bytes(), MimeType: "image/png", Width: w, Height: h}, true
+}
@@ -0,0 +1,309 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// PDF 对象类型
+type (
+	name    string                 // 名称对象，如 /Type
+	keyword string                 // 关键字或内容流操作符，如 obj、Tj
+	dict    map[name]any           // 字典对象
+	array   []any                  // 数组对象
+	objRef  struct{ num, gen int } // 间接引用，如 12 0 R
+)
+
+// stream 流对象，data 为未解码的原始数据
+type stream struct {
+	dict dict
+	data []byte
+}
+
+// errSyntax 无法解析的 PDF 语法
+var errSyntax = errors.New("pdf: 语法错误")
+
+// maxNestingDepth 数组和字典的最大嵌套层数，防止恶意文件递归过深导致栈溢出
+const maxNestingDepth = 64
+
+// lexer PDF 词法/语法解析器，同时用于文件正文、对象流、内容流和 CMap
+type lexer struct {
+	data  []byte
+	pos   int
+	depth int // 当前数组/字典嵌套层数
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace 跳过空白和注释
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token 读取下一个记号：数字为 float64，字符串为 []byte，定界符 [ ] << >> { } 以 keyword 表示
+// 到达末尾时返回 nil
+func (l *lexer) token() any {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.readName()
+	case c == '(':
+		return l.readLiteralString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return keyword("<<")
+		}
+		return l.readHexString()
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return keyword(">>")
+		}
+		l.pos++
+		return keyword(">")
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return keyword(string(c))
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '.' || word[0] == '+' || word[0] == '-' || (word[0] >= '0' && word[0] <= '9')) {
+		return n
+	}
+	return keyword(word)
+}
+
+func (l *lexer) readName() name {
+	l.pos++ // '/'
+	var buf []byte
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				buf = append(buf, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		buf = append(buf, c)
+		l.pos++
+	}
+	return name(buf)
+}
+
+func (l *lexer) readLiteralString() []byte {
+	l.pos++ // '('
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return buf
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// 行连接符
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func (l *lexer) readHexString() []byte {
+	l.pos++ // '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	buf := make([]byte, len(digits)/2)
+	for i := range buf {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		buf[i] = byte(v)
+	}
+	return buf
+}
+
+// object 读取一个完整对象（字典、数组、引用等），到达末尾或嵌套过深时返回 errSyntax
+func (l *lexer) object() (any, error) {
+	tok := l.token()
+	switch t := tok.(type) {
+	case nil:
+		return nil, errSyntax
+	case keyword:
+		switch t {
+		case "<<", "[":
+			if l.depth >= maxNestingDepth {
+				return nil, errSyntax
+			}
+			l.depth++
+			defer func() { l.depth-- }()
+			if t == "<<" {
+				return l.readDict()
+			}
+			return l.readArray()
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	case float64:
+		// 可能是间接引用 “num gen R”
+		save := l.pos
+		if gen, ok := l.token().(float64); ok {
+			if kw, ok := l.token().(keyword); ok && kw == "R" {
+				return objRef{int(t), int(gen)}, nil
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+func (l *lexer) readDict() (dict, error) {
+	d := dict{}
+	for {
+		tok := l.token()
+		if kw, ok := tok.(keyword); ok && kw == ">>" {
+			return d, nil
+		}
+		key, ok := tok.(name)
+		if !ok {
+			return nil, errSyntax
+		}
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		if kw, ok := value.(keyword); ok && kw == ">>" {
+			// 缺少值的键，视为字典结束
+			return d, nil
+		}
+		d[key] = value
+	}
+}
+
+func (l *lexer) readArray() (array, error) {
+	var a array
+	for {
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		if kw, ok := value.(keyword); ok && kw == "]" {
+			return a, nil
+		}
+		a = append(a, value)
+	}
+}
+
+// streamData 在 stream 关键字之后读取流数据
+// 优先使用直接给出的 /Length，长度不可信时搜索 endstream
+func (l *lexer) streamData(d dict) []byte {
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+	if n, ok := d["Length"].(float64); ok && n >= 0 && n <= float64(len(l.data)-start) {
+		end := start + int(n)
+		if bytes.HasPrefix(bytes.TrimLeft(l.data[end:], "\r\n \t"), []byte("endstream")) {
+			l.pos = end
+			return l.data[start:end]
+		}
+	}
+	i := bytes.Index(l.data[start:], []byte("endstream"))
+	if i < 0 {
+		l.pos = len(l.data)
+		return l.data[start:]
+	}
+	end := start + i
+	l.pos = end
+	return bytes.TrimRight(l.data[start:end], "\r\n")
+}
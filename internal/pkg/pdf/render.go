@@ -0,0 +1,70 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Renderer 将 PDF 页面渲染为图片
+type Renderer interface {
+	// Render 渲染第一页，返回图片数据和 MIME 类型
+	Render(ctx context.Context, data []byte) ([]byte, string, error)
+}
+
+// CommandRenderer 调用外部命令渲染 PDF 页面
+// 命令中的 {input} 替换为临时 PDF 文件路径，渲染结果（PNG/JPEG）从标准输出读取，如：
+//
+//	pdftoppm -png -r 150 -f 1 -l 1 -singlefile {input}
+//	mutool draw -F png -r 150 -o - {input} 1
+type CommandRenderer struct {
+	command []string
+}
+
+// NewCommandRenderer 创建外部命令渲染器，command 为空时返回 nil
+func NewCommandRenderer(command string) *CommandRenderer {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	return &CommandRenderer{command: fields}
+}
+
+// Render 渲染第一页
+func (r *CommandRenderer) Render(ctx context.Context, data []byte) ([]byte, string, error) {
+	file, err := os.CreateTemp("", "render-*.pdf")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	if err := file.Close(); err != nil {
+		return nil, "", err
+	}
+
+	args := make([]string, len(r.command)-1)
+	for i, arg := range r.command[1:] {
+		args[i] = strings.ReplaceAll(arg, "{input}", file.Name())
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.command[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("pdf: 渲染失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := stdout.Bytes()
+	mimeType := http.DetectContentType(output)
+	if mimeType != "image/png" && mimeType != "image/jpeg" {
+		return nil, "", fmt.Errorf("pdf: 渲染结果不是图片: %s", mimeType)
+	}
+	return output, mimeType, nil
+}
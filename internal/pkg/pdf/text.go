@@ -0,0 +1,369 @@
+package pdf
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// maxFormDepth 表单 XObject 的最大嵌套层数
+const maxFormDepth = 4
+
+// font 文本解码所需的字体信息
+type font struct {
+	toUnicode map[string]string // 字符编码 -> Unicode 文本（ToUnicode CMap）
+	codeLen   int               // 字符编码字节数：简单字体为 1，复合字体通常为 2
+	encoding  string            // 未提供 ToUnicode 时的预定义 CMap，如 UniGB-UCS2-H
+}
+
+// Text 提取全部页面的文本，页面之间以换行分隔
+// 不能解码的文字（未嵌入 ToUnicode 的复合字体）会被忽略
+func (d *Document) Text() string {
+	var sb strings.Builder
+	for i := range d.pages {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(d.PageText(i))
+	}
+	return sb.String()
+}
+
+// PageText 提取指定页面（从 0 开始）的文本
+func (d *Document) PageText(index int) string {
+	if index < 0 || index >= len(d.pages) {
+		return ""
+	}
+	p := d.pages[index]
+	var sb strings.Builder
+	for _, content := range d.contentStreams(p.dict["Contents"]) {
+		d.extractText(&sb, content, p.resources, 0)
+		sb.WriteString("\n")
+	}
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// contentStreams 获取页面内容流（单个流或流数组）并解码
+func (d *Document) contentStreams(contents any) [][]byte {
+	var streams []*stream
+	switch c := d.resolve(contents).(type) {
+	case *stream:
+		streams = append(streams, c)
+	case array:
+		for _, item := range c {
+			if s, ok := d.resolve(item).(*stream); ok {
+				streams = append(streams, s)
+			}
+		}
+	}
+	var result [][]byte
+	for _, s := range streams {
+		if data, err := d.decode(s); err == nil {
+			result = append(result, data)
+		}
+	}
+	return result
+}
+
+// extractText 解释内容流中的文本操作符，按文本定位操作插入换行和空格
+func (d *Document) extractText(sb *strings.Builder, content []byte, resources dict, depth int) {
+	fonts := map[name]*font{}
+	var current *font
+	var operands []any
+	lastY, hasY := 0.0, false
+
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	space := func() {
+		if s := sb.String(); len(s) > 0 && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") {
+			sb.WriteString(" ")
+		}
+	}
+	show := func(b []byte) {
+		if current != nil {
+			sb.WriteString(current.decode(b))
+		}
+	}
+
+	l := &lexer{data: content}
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch op {
+		case "BI":
+			// 内联图片，跳过到 EI
+			if i := strings.Index(string(content[l.pos:]), "EI"); i >= 0 {
+				l.pos += i + 2
+			}
+		case "Tf":
+			if len(operands) >= 1 {
+				if fontName, ok := operands[0].(name); ok {
+					f, cached := fonts[fontName]
+					if !cached {
+						f = d.loadFont(resources, fontName)
+						fonts[fontName] = f
+					}
+					current = f
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				if b, ok := operands[len(operands)-1].([]byte); ok {
+					show(b)
+				}
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) >= 1 {
+				if b, ok := operands[len(operands)-1].([]byte); ok {
+					show(b)
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				if items, ok := operands[len(operands)-1].(array); ok {
+					for _, item := range items {
+						switch v := item.(type) {
+						case []byte:
+							show(v)
+						case float64:
+							// 较大的负向字距通常表示词间空白
+							if v < -250 {
+								space()
+							}
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, ok := operands[1].(float64); ok && ty != 0 {
+					newline()
+				} else {
+					space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[5].(float64); ok {
+					if !hasY || y != lastY {
+						newline()
+					} else {
+						space()
+					}
+					lastY, hasY = y, true
+				}
+			}
+		case "T*":
+			newline()
+		case "ET":
+			space()
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				if xName, ok := operands[0].(name); ok {
+					d.extractForm(sb, resources, xName, depth)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// extractForm 提取表单 XObject 中的文本
+func (d *Document) extractForm(sb *strings.Builder, resources dict, xName name, depth int) {
+	xobjects, _ := d.resolve(resources["XObject"]).(dict)
+	s, ok := d.resolve(xobjects[xName]).(*stream)
+	if !ok {
+		return
+	}
+	if subtype, _ := s.dict["Subtype"].(name); subtype != "Form" {
+		return
+	}
+	data, err := d.decode(s)
+	if err != nil {
+		return
+	}
+	formResources, ok := d.resolve(s.dict["Resources"]).(dict)
+	if !ok {
+		formResources = resources
+	}
+	d.extractText(sb, data, formResources, depth+1)
+}
+
+// loadFont 加载字体的编码信息
+func (d *Document) loadFont(resources dict, fontName name) *font {
+	f := &font{codeLen: 1}
+	fonts, _ := d.resolve(resources["Font"]).(dict)
+	fd, ok := d.resolve(fonts[fontName]).(dict)
+	if !ok {
+		return f
+	}
+	if subtype, _ := fd["Subtype"].(name); subtype == "Type0" {
+		f.codeLen = 2
+		if enc, ok := d.resolve(fd["Encoding"]).(name); ok {
+			f.encoding = string(enc)
+		}
+	}
+	if s, ok := d.resolve(fd["ToUnicode"]).(*stream); ok {
+		if data, err := d.decode(s); err == nil {
+			f.toUnicode, f.codeLen = parseCMap(data, f.codeLen)
+		}
+	}
+	return f
+}
+
+// decode 将字符串中的字符编码转换为文本
+func (f *font) decode(b []byte) string {
+	if f.toUnicode == nil {
+		switch {
+		case f.codeLen == 1:
+			// 简单字体按 Latin-1 近似，足以还原数字和英文
+			runes := make([]rune, len(b))
+			for i, c := range b {
+				runes[i] = rune(c)
+			}
+			return string(runes)
+		case strings.Contains(f.encoding, "UCS2") || strings.Contains(f.encoding, "UTF16"):
+			return decodeUTF16(b)
+		case strings.Contains(f.encoding, "GBK") || strings.Contains(f.encoding, "GB-EUC"):
+			if s, err := simplifiedchinese.GBK.NewDecoder().Bytes(b); err == nil {
+				return string(s)
+			}
+		}
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 0; i+f.codeLen <= len(b); i += f.codeLen {
+		if s, ok := f.toUnicode[string(b[i:i+f.codeLen])]; ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// parseCMap 解析 ToUnicode CMap 的 bfchar/bfrange 映射，返回映射表和字符编码字节数
+func parseCMap(data []byte, defaultCodeLen int) (map[string]string, int) {
+	mapping := map[string]string{}
+	codeLen := defaultCodeLen
+	l := &lexer{data: data}
+	for {
+		tok := l.token()
+		if tok == nil {
+			break
+		}
+		kw, ok := tok.(keyword)
+		if !ok {
+			continue
+		}
+		switch kw {
+		case "begincodespacerange":
+			if lo, ok := l.token().([]byte); ok && len(lo) > 0 {
+				codeLen = len(lo)
+			}
+		case "beginbfchar":
+			for {
+				src, ok := l.token().([]byte)
+				if !ok {
+					break
+				}
+				if dst, ok := l.token().([]byte); ok {
+					mapping[string(src)] = decodeUTF16(dst)
+				}
+			}
+		case "beginbfrange":
+			for {
+				lo, ok := l.token().([]byte)
+				if !ok {
+					break
+				}
+				hi, _ := l.token().([]byte)
+				dst, err := l.object()
+				if err != nil || len(hi) != len(lo) {
+					break
+				}
+				addRange(mapping, lo, hi, dst)
+			}
+		}
+	}
+	return mapping, codeLen
+}
+
+// maxRangeSize 单个 bfrange 的最大展开数量
+const maxRangeSize = 1 << 16
+
+// addRange 展开 bfrange：目标为字符串时依次递增末位，为数组时逐一对应
+func addRange(mapping map[string]string, lo, hi []byte, dst any) {
+	start, end := codeValue(lo), codeValue(hi)
+	if end < start || end-start > maxRangeSize {
+		return
+	}
+	for code := start; code <= end; code++ {
+		src := codeBytes(code, len(lo))
+		offset := int(code - start)
+		switch v := dst.(type) {
+		case []byte:
+			runes := []rune(decodeUTF16(v))
+			if len(runes) == 0 {
+				return
+			}
+			runes[len(runes)-1] += rune(offset)
+			mapping[string(src)] = string(runes)
+		case array:
+			if offset < len(v) {
+				if b, ok := v[offset].([]byte); ok {
+					mapping[string(src)] = decodeUTF16(b)
+				}
+			}
+		}
+	}
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) []byte {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// decodeUTF16 解码 UTF-16BE 文本
+func decodeUTF16(b []byte) string {
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	s := string(utf16.Decode(units))
+	if !utf8.ValidString(s) {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package quickentry
+
+import (
+	"strings"
+
+	"smart-ledger-server/internal/model"
+)
+
+// categoryAliases 常见口语描述与默认分类名称的对应关系
+// 仅在描述中不直接包含分类名称时使用，分类被用户改名后自然失效
+var categoryAliases = []struct {
+	words      []string
+	categories []string // 依次尝试的分类名称（二级分类在前）
+}{
+	{[]string{"早饭", "早餐", "午饭", "午餐", "晚饭", "晚餐", "夜宵", "宵夜", "吃饭", "聚餐"}, []string{"正餐", "餐饮"}},
+	{[]string{"外卖"}, []string{"外卖配送费", "正餐", "餐饮"}},
+	{[]string{"咖啡", "奶茶", "饮料", "星巴克", "瑞幸"}, []string{"咖啡饮品", "餐饮"}},
+	{[]string{"零食", "小吃", "面包", "甜品"}, []string{"小吃零食", "餐饮"}},
+	{[]string{"水果", "买菜", "蔬菜", "生鲜"}, []string{"水果生鲜", "餐饮"}},
+	{[]string{"打车", "滴滴", "出租车", "网约车"}, []string{"打车", "交通"}},
+	{[]string{"地铁", "公交", "火车", "高铁", "机票"}, []string{"公共交通", "交通"}},
+	{[]string{"单车", "骑车"}, []string{"共享单车", "交通"}},
+	{[]string{"加油", "停车", "过路费"}, []string{"加油停车", "交通"}},
+	{[]string{"电影", "演出", "演唱会", "KTV"}, []string{"电影演出", "娱乐"}},
+	{[]string{"健身", "游泳", "球馆"}, []string{"运动健身", "娱乐"}},
+	{[]string{"会员", "订阅"}, []string{"会员订阅", "娱乐"}},
+	{[]string{"话费", "流量"}, []string{"话费充值", "生活服务"}},
+	{[]string{"水费", "电费", "燃气", "煤气"}, []string{"水电燃气", "生活服务"}},
+	{[]string{"看病", "买药", "药店", "医院"}, []string{"医疗健康", "生活服务"}},
+	{[]string{"快递"}, []string{"快递物流", "生活服务"}},
+	{[]string{"衣服", "鞋", "包"}, []string{"服饰鞋包", "购物"}},
+	{[]string{"日用", "超市"}, []string{"日用百货", "购物"}},
+	{[]string{"工资", "薪水"}, []string{"工资", "薪资"}},
+	{[]string{"奖金"}, []string{"奖金", "薪资"}},
+	{[]string{"红包"}, []string{"微信红包", "收红包"}},
+	{[]string{"退款"}, []string{"退款", "其他收入"}},
+	{[]string{"报销"}, []string{"报销", "其他收入"}},
+	{[]string{"利息", "分红"}, []string{"利息", "理财收益"}},
+}
+
+// MatchCategory 根据描述匹配用户分类，categories 为包含 Children 的分类树
+// 优先匹配描述中直接出现的二级分类、一级分类名称，其次按常见口语匹配，找不到时返回 nil
+func MatchCategory(keyword string, billType model.BillType, categories []model.Category) *model.Category {
+	if keyword == "" {
+		return nil
+	}
+
+	byName := make(map[string]*model.Category)
+	var parents, children []*model.Category
+	for i := range categories {
+		parent := &categories[i]
+		if model.BillType(parent.Type) != billType {
+			continue
+		}
+		parents = append(parents, parent)
+		for j := range parent.Children {
+			children = append(children, &parent.Children[j])
+		}
+	}
+	// 二级分类优先，同名时不覆盖先出现的分类
+	for _, list := range [][]*model.Category{children, parents} {
+		for _, category := range list {
+			if _, exists := byName[category.Name]; !exists {
+				byName[category.Name] = category
+			}
+		}
+	}
+
+	for _, list := range [][]*model.Category{children, parents} {
+		for _, category := range list {
+			if strings.Contains(keyword, category.Name) {
+				return category
+			}
+		}
+	}
+
+	for _, alias := range categoryAliases {
+		for _, word := range alias.words {
+			if !strings.Contains(keyword, word) {
+				continue
+			}
+			for _, name := range alias.categories {
+				if category, ok := byName[name]; ok {
+					return category
+				}
+			}
+		}
+	}
+	return nil
+}
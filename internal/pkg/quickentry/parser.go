@@ -0,0 +1,153 @@
+package quickentry
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"smart-ledger-server/internal/model"
+)
+
+// ErrNoAmount 输入中没有金额
+var ErrNoAmount = errors.New("quickentry: 输入中没有金额")
+
+// Draft 规则解析得到的账单草稿
+type Draft struct {
+	Amount    decimal.Decimal
+	BillType  model.BillType
+	Platform  string    // 支付平台（微信支付/支付宝），未提及时为空
+	PayMethod string    // 支付方式（花呗/信用卡/现金等），未提及时为空
+	PayTime   time.Time // 已按 now 的时区换算相对日期
+	Keyword   string    // 去掉金额、日期、支付方式后的描述，用于匹配分类
+}
+
+var (
+	// amountRe 金额：可带货币符号、正负号、“元/块”后缀
+	amountRe = regexp.MustCompile(`([+-]?)[¥￥]?\s*(\d+(?:\.\d{1,2})?)\s*(?:元|块钱|块|rmb|RMB)?`)
+	// monthDayRe 月日：3月5日、3月5号、3/5、3-5
+	monthDayRe = regexp.MustCompile(`(\d{1,2})\s*(?:月\s*(\d{1,2})\s*[日号]?|[/-](\d{1,2}))`)
+	// clockRe 时刻：12:30、12点30、12点
+	clockRe = regexp.MustCompile(`(\d{1,2})\s*(?:[:：]\s*(\d{2})|点\s*(?:(\d{1,2})\s*分?|半)?)`)
+	// daysAgoRe N天前
+	daysAgoRe = regexp.MustCompile(`(\d{1,2})\s*天前`)
+)
+
+// relativeDays 相对日期词及其与今天相差的天数，长词在前避免“前天”误匹配“大前天”
+var relativeDays = []struct {
+	word   string
+	offset int
+}{
+	{"大前天", -3},
+	{"前天", -2},
+	{"昨天", -1},
+	{"昨晚", -1},
+	{"今天", 0},
+	{"今晚", 0},
+}
+
+// paymentKeywords 支付平台/支付方式关键词
+var paymentKeywords = []struct {
+	word      string
+	platform  string
+	payMethod string
+}{
+	{"微信", "微信支付", ""},
+	{"支付宝", "支付宝", ""},
+	{"花呗", "支付宝", "花呗"},
+	{"信用卡", "", "信用卡"},
+	{"银行卡", "", "银行卡"},
+	{"现金", "", "现金"},
+	{"云闪付", "云闪付", ""},
+}
+
+// incomeKeywords 出现即视为收入的关键词
+var incomeKeywords = []string{"工资", "薪水", "奖金", "收入", "收到", "收款", "退款", "报销", "红包", "利息", "分红", "补贴"}
+
+// Parse 按规则解析一句话记账文本，如“午饭 35 微信”“昨天打车 28.5”
+// now 为用户所在时区的当前时间；输入中没有金额时返回 ErrNoAmount
+func Parse(text string, now time.Time) (*Draft, error) {
+	rest := strings.TrimSpace(text)
+	draft := &Draft{BillType: model.BillTypeExpense, PayTime: now}
+
+	// 先提取日期和时刻，避免其中的数字被当作金额
+	date := now
+	for _, rd := range relativeDays {
+		if strings.Contains(rest, rd.word) {
+			date = now.AddDate(0, 0, rd.offset)
+			rest = strings.Replace(rest, rd.word, " ", 1)
+			break
+		}
+	}
+	if match := daysAgoRe.FindStringSubmatch(rest); match != nil {
+		days, _ := strconv.Atoi(match[1])
+		date = now.AddDate(0, 0, -days)
+		rest = strings.Replace(rest, match[0], " ", 1)
+	}
+	if match := monthDayRe.FindStringSubmatch(rest); match != nil {
+		month, _ := strconv.Atoi(match[1])
+		day, _ := strconv.Atoi(match[2] + match[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			date = time.Date(now.Year(), time.Month(month), day, now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+			// 未写年份且日期在未来时视为去年
+			if date.After(now) {
+				date = date.AddDate(-1, 0, 0)
+			}
+			rest = strings.Replace(rest, match[0], " ", 1)
+		}
+	}
+	hour, minute, second := now.Clock()
+	if match := clockRe.FindStringSubmatch(rest); match != nil {
+		h, _ := strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2] + match[3])
+		if strings.HasSuffix(match[0], "半") {
+			m = 30
+		}
+		if h <= 23 && m <= 59 {
+			hour, minute, second = h, m, 0
+			rest = strings.Replace(rest, match[0], " ", 1)
+		}
+	}
+	draft.PayTime = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, now.Location())
+
+	// 金额取最后一个数字，描述中的数字（如“2杯咖啡”）通常在金额之前
+	matches := amountRe.FindAllStringSubmatchIndex(rest, -1)
+	if len(matches) == 0 {
+		return nil, ErrNoAmount
+	}
+	last := matches[len(matches)-1]
+	amount, err := decimal.NewFromString(rest[last[4]:last[5]])
+	if err != nil || !amount.IsPositive() {
+		return nil, ErrNoAmount
+	}
+	draft.Amount = amount
+	if rest[last[2]:last[3]] == "+" {
+		draft.BillType = model.BillTypeIncome
+	}
+	rest = rest[:last[0]] + " " + rest[last[1]:]
+
+	for _, kw := range paymentKeywords {
+		if strings.Contains(rest, kw.word) {
+			if draft.Platform == "" {
+				draft.Platform = kw.platform
+			}
+			if draft.PayMethod == "" {
+				draft.PayMethod = kw.payMethod
+			}
+			rest = strings.Replace(rest, kw.word, " ", 1)
+		}
+	}
+
+	for _, word := range incomeKeywords {
+		if strings.Contains(rest, word) {
+			draft.BillType = model.BillTypeIncome
+			break
+		}
+	}
+
+	draft.Keyword = strings.Join(strings.Fields(strings.Trim(rest, " ，,。.")), " ")
+	return draft, nil
+}
@@ -0,0 +1,147 @@
+package quickentry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	now := time.Date(2025, 3, 10, 20, 15, 0, 0, loc)
+
+	tests := []struct {
+		name          string
+		text          string
+		wantAmount    string
+		wantBillType  model.BillType
+		wantPlatform  string
+		wantPayMethod string
+		wantPayTime   time.Time
+		wantKeyword   string
+	}{
+		{
+			name:         "category amount platform",
+			text:         "午饭 35 微信",
+			wantAmount:   "35",
+			wantBillType: model.BillTypeExpense,
+			wantPlatform: "微信支付",
+			wantPayTime:  now,
+			wantKeyword:  "午饭",
+		},
+		{
+			name:         "relative date",
+			text:         "昨天打车 28.5",
+			wantAmount:   "28.5",
+			wantBillType: model.BillTypeExpense,
+			wantPayTime:  now.AddDate(0, 0, -1),
+			wantKeyword:  "打车",
+		},
+		{
+			name:          "month day clock and currency",
+			text:          "3月8日 12:30 花呗 买衣服 ¥299.9元",
+			wantAmount:    "299.9",
+			wantBillType:  model.BillTypeExpense,
+			wantPlatform:  "支付宝",
+			wantPayMethod: "花呗",
+			wantPayTime:   time.Date(2025, 3, 8, 12, 30, 0, 0, loc),
+			wantKeyword:   "买衣服",
+		},
+		{
+			name:         "future month day means last year",
+			text:         "12/24 聚餐 200",
+			wantAmount:   "200",
+			wantBillType: model.BillTypeExpense,
+			wantPayTime:  time.Date(2024, 12, 24, 20, 15, 0, 0, loc),
+			wantKeyword:  "聚餐",
+		},
+		{
+			name:         "income keyword",
+			text:         "工资 8000",
+			wantAmount:   "8000",
+			wantBillType: model.BillTypeIncome,
+			wantPayTime:  now,
+			wantKeyword:  "工资",
+		},
+		{
+			name:          "last number is amount",
+			text:          "2杯咖啡 36块 现金",
+			wantAmount:    "36",
+			wantBillType:  model.BillTypeExpense,
+			wantPayMethod: "现金",
+			wantPayTime:   now,
+			wantKeyword:   "2杯咖啡",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft, err := Parse(tt.text, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAmount, draft.Amount.String())
+			assert.Equal(t, tt.wantBillType, draft.BillType)
+			assert.Equal(t, tt.wantPlatform, draft.Platform)
+			assert.Equal(t, tt.wantPayMethod, draft.PayMethod)
+			assert.True(t, tt.wantPayTime.Equal(draft.PayTime), "pay time: %s", draft.PayTime)
+			assert.Equal(t, tt.wantKeyword, draft.Keyword)
+		})
+	}
+}
+
+func TestParse_NoAmount(t *testing.T) {
+	for _, text := range []string{"午饭", "昨天 微信", "0"} {
+		_, err := Parse(text, time.Now())
+		assert.ErrorIs(t, err, ErrNoAmount, text)
+	}
+}
+
+func TestMatchCategory(t *testing.T) {
+	categories := []model.Category{
+		{
+			BaseModel: model.BaseModel{ID: 1},
+			Name:      "餐饮",
+			Type:      model.CategoryTypeExpense,
+			Children: []model.Category{
+				{BaseModel: model.BaseModel{ID: 11}, Name: "正餐", Type: model.CategoryTypeExpense},
+				{BaseModel: model.BaseModel{ID: 12}, Name: "咖啡饮品", Type: model.CategoryTypeExpense},
+			},
+		},
+		{
+			BaseModel: model.BaseModel{ID: 2},
+			Name:      "交通",
+			Type:      model.CategoryTypeExpense,
+			Children: []model.Category{
+				{BaseModel: model.BaseModel{ID: 21}, Name: "打车", Type: model.CategoryTypeExpense},
+			},
+		},
+		{BaseModel: model.BaseModel{ID: 3}, Name: "薪资", Type: model.CategoryTypeIncome},
+	}
+
+	tests := []struct {
+		keyword  string
+		billType model.BillType
+		wantID   uint64
+	}{
+		{"打车", model.BillTypeExpense, 21},
+		{"午饭", model.BillTypeExpense, 11},
+		{"瑞幸", model.BillTypeExpense, 12},
+		{"地铁", model.BillTypeExpense, 2},
+		{"工资", model.BillTypeIncome, 3},
+		{"工资", model.BillTypeExpense, 0},
+		{"随便买点", model.BillTypeExpense, 0},
+	}
+	for _, tt := range tests {
+		category := MatchCategory(tt.keyword, tt.billType, categories)
+		if tt.wantID == 0 {
+			assert.Nil(t, category, tt.keyword)
+			continue
+		}
+		require.NotNil(t, category, tt.keyword)
+		assert.Equal(t, tt.wantID, category.ID, tt.keyword)
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage 创建本地存储
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if root == "" {
+		return nil, errors.New("本地存储根目录不能为空")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, pkgerrors.Wrap(err, "创建本地存储目录失败")
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Save 保存文件
+func (s *LocalStorage) Save(ctx context.Context, key string, data []byte, contentType string) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return pkgerrors.Wrap(err, "创建存储目录失败")
+	}
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return pkgerrors.Wrap(err, "写入文件失败")
+	}
+	return nil
+}
+
+// Open 打开文件
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, pkgerrors.Wrap(err, "打开文件失败")
+	}
+	return f, nil
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return pkgerrors.Wrap(err, "删除文件失败")
+	}
+	return nil
+}
+
+// fullPath 获取文件在本地的完整路径
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"smart-ledger-server/internal/config"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("storage: 对象不存在")
+
+// Storage 文件存储接口
+type Storage interface {
+	// Save 保存文件
+	Save(ctx context.Context, key string, data []byte, contentType string) error
+	// Open 打开文件，调用方负责关闭
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage 根据配置创建存储实现
+func NewStorage(cfg *config.StorageConfig) (Storage, error) {
+	switch cfg.Type {
+	case "", "local":
+		return NewLocalStorage(cfg.Local.Root)
+	case "s3":
+		return NewS3Storage(&cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Type)
+	}
+}
+
+// BillImageKey 生成账单图片存储路径
+// 格式：bills/{userID}/{yyyyMM}/{uuid}{ext}
+func BillImageKey(userID uint64, mimeType string) string {
+	return fmt.Sprintf("bills/%d/%s/%s%s", userID, time.Now().Format("200601"), uuid.New().String(), extByMimeType(mimeType))
+}
+
+// JobImageKey 生成异步识别任务图片存储路径
+// 格式：jobs/{userID}/{jobUUID}/{index}{ext}
+func JobImageKey(userID uint64, jobUUID string, index int, mimeType string) string {
+	return fmt.Sprintf("jobs/%d/%s/%d%s", userID, jobUUID, index, extByMimeType(mimeType))
+}
+
+// ContentTypeByKey 根据存储路径推断文件类型
+func ContentTypeByKey(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".pdf":
+		return "application/pdf"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// extByMimeType 根据文件类型获取扩展名
+func extByMimeType(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "application/pdf":
+		return ".pdf"
+	default:
+		return ""
+	}
+}
+
+// validateKey 校验存储路径，防止路径穿越
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("非法的存储路径: %s", key)
+	}
+	return nil
+}
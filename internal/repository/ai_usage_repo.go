@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"smart-ledger-server/internal/model"
+)
+
+// reservationTTL 预留配额的有效期，超过该时间仍未结束的预留（如进程崩溃）不再计入配额
+const reservationTTL = time.Hour
+
+// AIUsageRepository AI调用用量数据访问层
+type AIUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewAIUsageRepository 创建AI调用用量仓库
+func NewAIUsageRepository(db *gorm.DB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// Create 写入用量记录
+func (r *AIUsageRepository) Create(ctx context.Context, record *model.AIUsageRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+// UsageSummary 用量汇总
+type UsageSummary struct {
+	Calls            int64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// QuotaLimit 单个配额周期的限制
+type QuotaLimit struct {
+	Name  string    // 周期名称，如“今日”
+	Since time.Time // 周期起始时间
+	Limit int       // 周期内最多调用次数
+}
+
+// QuotaExceededError 预留配额时超出限制
+type QuotaExceededError struct {
+	QuotaLimit
+	Used int64 // 周期内已使用（含进行中）的次数
+}
+
+func (e *QuotaExceededError) Error() string {
+	return e.Name + "AI调用次数已达上限"
+}
+
+// Reserve 为 n 次调用预留配额，写入 n 条 reserved 记录
+// 在事务中锁定用户行后统计用量，同一用户的并发预留依次执行，不会超出配额；超出时返回 *QuotaExceededError
+func (r *AIUsageRepository) Reserve(ctx context.Context, userID uint64, n int, limits []QuotaLimit) ([]model.AIUsageRecord, error) {
+	records := make([]model.AIUsageRecord, n)
+	for i := range records {
+		records[i] = model.AIUsageRecord{UserID: userID, Status: model.UsageStatusReserved, Source: model.UsageSourceUser}
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(limits) > 0 {
+			var user model.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", userID).Take(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, limit := range limits {
+			var used int64
+			if err := countedUsage(tx.Model(&model.AIUsageRecord{}), userID, limit.Since).Count(&used).Error; err != nil {
+				return err
+			}
+			if used+int64(n) > int64(limit.Limit) {
+				return &QuotaExceededError{QuotaLimit: limit, Used: used}
+			}
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Finish 保存调用结果（状态、用量、耗时）
+func (r *AIUsageRepository) Finish(ctx context.Context, record *model.AIUsageRecord) error {
+	return r.db.WithContext(ctx).Save(record).Error
+}
+
+// Release 删除未使用的预留记录（如图片读取失败，未实际调用模型）
+func (r *AIUsageRepository) Release(ctx context.Context, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Unscoped().
+		Where("id IN ? AND status = ?", ids, model.UsageStatusReserved).
+		Delete(&model.AIUsageRecord{}).Error
+}
+
+// Summarize 汇总用户自 since 起计入配额的用量：成功且未命中缓存的调用，以及进行中的预留
+func (r *AIUsageRepository) Summarize(ctx context.Context, userID uint64, since time.Time) (*UsageSummary, error) {
+	var summary UsageSummary
+	err := countedUsage(r.db.WithContext(ctx).Model(&model.AIUsageRecord{}), userID, since).
+		Select("COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens").
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// countedUsage 筛选计入配额的用量记录，后台任务的调用、失败的调用和过期的预留不计入
+func countedUsage(db *gorm.DB, userID uint64, since time.Time) *gorm.DB {
+	return db.Where("user_id = ? AND source = ? AND cached = ? AND created_at >= ?", userID, model.UsageSourceUser, false, since).
+		Where("status = ? OR (status = ? AND created_at >= ?)",
+			model.UsageStatusSucceeded, model.UsageStatusReserved, time.Now().Add(-reservationTTL))
+}
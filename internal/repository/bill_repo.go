@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -30,6 +31,9 @@ func (r *BillRepository) GetByID(ctx context.Context, id uint64) (*model.Bill, e
 	var bill model.Bill
 	err := r.db.WithContext(ctx).
 		Preload("Category").
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, id ASC")
+		}).
 		First(&bill, id).Error
 	if err != nil {
 		return nil, err
@@ -49,6 +53,89 @@ func (r *BillRepository) GetByUUID(ctx context.Context, uuid string) (*model.Bil
 	return &bill, nil
 }
 
+// GetByImageHash 根据截图摘要获取用户最早的账单
+func (r *BillRepository) GetByImageHash(ctx context.Context, userID uint64, imageHash string) (*model.Bill, error) {
+	var bill model.Bill
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND image_hash = ?", userID, imageHash).
+		Order("id ASC").
+		First(&bill).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// GetBySourceHash 根据通知原文摘要获取用户最早的账单
+func (r *BillRepository) GetBySourceHash(ctx context.Context, userID uint64, sourceHash string) (*model.Bill, error) {
+	var bill model.Bill
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND source_hash = ?", userID, sourceHash).
+		Order("id ASC").
+		First(&bill).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// duplicateWindow 无订单号时按支付时间判重的时间窗口，账单列表截图通常只显示到分钟
+const duplicateWindow = time.Minute
+
+// FindDuplicate 查找与账单重复的已有账单
+// 有订单号时按订单号和账单类型匹配，否则按金额、账单类型和支付时间（前后一分钟内）匹配
+func (r *BillRepository) FindDuplicate(ctx context.Context, bill *model.Bill) (*model.Bill, error) {
+	return findDuplicate(r.db.WithContext(ctx), bill, nil)
+}
+
+// CreateBatch 在同一事务中创建多条账单，与已有账单重复的不再创建
+// 返回与 bills 一一对应的重复账单ID，未重复时为 0；同一批次内的账单互不判重
+func (r *BillRepository) CreateBatch(ctx context.Context, bills []*model.Bill) ([]uint64, error) {
+	duplicates := make([]uint64, len(bills))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		created := make([]uint64, 0, len(bills))
+		for i, bill := range bills {
+			existing, err := findDuplicate(tx, bill, created)
+			if err == nil {
+				duplicates[i] = existing.ID
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err := tx.Create(bill).Error; err != nil {
+				return err
+			}
+			created = append(created, bill.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// findDuplicate 查找与账单重复的已有账单，跳过 excludeIDs 中的账单
+func findDuplicate(db *gorm.DB, bill *model.Bill, excludeIDs []uint64) (*model.Bill, error) {
+	query := db.Where("user_id = ? AND bill_type = ?", bill.UserID, bill.BillType)
+	if bill.OrderNo != "" {
+		query = query.Where("order_no = ?", bill.OrderNo)
+	} else {
+		query = query.Where("amount = ? AND pay_time BETWEEN ? AND ?",
+			bill.Amount, bill.PayTime.Add(-duplicateWindow), bill.PayTime.Add(duplicateWindow))
+	}
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
+	}
+
+	var existing model.Bill
+	if err := query.Order("id ASC").First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
 // BillQuery 账单查询条件
 type BillQuery struct {
 	UserID     uint64
@@ -59,6 +146,12 @@ type BillQuery struct {
 	Keyword    string
 	Page       int
 	PageSize   int
+
+	IsConfirmed   *bool
+	MinConfidence *float64
+	MaxConfidence *float64
+	// OrderByConfidence 按置信度升序排列（复核队列），否则按支付时间倒序
+	OrderByConfidence bool
 }
 
 // List 查询账单列表
@@ -92,16 +185,35 @@ func (r *BillRepository) List(ctx context.Context, query *BillQuery) ([]model.Bi
 		db = db.Where("merchant LIKE ? OR remark LIKE ?", keyword, keyword)
 	}
 
+	// 确认状态与置信度
+	if query.IsConfirmed != nil {
+		db = db.Where("is_confirmed = ?", *query.IsConfirmed)
+	}
+	if query.MinConfidence != nil {
+		db = db.Where("confidence >= ?", *query.MinConfidence)
+	}
+	if query.MaxConfidence != nil {
+		db = db.Where("confidence <= ?", *query.MaxConfidence)
+	}
+
 	// 统计总数
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
+	order := "pay_time DESC"
+	if query.OrderByConfidence {
+		order = "confidence ASC, pay_time DESC"
+	}
+
 	// 分页查询
 	offset := (query.Page - 1) * query.PageSize
 	err := db.
 		Preload("Category", "user_id = ?", query.UserID).
-		Order("pay_time DESC").
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, id ASC")
+		}).
+		Order(order).
 		Offset(offset).
 		Limit(query.PageSize).
 		Find(&bills).Error
@@ -109,14 +221,73 @@ func (r *BillRepository) List(ctx context.Context, query *BillQuery) ([]model.Bi
 	return bills, total, err
 }
 
-// Update 更新账单
+// Update 更新账单（明细通过 ReplaceItems 单独维护）
 func (r *BillRepository) Update(ctx context.Context, bill *model.Bill) error {
-	return r.db.WithContext(ctx).Save(bill).Error
+	return r.db.WithContext(ctx).Omit("Items").Save(bill).Error
+}
+
+// ReplaceItems 替换账单明细
+func (r *BillRepository) ReplaceItems(ctx context.Context, billID uint64, items []model.BillItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bill_id = ?", billID).Delete(&model.BillItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		for i := range items {
+			items[i].ID = 0
+			items[i].BillID = billID
+		}
+		return tx.Create(&items).Error
+	})
 }
 
-// Delete 删除账单(软删除)
+// Delete 删除账单及其明细(软删除)
 func (r *BillRepository) Delete(ctx context.Context, id uint64) error {
-	return r.db.WithContext(ctx).Delete(&model.Bill{}, id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bill_id = ?", id).Delete(&model.BillItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Bill{}, id).Error
+	})
+}
+
+// ListUnconfirmedByIDs 获取用户指定ID中尚未确认的账单
+func (r *BillRepository) ListUnconfirmedByIDs(ctx context.Context, userID uint64, ids []uint64) ([]model.Bill, error) {
+	var bills []model.Bill
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND id IN ? AND is_confirmed = ?", userID, ids, false).
+		Find(&bills).Error
+	return bills, err
+}
+
+// ConfirmByIDs 批量确认账单，返回实际更新的数量
+func (r *BillRepository) ConfirmByIDs(ctx context.Context, userID uint64, ids []uint64) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Bill{}).
+		Where("user_id = ? AND id IN ? AND is_confirmed = ?", userID, ids, false).
+		Update("is_confirmed", true)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByIDs 批量删除账单及其明细(软删除)
+func (r *BillRepository) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bill_id IN ?", ids).Delete(&model.BillItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Bill{}, ids).Error
+	})
+}
+
+// confirmedScope confirmedOnly 为 true 时仅统计已确认账单
+func confirmedScope(confirmedOnly bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if confirmedOnly {
+			return db.Where("bills.is_confirmed = ?", true)
+		}
+		return db
+	}
 }
 
 // StatsSummary 统计结果
@@ -127,12 +298,12 @@ type StatsSummary struct {
 }
 
 // GetStatsSummary 获取统计摘要
-func (r *BillRepository) GetStatsSummary(ctx context.Context, userID uint64, startDate, endDate time.Time) (*StatsSummary, error) {
+func (r *BillRepository) GetStatsSummary(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) (*StatsSummary, error) {
 	var result StatsSummary
 
 	// 统计支出
 	var expense decimal.Decimal
-	err := r.db.WithContext(ctx).Model(&model.Bill{}).
+	err := r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
 		Select("COALESCE(SUM(amount), 0)").
 		Where("user_id = ? AND bill_type = ? AND pay_time >= ? AND pay_time <= ?",
 			userID, model.BillTypeExpense, startDate, endDate).
@@ -144,7 +315,7 @@ func (r *BillRepository) GetStatsSummary(ctx context.Context, userID uint64, sta
 
 	// 统计收入
 	var income decimal.Decimal
-	err = r.db.WithContext(ctx).Model(&model.Bill{}).
+	err = r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
 		Select("COALESCE(SUM(amount), 0)").
 		Where("user_id = ? AND bill_type = ? AND pay_time >= ? AND pay_time <= ?",
 			userID, model.BillTypeIncome, startDate, endDate).
@@ -155,7 +326,7 @@ func (r *BillRepository) GetStatsSummary(ctx context.Context, userID uint64, sta
 	result.TotalIncome = income
 
 	// 统计数量
-	err = r.db.WithContext(ctx).Model(&model.Bill{}).
+	err = r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
 		Where("user_id = ? AND pay_time >= ? AND pay_time <= ?", userID, startDate, endDate).
 		Count(&result.BillCount).Error
 	if err != nil {
@@ -173,9 +344,9 @@ type CategoryStats struct {
 }
 
 // GetCategoryStats 获取一级分类统计，包含二级分类和一级分类本身的金额
-func (r *BillRepository) GetCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time) ([]CategoryStats, error) {
+func (r *BillRepository) GetCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, confirmedOnly bool) ([]CategoryStats, error) {
 	var stats []CategoryStats
-	err := r.db.WithContext(ctx).Model(&model.Bill{}).
+	err := r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
 		Select(`case
 		when c.parent_id = 0 then c.name
 		else pc.name
@@ -203,9 +374,9 @@ func (r *BillRepository) GetCategoryStats(ctx context.Context, userID uint64, bi
 }
 
 // GetSecondaryCategoryStats 获取二级分类统计
-func (r *BillRepository) GetSecondaryCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, categoryID uint64) ([]CategoryStats, error) {
+func (r *BillRepository) GetSecondaryCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, categoryID uint64, confirmedOnly bool) ([]CategoryStats, error) {
 	var stats []CategoryStats
-	err := r.db.Model(&model.Bill{}).Select("category_id, categories.name as category_name, SUM(bills.amount) as amount").
+	err := r.db.Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).Select("category_id, categories.name as category_name, SUM(bills.amount) as amount").
 		Joins("Left Join categories on categories.id = bills.category_id and categories.user_id = bills.user_id").
 		Where("(bills.user_id = ? AND bills.bill_type = ? AND bills.pay_time >= ? AND bills.pay_time <= ?) AND (categories.parent_id = ? OR categories.id = ?)", userID, billType, startDate, endDate, categoryID, categoryID).
 		Group("category_id").
@@ -237,9 +408,9 @@ func (m *MonthlyStats) GetLabel() string {
 }
 
 // GetDailyStats 获取每日统计
-func (r *BillRepository) GetDailyStats(ctx context.Context, userID uint64, startDate, endDate time.Time) ([]DailyStats, error) {
+func (r *BillRepository) GetDailyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]DailyStats, error) {
 	var stats []DailyStats
-	err := r.db.WithContext(ctx).Model(&model.Bill{}).
+	err := r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
 		Select(`
 			DATE(pay_time) as date,
 			SUM(CASE WHEN bill_type = 1 THEN amount ELSE 0 END) as expense,
@@ -252,9 +423,9 @@ func (r *BillRepository) GetDailyStats(ctx context.Context, userID uint64, start
 	return stats, err
 }
 
-func (r *BillRepository) GetMonthlyStats(ctx context.Context, userID uint64, startDate, endDate time.Time) ([]MonthlyStats, error) {
+func (r *BillRepository) GetMonthlyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]MonthlyStats, error) {
 	var stats []MonthlyStats
-	err := r.db.WithContext(ctx).Model(&model.Bill{}).Select(`
+	err := r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).Select(`
 	DATE_FORMAT(pay_time, "%Y-%m") as month,
 	SUM(CASE WHEN bill_type = 1 THEN amount ELSE 0 END) as expense,
 	SUM(CASE WHEN bill_type = 2 THEN amount ELSE 0 END) as income
@@ -265,3 +436,36 @@ func (r *BillRepository) GetMonthlyStats(ctx context.Context, userID uint64, sta
 		Scan(&stats).Error
 	return stats, err
 }
+
+// MerchantStats 商户统计结果
+type MerchantStats struct {
+	Merchant string
+	Amount   decimal.Decimal
+	Count    int64
+}
+
+// GetMerchantStats 获取商户统计，按金额倒序，忽略商户为空的账单；limit 不大于 0 时不限制数量
+func (r *BillRepository) GetMerchantStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, limit int, confirmedOnly bool) ([]MerchantStats, error) {
+	var stats []MerchantStats
+	query := r.db.WithContext(ctx).Model(&model.Bill{}).Scopes(confirmedScope(confirmedOnly)).
+		Select("merchant, SUM(amount) as amount, COUNT(*) as count").
+		Where("user_id = ? AND bill_type = ? AND pay_time >= ? AND pay_time <= ? AND merchant <> ''", userID, billType, startDate, endDate).
+		Group("merchant").
+		Order("amount DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&stats).Error
+	return stats, err
+}
+
+// ListUserIDsWithBills 获取时间范围内有账单的用户ID
+func (r *BillRepository) ListUserIDsWithBills(ctx context.Context, startDate, endDate time.Time) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Model(&model.Bill{}).
+		Where("pay_time >= ? AND pay_time <= ?", startDate, endDate).
+		Distinct().
+		Order("user_id ASC").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"smart-ledger-server/internal/model"
+)
+
+// CategoryCorrectionRepository 分类修正记录数据访问层
+type CategoryCorrectionRepository struct {
+	db *gorm.DB
+}
+
+// NewCategoryCorrectionRepository 创建分类修正记录仓库
+func NewCategoryCorrectionRepository(db *gorm.DB) *CategoryCorrectionRepository {
+	return &CategoryCorrectionRepository{db: db}
+}
+
+// Record 记录一次修正，已存在相同用户、商户、分类的记录时累加命中次数
+func (r *CategoryCorrectionRepository) Record(ctx context.Context, correction *model.CategoryCorrection) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "merchant"}, {Name: "category_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"hit_count":         gorm.Expr("hit_count + 1"),
+			"platform":          correction.Platform,
+			"bill_type":         correction.BillType,
+			"last_corrected_at": correction.LastCorrectedAt,
+		}),
+	}).Create(correction).Error
+}
+
+// ListByMerchant 获取用户对某个商户的修正记录，按命中次数倒序
+func (r *CategoryCorrectionRepository) ListByMerchant(ctx context.Context, userID uint64, merchant string) ([]model.CategoryCorrection, error) {
+	var corrections []model.CategoryCorrection
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND merchant = ?", userID, merchant).
+		Order("hit_count DESC, last_corrected_at DESC").
+		Find(&corrections).Error
+	return corrections, err
+}
+
+// ListTop 获取用户最常用的修正记录（含分类及其父分类）
+func (r *CategoryCorrectionRepository) ListTop(ctx context.Context, userID uint64, limit int) ([]model.CategoryCorrection, error) {
+	var corrections []model.CategoryCorrection
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Preload("Category", "user_id = ?", userID).
+		Preload("Category.Parent").
+		Order("hit_count DESC, last_corrected_at DESC").
+		Limit(limit).
+		Find(&corrections).Error
+	return corrections, err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+)
+
+// ImportProfileRepository 账单导入配置数据访问层
+type ImportProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewImportProfileRepository 创建账单导入配置仓库
+func NewImportProfileRepository(db *gorm.DB) *ImportProfileRepository {
+	return &ImportProfileRepository{db: db}
+}
+
+// Create 创建导入配置
+func (r *ImportProfileRepository) Create(ctx context.Context, profile *model.ImportProfile) error {
+	return r.db.WithContext(ctx).Create(profile).Error
+}
+
+// GetByID 根据ID获取导入配置
+func (r *ImportProfileRepository) GetByID(ctx context.Context, id uint64) (*model.ImportProfile, error) {
+	var profile model.ImportProfile
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// ListByUser 获取用户的导入配置，按创建时间排列
+func (r *ImportProfileRepository) ListByUser(ctx context.Context, userID uint64) ([]model.ImportProfile, error) {
+	var profiles []model.ImportProfile
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+// ExistsByName 检查用户是否已有同名导入配置
+func (r *ImportProfileRepository) ExistsByName(ctx context.Context, userID uint64, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ImportProfile{}).Where("user_id = ? AND name = ?", userID, name).Count(&count).Error
+	return count > 0, err
+}
+
+// Update 更新导入配置
+func (r *ImportProfileRepository) Update(ctx context.Context, profile *model.ImportProfile) error {
+	return r.db.WithContext(ctx).Save(profile).Error
+}
+
+// Delete 删除导入配置
+func (r *ImportProfileRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&model.ImportProfile{}, id).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"smart-ledger-server/internal/model"
+)
+
+// MonthlyInsightRepository 月度消费洞察数据访问层
+type MonthlyInsightRepository struct {
+	db *gorm.DB
+}
+
+// NewMonthlyInsightRepository 创建月度消费洞察仓库
+func NewMonthlyInsightRepository(db *gorm.DB) *MonthlyInsightRepository {
+	return &MonthlyInsightRepository{db: db}
+}
+
+// Save 保存洞察，同一用户同一月份已存在时覆盖
+func (r *MonthlyInsightRepository) Save(ctx context.Context, insight *model.MonthlyInsight) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "month"}},
+		DoUpdates: clause.AssignmentColumns([]string{"facts", "narrative", "prompt_version", "updated_at"}),
+	}).Create(insight).Error
+}
+
+// GetByUserAndMonth 获取用户某月的洞察
+func (r *MonthlyInsightRepository) GetByUserAndMonth(ctx context.Context, userID uint64, month string) (*model.MonthlyInsight, error) {
+	var insight model.MonthlyInsight
+	err := r.db.WithContext(ctx).Where("user_id = ? AND month = ?", userID, month).First(&insight).Error
+	if err != nil {
+		return nil, err
+	}
+	return &insight, nil
+}
+
+// ListUserIDsByMonth 获取某月已生成洞察的用户ID
+func (r *MonthlyInsightRepository) ListUserIDsByMonth(ctx context.Context, month string) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Model(&model.MonthlyInsight{}).Where("month = ?", month).Pluck("user_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+)
+
+// RecognitionJobRepository 异步识别任务数据访问层
+type RecognitionJobRepository struct {
+	db *gorm.DB
+}
+
+// NewRecognitionJobRepository 创建异步识别任务仓库
+func NewRecognitionJobRepository(db *gorm.DB) *RecognitionJobRepository {
+	return &RecognitionJobRepository{db: db}
+}
+
+// Create 创建任务及其图片
+func (r *RecognitionJobRepository) Create(ctx context.Context, job *model.RecognitionJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// GetByID 根据ID获取任务（含图片）
+func (r *RecognitionJobRepository) GetByID(ctx context.Context, id uint64) (*model.RecognitionJob, error) {
+	var job model.RecognitionJob
+	err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("item_index ASC")
+		}).
+		First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetByUUID 根据UUID获取任务（含图片）
+func (r *RecognitionJobRepository) GetByUUID(ctx context.Context, uuid string) (*model.RecognitionJob, error) {
+	var job model.RecognitionJob
+	err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("item_index ASC")
+		}).
+		Where("uuid = ?", uuid).
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListIDsByStatus 获取指定状态的任务ID，按创建顺序排列
+func (r *RecognitionJobRepository) ListIDsByStatus(ctx context.Context, statuses ...model.JobStatus) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Model(&model.RecognitionJob{}).
+		Where("status IN ?", statuses).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Claim 抢占任务，返回是否抢占成功
+// 可抢占等待中的任务，以及心跳早于 staleBefore 的处理中任务（处理实例已退出或失联）
+func (r *RecognitionJobRepository) Claim(ctx context.Context, id uint64, owner string, now, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RecognitionJob{}).
+		Where("id = ?", id).
+		Where(r.db.Where("status = ?", model.JobStatusPending).
+			Or("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", model.JobStatusRunning, staleBefore)).
+		Updates(map[string]interface{}{
+			"status":       model.JobStatusRunning,
+			"claimed_by":   owner,
+			"heartbeat_at": now,
+			"started_at":   gorm.Expr("COALESCE(started_at, ?)", now),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Heartbeat 续期任务租约，返回任务是否仍由 owner 处理
+func (r *RecognitionJobRepository) Heartbeat(ctx context.Context, id uint64, owner string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RecognitionJob{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, model.JobStatusRunning, owner).
+		Update("heartbeat_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Update 更新任务（不含图片和租约，租约只通过 Claim/Heartbeat 修改）
+func (r *RecognitionJobRepository) Update(ctx context.Context, job *model.RecognitionJob) error {
+	return r.db.WithContext(ctx).Omit("Items", "ClaimedBy", "HeartbeatAt").Save(job).Error
+}
+
+// UpdateItem 更新任务图片
+func (r *RecognitionJobRepository) UpdateItem(ctx context.Context, item *model.RecognitionJobItem) error {
+	return r.db.WithContext(ctx).Save(item).Error
+}
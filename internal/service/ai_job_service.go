@@ -0,0 +1,554 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/storage"
+	"smart-ledger-server/pkg/errcode"
+)
+
+const (
+	// jobPollInterval 调度器轮询等待中任务的间隔，提交任务时会立即唤醒
+	jobPollInterval = 30 * time.Second
+	// jobHeartbeatInterval 处理中任务的租约续期间隔
+	jobHeartbeatInterval = 30 * time.Second
+	// jobLeaseTimeout 任务心跳超时时间，超时后视为处理实例已退出，其他实例可接管
+	jobLeaseTimeout = 2 * time.Minute
+)
+
+// AIJobService 异步识别任务服务
+// 上传的图片先写入存储并持久化任务，由后台调度器基于 WorkerPool 逐个处理
+// 处理中的任务由实例定期续期租约，实例退出后任务在租约超时后由任一实例接管继续处理
+type AIJobService struct {
+	aiService  *AIService
+	jobRepo    RecognitionJobRepo
+	storage    storage.Storage
+	broker     *jobBroker
+	wakeup     chan struct{}
+	instanceID string
+}
+
+// NewAIJobService 创建异步识别任务服务
+func NewAIJobService(aiService *AIService, jobRepo RecognitionJobRepo, store storage.Storage) *AIJobService {
+	return &AIJobService{
+		aiService:  aiService,
+		jobRepo:    jobRepo,
+		storage:    store,
+		broker:     newJobBroker(),
+		wakeup:     make(chan struct{}, 1),
+		instanceID: newInstanceID(),
+	}
+}
+
+// newInstanceID 生成调度器实例标识，用于任务租约
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	if len(host) > 27 {
+		host = host[:27]
+	}
+	return host + "-" + uuid.New().String()
+}
+
+// Start 启动后台调度器，ctx 取消后停止
+// 中断的任务（含本实例上次退出时未完成的）在租约超时后被接管
+func (s *AIJobService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(jobPollInterval)
+		defer ticker.Stop()
+		for {
+			s.processPending(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wakeup:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Submit 提交异步识别任务，图片写入存储后立即返回任务ID
+// save 为 true 时识别成功后自动创建账单
+func (s *AIJobService) Submit(ctx context.Context, userID uint64, files []*multipart.FileHeader, save bool) (*dto.AIJobResponse, error) {
+	if len(files) == 0 {
+		return nil, errcode.ErrParams.WithMessage("请上传图片")
+	}
+	if len(files) > s.aiService.batchConfig.MaxImages {
+		return nil, errcode.ErrTooManyImages.WithMessage(fmt.Sprintf("单次最多上传%d张图片", s.aiService.batchConfig.MaxImages))
+	}
+	if err := s.aiService.checkQuota(ctx, userID, len(files)); err != nil {
+		return nil, err
+	}
+
+	job := &model.RecognitionJob{
+		UUID:   uuid.New().String(),
+		UserID: userID,
+		Status: model.JobStatusPending,
+		Save:   save,
+		Total:  len(files),
+		Items:  make([]model.RecognitionJobItem, len(files)),
+	}
+
+	var savedPaths []string
+	cleanup := func() {
+		for _, path := range savedPaths {
+			_ = s.storage.Delete(context.Background(), path)
+		}
+	}
+
+	for i, file := range files {
+		item := &job.Items[i]
+		item.Index = i
+		item.FileName = file.Filename
+		item.Status = model.JobItemStatusPending
+
+		// 校验失败的图片直接标记失败，不影响其他图片
+		imageData, mimeType, err := s.aiService.readImage(ctx, file)
+		if err != nil {
+			item.Status = model.JobItemStatusFailed
+			item.Error = errorMessage(err)
+			job.Failed++
+			continue
+		}
+
+		item.MimeType = mimeType
+		item.ImagePath = storage.JobImageKey(userID, job.UUID, i, mimeType)
+		if err := s.storage.Save(ctx, item.ImagePath, imageData, mimeType); err != nil {
+			logger.Log.Error("保存识别任务图片失败", zap.String("job_id", job.UUID), zap.Error(err))
+			cleanup()
+			return nil, errcode.ErrServer
+		}
+		savedPaths = append(savedPaths, item.ImagePath)
+	}
+
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		logger.Log.Error("创建识别任务失败", zap.Uint64("user_id", userID), zap.Error(err))
+		cleanup()
+		return nil, errcode.ErrServer
+	}
+
+	// 唤醒调度器
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+
+	return toAIJobResponse(job, true), nil
+}
+
+// Get 获取任务状态及各图片结果
+func (s *AIJobService) Get(ctx context.Context, userID uint64, jobID string) (*dto.AIJobResponse, error) {
+	job, err := s.getJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return toAIJobResponse(job, true), nil
+}
+
+// Subscribe 订阅任务进度，返回当前快照和后续事件
+// 调用方处理完毕后必须调用 cancel 取消订阅
+func (s *AIJobService) Subscribe(ctx context.Context, userID uint64, jobID string) (*dto.AIJobResponse, <-chan dto.AIJobEvent, func(), error) {
+	job, err := s.getJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 先订阅再读取快照，避免丢失两者之间产生的事件
+	events, cancel := s.broker.subscribe(job.UUID, job.Total+1)
+	job, err = s.getJob(ctx, userID, jobID)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return toAIJobResponse(job, true), events, cancel, nil
+}
+
+// Poll 重新读取任务，返回订阅者尚未收到的图片进度和结束事件
+// 进度事件只在处理任务的实例内广播，任务由其他实例处理时订阅方需定期轮询
+// seen 记录订阅者已知的各图片状态，调用后更新为最新状态
+func (s *AIJobService) Poll(ctx context.Context, userID uint64, jobID string, seen map[int]string) ([]dto.AIJobEvent, error) {
+	job, err := s.getJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []dto.AIJobEvent
+	for i := range job.Items {
+		item := &job.Items[i]
+		if item.Status == model.JobItemStatusPending || seen[item.Index] == string(item.Status) {
+			continue
+		}
+		seen[item.Index] = string(item.Status)
+		events = append(events, dto.AIJobEvent{Type: "item", Item: toAIJobItemResponse(item), Job: toAIJobResponse(job, false)})
+	}
+	if job.IsFinished() {
+		events = append(events, dto.AIJobEvent{Type: "done", Job: toAIJobResponse(job, false)})
+	}
+	return events, nil
+}
+
+// getJob 获取任务并校验归属
+func (s *AIJobService) getJob(ctx context.Context, userID uint64, jobID string) (*model.RecognitionJob, error) {
+	job, err := s.jobRepo.GetByUUID(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.ErrJobNotFound
+		}
+		return nil, errcode.ErrServer
+	}
+	if job.UserID != userID {
+		return nil, errcode.ErrJobNotFound
+	}
+	return job, nil
+}
+
+// processPending 按提交顺序处理等待中的任务和租约超时的处理中任务
+// 其他实例正在处理的任务抢占失败，直接跳过
+func (s *AIJobService) processPending(ctx context.Context) {
+	ids, err := s.jobRepo.ListIDsByStatus(ctx, model.JobStatusPending, model.JobStatusRunning)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Log.Error("查询待处理识别任务失败", zap.Error(err))
+		}
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		claimed, err := s.jobRepo.Claim(ctx, id, s.instanceID, now, now.Add(-jobLeaseTimeout))
+		if err != nil || !claimed {
+			continue
+		}
+		s.process(ctx, id)
+	}
+}
+
+// process 处理已抢占的任务，只处理仍在等待中的图片
+// ctx 取消或租约被接管时保留未完成图片的状态，由持有租约的实例继续处理
+func (s *AIJobService) process(ctx context.Context, id uint64) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.keepAlive(ctx, cancel, id)
+
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Log.Error("读取识别任务失败", zap.Uint64("job_id", id), zap.Error(err))
+		return
+	}
+	if job.IsFinished() {
+		return
+	}
+
+	prompt, err := s.aiService.renderPrompt(ctx, job.UserID, ai.PromptRecognition, time.Now())
+	if err != nil {
+		// 提示词模板有误时重试也无法恢复，直接结束任务
+		now := time.Now()
+		job.Status = model.JobStatusFailed
+		job.Error = "生成识别提示词失败"
+		job.FinishedAt = &now
+		if err := s.jobRepo.Update(ctx, job); err != nil {
+			logger.Log.Error("更新识别任务失败", zap.String("job_id", job.UUID), zap.Error(err))
+			return
+		}
+		s.broker.publish(job.UUID, dto.AIJobEvent{Type: "done", Job: toAIJobResponse(job, false)})
+		return
+	}
+
+	items := make(map[int]*model.RecognitionJobItem, len(job.Items))
+	images := make(map[int][]byte, len(job.Items))
+	var tasks []ai.Task
+	for i := range job.Items {
+		item := &job.Items[i]
+		if item.Status != model.JobItemStatusPending {
+			continue
+		}
+		items[item.Index] = item
+
+		imageData, err := s.readJobImage(ctx, item.ImagePath)
+		if err != nil {
+			logger.Log.Warn("读取识别任务图片失败", zap.String("job_id", job.UUID), zap.Int("index", item.Index), zap.Error(err))
+			s.finishItem(ctx, job, item, ai.TaskResult{Index: item.Index, Error: "读取图片失败"}, nil, nil)
+			continue
+		}
+		images[item.Index] = imageData
+		tasks = append(tasks, ai.Task{
+			Index:      item.Index,
+			Data:       imageData,
+			MimeType:   item.MimeType,
+			FileName:   item.FileName,
+			Prompt:     prompt.Text,
+			CacheScope: prompt.CacheScope,
+		})
+	}
+
+	// 调用模型前预留配额，配额不足时剩余图片直接标记失败
+	reserved, err := s.aiService.reserveQuota(ctx, job.UserID, len(tasks))
+	if err != nil {
+		message := errorMessage(err)
+		for _, task := range tasks {
+			s.finishItem(ctx, job, items[task.Index], ai.TaskResult{Index: task.Index, Error: message}, nil, nil)
+		}
+		tasks = nil
+	}
+	reservations := make(map[int]*model.AIUsageRecord, len(tasks))
+	for i, task := range tasks {
+		reservations[task.Index] = &reserved[i]
+	}
+
+	s.aiService.workerPool.ExecuteWithProgress(ctx, tasks, func(result ai.TaskResult) {
+		if result.Data != nil {
+			result.Data.PromptVersion = prompt.Version
+		}
+		reservation := reservations[result.Index]
+		if result.Called && ctx.Err() == nil {
+			delete(reservations, result.Index)
+		}
+		s.finishItem(ctx, job, items[result.Index], result, images[result.Index], reservation)
+	})
+
+	// 未调用模型（服务关闭、限流等待超时）的预留立即释放，重启后重新预留
+	var unused []model.AIUsageRecord
+	for _, reservation := range reservations {
+		unused = append(unused, *reservation)
+	}
+	s.aiService.releaseUsage(context.Background(), unused)
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.JobStatusCompleted
+	if job.Failed == job.Total {
+		job.Status = model.JobStatusFailed
+		job.Error = "全部图片识别失败"
+	}
+	job.FinishedAt = &now
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		logger.Log.Error("更新识别任务失败", zap.String("job_id", job.UUID), zap.Error(err))
+		return
+	}
+	s.broker.publish(job.UUID, dto.AIJobEvent{Type: "done", Job: toAIJobResponse(job, false)})
+
+	// 任务结束后清理待识别图片，账单保存的是单独的副本
+	for _, item := range job.Items {
+		if item.ImagePath == "" {
+			continue
+		}
+		if err := s.storage.Delete(context.Background(), item.ImagePath); err != nil {
+			logger.Log.Warn("清理识别任务图片失败", zap.String("image_path", item.ImagePath), zap.Error(err))
+		}
+	}
+}
+
+// keepAlive 定期续期任务租约，租约已被其他实例接管时取消本实例的处理
+func (s *AIJobService) keepAlive(ctx context.Context, cancel context.CancelFunc, id uint64) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		owned, err := s.jobRepo.Heartbeat(ctx, id, s.instanceID, time.Now())
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Log.Warn("续期识别任务租约失败", zap.Uint64("job_id", id), zap.Error(err))
+			}
+			continue
+		}
+		if !owned {
+			logger.Log.Warn("识别任务已被其他实例接管", zap.Uint64("job_id", id))
+			cancel()
+			return
+		}
+	}
+}
+
+// finishItem 保存单张图片的处理结果并推送进度，reservation 为调用模型前预留的用量记录
+func (s *AIJobService) finishItem(ctx context.Context, job *model.RecognitionJob, item *model.RecognitionJobItem, result ai.TaskResult, imageData []byte, reservation *model.AIUsageRecord) {
+	// 服务关闭导致的失败不落库，保持等待状态以便重启后继续
+	if ctx.Err() != nil {
+		return
+	}
+
+	if result.Called && reservation != nil {
+		s.aiService.finishTaskUsage(ctx, reservation, result)
+	}
+
+	item.Duration = result.Duration
+	item.Status = model.JobItemStatusFailed
+	item.Error = result.Error
+	if result.Success {
+		item.Status = model.JobItemStatusSucceeded
+		item.Error = ""
+		if err := s.saveItemResult(ctx, job, item, result.Data, imageData); err != nil {
+			item.Status = model.JobItemStatusFailed
+			item.Error = errorMessage(err)
+		}
+	}
+
+	if item.Status == model.JobItemStatusSucceeded {
+		job.Succeeded++
+	} else {
+		job.Failed++
+	}
+
+	if err := s.jobRepo.UpdateItem(ctx, item); err != nil {
+		logger.Log.Error("更新识别任务图片失败", zap.String("job_id", job.UUID), zap.Int("index", item.Index), zap.Error(err))
+	}
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		logger.Log.Error("更新识别任务失败", zap.String("job_id", job.UUID), zap.Error(err))
+	}
+
+	s.broker.publish(job.UUID, dto.AIJobEvent{
+		Type: "item",
+		Item: toAIJobItemResponse(item),
+		Job:  toAIJobResponse(job, false),
+	})
+}
+
+// saveItemResult 保存识别结果，需要时创建账单；重复上传的截图关联到已有账单
+func (s *AIJobService) saveItemResult(ctx context.Context, job *model.RecognitionJob, item *model.RecognitionJobItem, data *dto.AIRecognizeResponse, imageData []byte) error {
+	if duplicate := s.aiService.findDuplicate(ctx, job.UserID, imageData); duplicate != nil {
+		data.DuplicateBillID = &duplicate.ID
+		item.BillID = &duplicate.ID
+	} else if job.Save {
+		bill, err := s.aiService.createBillWithImage(ctx, job.UserID, data, imageData, item.MimeType)
+		if err != nil {
+			return err
+		}
+		item.BillID = &bill.ID
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	item.Result = string(raw)
+	return nil
+}
+
+// readJobImage 从存储读取任务图片
+func (s *AIJobService) readJobImage(ctx context.Context, path string) ([]byte, error) {
+	reader, err := s.storage.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// errorMessage 获取面向用户的错误信息
+func errorMessage(err error) string {
+	if e, ok := err.(*errcode.ErrCode); ok {
+		return e.Message
+	}
+	return err.Error()
+}
+
+// toAIJobResponse 转换为任务响应，withItems 为 false 时不包含图片明细
+func toAIJobResponse(job *model.RecognitionJob, withItems bool) *dto.AIJobResponse {
+	resp := &dto.AIJobResponse{
+		ID:         job.UUID,
+		Status:     string(job.Status),
+		Finished:   job.IsFinished(),
+		Save:       job.Save,
+		Total:      job.Total,
+		Succeeded:  job.Succeeded,
+		Failed:     job.Failed,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if withItems {
+		resp.Items = make([]dto.AIJobItemResponse, len(job.Items))
+		for i := range job.Items {
+			resp.Items[i] = *toAIJobItemResponse(&job.Items[i])
+		}
+	}
+	return resp
+}
+
+// toAIJobItemResponse 转换为任务图片响应
+func toAIJobItemResponse(item *model.RecognitionJobItem) *dto.AIJobItemResponse {
+	resp := &dto.AIJobItemResponse{
+		Index:    item.Index,
+		FileName: item.FileName,
+		Status:   string(item.Status),
+		BillID:   item.BillID,
+		Error:    item.Error,
+		Duration: item.Duration,
+	}
+	if item.Result != "" {
+		var data dto.AIRecognizeResponse
+		if err := json.Unmarshal([]byte(item.Result), &data); err == nil {
+			resp.Data = &data
+		}
+	}
+	return resp
+}
+
+// jobBroker 进程内的任务进度广播
+type jobBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan dto.AIJobEvent]struct{}
+}
+
+// newJobBroker 创建任务进度广播
+func newJobBroker() *jobBroker {
+	return &jobBroker{subs: make(map[string]map[chan dto.AIJobEvent]struct{})}
+}
+
+// subscribe 订阅任务事件，buffer 应不小于任务剩余事件数，避免慢订阅者丢事件
+func (b *jobBroker) subscribe(jobID string, buffer int) (<-chan dto.AIJobEvent, func()) {
+	ch := make(chan dto.AIJobEvent, buffer)
+
+	b.mu.Lock()
+	if b.subs[jobID] == nil {
+		b.subs[jobID] = make(map[chan dto.AIJobEvent]struct{})
+	}
+	b.subs[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[jobID], ch)
+		if len(b.subs[jobID]) == 0 {
+			delete(b.subs, jobID)
+		}
+	}
+}
+
+// publish 广播任务事件，订阅者缓冲区已满时丢弃
+func (b *jobBroker) publish(jobID string, event dto.AIJobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[jobID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
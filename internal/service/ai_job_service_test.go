@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/internal/pkg/storage"
+)
+
+// newTestAIJobService 基于测试AI服务创建异步识别任务服务
+func newTestAIJobService(t *testing.T) (*AIJobService, *testAIService, *fakeJobRepo) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	jobs := newFakeJobRepo()
+	return NewAIJobService(s.AIService, jobs, s.storage), s, jobs
+}
+
+// drainEvents 读取订阅通道中已推送的全部事件
+func drainEvents(events <-chan dto.AIJobEvent) []dto.AIJobEvent {
+	var result []dto.AIJobEvent
+	for {
+		select {
+		case event := <-events:
+			result = append(result, event)
+		default:
+			return result
+		}
+	}
+}
+
+func TestAIJobService_Submit(t *testing.T) {
+	jobService, s, jobs := newTestAIJobService(t)
+	ctx := context.Background()
+
+	resp, err := jobService.Submit(ctx, testUserID, uploadFiles(t, testPNG(t, 4), []byte("not an image")), true)
+	require.NoError(t, err)
+	assert.Equal(t, string(model.JobStatusPending), resp.Status)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, 1, resp.Failed)
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, string(model.JobItemStatusPending), resp.Items[0].Status)
+	assert.Equal(t, "图片格式无效", resp.Items[1].Error)
+
+	// 提交时只保存图片，不调用AI，并唤醒调度器
+	assert.Empty(t, s.client.Calls())
+	assert.Len(t, jobService.wakeup, 1)
+	job, err := jobs.GetByUUID(ctx, resp.ID)
+	require.NoError(t, err)
+	_, err = s.storage.Open(ctx, job.Items[0].ImagePath)
+	assert.NoError(t, err)
+	assert.Empty(t, job.Items[1].ImagePath)
+
+	// 其他用户无法查看任务
+	_, err = jobService.Get(ctx, testUserID+1, resp.ID)
+	require.Error(t, err)
+}
+
+func TestAIJobService_Process(t *testing.T) {
+	jobService, s, jobs := newTestAIJobService(t)
+	ctx := context.Background()
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()}, ai.FakeReply{Result: coffeeResult()})
+
+	resp, err := jobService.Submit(ctx, testUserID, uploadFiles(t, testPNG(t, 4), testPNG(t, 5)), true)
+	require.NoError(t, err)
+	jobService.processPending(ctx)
+
+	result, err := jobService.Get(ctx, testUserID, resp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(model.JobStatusCompleted), result.Status)
+	assert.True(t, result.Finished)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.NotNil(t, result.StartedAt)
+	assert.NotNil(t, result.FinishedAt)
+	for _, item := range result.Items {
+		require.NotNil(t, item.BillID)
+		require.NotNil(t, item.Data)
+		assert.Equal(t, "瑞幸咖啡", item.Data.Merchant)
+	}
+	assert.Len(t, s.client.Calls(), 2)
+	require.Len(t, s.usage.records, 2)
+	assert.Equal(t, model.UsageStatusSucceeded, s.usage.records[0].Status)
+
+	// 任务结束后清理待识别图片，处理者为当前实例
+	job, err := jobs.GetByUUID(ctx, resp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jobService.instanceID, job.ClaimedBy)
+	for _, item := range job.Items {
+		_, err := s.storage.Open(ctx, item.ImagePath)
+		assert.Error(t, err)
+	}
+
+	// 已结束的任务不再处理
+	jobService.processPending(ctx)
+	assert.Len(t, s.client.Calls(), 2)
+}
+
+func TestAIJobService_ResumeAfterRestart(t *testing.T) {
+	jobService, s, jobs := newTestAIJobService(t)
+	ctx := context.Background()
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()})
+
+	// 中断的任务：第一张已完成，第二张仍在等待
+	newRunningJob := func(uuid, owner string, heartbeat time.Time) *model.RecognitionJob {
+		imagePath := storage.JobImageKey(testUserID, uuid, 1, "image/png")
+		require.NoError(t, s.storage.Save(ctx, imagePath, testPNG(t, 4), "image/png"))
+		job := &model.RecognitionJob{
+			UUID:        uuid,
+			UserID:      testUserID,
+			Status:      model.JobStatusRunning,
+			Total:       2,
+			Succeeded:   1,
+			ClaimedBy:   owner,
+			HeartbeatAt: &heartbeat,
+			Items: []model.RecognitionJobItem{
+				{Index: 0, Status: model.JobItemStatusSucceeded},
+				{Index: 1, Status: model.JobItemStatusPending, ImagePath: imagePath, MimeType: "image/png"},
+			},
+		}
+		require.NoError(t, jobs.Create(ctx, job))
+		return job
+	}
+	stale := newRunningJob("stale-job", "exited-instance", time.Now().Add(-jobLeaseTimeout-time.Minute))
+	active := newRunningJob("active-job", "other-instance", time.Now())
+
+	jobService.processPending(ctx)
+
+	// 心跳超时的任务被接管，只处理剩余图片
+	job, err := jobs.GetByID(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobStatusCompleted, job.Status)
+	assert.Equal(t, jobService.instanceID, job.ClaimedBy)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Equal(t, model.JobItemStatusSucceeded, job.Items[1].Status)
+	assert.Len(t, s.client.Calls(), 1)
+
+	// 其他实例仍在处理的任务不重复处理
+	job, err = jobs.GetByID(ctx, active.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobStatusRunning, job.Status)
+	assert.Equal(t, "other-instance", job.ClaimedBy)
+	assert.Equal(t, model.JobItemStatusPending, job.Items[1].Status)
+}
+
+func TestAIJobService_Subscribe(t *testing.T) {
+	jobService, s, jobs := newTestAIJobService(t)
+	ctx := context.Background()
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()}, ai.FakeReply{Result: coffeeResult()})
+
+	resp, err := jobService.Submit(ctx, testUserID, uploadFiles(t, testPNG(t, 4), testPNG(t, 5)), false)
+	require.NoError(t, err)
+	other, err := jobService.Submit(ctx, testUserID, uploadFiles(t, testPNG(t, 6)), false)
+	require.NoError(t, err)
+
+	_, _, _, err = jobService.Subscribe(ctx, testUserID+1, resp.ID)
+	require.Error(t, err)
+
+	snapshot, first, cancelFirst, err := jobService.Subscribe(ctx, testUserID, resp.ID)
+	require.NoError(t, err)
+	defer cancelFirst()
+	assert.Equal(t, string(model.JobStatusPending), snapshot.Status)
+	_, second, cancelSecond, err := jobService.Subscribe(ctx, testUserID, resp.ID)
+	require.NoError(t, err)
+	defer cancelSecond()
+	_, cancelled, cancel, err := jobService.Subscribe(ctx, testUserID, resp.ID)
+	require.NoError(t, err)
+	cancel()
+
+	_, otherEvents, cancelOther, err := jobService.Subscribe(ctx, testUserID, other.ID)
+	require.NoError(t, err)
+	defer cancelOther()
+
+	// 另一个任务由其他实例处理中，本实例只处理第一个任务
+	otherJob, err := jobs.GetByUUID(ctx, other.ID)
+	require.NoError(t, err)
+	claimed, err := jobs.Claim(ctx, otherJob.ID, "other-instance", time.Now(), time.Now())
+	require.NoError(t, err)
+	require.True(t, claimed)
+	jobService.processPending(ctx)
+
+	// 每个订阅者都收到每张图片的进度和结束事件
+	for _, events := range []<-chan dto.AIJobEvent{first, second} {
+		received := drainEvents(events)
+		require.Len(t, received, 3)
+		assert.Equal(t, "item", received[0].Type)
+		assert.Equal(t, "item", received[1].Type)
+		assert.Equal(t, 2, received[1].Job.Succeeded)
+		assert.Equal(t, "done", received[2].Type)
+		assert.True(t, received[2].Job.Finished)
+	}
+	assert.Empty(t, drainEvents(cancelled))
+	assert.Empty(t, drainEvents(otherEvents))
+}
+
+func TestAIJobService_Poll(t *testing.T) {
+	jobService, s, jobs := newTestAIJobService(t)
+	ctx := context.Background()
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()}, ai.FakeReply{Result: coffeeResult()})
+
+	resp, err := jobService.Submit(ctx, testUserID, uploadFiles(t, testPNG(t, 4), testPNG(t, 5), []byte("not an image")), false)
+	require.NoError(t, err)
+	snapshot, events, cancel, err := jobService.Subscribe(ctx, testUserID, resp.ID)
+	require.NoError(t, err)
+	defer cancel()
+
+	seen := make(map[int]string)
+	for _, item := range snapshot.Items {
+		seen[item.Index] = item.Status
+	}
+	polled, err := jobService.Poll(ctx, testUserID, resp.ID, seen)
+	require.NoError(t, err)
+	assert.Empty(t, polled)
+	_, err = jobService.Poll(ctx, testUserID+1, resp.ID, seen)
+	require.Error(t, err)
+
+	// 任务由其他实例处理，进度不会广播到本实例的订阅者
+	other := NewAIJobService(s.AIService, jobs, s.storage)
+	other.processPending(ctx)
+	assert.Empty(t, drainEvents(events))
+
+	// 轮询补发提交时已失败图片之外的进度和结束事件
+	polled, err = jobService.Poll(ctx, testUserID, resp.ID, seen)
+	require.NoError(t, err)
+	require.Len(t, polled, 3)
+	assert.Equal(t, "item", polled[0].Type)
+	assert.Equal(t, 0, polled[0].Item.Index)
+	assert.Equal(t, string(model.JobItemStatusSucceeded), polled[0].Item.Status)
+	assert.Equal(t, 1, polled[1].Item.Index)
+	assert.Equal(t, string(model.JobItemStatusSucceeded), polled[1].Item.Status)
+	assert.Equal(t, "done", polled[2].Type)
+	assert.True(t, polled[2].Job.Finished)
+
+	// 已推送的图片不重复推送
+	polled, err = jobService.Poll(ctx, testUserID, resp.ID, seen)
+	require.NoError(t, err)
+	require.Len(t, polled, 1)
+	assert.Equal(t, "done", polled[0].Type)
+}
@@ -2,31 +2,72 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"time"
 
+	"go.uber.org/zap"
+
 	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
 	"smart-ledger-server/internal/model/dto"
 	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/internal/pkg/invoice"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/pdf"
+	"smart-ledger-server/internal/pkg/quickentry"
+	"smart-ledger-server/internal/pkg/storage"
+	"smart-ledger-server/internal/repository"
 	"smart-ledger-server/pkg/errcode"
 )
 
+// correctionHintLimit 提示词中最多携带的用户修正示例数
+const correctionHintLimit = 20
+
+const (
+	// invoiceConfidence 本地解析电子发票且匹配到分类时的置信度
+	invoiceConfidence = 0.9
+	// invoiceConfidenceUncertain 本地解析电子发票但未匹配到分类时的置信度
+	invoiceConfidenceUncertain = 0.6
+	// invoiceRawContentLimit 随账单保存的发票文本最大长度
+	invoiceRawContentLimit = 4000
+)
+
 // AIService AI识别服务
 type AIService struct {
 	client          ai.Client
 	billService     BillServiceInterface
 	categoryService CategoryServiceInterface
+	userRepo        UserRepo
 	maxImageSize    int64
 	workerPool      *ai.WorkerPool
 	batchConfig     *config.BatchConfig
+	storage         storage.Storage
+	usageRepo       AIUsageRepo
+	quota           config.QuotaConfig
+	provider        string
+	pdfRenderer     pdf.Renderer // 未配置渲染命令时为 nil
+	preprocessor    *ai.Preprocessor
+	prompts         *ai.PromptStore
 }
 
 // NewAIService 创建AI服务
-func NewAIService(cfg *config.AIConfig, billService BillServiceInterface, categoryService CategoryServiceInterface) (*AIService, error) {
+// cache 不为空时在客户端前增加识别结果缓存
+func NewAIService(cfg *config.AIConfig, billService BillServiceInterface, categoryService CategoryServiceInterface, userRepo UserRepo, usageRepo AIUsageRepo, store storage.Storage, cache ai.Cache) (*AIService, error) {
 	client, err := ai.NewClient(cfg)
 	if err != nil {
 		return nil, err
 	}
+	prompts, err := ai.NewPromptStore(cfg.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		client = ai.NewCachedClient(client, cache, cfg.Cache.TTL)
+	}
 
 	// 创建RPM限流器
 	limiter := ai.NewRPMLimiter(cfg.Batch.RPM)
@@ -41,77 +82,861 @@ func NewAIService(cfg *config.AIConfig, billService BillServiceInterface, catego
 		cfg.MaxImageSize,
 	)
 
+	// 未配置渲染命令时保持接口为 nil
+	var renderer pdf.Renderer
+	if r := pdf.NewCommandRenderer(cfg.PDF.RenderCommand); r != nil {
+		renderer = r
+	}
+
 	return &AIService{
 		client:          client,
 		billService:     billService,
 		categoryService: categoryService,
+		userRepo:        userRepo,
 		maxImageSize:    cfg.MaxImageSize,
 		workerPool:      workerPool,
 		batchConfig:     &cfg.Batch,
+		storage:         store,
+		usageRepo:       usageRepo,
+		quota:           cfg.Quota,
+		provider:        cfg.Provider,
+		pdfRenderer:     renderer,
+		preprocessor:    ai.NewPreprocessor(cfg.Preprocess),
+		prompts:         prompts,
 	}, nil
 }
 
-// RecognizeImage 识别图片
+// RecognizeImage 识别图片或PDF电子发票/回单
 func (s *AIService) RecognizeImage(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.AIRecognizeResponse, error) {
-	// 检查文件大小
-	if file.Size > s.maxImageSize {
-		return nil, errcode.ErrImageTooLarge
+	imageData, mimeType, err := s.readUpload(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.recognizeFile(ctx, userID, imageData, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 标记重复上传
+	if duplicate := s.findDuplicate(ctx, userID, imageData); duplicate != nil {
+		result.DuplicateBillID = &duplicate.ID
+	}
+	return result, nil
+}
+
+// RecognizeAndCreateBill 识别图片或PDF电子发票/回单并创建账单，PDF 原文件作为账单附件保存
+func (s *AIService) RecognizeAndCreateBill(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.BillResponse, error) {
+	imageData, mimeType, err := s.readUpload(ctx, file)
+	if err != nil {
+		return nil, err
 	}
 
-	// 检查文件类型
-	contentType := file.Header.Get("Content-Type")
-	if !isValidImageType(contentType) {
-		return nil, errcode.ErrImageFormatInvalid
+	// 同一截图已生成过账单时直接返回已有账单，不再重复识别和创建
+	if duplicate := s.findDuplicate(ctx, userID, imageData); duplicate != nil {
+		return duplicate, nil
 	}
 
-	// 读取图片数据
-	imageData, mimeType, err := ai.ReadImageFromFile(file)
+	// 识别图片
+	aiResult, err := s.recognizeFile(ctx, userID, imageData, mimeType)
 	if err != nil {
+		return nil, err
+	}
+
+	// 保存图片并创建账单
+	return s.createBillWithImage(ctx, userID, aiResult, imageData, mimeType)
+}
+
+// ReRecognizeBill 使用账单保存的截图和当前分类重新识别
+// apply 为 false 时只返回差异，为 true 时将识别结果写回账单
+func (s *AIService) ReRecognizeBill(ctx context.Context, userID, billID uint64, apply bool) (*dto.ReRecognizeResponse, error) {
+	reader, mimeType, err := s.billService.GetImage(ctx, userID, billID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	imageData, err := io.ReadAll(reader)
+	if err != nil {
+		logger.Log.Error("读取账单图片失败", zap.Uint64("bill_id", billID), zap.Error(err))
 		return nil, errcode.ErrServer
 	}
+	// 早期保存的截图未经过预处理
+	if mimeType != pdfMimeType {
+		if imageData, mimeType, err = s.preprocess(ctx, imageData); err != nil {
+			return nil, err
+		}
+	}
 
-	// 获取分类数据，构建提示词
-	var prompt string
-	categories, err := s.categoryService.GetCategoriesForAI(ctx, userID)
-	if err != nil || len(categories) == 0 {
-		// 降级方案：使用默认提示词
-		prompt = ai.GetRecognitionPrompt()
+	aiResult, err := s.recognizeFile(ctx, userID, imageData, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.billService.UpdateFromAI(ctx, userID, billID, aiResult, apply)
+}
+
+// readImage 读取上传的图片并预处理
+func (s *AIService) readImage(ctx context.Context, file *multipart.FileHeader) ([]byte, string, error) {
+	data, _, err := s.readFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.preprocess(ctx, data)
+}
+
+// readUpload 读取上传的图片或PDF文件，图片经过预处理，PDF 原样返回
+func (s *AIService) readUpload(ctx context.Context, file *multipart.FileHeader) ([]byte, string, error) {
+	data, mimeType, err := s.readFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	if mimeType == pdfMimeType {
+		return data, mimeType, nil
+	}
+	return s.preprocess(ctx, data)
+}
+
+// readFile 校验大小并读取上传的文件，文件类型根据内容判断
+func (s *AIService) readFile(file *multipart.FileHeader) ([]byte, string, error) {
+	if file.Size > s.maxImageSize {
+		return nil, "", errcode.ErrImageTooLarge
+	}
+	data, mimeType, err := ai.ReadImageFromFile(file)
+	if err != nil {
+		return nil, "", errcode.ErrServer
+	}
+	return data, mimeType, nil
+}
+
+// preprocess 校验图片格式并旋转、去除元数据、缩小尺寸
+func (s *AIService) preprocess(ctx context.Context, data []byte) ([]byte, string, error) {
+	imageData, mimeType, err := s.preprocessor.Process(ctx, data)
+	if err != nil {
+		if errors.Is(err, ai.ErrUnsupportedImage) {
+			return nil, "", errcode.ErrImageFormatInvalid
+		}
+		logger.Log.Error("图片预处理失败", zap.Error(err))
+		return nil, "", errcode.ErrServer
+	}
+	return imageData, mimeType, nil
+}
+
+// recognizeFile 按文件类型识别：PDF 走电子发票/回单流程，其余按图片识别
+func (s *AIService) recognizeFile(ctx context.Context, userID uint64, data []byte, mimeType string) (*dto.AIRecognizeResponse, error) {
+	if mimeType == pdfMimeType {
+		return s.recognizePDF(ctx, userID, data)
+	}
+	return s.recognize(ctx, userID, data, mimeType)
+}
+
+// recognizePDF 识别PDF电子发票/回单
+// 能从文本中解析出发票号码和价税合计时直接生成结果，不调用AI也不计入配额；
+// 否则将页面内嵌图片（扫描件）或渲染出的首页图片交给AI识别，未配置渲染命令时返回 ErrPDFRenderUnavailable
+func (s *AIService) recognizePDF(ctx context.Context, userID uint64, data []byte) (*dto.AIRecognizeResponse, error) {
+	local, _ := time.LoadLocation("Asia/Shanghai")
+
+	var partial *invoice.Invoice
+	doc, err := pdf.Open(data)
+	if err != nil {
+		logger.Log.Info("解析PDF失败", zap.Uint64("user_id", userID), zap.Error(err))
 	} else {
-		prompt = ai.BuildRecognitionPrompt(categories)
+		text := doc.Text()
+		inv, ok := invoice.Parse(text, local)
+		if ok {
+			return s.invoiceResult(ctx, userID, inv, text), nil
+		}
+		partial = inv
+
+		if img, ok := doc.PageImage(0); ok {
+			return s.recognizePDFImage(ctx, userID, img.Data, partial)
+		}
+	}
+
+	if s.pdfRenderer == nil {
+		logger.Log.Warn("PDF中没有可提取的文本或图片，未配置 pdf.render_command 无法渲染", zap.Uint64("user_id", userID))
+		return nil, errcode.ErrPDFRenderUnavailable
+	}
+	imageData, _, err := s.pdfRenderer.Render(ctx, data)
+	if err != nil {
+		logger.Log.Warn("渲染PDF失败", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, errcode.ErrPDFUnreadable
+	}
+	return s.recognizePDFImage(ctx, userID, imageData, partial)
+}
+
+// recognizePDFImage 识别PDF页面图片，文本中已解析出的发票号码优先于AI结果
+func (s *AIService) recognizePDFImage(ctx context.Context, userID uint64, imageData []byte, partial *invoice.Invoice) (*dto.AIRecognizeResponse, error) {
+	// 扫描件内嵌的图片通常分辨率很高
+	imageData, mimeType, err := s.preprocess(ctx, imageData)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.recognize(ctx, userID, imageData, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	if partial != nil && partial.Number != "" {
+		result.InvoiceNo = partial.Number
+	}
+	return result, nil
+}
+
+// invoiceResult 将本地解析的电子发票转换为识别结果，分类按销售方和项目名称匹配
+func (s *AIService) invoiceResult(ctx context.Context, userID uint64, inv *invoice.Invoice, text string) *dto.AIRecognizeResponse {
+	result := &dto.AIRecognizeResponse{
+		Platform:   inv.Platform(),
+		Amount:     inv.Amount,
+		Merchant:   inv.Seller,
+		PayMethod:  "电子发票",
+		InvoiceNo:  inv.Number,
+		BillType:   int(model.BillTypeExpense),
+		Confidence: invoiceConfidenceUncertain,
+		RawContent: truncateRunes(text, invoiceRawContentLimit),
+	}
+	if !inv.Date.IsZero() {
+		result.PayTime = inv.Date.Format(time.RFC3339)
+	}
+	categories, err := s.categoryService.GetCategoriesForAI(ctx, userID)
+	if err != nil {
+		logger.Log.Warn("获取分类失败", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	if category := quickentry.MatchCategory(inv.Seller+inv.Description(), model.BillTypeExpense, categories); category != nil {
+		result.SubCategory = category.Name
+		result.Confidence = invoiceConfidence
+	}
+	return result
+}
+
+// recognize 调用AI识别图片
+func (s *AIService) recognize(ctx context.Context, userID uint64, imageData []byte, mimeType string) (*dto.AIRecognizeResponse, error) {
+	// 获取分类数据，构建提示词
+	prompt, err := s.renderPrompt(ctx, userID, ai.PromptRecognition, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.reserveQuota(ctx, userID, 1)
+	if err != nil {
+		return nil, err
 	}
 
 	// 调用AI识别
-	result, err := s.client.RecognizePayment(ctx, imageData, mimeType, prompt)
+	startTime := time.Now()
+	result, err := s.client.RecognizePayment(ai.WithCacheScope(ctx, prompt.CacheScope), imageData, mimeType, prompt.Text)
+	s.finishUsage(ctx, &reserved[0], usageOf(result), time.Since(startTime), err)
 	if err != nil {
-		return nil, errcode.ErrAIRecognizeFailed.WithMessage(err.Error())
+		return nil, toAIError(err)
 	}
 
+	result.PromptVersion = prompt.Version
 	return result, nil
 }
 
-// RecognizeAndCreateBill 识别图片并创建账单
-func (s *AIService) RecognizeAndCreateBill(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.BillResponse, error) {
-	// 识别图片
-	aiResult, err := s.RecognizeImage(ctx, userID, file)
+// RecognizeTransactions 识别包含多笔交易的截图（银行APP、支付宝账单列表等），仅返回预览不保存
+// 与已有账单可能重复的交易会标记 duplicate_bill_id
+func (s *AIService) RecognizeTransactions(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.AIRecognizeListResponse, error) {
+	imageData, mimeType, err := s.readImage(ctx, file)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: 保存图片到对象存储，获取图片路径
-	imagePath := ""
+	recognizer, ok := s.client.(ai.TransactionRecognizer)
+	if !ok {
+		return nil, toAIError(ai.ErrUnsupported)
+	}
+	prompt, err := s.renderPrompt(ctx, userID, ai.PromptTransactions, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.reserveQuota(ctx, userID, 1)
+	if err != nil {
+		return nil, err
+	}
 
-	// 创建账单
-	return s.billService.CreateFromAI(ctx, userID, aiResult, imagePath)
+	startTime := time.Now()
+	result, err := recognizer.RecognizeTransactions(ai.WithCacheScope(ctx, prompt.CacheScope), imageData, mimeType, prompt.Text)
+	var usage *dto.AIUsage
+	if result != nil {
+		usage = result.Usage
+	}
+	s.finishUsage(ctx, &reserved[0], usage, time.Since(startTime), err)
+	if err != nil {
+		return nil, toAIError(err)
+	}
+	for i := range result.Transactions {
+		result.Transactions[i].PromptVersion = prompt.Version
+	}
+
+	if err := s.billService.MarkDuplicateTransactions(ctx, userID, result.Transactions); err != nil {
+		logger.Log.Warn("查询重复交易失败", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return result, nil
+}
+
+// RecognizeText 解析一句话记账文本，now 为用户所在时区的当前时间
+func (s *AIService) RecognizeText(ctx context.Context, userID uint64, text string, now time.Time) (*dto.AIRecognizeResponse, error) {
+	return s.recognizeText(ctx, userID, text, ai.PromptText, now)
+}
+
+// RecognizeNotification 解析银行动账短信或支付APP通知，receivedAt 为收到通知的时间
+func (s *AIService) RecognizeNotification(ctx context.Context, userID uint64, text string, receivedAt time.Time) (*dto.AIRecognizeResponse, error) {
+	return s.recognizeText(ctx, userID, text, ai.PromptNotification, receivedAt)
 }
 
-// isValidImageType 检查是否为有效的图片类型
-func isValidImageType(contentType string) bool {
-	validTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/jpg":  true,
-		"image/png":  true,
-		"image/gif":  true,
-		"image/webp": true,
+// recognizeText 调用AI识别文本，now 为提示词中的当前时间
+func (s *AIService) recognizeText(ctx context.Context, userID uint64, text string, kind ai.PromptKind, now time.Time) (*dto.AIRecognizeResponse, error) {
+	recognizer, ok := s.client.(ai.TextRecognizer)
+	if !ok {
+		return nil, toAIError(ai.ErrUnsupported)
+	}
+	prompt, err := s.renderPrompt(ctx, userID, kind, now)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.reserveQuota(ctx, userID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result, err := recognizer.RecognizeText(ctx, text, prompt.Text)
+	s.finishUsage(ctx, &reserved[0], usageOf(result), time.Since(startTime), err)
+	if err != nil {
+		return nil, toAIError(err)
 	}
-	return validTypes[contentType]
+	result.PromptVersion = prompt.Version
+	return result, nil
+}
+
+// PlanQuery 将自然语言问题转换为账本查询计划，now 为提示词中的当前时间
+func (s *AIService) PlanQuery(ctx context.Context, userID uint64, question string, now time.Time) (*dto.AIQueryPlan, error) {
+	planner, ok := s.client.(ai.QueryPlanner)
+	if !ok {
+		return nil, toAIError(ai.ErrUnsupported)
+	}
+	prompt, err := s.renderPrompt(ctx, userID, ai.PromptQuery, now)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.reserveQuota(ctx, userID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	plan, err := planner.PlanQuery(ctx, question, prompt.Text)
+	var usage *dto.AIUsage
+	if plan != nil {
+		usage = plan.Usage
+	}
+	s.finishUsage(ctx, &reserved[0], usage, time.Since(startTime), err)
+	if err != nil {
+		return nil, toAIError(err)
+	}
+	return plan, nil
 }
+
+// WriteInsight 根据统计数据（JSON）撰写月度消费洞察
+// 由后台任务调用，不检查也不占用用户配额，用量按系统调用记录
+func (s *AIService) WriteInsight(ctx context.Context, userID uint64, facts string, now time.Time) (*dto.AIInsightResponse, error) {
+	writer, ok := s.client.(ai.InsightWriter)
+	if !ok {
+		return nil, toAIError(ai.ErrUnsupported)
+	}
+	prompt, err := s.renderPrompt(ctx, userID, ai.PromptInsight, now)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result, err := writer.WriteInsight(ctx, facts, prompt.Text)
+	var usage *dto.AIUsage
+	if result != nil {
+		usage = result.Usage
+	}
+	s.recordSystemUsage(ctx, userID, usage, time.Since(startTime), err)
+	if err != nil {
+		return nil, toAIError(err)
+	}
+	result.PromptVersion = prompt.Version
+	return result, nil
+}
+
+// SaveTransactions 将用户在预览中勾选的交易在同一事务中保存为账单，保存时按订单号和时间金额判重
+func (s *AIService) SaveTransactions(ctx context.Context, userID uint64, req *dto.SaveAITransactionsRequest) (*dto.SaveAITransactionsResponse, error) {
+	aiResults := make([]dto.AIRecognizeResponse, len(req.Transactions))
+	for i, transaction := range req.Transactions {
+		if !transaction.Amount.IsPositive() {
+			return nil, errcode.ErrParams.WithMessage(fmt.Sprintf("第%d笔交易的金额必须大于0", i+1))
+		}
+		// 保存用户确认后的交易内容，便于追溯账单来源
+		raw, _ := json.Marshal(transaction)
+		aiResults[i] = dto.AIRecognizeResponse{
+			Platform:      transaction.Platform,
+			Amount:        transaction.Amount,
+			Merchant:      transaction.Merchant,
+			Category:      transaction.Category,
+			SubCategory:   transaction.SubCategory,
+			PayTime:       transaction.PayTime,
+			PayMethod:     transaction.PayMethod,
+			OrderNo:       transaction.OrderNo,
+			BillType:      transaction.BillType,
+			Confidence:    transaction.Confidence,
+			Remark:        transaction.Remark,
+			PromptVersion: transaction.PromptVersion,
+			RawContent:    string(raw),
+		}
+	}
+	return s.billService.CreateBatchFromAI(ctx, userID, aiResults)
+}
+
+// Quota 获取用户AI调用配额使用情况
+func (s *AIService) Quota(ctx context.Context, userID uint64) (*dto.AIQuotaResponse, error) {
+	dayStart, monthStart := quotaPeriodStart(time.Now())
+
+	daily, err := s.quotaPeriod(ctx, userID, dayStart, s.quota.Daily)
+	if err != nil {
+		return nil, err
+	}
+	daily.ResetAt = dayStart.AddDate(0, 0, 1)
+
+	monthly, err := s.quotaPeriod(ctx, userID, monthStart, s.quota.Monthly)
+	if err != nil {
+		return nil, err
+	}
+	monthly.ResetAt = monthStart.AddDate(0, 1, 0)
+
+	return &dto.AIQuotaResponse{Daily: *daily, Monthly: *monthly}, nil
+}
+
+// quotaPeriod 统计单个周期的配额使用情况
+func (s *AIService) quotaPeriod(ctx context.Context, userID uint64, since time.Time, limit int) (*dto.AIQuotaPeriod, error) {
+	summary, err := s.usageRepo.Summarize(ctx, userID, since)
+	if err != nil {
+		logger.Log.Error("统计AI用量失败", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+
+	period := &dto.AIQuotaPeriod{
+		Limit:            limit,
+		Used:             summary.Calls,
+		Remaining:        -1,
+		PromptTokens:     summary.PromptTokens,
+		CompletionTokens: summary.CompletionTokens,
+	}
+	if limit > 0 {
+		period.Remaining = max(int64(limit)-summary.Calls, 0)
+	}
+	return period, nil
+}
+
+// checkQuota 检查用户剩余配额是否足够发起 n 次调用
+// 仅用于提交异步任务时提前提示，不占用配额；实际调用前由 reserveQuota 预留
+func (s *AIService) checkQuota(ctx context.Context, userID uint64, n int) error {
+	if s.quota.Daily <= 0 && s.quota.Monthly <= 0 {
+		return nil
+	}
+
+	quota, err := s.Quota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, period := range []struct {
+		name string
+		dto.AIQuotaPeriod
+	}{{"今日", quota.Daily}, {"本月", quota.Monthly}} {
+		if period.Limit > 0 && period.Remaining < int64(n) {
+			return errcode.ErrAIQuotaExceeded.WithMessage(
+				fmt.Sprintf("%sAI识别次数已达上限（%d次），剩余%d次", period.name, period.Limit, period.Remaining))
+		}
+	}
+	return nil
+}
+
+// reserveQuota 调用模型前为 n 次调用预留配额，同一用户的并发请求不会超出配额
+// 返回的预留记录在调用结束后由 finishUsage 更新，未调用模型的由 releaseUsage 释放
+func (s *AIService) reserveQuota(ctx context.Context, userID uint64, n int) ([]model.AIUsageRecord, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	dayStart, monthStart := quotaPeriodStart(time.Now())
+	var limits []repository.QuotaLimit
+	if s.quota.Daily > 0 {
+		limits = append(limits, repository.QuotaLimit{Name: "今日", Since: dayStart, Limit: s.quota.Daily})
+	}
+	if s.quota.Monthly > 0 {
+		limits = append(limits, repository.QuotaLimit{Name: "本月", Since: monthStart, Limit: s.quota.Monthly})
+	}
+
+	records, err := s.usageRepo.Reserve(ctx, userID, n, limits)
+	if err != nil {
+		var exceeded *repository.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			return nil, errcode.ErrAIQuotaExceeded.WithMessage(fmt.Sprintf("%sAI识别次数已达上限（%d次），剩余%d次",
+				exceeded.Name, exceeded.Limit, max(int64(exceeded.Limit)-exceeded.Used, 0)))
+		}
+		logger.Log.Error("预留AI配额失败", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	return records, nil
+}
+
+// finishUsage 保存一次AI调用的结果，失败的调用和命中缓存的调用不计入配额，写入失败不影响识别结果
+func (s *AIService) finishUsage(ctx context.Context, record *model.AIUsageRecord, usage *dto.AIUsage, latency time.Duration, callErr error) {
+	applyUsage(record, s.provider, usage, latency, callErr)
+	if err := s.usageRepo.Finish(ctx, record); err != nil {
+		logger.Log.Warn("记录AI用量失败", zap.Uint64("user_id", record.UserID), zap.Error(err))
+	}
+}
+
+// finishTaskUsage 保存批量识别中单个任务的调用结果
+func (s *AIService) finishTaskUsage(ctx context.Context, record *model.AIUsageRecord, result ai.TaskResult) {
+	var callErr error
+	if !result.Success {
+		callErr = errors.New(result.Error)
+	}
+	s.finishUsage(ctx, record, usageOf(result.Data), time.Duration(result.Duration)*time.Millisecond, callErr)
+}
+
+// releaseUsage 释放未调用模型的预留配额
+func (s *AIService) releaseUsage(ctx context.Context, records []model.AIUsageRecord) {
+	if len(records) == 0 {
+		return
+	}
+	ids := make([]uint64, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	if err := s.usageRepo.Release(ctx, ids); err != nil {
+		logger.Log.Warn("释放AI配额失败", zap.Uint64("user_id", records[0].UserID), zap.Error(err))
+	}
+}
+
+// recordSystemUsage 记录后台任务发起的AI调用，不计入用户配额，写入失败不影响结果
+func (s *AIService) recordSystemUsage(ctx context.Context, userID uint64, usage *dto.AIUsage, latency time.Duration, callErr error) {
+	record := &model.AIUsageRecord{UserID: userID, Source: model.UsageSourceSystem}
+	applyUsage(record, s.provider, usage, latency, callErr)
+	if err := s.usageRepo.Create(ctx, record); err != nil {
+		logger.Log.Warn("记录AI用量失败", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+}
+
+// applyUsage 将调用结果写入用量记录
+func applyUsage(record *model.AIUsageRecord, provider string, usage *dto.AIUsage, latency time.Duration, callErr error) {
+	record.Provider = provider
+	record.LatencyMs = latency.Milliseconds()
+	record.Status = model.UsageStatusSucceeded
+	if usage != nil {
+		if usage.Provider != "" {
+			record.Provider = usage.Provider
+		}
+		record.Model = usage.Model
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+		record.Cached = usage.Cached
+	}
+	if callErr != nil {
+		record.Status = model.UsageStatusFailed
+		record.ErrorMessage = truncateRunes(callErr.Error(), 500)
+	}
+}
+
+// usageOf 获取识别结果中的用量信息
+func usageOf(result *dto.AIRecognizeResponse) *dto.AIUsage {
+	if result == nil {
+		return nil
+	}
+	return result.Usage
+}
+
+// quotaPeriodStart 计算配额周期的起始时间（当日零点、当月一日零点）
+func quotaPeriodStart(now time.Time) (dayStart, monthStart time.Time) {
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return dayStart, monthStart
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// Health 获取AI提供方健康状态
+func (s *AIService) Health(ctx context.Context) *dto.AIHealthResponse {
+	resp := &dto.AIHealthResponse{Providers: []dto.AIProviderHealth{}}
+	reporter, ok := s.client.(ai.HealthReporter)
+	if !ok {
+		resp.Available = true
+		return resp
+	}
+	for _, h := range reporter.Health() {
+		if h.State != ai.BreakerOpen {
+			resp.Available = true
+		}
+		resp.Providers = append(resp.Providers, dto.AIProviderHealth{
+			Name:                h.Name,
+			State:               string(h.State),
+			ConsecutiveFailures: h.ConsecutiveFailures,
+			LastError:           h.LastError,
+			LastFailureAt:       h.LastFailureAt,
+			RetryAt:             h.RetryAt,
+		})
+	}
+	return resp
+}
+
+// toAIError 将AI客户端错误转换为业务错误码
+func toAIError(err error) error {
+	if errors.Is(err, ai.ErrAllProvidersUnavailable) {
+		return errcode.ErrAIServiceUnavailable
+	}
+	return errcode.ErrAIRecognizeFailed.WithMessage(err.Error())
+}
+
+// createBillWithImage 保存截图并创建账单
+// 图片保存失败时降级为不带图片的账单，账单创建失败时清理已保存的图片
+func (s *AIService) createBillWithImage(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, imageData []byte, mimeType string) (*dto.BillResponse, error) {
+	imagePath := storage.BillImageKey(userID, mimeType)
+	if err := s.storage.Save(ctx, imagePath, imageData, mimeType); err != nil {
+		logger.Log.Warn("保存账单图片失败", zap.Uint64("user_id", userID), zap.Error(err))
+		imagePath = ""
+	}
+
+	bill, err := s.billService.CreateFromAI(ctx, userID, aiResult, imagePath, ai.ImageHash(imageData))
+	if err != nil {
+		if imagePath != "" {
+			if delErr := s.storage.Delete(ctx, imagePath); delErr != nil {
+				logger.Log.Warn("清理账单图片失败", zap.String("image_path", imagePath), zap.Error(delErr))
+			}
+		}
+		return nil, err
+	}
+	return bill, nil
+}
+
+// findDuplicate 查找由同一截图生成的账单，查询失败时不影响识别流程
+func (s *AIService) findDuplicate(ctx context.Context, userID uint64, imageData []byte) *dto.BillResponse {
+	bill, err := s.billService.FindByImageHash(ctx, userID, ai.ImageHash(imageData))
+	if err != nil {
+		logger.Log.Warn("查询重复账单失败", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil
+	}
+	if bill != nil {
+		bill.Duplicate = true
+	}
+	return bill
+}
+
+// BatchRecognize 批量识别图片
+// 结果顺序与上传顺序一致，单张失败不影响其他图片
+func (s *AIService) BatchRecognize(ctx context.Context, userID uint64, files []*multipart.FileHeader) (*dto.BatchRecognizeResponse, error) {
+	startTime := time.Now()
+	results, images, err := s.executeBatch(ctx, userID, files)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.BatchRecognizeItem, len(results))
+	for i, result := range results {
+		items[i] = toBatchRecognizeItem(result)
+		if !result.Success {
+			continue
+		}
+
+		// 标记重复上传
+		if duplicate := s.findDuplicate(ctx, userID, images[i].data); duplicate != nil {
+			result.Data.DuplicateBillID = &duplicate.ID
+		}
+	}
+
+	return buildBatchResponse(items, startTime), nil
+}
+
+// BatchRecognizeAndCreateBill 批量识别图片并创建账单
+func (s *AIService) BatchRecognizeAndCreateBill(ctx context.Context, userID uint64, files []*multipart.FileHeader) (*dto.BatchRecognizeResponse, error) {
+	startTime := time.Now()
+	results, images, err := s.executeBatch(ctx, userID, files)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.BatchRecognizeItem, len(results))
+	for i, result := range results {
+		items[i] = toBatchRecognizeItem(result)
+		if !result.Success {
+			continue
+		}
+
+		// 同一截图已生成过账单时返回已有账单
+		if duplicate := s.findDuplicate(ctx, userID, images[i].data); duplicate != nil {
+			items[i].Bill = duplicate
+			continue
+		}
+
+		bill, err := s.createBillWithImage(ctx, userID, result.Data, images[i].data, images[i].mimeType)
+		if err != nil {
+			items[i].Success = false
+			items[i].Error = err.Error()
+			if e, ok := err.(*errcode.ErrCode); ok {
+				items[i].Error = e.Message
+			}
+			continue
+		}
+		items[i].Bill = bill
+	}
+
+	return buildBatchResponse(items, startTime), nil
+}
+
+// batchImage 批量识别中预处理后的图片
+type batchImage struct {
+	data     []byte
+	mimeType string
+}
+
+// executeBatch 校验图片数量，预处理图片后通过 WorkerPool 执行批量识别
+// 返回的结果和图片与上传顺序一致，读取或预处理失败的图片直接标记失败，不调用AI
+func (s *AIService) executeBatch(ctx context.Context, userID uint64, files []*multipart.FileHeader) ([]ai.TaskResult, []batchImage, error) {
+	if len(files) == 0 {
+		return nil, nil, errcode.ErrParams.WithMessage("请上传图片")
+	}
+	if len(files) > s.batchConfig.MaxImages {
+		return nil, nil, errcode.ErrTooManyImages.WithMessage(fmt.Sprintf("单次最多上传%d张图片", s.batchConfig.MaxImages))
+	}
+
+	// 同一批次的图片共用一份提示词
+	prompt, err := s.renderPrompt(ctx, userID, ai.PromptRecognition, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]ai.TaskResult, len(files))
+	images := make([]batchImage, len(files))
+	tasks := make([]ai.Task, 0, len(files))
+	for i, file := range files {
+		imageData, mimeType, err := s.readImage(ctx, file)
+		if err != nil {
+			results[i] = ai.TaskResult{Index: i, FileName: file.Filename, Error: errorMessage(err)}
+			continue
+		}
+		images[i] = batchImage{data: imageData, mimeType: mimeType}
+		tasks = append(tasks, ai.Task{
+			Index:      i,
+			Data:       imageData,
+			MimeType:   mimeType,
+			FileName:   file.Filename,
+			Prompt:     prompt.Text,
+			CacheScope: prompt.CacheScope,
+		})
+	}
+
+	// 为读取成功的图片预留配额，每个任务对应一条预留记录
+	reserved, err := s.reserveQuota(ctx, userID, len(tasks))
+	if err != nil {
+		return nil, nil, err
+	}
+	reservations := make(map[int]*model.AIUsageRecord, len(tasks))
+	for i, task := range tasks {
+		reservations[task.Index] = &reserved[i]
+	}
+
+	var unused []model.AIUsageRecord
+	for _, result := range s.workerPool.Execute(ctx, tasks) {
+		if result.Data != nil {
+			result.Data.PromptVersion = prompt.Version
+		}
+		results[result.Index] = result
+		if !result.Called {
+			unused = append(unused, *reservations[result.Index])
+			continue
+		}
+		s.finishTaskUsage(ctx, reservations[result.Index], result)
+	}
+	s.releaseUsage(ctx, unused)
+	return results, images, nil
+}
+
+// renderPrompt 根据用户分类、历史修正和自定义说明渲染提示词
+func (s *AIService) renderPrompt(ctx context.Context, userID uint64, kind ai.PromptKind, now time.Time) (*ai.Prompt, error) {
+	prompt, err := s.prompts.Render(kind, s.promptData(ctx, userID, now))
+	if err != nil {
+		logger.Log.Error("渲染提示词失败", zap.String("kind", string(kind)), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	return prompt, nil
+}
+
+// promptData 获取渲染提示词所需的用户数据，获取失败的部分留空，不影响识别
+func (s *AIService) promptData(ctx context.Context, userID uint64, now time.Time) ai.PromptData {
+	data := ai.PromptData{UserID: userID, Now: now}
+	if user, err := s.userRepo.GetByID(ctx, userID); err == nil {
+		data.Instructions = user.RecognitionInstructions
+	}
+
+	// 获取不到用户分类时使用默认分类，此时修正示例中的分类无意义
+	categories, err := s.categoryService.GetCategoriesForAI(ctx, userID)
+	if err != nil || len(categories) == 0 {
+		return data
+	}
+	data.Categories = categories
+	corrections, _ := s.billService.ListCorrections(ctx, userID, correctionHintLimit)
+	data.Hints = toCorrectionHints(corrections)
+	return data
+}
+
+// toCorrectionHints 将修正记录转换为提示词示例，跳过分类已删除的记录
+func toCorrectionHints(corrections []model.CategoryCorrection) []ai.CorrectionHint {
+	hints := make([]ai.CorrectionHint, 0, len(corrections))
+	for _, c := range corrections {
+		if c.Category == nil {
+			continue
+		}
+		hint := ai.CorrectionHint{
+			Merchant: c.Merchant,
+			Platform: c.Platform,
+			BillType: int(c.BillType),
+			Category: c.Category.Name,
+		}
+		if c.Category.Parent != nil {
+			hint.Category = c.Category.Parent.Name
+			hint.SubCategory = c.Category.Name
+		}
+		hints = append(hints, hint)
+	}
+	return hints
+}
+
+// toBatchRecognizeItem 转换为批量识别结果项
+func toBatchRecognizeItem(result ai.TaskResult) dto.BatchRecognizeItem {
+	return dto.BatchRecognizeItem{
+		Index:    result.Index,
+		FileName: result.FileName,
+		Success:  result.Success,
+		Data:     result.Data,
+		Error:    result.Error,
+		Duration: result.Duration,
+	}
+}
+
+// buildBatchResponse 汇总批量识别结果
+func buildBatchResponse(items []dto.BatchRecognizeItem, startTime time.Time) *dto.BatchRecognizeResponse {
+	resp := &dto.BatchRecognizeResponse{
+		Total:    len(items),
+		Results:  items,
+		Duration: time.Since(startTime).Milliseconds(),
+	}
+	for _, item := range items {
+		if item.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp
+}
+
+// pdfMimeType PDF 文件的 MIME 类型
+const pdfMimeType = "application/pdf"
@@ -0,0 +1,429 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/internal/pkg/storage"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// testAIService 使用 FakeClient 和内存仓库的AI服务
+type testAIService struct {
+	*AIService
+	client *ai.FakeClient
+	bills  *fakeBillRepo
+	usage  *fakeUsageRepo
+}
+
+func newTestAIService(t *testing.T, quota config.QuotaConfig) *testAIService {
+	client := ai.NewFakeClient()
+	categories := &fakeCategoryRepo{categories: testCategories()}
+	bills := newFakeBillRepo(categories)
+	users := &fakeUserRepo{users: map[uint64]*model.User{
+		testUserID: {BaseModel: model.BaseModel{ID: testUserID}, RecognitionInstructions: "打车账单标注可报销"},
+	}}
+	usage := &fakeUsageRepo{}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	prompts, err := ai.NewPromptStore(config.PromptConfig{})
+	require.NoError(t, err)
+
+	const maxImageSize = 1 << 20
+	return &testAIService{
+		AIService: &AIService{
+			client:          client,
+			billService:     NewBillService(bills, categories, &fakeCorrectionRepo{}, users, store),
+			categoryService: NewCategoryService(categories, nil),
+			userRepo:        users,
+			maxImageSize:    maxImageSize,
+			workerPool:      ai.NewWorkerPool(2, ai.NewRPMLimiter(6000), time.Second, client, maxImageSize),
+			batchConfig:     &config.BatchConfig{MaxImages: 3},
+			storage:         store,
+			usageRepo:       usage,
+			quota:           quota,
+			provider:        "fake",
+			preprocessor:    ai.NewPreprocessor(config.PreprocessConfig{MaxDimension: 2048, JPEGQuality: 85}),
+			prompts:         prompts,
+		},
+		client: client,
+		bills:  bills,
+		usage:  usage,
+	}
+}
+
+// testPNG 生成指定尺寸的 PNG，尺寸不同内容摘要不同
+func testPNG(t *testing.T, width int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, 4))))
+	return buf.Bytes()
+}
+
+// uploadFiles 构造 multipart 上传的文件
+func uploadFiles(t *testing.T, contents ...[]byte) []*multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, content := range contents {
+		part, err := writer.CreateFormFile("files", string(rune('a'+i))+".png")
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["files"]
+}
+
+func coffeeResult() *dto.AIRecognizeResponse {
+	return &dto.AIRecognizeResponse{
+		Platform:    "微信支付",
+		Amount:      decimal.RequireFromString("18.50"),
+		Merchant:    "瑞幸咖啡",
+		Category:    "餐饮",
+		SubCategory: "咖啡饮品",
+		BillType:    1,
+		Confidence:  0.95,
+		Usage:       &dto.AIUsage{Provider: "fake", Model: "fake-vl", PromptTokens: 100, CompletionTokens: 20},
+	}
+}
+
+func TestAIService_RecognizeAndCreateBill(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()})
+	file := uploadFiles(t, testPNG(t, 4))[0]
+
+	bill, err := s.RecognizeAndCreateBill(context.Background(), testUserID, file)
+	require.NoError(t, err)
+	assert.False(t, bill.Duplicate)
+	assert.True(t, bill.HasImage)
+	require.NotNil(t, bill.Category)
+	assert.Equal(t, "咖啡饮品", bill.Category.Name)
+	assert.Equal(t, "recognition-v2", bill.PromptVersion)
+
+	// 提示词包含用户分类和自定义说明
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "image/png", calls[0].MimeType)
+	assert.Contains(t, calls[0].Prompt, "- 餐饮：咖啡饮品")
+	assert.Contains(t, calls[0].Prompt, "打车账单标注可报销")
+
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusSucceeded, s.usage.records[0].Status)
+	assert.Equal(t, "fake-vl", s.usage.records[0].Model)
+
+	// 同一截图再次上传时返回已有账单，不再调用AI
+	again, err := s.RecognizeAndCreateBill(context.Background(), testUserID, uploadFiles(t, testPNG(t, 4))[0])
+	require.NoError(t, err)
+	assert.True(t, again.Duplicate)
+	assert.Equal(t, bill.ID, again.ID)
+	assert.Len(t, s.client.Calls(), 1)
+}
+
+func TestAIService_RecognizeImage_Errors(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+
+	// 非图片文件不调用AI
+	_, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, []byte("not an image"))[0])
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImageFormatInvalid.Code, err.(*errcode.ErrCode).Code)
+	assert.Empty(t, s.client.Calls())
+
+	// 识别失败时记录失败用量
+	s.client.Enqueue(ai.FakeReply{Err: errors.New("模型返回格式错误")})
+	_, err = s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 4))[0])
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrAIRecognizeFailed.Code, err.(*errcode.ErrCode).Code)
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusFailed, s.usage.records[0].Status)
+	assert.Equal(t, "模型返回格式错误", s.usage.records[0].ErrorMessage)
+
+	// 所有提供方不可用
+	s.client.Enqueue(ai.FakeReply{Err: ai.ErrAllProvidersUnavailable})
+	_, err = s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 4))[0])
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrAIServiceUnavailable.Code, err.(*errcode.ErrCode).Code)
+}
+
+// testPDF 按顺序拼接间接对象生成单页 PDF，第 4 个对象为页面内容
+// lines 中的文字通过 ToUnicode CMap 编码，images 为页面引用的 JPEG 图片
+func testPDF(t *testing.T, lines []string, images ...[]byte) []byte {
+	codes := map[rune]int{}
+	var cmap, content strings.Builder
+	cmap.WriteString("begincmap\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n")
+	for i, line := range lines {
+		var hex strings.Builder
+		for _, r := range line {
+			code, ok := codes[r]
+			if !ok {
+				code = len(codes) + 1
+				codes[r] = code
+				fmt.Fprintf(&cmap, "1 beginbfchar <%04X> <%04X> endbfchar\n", code, r)
+			}
+			fmt.Fprintf(&hex, "%04X", code)
+		}
+		fmt.Fprintf(&content, "BT /F1 12 Tf 1 0 0 1 72 %d Tm <%s> Tj ET\n", 800-20*i, hex.String())
+	}
+	cmap.WriteString("endcmap")
+
+	var xobjects strings.Builder
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"",
+		"",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		pdfStream("", []byte(cmap.String())),
+	}
+	for i, data := range images {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		objects = append(objects, pdfStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode", cfg.Width, cfg.Height), data))
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i+1, len(objects))
+		fmt.Fprintf(&content, "q %d 0 0 %d 0 0 cm /Im%d Do Q\n", cfg.Width, cfg.Height, i+1)
+	}
+	objects[2] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> /XObject << %s>> >> /Contents 4 0 R >>", xobjects.String())
+	objects[3] = pdfStream("", []byte(content.String()))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func pdfStream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// testJPEG 生成指定宽度的灰度 JPEG
+func testJPEG(t *testing.T, width int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, 30)), nil))
+	return buf.Bytes()
+}
+
+// fakeRenderer 返回固定图片的 PDF 渲染器
+type fakeRenderer struct {
+	image []byte
+	calls int
+}
+
+func (r *fakeRenderer) Render(ctx context.Context, data []byte) ([]byte, string, error) {
+	r.calls++
+	return r.image, "image/png", nil
+}
+
+func TestAIService_RecognizePDF_LocalInvoice(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{Daily: 1})
+	data := testPDF(t, []string{
+		"电子发票（普通发票）",
+		"发票号码：25312000000123456789",
+		"开票日期：2025年03月05日",
+		"购买方信息 名称：张三",
+		"销售方信息 名称：北京三快在线科技有限公司",
+		"项目名称 规格型号 单位 数量 单价 金额 税率/征收率 税额",
+		"*餐饮服务*餐费 1 54.72 54.72 6% 3.28",
+		"合 计 ¥54.72 ¥3.28",
+		"价税合计（大写） 伍拾捌圆整 （小写）¥58.00",
+	})
+
+	result, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, data)[0])
+	require.NoError(t, err)
+	assert.Equal(t, "25312000000123456789", result.InvoiceNo)
+	assert.Equal(t, "北京三快在线科技有限公司", result.Merchant)
+	assert.True(t, result.Amount.Equal(decimal.NewFromInt(58)))
+	assert.Equal(t, "电子发票", result.PayMethod)
+	assert.Contains(t, result.PayTime, "2025-03-05")
+
+	// 本地解析不调用AI，也不消耗配额
+	assert.Empty(t, s.client.Calls())
+	assert.Empty(t, s.usage.records)
+}
+
+func TestAIService_RecognizePDF_EmbeddedImage(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()})
+	renderer := &fakeRenderer{image: testPNG(t, 8)}
+	s.pdfRenderer = renderer
+
+	// 扫描件只有发票号码可提取，金额等交给AI识别页面内嵌的图片
+	data := testPDF(t, []string{"发票号码：12345678"}, testJPEG(t, 40))
+	result, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, data)[0])
+	require.NoError(t, err)
+	assert.Equal(t, "12345678", result.InvoiceNo, "文本中解析出的发票号码优先")
+	assert.Equal(t, "瑞幸咖啡", result.Merchant)
+
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "image/jpeg", calls[0].MimeType)
+	assert.Zero(t, renderer.calls, "有内嵌图片时不渲染")
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusSucceeded, s.usage.records[0].Status)
+}
+
+func TestAIService_RecognizePDF_Render(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	data := testPDF(t, nil)
+
+	// 没有文本和内嵌图片，未配置渲染命令时返回明确的错误码
+	_, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, data)[0])
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrPDFRenderUnavailable.Code, err.(*errcode.ErrCode).Code)
+	assert.Empty(t, s.client.Calls())
+
+	// 配置渲染命令后识别渲染出的首页图片
+	renderer := &fakeRenderer{image: testPNG(t, 8)}
+	s.pdfRenderer = renderer
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()})
+	result, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, data)[0])
+	require.NoError(t, err)
+	assert.Equal(t, "瑞幸咖啡", result.Merchant)
+	assert.Equal(t, 1, renderer.calls)
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "image/png", calls[0].MimeType)
+}
+
+func TestAIService_Quota(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{Daily: 1})
+	s.client.Enqueue(ai.FakeReply{Result: coffeeResult()}, ai.FakeReply{Result: coffeeResult()})
+
+	_, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 4))[0])
+	require.NoError(t, err)
+
+	_, err = s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 5))[0])
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrAIQuotaExceeded.Code, err.(*errcode.ErrCode).Code)
+	assert.Len(t, s.client.Calls(), 1)
+}
+
+func TestAIService_Quota_FailedCallsNotCounted(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{Daily: 1})
+	s.client.Enqueue(ai.FakeReply{Err: errors.New("模型返回格式错误")}, ai.FakeReply{Result: coffeeResult()})
+
+	// 提供方出错不消耗配额
+	_, err := s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 4))[0])
+	require.Error(t, err)
+	quota, err := s.Quota(context.Background(), testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), quota.Daily.Used)
+
+	_, err = s.RecognizeImage(context.Background(), testUserID, uploadFiles(t, testPNG(t, 5))[0])
+	require.NoError(t, err)
+	quota, err = s.Quota(context.Background(), testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), quota.Daily.Used)
+	assert.Equal(t, int64(0), quota.Daily.Remaining)
+}
+
+func TestAIService_Quota_ConcurrentReservations(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{Daily: 2})
+	// 模型调用期间其他请求检查配额：进行中的调用已预留配额
+	release := make(chan struct{})
+	s.client.Handler = func(ctx context.Context, call ai.FakeCall) ai.FakeReply {
+		<-release
+		return ai.FakeReply{Result: coffeeResult()}
+	}
+
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		file := uploadFiles(t, testPNG(t, 4+i))[0]
+		go func() {
+			_, err := s.RecognizeImage(context.Background(), testUserID, file)
+			errs <- err
+		}()
+	}
+	// 超出配额的请求不等待模型调用，直接返回
+	for i := 0; i < n-2; i++ {
+		err := <-errs
+		require.Error(t, err)
+		assert.Equal(t, errcode.ErrAIQuotaExceeded.Code, err.(*errcode.ErrCode).Code)
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Len(t, s.client.Calls(), 2)
+}
+
+func TestAIService_BatchRecognizeAndCreateBill(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	s.client.Handler = func(ctx context.Context, call ai.FakeCall) ai.FakeReply {
+		// 按图片宽度区分，宽度为 5 的图片识别失败
+		cfg, err := png.DecodeConfig(bytes.NewReader(call.ImageData))
+		if err != nil || cfg.Width == 5 {
+			return ai.FakeReply{Err: errors.New("识别失败")}
+		}
+		result := coffeeResult()
+		result.Amount = decimal.NewFromInt(int64(cfg.Width))
+		return ai.FakeReply{Result: result}
+	}
+
+	files := uploadFiles(t, testPNG(t, 4), []byte("not an image"), testPNG(t, 5))
+	resp, err := s.BatchRecognizeAndCreateBill(context.Background(), testUserID, files)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 1, resp.Succeeded)
+	require.Len(t, resp.Results, 3)
+	for i, result := range resp.Results {
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, files[i].Filename, result.FileName)
+	}
+	require.True(t, resp.Results[0].Success)
+	require.NotNil(t, resp.Results[0].Bill)
+	assert.True(t, resp.Results[0].Bill.Amount.Equal(decimal.NewFromInt(4)))
+	assert.Equal(t, "recognition-v2", resp.Results[0].Data.PromptVersion)
+	assert.Equal(t, "图片格式无效", resp.Results[1].Error)
+	assert.Equal(t, "识别失败", resp.Results[2].Error)
+
+	// 无效图片不调用AI，调用过的两张都记录用量
+	assert.Len(t, s.client.Calls(), 2)
+	assert.Len(t, s.usage.records, 2)
+
+	// 超过单次图片数量上限
+	_, err = s.BatchRecognize(context.Background(), testUserID, uploadFiles(t, testPNG(t, 1), testPNG(t, 2), testPNG(t, 3), testPNG(t, 4)))
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrTooManyImages.Code, err.(*errcode.ErrCode).Code)
+}
+
+func TestAIService_RecognizeText(t *testing.T) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	s.client.Enqueue(ai.FakeReply{Result: &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(35), Merchant: "午饭", Category: "餐饮", BillType: 1,
+	}})
+	now := time.Date(2025, 3, 7, 12, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+	result, err := s.RecognizeText(context.Background(), testUserID, "午饭 35 微信", now)
+	require.NoError(t, err)
+	assert.Equal(t, "text-v2", result.PromptVersion)
+
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "RecognizeText", calls[0].Method)
+	assert.Equal(t, "午饭 35 微信", calls[0].Text)
+	assert.Contains(t, calls[0].Prompt, "2025-03-07T12:00:00+08:00")
+}
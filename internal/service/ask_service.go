@@ -0,0 +1,443 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// defaultAskTimezone 未指定时区时使用的时区
+const defaultAskTimezone = "Asia/Shanghai"
+
+// 查询计划的统计方式
+const (
+	askMetricTotal       = "total"
+	askMetricByCategory  = "by_category"
+	askMetricByDay       = "by_day"
+	askMetricByMonth     = "by_month"
+	askMetricUnsupported = "unsupported"
+)
+
+// 查询计划的取值方式
+const (
+	askPickAll = "all"
+	askPickMax = "max"
+	askPickMin = "min"
+	askPickAvg = "avg"
+)
+
+// 查询范围上限，限制单次提问触发的统计查询次数
+const (
+	askMaxYears        = 3  // 总额、按分类查询的最长年数
+	askMaxMonths       = 36 // 按月查询的最多月数
+	askMaxDays         = 92 // 按日查询的最多天数
+	askMaxCategoryDays = 31 // 限定分类按日查询的最多天数（每天单独查询一次）
+)
+
+// LedgerQueryPlanner 将自然语言问题转换为查询计划的AI能力（由 AIService 实现）
+type LedgerQueryPlanner interface {
+	PlanQuery(ctx context.Context, userID uint64, question string, now time.Time) (*dto.AIQueryPlan, error)
+}
+
+// AskService 自然语言查询账本服务
+// AI只负责把问题转换为查询计划，计划经校验后由已有的统计方法执行，回答和说明在本地生成
+type AskService struct {
+	planner      LedgerQueryPlanner // AI未启用时为 nil
+	billRepo     BillRepo
+	categoryRepo CategoryRepo
+}
+
+// NewAskService 创建自然语言查询账本服务，planner 为 nil 时提问返回AI服务不可用
+func NewAskService(planner LedgerQueryPlanner, billRepo BillRepo, categoryRepo CategoryRepo) *AskService {
+	return &AskService{
+		planner:      planner,
+		billRepo:     billRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// askQuery 校验后的查询
+type askQuery struct {
+	plan       dto.AIQueryPlan
+	billType   model.BillType
+	start, end time.Time       // 统计的起止时间，end 为结束日期当天的最后一秒
+	category   *model.Category // 限定的分类，为 nil 时不限分类
+}
+
+// Ask 回答关于账本的自然语言问题
+func (s *AskService) Ask(ctx context.Context, userID uint64, req *dto.AskLedgerRequest) (*dto.AskLedgerResponse, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, errcode.ErrParams.WithMessage("请输入问题")
+	}
+	if s.planner == nil {
+		return nil, errcode.ErrAIServiceUnavailable
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = defaultAskTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errcode.ErrParams.WithMessage("无效的时区：" + timezone)
+	}
+
+	plan, err := s.planner.PlanQuery(ctx, userID, question, time.Now().In(location))
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.validatePlan(ctx, userID, plan, location)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.execute(ctx, userID, q, req.ConfirmedOnly)
+	if err != nil {
+		logger.Log.Error("执行账本查询失败", zap.Uint64("user_id", userID), zap.Any("plan", q.plan), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	return buildAskResponse(question, q, rows, req.ConfirmedOnly), nil
+}
+
+// validatePlan 校验并规范化模型返回的查询计划
+func (s *AskService) validatePlan(ctx context.Context, userID uint64, plan *dto.AIQueryPlan, location *time.Location) (*askQuery, error) {
+	q := &askQuery{plan: *plan}
+	q.plan.Usage = nil
+
+	switch q.plan.Metric {
+	case askMetricTotal, askMetricByCategory, askMetricByDay, askMetricByMonth:
+	case askMetricUnsupported:
+		return nil, errcode.ErrAskUnanswerable
+	default:
+		logger.Log.Warn("查询计划的统计方式无效", zap.Uint64("user_id", userID), zap.String("metric", q.plan.Metric))
+		return nil, errcode.ErrAskUnanswerable
+	}
+	switch q.plan.Pick {
+	case askPickAll, askPickMax, askPickMin, askPickAvg:
+	case "":
+		q.plan.Pick = askPickAll
+	default:
+		return nil, errcode.ErrAskUnanswerable
+	}
+	if q.plan.Metric == askMetricTotal {
+		q.plan.Pick = askPickAll
+	}
+	if q.plan.BillType != int(model.BillTypeIncome) {
+		q.plan.BillType = int(model.BillTypeExpense)
+	}
+	q.billType = model.BillType(q.plan.BillType)
+
+	start, err := time.ParseInLocation("2006-01-02", q.plan.StartDate, location)
+	if err != nil {
+		return nil, errcode.ErrAskUnanswerable
+	}
+	end, err := time.ParseInLocation("2006-01-02", q.plan.EndDate, location)
+	if err != nil || end.Before(start) {
+		return nil, errcode.ErrAskUnanswerable
+	}
+	q.start, q.end = start, end.AddDate(0, 0, 1).Add(-time.Second)
+
+	if name := strings.TrimSpace(q.plan.Category); name != "" {
+		category, err := s.categoryRepo.GetByNameAndType(ctx, userID, name, model.CategoryType(q.billType))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.ErrAskUnanswerable.WithMessage(fmt.Sprintf("没有找到分类“%s”", name))
+		}
+		if err != nil {
+			logger.Log.Error("获取分类失败", zap.Uint64("user_id", userID), zap.Error(err))
+			return nil, errcode.ErrServer
+		}
+		q.category = category
+		q.plan.Category = category.Name
+	} else {
+		q.plan.Category = ""
+	}
+
+	days := int(end.Sub(start).Hours()/24) + 1
+	switch {
+	case q.plan.Metric == askMetricByDay && q.category != nil && days > askMaxCategoryDays:
+		return nil, errcode.ErrAskUnanswerable.WithMessage(fmt.Sprintf("限定分类按日查询最多支持%d天，请缩小时间范围", askMaxCategoryDays))
+	case q.plan.Metric == askMetricByDay && days > askMaxDays:
+		return nil, errcode.ErrAskUnanswerable.WithMessage(fmt.Sprintf("按日查询最多支持%d天，请缩小时间范围", askMaxDays))
+	case q.plan.Metric == askMetricByMonth && monthsBetween(start, end) > askMaxMonths:
+		return nil, errcode.ErrAskUnanswerable.WithMessage(fmt.Sprintf("按月查询最多支持%d个月，请缩小时间范围", askMaxMonths))
+	case !end.Before(start.AddDate(askMaxYears, 0, 0)):
+		return nil, errcode.ErrAskUnanswerable.WithMessage(fmt.Sprintf("查询范围最多支持%d年，请缩小时间范围", askMaxYears))
+	}
+	return q, nil
+}
+
+// execute 按查询计划调用统计方法，返回明细行；查询总额时返回一行汇总
+func (s *AskService) execute(ctx context.Context, userID uint64, q *askQuery, confirmedOnly bool) ([]dto.AskLedgerRow, error) {
+	switch q.plan.Metric {
+	case askMetricByCategory:
+		if q.category != nil {
+			return s.categoryRows(ctx, userID, q, q.start, q.end, confirmedOnly)
+		}
+		stats, err := s.billRepo.GetCategoryStats(ctx, userID, q.billType, q.start, q.end, confirmedOnly)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]dto.AskLedgerRow, len(stats))
+		for i, stat := range stats {
+			rows[i] = dto.AskLedgerRow{Label: categoryLabel(stat.CategoryName), Amount: stat.Amount}
+		}
+		return rows, nil
+
+	case askMetricByMonth:
+		return s.periodRows(ctx, userID, q, confirmedOnly, "2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) })
+
+	case askMetricByDay:
+		return s.periodRows(ctx, userID, q, confirmedOnly, "2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) })
+
+	default:
+		amount, err := s.amount(ctx, userID, q, q.start, q.end, confirmedOnly)
+		if err != nil {
+			return nil, err
+		}
+		return []dto.AskLedgerRow{{Label: q.plan.StartDate + "~" + q.plan.EndDate, Amount: amount}}, nil
+	}
+}
+
+// periodRows 按月或按日统计，没有账单的日期或月份金额为 0
+// 不限分类时使用日度、月度统计；限定分类时逐个周期统计该分类的金额
+func (s *AskService) periodRows(ctx context.Context, userID uint64, q *askQuery, confirmedOnly bool, layout string, next func(time.Time) time.Time) ([]dto.AskLedgerRow, error) {
+	amounts := make(map[string]decimal.Decimal)
+	if q.category == nil {
+		if layout == "2006-01" {
+			stats, err := s.billRepo.GetMonthlyStats(ctx, userID, q.start, q.end, confirmedOnly)
+			if err != nil {
+				return nil, err
+			}
+			for _, stat := range stats {
+				amounts[stat.GetLabel()] = pickBillType(q.billType, stat.Expense, stat.Income)
+			}
+		} else {
+			stats, err := s.billRepo.GetDailyStats(ctx, userID, q.start, q.end, confirmedOnly)
+			if err != nil {
+				return nil, err
+			}
+			for _, stat := range stats {
+				amounts[stat.GetLabel()] = pickBillType(q.billType, stat.Expense, stat.Income)
+			}
+		}
+	}
+
+	var rows []dto.AskLedgerRow
+	for periodStart := periodOf(q.start, layout); !periodStart.After(q.end); periodStart = next(periodStart) {
+		label := periodStart.Format(layout)
+		amount := amounts[label]
+		if q.category != nil {
+			start, end := maxTime(periodStart, q.start), minTime(next(periodStart).Add(-time.Second), q.end)
+			var err error
+			if amount, err = s.amount(ctx, userID, q, start, end, confirmedOnly); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, dto.AskLedgerRow{Label: label, Amount: amount})
+	}
+	return rows, nil
+}
+
+// amount 统计时间范围内的总金额，限定分类时只统计该分类（一级分类包含其二级分类）
+func (s *AskService) amount(ctx context.Context, userID uint64, q *askQuery, start, end time.Time, confirmedOnly bool) (decimal.Decimal, error) {
+	var rows []dto.AskLedgerRow
+	if q.category != nil {
+		var err error
+		if rows, err = s.categoryRows(ctx, userID, q, start, end, confirmedOnly); err != nil {
+			return decimal.Zero, err
+		}
+	} else {
+		stats, err := s.billRepo.GetCategoryStats(ctx, userID, q.billType, start, end, confirmedOnly)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		for _, stat := range stats {
+			rows = append(rows, dto.AskLedgerRow{Amount: stat.Amount})
+		}
+	}
+	total := decimal.Zero
+	for _, row := range rows {
+		total = total.Add(row.Amount)
+	}
+	return total, nil
+}
+
+// categoryRows 限定分类的明细：一级分类返回其二级分类（含直接记在一级分类下的账单），二级分类只返回自身
+func (s *AskService) categoryRows(ctx context.Context, userID uint64, q *askQuery, start, end time.Time, confirmedOnly bool) ([]dto.AskLedgerRow, error) {
+	parentID := q.category.ID
+	if q.category.ParentID != 0 {
+		parentID = q.category.ParentID
+	}
+	stats, err := s.billRepo.GetSecondaryCategoryStats(ctx, userID, q.billType, start, end, parentID, confirmedOnly)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]dto.AskLedgerRow, 0, len(stats))
+	for _, stat := range stats {
+		if q.category.ParentID != 0 && stat.CategoryID != q.category.ID {
+			continue
+		}
+		rows = append(rows, dto.AskLedgerRow{Label: categoryLabel(stat.CategoryName), Amount: stat.Amount})
+	}
+	return rows, nil
+}
+
+// buildAskResponse 根据取值方式生成回答和统计口径说明
+func buildAskResponse(question string, q *askQuery, rows []dto.AskLedgerRow, confirmedOnly bool) *dto.AskLedgerResponse {
+	resp := &dto.AskLedgerResponse{
+		Question:    question,
+		Plan:        q.plan,
+		Rows:        rows,
+		Explanation: askExplanation(q, confirmedOnly),
+	}
+	if q.plan.Metric == askMetricTotal || len(rows) == 0 {
+		resp.Rows = []dto.AskLedgerRow{}
+	}
+
+	subject := askPeriodLabel(q) + q.plan.Category + billTypeName(q.billType)
+	total := decimal.Zero
+	for _, row := range rows {
+		total = total.Add(row.Amount)
+	}
+	if total.IsZero() {
+		resp.Value = decimal.Zero
+		resp.Answer = fmt.Sprintf("%s没有%s记录", askPeriodLabel(q)+q.plan.Category, billTypeName(q.billType))
+		return resp
+	}
+
+	unit := map[string]string{askMetricByCategory: "分类", askMetricByDay: "一天", askMetricByMonth: "月份"}[q.plan.Metric]
+	switch q.plan.Pick {
+	case askPickMax, askPickMin:
+		picked := rows[0]
+		for _, row := range rows[1:] {
+			if (q.plan.Pick == askPickMax && row.Amount.GreaterThan(picked.Amount)) ||
+				(q.plan.Pick == askPickMin && row.Amount.LessThan(picked.Amount)) {
+				picked = row
+			}
+		}
+		word := "最多"
+		if q.plan.Pick == askPickMin {
+			word = "最少"
+		}
+		resp.Value, resp.Label = picked.Amount.Round(2), picked.Label
+		resp.Answer = fmt.Sprintf("%s%s的%s是%s，共 %s 元", subject, word, unit, picked.Label, resp.Value.StringFixed(2))
+	case askPickAvg:
+		per := map[string]string{askMetricByCategory: "个分类", askMetricByDay: "天", askMetricByMonth: "月"}[q.plan.Metric]
+		resp.Value = total.Div(decimal.NewFromInt(int64(len(rows)))).Round(2)
+		resp.Answer = fmt.Sprintf("%s平均每%s %s 元", subject, per, resp.Value.StringFixed(2))
+	default:
+		resp.Value = total.Round(2)
+		resp.Answer = fmt.Sprintf("%s共 %s 元", subject, resp.Value.StringFixed(2))
+	}
+	return resp
+}
+
+// askExplanation 说明实际执行的统计口径，便于用户核对AI对问题的理解
+func askExplanation(q *askQuery, confirmedOnly bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "统计 %s 至 %s 的%s账单", q.plan.StartDate, q.plan.EndDate, billTypeName(q.billType))
+	if q.category != nil {
+		if q.category.ParentID == 0 {
+			fmt.Fprintf(&b, "，限定分类“%s”（含二级分类）", q.category.Name)
+		} else {
+			fmt.Fprintf(&b, "，限定分类“%s”", q.category.Name)
+		}
+	}
+	switch q.plan.Metric {
+	case askMetricByCategory:
+		b.WriteString("，按分类汇总")
+	case askMetricByDay:
+		b.WriteString("，按日汇总")
+	case askMetricByMonth:
+		b.WriteString("，按月汇总")
+	}
+	switch q.plan.Pick {
+	case askPickMax:
+		b.WriteString("后取最大值")
+	case askPickMin:
+		b.WriteString("后取最小值")
+	case askPickAvg:
+		b.WriteString("后取平均值")
+	}
+	if confirmedOnly {
+		b.WriteString("；仅统计已确认账单")
+	}
+	return b.String()
+}
+
+// askPeriodLabel 时间范围的简短描述：整月为“2025-02”，整年为“2025年”，其余为起止日期
+func askPeriodLabel(q *askQuery) string {
+	start, end := q.start, q.end.Add(time.Second)
+	switch {
+	case start.Day() == 1 && end.Equal(start.AddDate(0, 1, 0)):
+		return start.Format("2006-01") + " "
+	case start.YearDay() == 1 && end.Equal(start.AddDate(1, 0, 0)):
+		return start.Format("2006") + "年"
+	case end.Equal(start.AddDate(0, 0, 1)):
+		return start.Format("2006-01-02") + " "
+	default:
+		return q.plan.StartDate + " 至 " + q.plan.EndDate + " "
+	}
+}
+
+// periodOf 时间所在月份或日期的起点
+func periodOf(t time.Time, layout string) time.Time {
+	if layout == "2006-01" {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// monthsBetween 起止日期跨越的月份数（含首尾月份）
+func monthsBetween(start, end time.Time) int {
+	return (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// pickBillType 按账单类型取支出或收入金额
+func pickBillType(billType model.BillType, expense, income decimal.Decimal) decimal.Decimal {
+	if billType == model.BillTypeIncome {
+		return income
+	}
+	return expense
+}
+
+// billTypeName 账单类型名称
+func billTypeName(billType model.BillType) string {
+	if billType == model.BillTypeIncome {
+		return "收入"
+	}
+	return "支出"
+}
+
+// categoryLabel 分类名称，未分类账单的分类名称为空
+func categoryLabel(name string) string {
+	if name == "" {
+		return "未分类"
+	}
+	return name
+}
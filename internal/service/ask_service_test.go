@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// newTestAskService 使用 AIService 作为查询计划来源的问答服务，并写入测试账单
+func newTestAskService(t *testing.T) (*AskService, *testAIService) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	location, err := time.LoadLocation(defaultAskTimezone)
+	require.NoError(t, err)
+
+	seed := []struct {
+		date       string
+		amount     int64
+		billType   model.BillType
+		categoryID uint64
+		userID     uint64
+	}{
+		{"2025-01-20", 300, model.BillTypeIncome, 5, testUserID},
+		{"2025-02-03", 30, model.BillTypeExpense, 1, testUserID},
+		{"2025-02-10", 20, model.BillTypeExpense, 2, testUserID},
+		{"2025-02-10", 15, model.BillTypeExpense, 3, testUserID},
+		{"2025-02-28", 8, model.BillTypeExpense, 0, testUserID},
+		{"2025-03-01", 100, model.BillTypeExpense, 1, testUserID},
+		{"2025-03-15", 40, model.BillTypeExpense, 3, testUserID},
+		{"2025-02-10", 999, model.BillTypeExpense, 6, 2},
+	}
+	for _, b := range seed {
+		payTime, err := time.ParseInLocation("2006-01-02 15:04", b.date+" 12:30", location)
+		require.NoError(t, err)
+		bill := &model.Bill{UserID: b.userID, Amount: decimal.NewFromInt(b.amount), BillType: b.billType, PayTime: payTime}
+		if b.categoryID != 0 {
+			bill.CategoryID = &b.categoryID
+		}
+		require.NoError(t, s.bills.Create(context.Background(), bill))
+	}
+	return NewAskService(s.AIService, s.bills, s.bills.categories), s
+}
+
+func TestAskService_Ask(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      dto.AIQueryPlan
+		wantValue string
+		wantLabel string
+		wantRows  []dto.AskLedgerRow
+		answer    string
+	}{
+		{
+			name:      "一级分类总额包含二级分类",
+			plan:      dto.AIQueryPlan{Metric: "total", BillType: 1, StartDate: "2025-02-01", EndDate: "2025-02-28", Category: "餐饮"},
+			wantValue: "50",
+			wantRows:  []dto.AskLedgerRow{},
+			answer:    "2025-02 餐饮支出共 50.00 元",
+		},
+		{
+			name:      "不限分类的总额包含未分类账单",
+			plan:      dto.AIQueryPlan{Metric: "total", BillType: 1, StartDate: "2025-02-01", EndDate: "2025-02-28"},
+			wantValue: "73",
+			wantRows:  []dto.AskLedgerRow{},
+			answer:    "2025-02 支出共 73.00 元",
+		},
+		{
+			name:      "限定分类按月取最大值，没有账单的月份为0",
+			plan:      dto.AIQueryPlan{Metric: "by_month", BillType: 1, StartDate: "2025-01-01", EndDate: "2025-12-31", Category: "交通", Pick: "max"},
+			wantValue: "40",
+			wantLabel: "2025-03",
+			answer:    "2025年交通支出最多的月份是2025-03，共 40.00 元",
+		},
+		{
+			name:      "按分类汇总",
+			plan:      dto.AIQueryPlan{Metric: "by_category", BillType: 1, StartDate: "2025-02-01", EndDate: "2025-02-28"},
+			wantValue: "73",
+			wantRows: []dto.AskLedgerRow{
+				{Label: "餐饮", Amount: decimal.NewFromInt(50)},
+				{Label: "交通", Amount: decimal.NewFromInt(15)},
+				{Label: "未分类", Amount: decimal.NewFromInt(8)},
+			},
+			answer: "2025-02 支出共 73.00 元",
+		},
+		{
+			name:      "二级分类按日取平均值",
+			plan:      dto.AIQueryPlan{Metric: "by_day", BillType: 1, StartDate: "2025-02-09", EndDate: "2025-02-12", Category: "咖啡饮品", Pick: "avg"},
+			wantValue: "5",
+			answer:    "2025-02-09 至 2025-02-12 咖啡饮品支出平均每天 5.00 元",
+		},
+		{
+			name:      "收入",
+			plan:      dto.AIQueryPlan{Metric: "total", BillType: 2, StartDate: "2025-01-01", EndDate: "2025-01-31"},
+			wantValue: "300",
+			wantRows:  []dto.AskLedgerRow{},
+			answer:    "2025-01 收入共 300.00 元",
+		},
+		{
+			name:      "没有记录",
+			plan:      dto.AIQueryPlan{Metric: "by_day", BillType: 1, StartDate: "2025-04-01", EndDate: "2025-04-01", Pick: "max"},
+			wantValue: "0",
+			answer:    "2025-04-01 没有支出记录",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, s := newTestAskService(t)
+			s.client.Enqueue(ai.FakeReply{Plan: &tt.plan})
+
+			resp, err := svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: " 上个月餐饮花了多少？ "})
+			require.NoError(t, err)
+			assert.Equal(t, "上个月餐饮花了多少？", resp.Question)
+			assert.True(t, resp.Value.Equal(decimal.RequireFromString(tt.wantValue)), "value = %s", resp.Value)
+			assert.Equal(t, tt.wantLabel, resp.Label)
+			assert.Equal(t, tt.answer, resp.Answer)
+			assert.NotEmpty(t, resp.Explanation)
+			if tt.wantRows != nil {
+				require.Len(t, resp.Rows, len(tt.wantRows))
+				for i, row := range tt.wantRows {
+					assert.Equal(t, row.Label, resp.Rows[i].Label)
+					assert.True(t, row.Amount.Equal(resp.Rows[i].Amount), "%s = %s", row.Label, resp.Rows[i].Amount)
+				}
+			}
+		})
+	}
+}
+
+func TestAskService_Ask_Prompt(t *testing.T) {
+	svc, s := newTestAskService(t)
+	s.client.Enqueue(ai.FakeReply{Plan: &dto.AIQueryPlan{
+		Metric: "by_month", BillType: 1, StartDate: "2025-01-01", EndDate: "2025-03-31", Category: "交通", Pick: "max",
+	}})
+
+	resp, err := svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: "今年哪个月交通最贵？", Timezone: "Asia/Shanghai"})
+	require.NoError(t, err)
+	assert.Equal(t, "统计 2025-01-01 至 2025-03-31 的支出账单，限定分类“交通”（含二级分类），按月汇总后取最大值", resp.Explanation)
+	assert.Equal(t, "交通", resp.Plan.Category)
+	require.Len(t, resp.Rows, 3)
+	assert.True(t, resp.Rows[0].Amount.IsZero())
+
+	// 问题作为用户消息，提示词包含用户分类和当前日期
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "PlanQuery", calls[0].Method)
+	assert.Equal(t, "今年哪个月交通最贵？", calls[0].Text)
+	assert.Contains(t, calls[0].Prompt, "- 餐饮：咖啡饮品")
+	assert.Contains(t, calls[0].Prompt, time.Now().In(time.FixedZone("CST", 8*3600)).Format("2006-01-02"))
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusSucceeded, s.usage.records[0].Status)
+}
+
+func TestAskService_Ask_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     dto.AIQueryPlan
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name:     "模型判断无法回答",
+			plan:     dto.AIQueryPlan{Metric: "unsupported"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+		},
+		{
+			name:     "未知的统计方式",
+			plan:     dto.AIQueryPlan{Metric: "sql", StartDate: "2025-01-01", EndDate: "2025-01-31"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+		},
+		{
+			name:     "日期无效",
+			plan:     dto.AIQueryPlan{Metric: "total", StartDate: "上个月", EndDate: "2025-01-31"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+		},
+		{
+			name:     "结束日期早于开始日期",
+			plan:     dto.AIQueryPlan{Metric: "total", StartDate: "2025-02-01", EndDate: "2025-01-31"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+		},
+		{
+			name:     "分类不存在",
+			plan:     dto.AIQueryPlan{Metric: "total", StartDate: "2025-01-01", EndDate: "2025-01-31", Category: "宠物"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+			wantMsg:  "没有找到分类“宠物”",
+		},
+		{
+			name:     "其他用户的分类",
+			plan:     dto.AIQueryPlan{Metric: "total", BillType: 2, StartDate: "2025-01-01", EndDate: "2025-01-31", Category: "交通"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+			wantMsg:  "没有找到分类“交通”",
+		},
+		{
+			name:     "按日查询范围过长",
+			plan:     dto.AIQueryPlan{Metric: "by_day", StartDate: "2025-01-01", EndDate: "2025-06-30"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+			wantMsg:  "按日查询最多支持92天，请缩小时间范围",
+		},
+		{
+			name:     "限定分类按日查询范围过长",
+			plan:     dto.AIQueryPlan{Metric: "by_day", StartDate: "2025-01-01", EndDate: "2025-02-28", Category: "餐饮"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+			wantMsg:  "限定分类按日查询最多支持31天，请缩小时间范围",
+		},
+		{
+			name:     "总额查询范围过长",
+			plan:     dto.AIQueryPlan{Metric: "total", StartDate: "2020-01-01", EndDate: "2025-01-01"},
+			wantCode: errcode.ErrAskUnanswerable.Code,
+			wantMsg:  "查询范围最多支持3年，请缩小时间范围",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, s := newTestAskService(t)
+			s.client.Enqueue(ai.FakeReply{Plan: &tt.plan})
+
+			_, err := svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: "问题"})
+			require.Error(t, err)
+			e, ok := err.(*errcode.ErrCode)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantCode, e.Code)
+			if tt.wantMsg != "" {
+				assert.Equal(t, tt.wantMsg, e.Message)
+			}
+		})
+	}
+}
+
+func TestAskService_Ask_Errors(t *testing.T) {
+	// AI未启用
+	svc := NewAskService(nil, nil, nil)
+	_, err := svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: "上个月花了多少"})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrAIServiceUnavailable.Code, err.(*errcode.ErrCode).Code)
+
+	svc, s := newTestAskService(t)
+	_, err = svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: "上个月花了多少", Timezone: "Mars/Olympus"})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrParams.Code, err.(*errcode.ErrCode).Code)
+	assert.Empty(t, s.client.Calls())
+
+	// 模型调用失败时记录失败用量
+	s.client.Enqueue(ai.FakeReply{Err: ai.ErrAllProvidersUnavailable})
+	_, err = svc.Ask(context.Background(), testUserID, &dto.AskLedgerRequest{Question: "上个月花了多少"})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrAIServiceUnavailable.Code, err.(*errcode.ErrCode).Code)
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusFailed, s.usage.records[0].Status)
+}
@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,21 +18,35 @@ import (
 	"smart-ledger-server/internal/model/dto"
 	"smart-ledger-server/internal/pkg/importer"
 	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/storage"
 	"smart-ledger-server/internal/repository"
 	"smart-ledger-server/pkg/errcode"
 )
 
+const (
+	// correctionMinHits 商户映射生效所需的最少修正次数
+	correctionMinHits = 2
+	// correctionMinShare 商户映射生效所需的修正占比
+	correctionMinShare = 0.75
+)
+
 // BillService 账单服务
 type BillService struct {
-	billRepo     BillRepo
-	categoryRepo CategoryRepo
+	billRepo       BillRepo
+	categoryRepo   CategoryRepo
+	correctionRepo CategoryCorrectionRepo
+	userRepo       UserRepo
+	storage        storage.Storage
 }
 
 // NewBillService 创建账单服务
-func NewBillService(billRepo BillRepo, categoryRepo CategoryRepo) *BillService {
+func NewBillService(billRepo BillRepo, categoryRepo CategoryRepo, correctionRepo CategoryCorrectionRepo, userRepo UserRepo, store storage.Storage) *BillService {
 	return &BillService{
-		billRepo:     billRepo,
-		categoryRepo: categoryRepo,
+		billRepo:       billRepo,
+		categoryRepo:   categoryRepo,
+		correctionRepo: correctionRepo,
+		userRepo:       userRepo,
+		storage:        store,
 	}
 }
 
@@ -52,17 +69,19 @@ func (s *BillService) Create(ctx context.Context, userID uint64, req *dto.Create
 	}
 
 	bill := &model.Bill{
-		UUID:       uuid.New().String(),
-		UserID:     userID,
-		Amount:     req.Amount,
-		BillType:   model.BillType(req.BillType),
-		Platform:   req.Platform,
-		Merchant:   req.Merchant,
-		CategoryID: categoryID,
-		PayTime:    req.PayTime,
-		PayMethod:  req.PayMethod,
-		OrderNo:    req.OrderNo,
-		Remark:     req.Remark,
+		UUID:        uuid.New().String(),
+		UserID:      userID,
+		Amount:      req.Amount,
+		BillType:    model.BillType(req.BillType),
+		Platform:    req.Platform,
+		Merchant:    req.Merchant,
+		CategoryID:  categoryID,
+		PayTime:     req.PayTime,
+		PayMethod:   req.PayMethod,
+		OrderNo:     req.OrderNo,
+		Remark:      req.Remark,
+		IsConfirmed: true, // 手动录入的账单无需复核
+		Items:       toBillItems(req.Items),
 	}
 
 	if err := s.billRepo.Create(ctx, bill); err != nil {
@@ -73,8 +92,354 @@ func (s *BillService) Create(ctx context.Context, userID uint64, req *dto.Create
 }
 
 // CreateFromAI 从AI识别结果创建账单
-func (s *BillService) CreateFromAI(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, imagePath string) (*dto.BillResponse, error) {
-	// 根据 AI 返回的 bill_type 确定账单类型和分类类型
+// imageHash 为截图内容摘要，用于识别后续的重复上传
+func (s *BillService) CreateFromAI(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, imagePath, imageHash string) (*dto.BillResponse, error) {
+	bill := s.newAIBill(ctx, userID, aiResult, s.autoConfirmThreshold(ctx, userID))
+	bill.ImagePath = imagePath
+	bill.ImageHash = imageHash
+
+	if err := s.billRepo.Create(ctx, bill); err != nil {
+		return nil, errcode.ErrBillCreateFailed
+	}
+
+	return s.GetByID(ctx, userID, bill.ID)
+}
+
+// CreateFromNotification 从支付通知/短信的解析结果创建账单
+// sourceHash 为通知原文摘要；同一条通知重复推送，或银行短信与支付平台通知描述同一笔交易时，返回已有账单并标记 duplicate
+func (s *BillService) CreateFromNotification(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, sourceHash string) (*dto.BillResponse, error) {
+	existing, err := s.billRepo.GetBySourceHash(ctx, userID, sourceHash)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errcode.ErrServer
+	}
+	bill := s.newAIBill(ctx, userID, aiResult, s.autoConfirmThreshold(ctx, userID))
+	bill.SourceHash = sourceHash
+	if existing == nil {
+		existing, err = s.billRepo.FindDuplicate(ctx, bill)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.ErrServer
+		}
+	}
+	if existing != nil {
+		resp, err := s.GetByID(ctx, userID, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Duplicate = true
+		return resp, nil
+	}
+
+	if err := s.billRepo.Create(ctx, bill); err != nil {
+		return nil, errcode.ErrBillCreateFailed
+	}
+	return s.GetByID(ctx, userID, bill.ID)
+}
+
+// CreateBatchFromAI 将多笔识别结果在同一事务中保存为账单
+// 与已有账单重复（订单号相同，或金额、类型相同且时间接近）的交易不再创建，返回已有账单并标记 duplicate
+func (s *BillService) CreateBatchFromAI(ctx context.Context, userID uint64, aiResults []dto.AIRecognizeResponse) (*dto.SaveAITransactionsResponse, error) {
+	threshold := s.autoConfirmThreshold(ctx, userID)
+	bills := make([]*model.Bill, len(aiResults))
+	for i := range aiResults {
+		bills[i] = s.newAIBill(ctx, userID, &aiResults[i], threshold)
+	}
+
+	duplicates, err := s.billRepo.CreateBatch(ctx, bills)
+	if err != nil {
+		logger.Log.Error("批量创建账单失败", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, errcode.ErrBillCreateFailed
+	}
+
+	resp := &dto.SaveAITransactionsResponse{Bills: make([]dto.BillResponse, len(bills))}
+	for i, bill := range bills {
+		id := bill.ID
+		if duplicates[i] != 0 {
+			id = duplicates[i]
+		}
+		billResp, err := s.GetByID(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if duplicates[i] != 0 {
+			billResp.Duplicate = true
+			resp.Duplicated++
+		} else {
+			resp.Created++
+		}
+		resp.Bills[i] = *billResp
+	}
+	return resp, nil
+}
+
+// MarkDuplicateTransactions 为多笔识别结果标记可能重复的已有账单（DuplicateBillID）
+func (s *BillService) MarkDuplicateTransactions(ctx context.Context, userID uint64, aiResults []dto.AIRecognizeResponse) error {
+	for i := range aiResults {
+		payTime, ok := parseAIPayTime(aiResults[i].PayTime)
+		if !ok && aiResults[i].OrderNo == "" {
+			// 既没有订单号也没有时间，无法判断是否重复
+			continue
+		}
+		billType := model.BillTypeExpense
+		if aiResults[i].BillType == 2 {
+			billType = model.BillTypeIncome
+		}
+
+		existing, err := s.billRepo.FindDuplicate(ctx, &model.Bill{
+			UserID:   userID,
+			Amount:   aiResults[i].Amount,
+			BillType: billType,
+			PayTime:  payTime,
+			OrderNo:  aiResults[i].OrderNo,
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return errcode.ErrServer
+		}
+		aiResults[i].DuplicateBillID = &existing.ID
+	}
+	return nil
+}
+
+// newAIBill 根据AI识别结果构建账单（不含截图信息）
+// 识别置信度不低于 threshold 时自动确认
+func (s *BillService) newAIBill(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, threshold float64) *model.Bill {
+	billType, category := s.aiBillCategory(ctx, userID, aiResult)
+	var categoryID *uint64
+	if category != nil {
+		categoryID = &category.ID
+	}
+
+	payTime, ok := parseAIPayTime(aiResult.PayTime)
+	if !ok {
+		payTime = time.Now()
+	}
+
+	return &model.Bill{
+		UUID:          uuid.New().String(),
+		UserID:        userID,
+		Amount:        aiResult.Amount,
+		BillType:      billType,
+		Platform:      aiResult.Platform,
+		Merchant:      aiResult.Merchant,
+		CategoryID:    categoryID,
+		PayTime:       payTime,
+		PayMethod:     aiResult.PayMethod,
+		OrderNo:       aiResult.OrderNo,
+		InvoiceNo:     aiResult.InvoiceNo,
+		Remark:        truncateRunes(aiResult.Remark, 500),
+		AIRawResponse: aiResult.RawContent,
+		Confidence:    aiResult.Confidence,
+		PromptVersion: aiResult.PromptVersion,
+		IsConfirmed:   threshold > 0 && aiResult.Confidence >= threshold,
+		Items:         aiItemsToBillItems(aiResult.Items),
+	}
+}
+
+// autoConfirmThreshold 获取用户设置的自动确认阈值
+// 阈值为 0 或读取用户设置失败时均不自动确认
+func (s *BillService) autoConfirmThreshold(ctx context.Context, userID uint64) float64 {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return 0
+	}
+	return user.AutoConfirmThreshold
+}
+
+// FindByImageHash 查找由同一截图生成的账单，不存在时返回 nil
+func (s *BillService) FindByImageHash(ctx context.Context, userID uint64, imageHash string) (*dto.BillResponse, error) {
+	if imageHash == "" {
+		return nil, nil
+	}
+	bill, err := s.billRepo.GetByImageHash(ctx, userID, imageHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errcode.ErrServer
+	}
+	return s.GetByID(ctx, userID, bill.ID)
+}
+
+// UpdateFromAI 对比账单与新的AI识别结果，apply 为 true 时将识别结果写回账单
+func (s *BillService) UpdateFromAI(ctx context.Context, userID, id uint64, aiResult *dto.AIRecognizeResponse, apply bool) (*dto.ReRecognizeResponse, error) {
+	bill, err := s.billRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.ErrBillNotFound
+		}
+		return nil, errcode.ErrServer
+	}
+
+	// 检查权限
+	if bill.UserID != userID {
+		return nil, errcode.ErrForbidden
+	}
+
+	billType, category := s.aiBillCategory(ctx, userID, aiResult)
+	payTime, hasPayTime := parseAIPayTime(aiResult.PayTime)
+	if !hasPayTime {
+		// 识别不到时间时保留原账单时间
+		payTime = bill.PayTime
+	}
+
+	// 计算字段差异
+	var changes []dto.FieldChange
+	addChange := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, dto.FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	addChange("amount", bill.Amount.String(), aiResult.Amount.String())
+	addChange("bill_type", strconv.Itoa(int(bill.BillType)), strconv.Itoa(int(billType)))
+	addChange("platform", bill.Platform, aiResult.Platform)
+	addChange("merchant", bill.Merchant, aiResult.Merchant)
+	addChange("category", categoryName(bill.Category), categoryName(category))
+	addChange("pay_time", bill.PayTime.Format(time.RFC3339), payTime.Format(time.RFC3339))
+	addChange("pay_method", bill.PayMethod, aiResult.PayMethod)
+	addChange("order_no", bill.OrderNo, aiResult.OrderNo)
+	addChange("invoice_no", bill.InvoiceNo, aiResult.InvoiceNo)
+
+	recognition := *aiResult
+	resp := &dto.ReRecognizeResponse{
+		Recognition: &recognition,
+		Changes:     changes,
+	}
+	if changes == nil {
+		resp.Changes = []dto.FieldChange{}
+	}
+
+	if apply {
+		bill.Amount = aiResult.Amount
+		bill.BillType = billType
+		bill.Platform = aiResult.Platform
+		bill.Merchant = aiResult.Merchant
+		bill.CategoryID = nil
+		if category != nil {
+			bill.CategoryID = &category.ID
+		}
+		bill.Category = nil
+		bill.PayTime = payTime
+		bill.PayMethod = aiResult.PayMethod
+		bill.OrderNo = aiResult.OrderNo
+		bill.InvoiceNo = aiResult.InvoiceNo
+		bill.AIRawResponse = aiResult.RawContent
+		bill.Confidence = aiResult.Confidence
+		bill.PromptVersion = aiResult.PromptVersion
+		// 重新识别后需要用户再次确认
+		bill.IsConfirmed = false
+
+		if err := s.billRepo.Update(ctx, bill); err != nil {
+			return nil, errcode.ErrBillUpdateFailed
+		}
+		if err := s.billRepo.ReplaceItems(ctx, id, aiItemsToBillItems(aiResult.Items)); err != nil {
+			return nil, errcode.ErrBillUpdateFailed
+		}
+		resp.Applied = true
+	}
+
+	resp.Bill, err = s.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// recordCorrection 记录用户对未确认AI账单的修正（商户 → 分类）
+// 修正记录仅用于改进后续识别，写入失败不影响账单更新
+func (s *BillService) recordCorrection(ctx context.Context, original, updated *model.Bill) {
+	if !isAIBill(original) || original.IsConfirmed || updated.CategoryID == nil {
+		return
+	}
+	categoryChanged := original.CategoryID == nil || *original.CategoryID != *updated.CategoryID
+	if !categoryChanged && original.Merchant == updated.Merchant && original.BillType == updated.BillType {
+		return
+	}
+
+	merchant := normalizeMerchant(updated.Merchant)
+	if merchant == "" {
+		return
+	}
+
+	err := s.correctionRepo.Record(ctx, &model.CategoryCorrection{
+		UserID:          updated.UserID,
+		Merchant:        merchant,
+		CategoryID:      *updated.CategoryID,
+		Platform:        updated.Platform,
+		BillType:        updated.BillType,
+		HitCount:        1,
+		LastCorrectedAt: time.Now(),
+	})
+	if err != nil {
+		logger.Log.Warn("记录分类修正失败", zap.Uint64("bill_id", updated.ID), zap.Error(err))
+	}
+}
+
+// aiBillCategory 确定AI识别结果对应的账单类型和分类
+// 根据 AI 返回的 bill_type 查找对应类型的分类，用户对该商户有稳定的修正记录时以修正结果为准
+func (s *BillService) aiBillCategory(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse) (model.BillType, *model.Category) {
+	if learned := s.learnedCategory(ctx, userID, aiResult.Merchant); learned != nil {
+		return model.BillType(learned.Type), learned
+	}
+	return s.resolveAICategory(ctx, userID, aiResult)
+}
+
+// learnedCategory 根据用户的修正记录查找商户对应的分类
+// 仅当某个分类的修正次数足够多且占绝对多数时才视为稳定映射，否则返回 nil
+func (s *BillService) learnedCategory(ctx context.Context, userID uint64, merchant string) *model.Category {
+	merchant = normalizeMerchant(merchant)
+	if merchant == "" {
+		return nil
+	}
+
+	corrections, err := s.correctionRepo.ListByMerchant(ctx, userID, merchant)
+	if err != nil || len(corrections) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, c := range corrections {
+		total += c.HitCount
+	}
+	top := corrections[0]
+	if top.HitCount < correctionMinHits || float64(top.HitCount) < float64(total)*correctionMinShare {
+		return nil
+	}
+
+	category, err := s.categoryRepo.GetByID(ctx, top.CategoryID)
+	if err != nil || category.UserID != userID {
+		return nil
+	}
+	return category
+}
+
+// ListCorrections 获取用户最常用的分类修正记录，用于构建识别提示词
+func (s *BillService) ListCorrections(ctx context.Context, userID uint64, limit int) ([]model.CategoryCorrection, error) {
+	corrections, err := s.correctionRepo.ListTop(ctx, userID, limit)
+	if err != nil {
+		return nil, errcode.ErrServer
+	}
+	return corrections, nil
+}
+
+// isAIBill 是否为AI识别创建的账单
+func isAIBill(bill *model.Bill) bool {
+	return bill.AIRawResponse != "" || bill.ImagePath != ""
+}
+
+// normalizeMerchant 规范化商户名称：去除首尾空白、合并连续空白并截断到 255 个字符
+func normalizeMerchant(merchant string) string {
+	merchant = strings.Join(strings.Fields(merchant), " ")
+	if runes := []rune(merchant); len(runes) > 255 {
+		merchant = string(runes[:255])
+	}
+	return merchant
+}
+
+// resolveAICategory 根据AI识别结果确定账单类型和分类
+// 优先匹配二级分类，其次匹配一级分类，找不到时返回 nil
+func (s *BillService) resolveAICategory(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse) (model.BillType, *model.Category) {
 	billType := model.BillTypeExpense
 	categoryType := model.CategoryTypeExpense
 	if aiResult.BillType == 2 {
@@ -83,50 +448,84 @@ func (s *BillService) CreateFromAI(ctx context.Context, userID uint64, aiResult
 	}
 
 	// 查找分类（按类型过滤）
-	var categoryID *uint64
 	if aiResult.SubCategory != "" {
 		category, err := s.categoryRepo.GetByNameAndType(ctx, userID, aiResult.SubCategory, categoryType)
 		if err == nil {
-			categoryID = &category.ID
+			return billType, category
 		}
 	}
-	if categoryID == nil && aiResult.Category != "" {
+	if aiResult.Category != "" {
 		category, err := s.categoryRepo.GetByNameAndType(ctx, userID, aiResult.Category, categoryType)
 		if err == nil {
-			categoryID = &category.ID
+			return billType, category
 		}
 	}
-	payTime := time.Now()
-	if aiResult.PayTime != "" {
-		local, _ := time.LoadLocation("Asia/Shanghai")
-		if parseTime, err := time.ParseInLocation(time.RFC3339, aiResult.PayTime, local); err == nil {
-			payTime = parseTime
-		} else {
-			logger.Log.Info("解析账单支付时间出现错误", zap.String("aiResult下的paytime", aiResult.PayTime), zap.Error(err))
-		}
+	return billType, nil
+}
+
+// parseAIPayTime 解析AI返回的支付时间，返回是否解析成功
+func parseAIPayTime(payTime string) (time.Time, bool) {
+	if payTime == "" {
+		return time.Time{}, false
+	}
+	local, _ := time.LoadLocation("Asia/Shanghai")
+	parseTime, err := time.ParseInLocation(time.RFC3339, payTime, local)
+	if err != nil {
+		logger.Log.Info("解析账单支付时间出现错误", zap.String("aiResult下的paytime", payTime), zap.Error(err))
+		return time.Time{}, false
 	}
+	return parseTime, true
+}
 
-	bill := &model.Bill{
-		UUID:        uuid.New().String(),
-		UserID:      userID,
-		Amount:      aiResult.Amount,
-		BillType:    billType,
-		Platform:    aiResult.Platform,
-		Merchant:    aiResult.Merchant,
-		CategoryID:  categoryID,
-		PayTime:     payTime,
-		PayMethod:   aiResult.PayMethod,
-		OrderNo:     aiResult.OrderNo,
-		ImagePath:   imagePath,
-		Confidence:  aiResult.Confidence,
-		IsConfirmed: false,
+// toBillItems 将明细请求转换为账单明细
+func toBillItems(reqs []dto.BillItemRequest) []model.BillItem {
+	items := make([]model.BillItem, 0, len(reqs))
+	for i, req := range reqs {
+		items = append(items, model.BillItem{
+			Name:      req.Name,
+			Price:     req.Price,
+			Quantity:  defaultQuantity(req.Quantity),
+			SortOrder: i,
+		})
+	}
+	return items
+}
+
+// aiItemsToBillItems 将AI识别的商品明细转换为账单明细，忽略没有名称的商品
+func aiItemsToBillItems(aiItems []dto.AIRecognizeItem) []model.BillItem {
+	items := make([]model.BillItem, 0, len(aiItems))
+	for _, aiItem := range aiItems {
+		name := strings.TrimSpace(aiItem.Name)
+		if name == "" {
+			continue
+		}
+		if len([]rune(name)) > 255 {
+			name = string([]rune(name)[:255])
+		}
+		items = append(items, model.BillItem{
+			Name:      name,
+			Price:     aiItem.Price,
+			Quantity:  defaultQuantity(aiItem.Quantity),
+			SortOrder: len(items),
+		})
 	}
+	return items
+}
 
-	if err := s.billRepo.Create(ctx, bill); err != nil {
-		return nil, errcode.ErrBillCreateFailed
+// defaultQuantity 数量未填写时默认为 1
+func defaultQuantity(quantity decimal.Decimal) decimal.Decimal {
+	if quantity.LessThanOrEqual(decimal.Zero) {
+		return decimal.NewFromInt(1)
 	}
+	return quantity
+}
 
-	return s.GetByID(ctx, userID, bill.ID)
+// categoryName 获取分类名称，分类为空时返回空字符串
+func categoryName(category *model.Category) string {
+	if category == nil {
+		return ""
+	}
+	return category.Name
 }
 
 // GetByID 获取账单详情
@@ -149,13 +548,30 @@ func (s *BillService) GetByID(ctx context.Context, userID, id uint64) (*dto.Bill
 
 // List 获取账单列表
 func (s *BillService) List(ctx context.Context, userID uint64, req *dto.BillListRequest) (*dto.BillListResponse, error) {
+	return s.list(ctx, req, s.buildBillQuery(userID, req))
+}
+
+// ListReview 获取待复核账单：未确认的账单按置信度从低到高排列
+func (s *BillService) ListReview(ctx context.Context, userID uint64, req *dto.BillListRequest) (*dto.BillListResponse, error) {
+	query := s.buildBillQuery(userID, req)
+	unconfirmed := false
+	query.IsConfirmed = &unconfirmed
+	query.OrderByConfidence = true
+	return s.list(ctx, req, query)
+}
+
+// buildBillQuery 将列表请求转换为查询条件
+func (s *BillService) buildBillQuery(userID uint64, req *dto.BillListRequest) *repository.BillQuery {
 	req.SetDefaults()
 
 	query := &repository.BillQuery{
-		UserID:   userID,
-		Page:     req.Page,
-		PageSize: req.PageSize,
-		Keyword:  req.Keyword,
+		UserID:        userID,
+		Page:          req.Page,
+		PageSize:      req.PageSize,
+		Keyword:       req.Keyword,
+		IsConfirmed:   req.IsConfirmed,
+		MinConfidence: req.MinConfidence,
+		MaxConfidence: req.MaxConfidence,
 	}
 
 	// 解析日期
@@ -180,7 +596,11 @@ func (s *BillService) List(ctx context.Context, userID uint64, req *dto.BillList
 	if req.BillType > 0 {
 		query.BillType = &req.BillType
 	}
+	return query
+}
 
+// list 按查询条件分页获取账单
+func (s *BillService) list(ctx context.Context, req *dto.BillListRequest, query *repository.BillQuery) (*dto.BillListResponse, error) {
 	bills, total, err := s.billRepo.List(ctx, query)
 	if err != nil {
 		return nil, errcode.ErrServer
@@ -199,6 +619,71 @@ func (s *BillService) List(ctx context.Context, userID uint64, req *dto.BillList
 	}, nil
 }
 
+// Review 批量复核未确认的账单
+// confirm 将账单标记为已确认；reject 视为识别错误，删除账单及其截图
+// 不存在、不属于当前用户或已确认的账单会被跳过
+func (s *BillService) Review(ctx context.Context, userID uint64, req *dto.ReviewBillsRequest) (*dto.ReviewBillsResponse, error) {
+	ids := uniqueIDs(req.IDs)
+	bills, err := s.billRepo.ListUnconfirmedByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, errcode.ErrServer
+	}
+
+	pending := make(map[uint64]bool, len(bills))
+	targets := make([]uint64, 0, len(bills))
+	for _, bill := range bills {
+		pending[bill.ID] = true
+		targets = append(targets, bill.ID)
+	}
+	resp := &dto.ReviewBillsResponse{Skipped: []uint64{}}
+	for _, id := range ids {
+		if !pending[id] {
+			resp.Skipped = append(resp.Skipped, id)
+		}
+	}
+	if len(targets) == 0 {
+		return resp, nil
+	}
+
+	if req.Action == "confirm" {
+		affected, err := s.billRepo.ConfirmByIDs(ctx, userID, targets)
+		if err != nil {
+			return nil, errcode.ErrBillUpdateFailed
+		}
+		resp.Affected = affected
+		return resp, nil
+	}
+
+	if err := s.billRepo.DeleteByIDs(ctx, targets); err != nil {
+		return nil, errcode.ErrBillDeleteFailed
+	}
+	resp.Affected = int64(len(targets))
+
+	// 删除账单关联的截图，失败不影响驳回结果
+	for _, bill := range bills {
+		if bill.ImagePath == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, bill.ImagePath); err != nil {
+			logger.Log.Warn("删除账单图片失败", zap.Uint64("bill_id", bill.ID), zap.String("image_path", bill.ImagePath), zap.Error(err))
+		}
+	}
+	return resp, nil
+}
+
+// uniqueIDs 去除重复ID并保持原有顺序
+func uniqueIDs(ids []uint64) []uint64 {
+	seen := make(map[uint64]bool, len(ids))
+	result := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 // Update 更新账单
 func (s *BillService) Update(ctx context.Context, userID, id uint64, req *dto.UpdateBillRequest) (*dto.BillResponse, error) {
 	bill, err := s.billRepo.GetByID(ctx, id)
@@ -214,6 +699,9 @@ func (s *BillService) Update(ctx context.Context, userID, id uint64, req *dto.Up
 		return nil, errcode.ErrForbidden
 	}
 
+	// 记录修改前的状态，用于学习用户对AI识别结果的修正
+	original := *bill
+
 	// 更新字段
 	if !req.Amount.IsZero() {
 		bill.Amount = req.Amount
@@ -264,6 +752,13 @@ func (s *BillService) Update(ctx context.Context, userID, id uint64, req *dto.Up
 	if err := s.billRepo.Update(ctx, bill); err != nil {
 		return nil, errcode.ErrBillUpdateFailed
 	}
+	if req.Items != nil {
+		if err := s.billRepo.ReplaceItems(ctx, id, toBillItems(*req.Items)); err != nil {
+			return nil, errcode.ErrBillUpdateFailed
+		}
+	}
+
+	s.recordCorrection(ctx, &original, bill)
 
 	return s.GetByID(ctx, userID, id)
 }
@@ -287,25 +782,75 @@ func (s *BillService) Delete(ctx context.Context, userID, id uint64) error {
 		return errcode.ErrBillDeleteFailed
 	}
 
+	// 删除账单关联的截图，失败不影响删除结果
+	if bill.ImagePath != "" {
+		if err := s.storage.Delete(ctx, bill.ImagePath); err != nil {
+			logger.Log.Warn("删除账单图片失败", zap.Uint64("bill_id", id), zap.String("image_path", bill.ImagePath), zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
+// GetImage 获取账单截图，调用方负责关闭返回的 ReadCloser
+func (s *BillService) GetImage(ctx context.Context, userID, id uint64) (io.ReadCloser, string, error) {
+	bill, err := s.billRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errcode.ErrBillNotFound
+		}
+		return nil, "", errcode.ErrServer
+	}
+
+	// 检查权限
+	if bill.UserID != userID {
+		return nil, "", errcode.ErrForbidden
+	}
+
+	if bill.ImagePath == "" {
+		return nil, "", errcode.ErrBillImageNotFound
+	}
+
+	reader, err := s.storage.Open(ctx, bill.ImagePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, "", errcode.ErrBillImageNotFound
+		}
+		logger.Log.Error("读取账单图片失败", zap.Uint64("bill_id", id), zap.Error(err))
+		return nil, "", errcode.ErrServer
+	}
+
+	return reader, storage.ContentTypeByKey(bill.ImagePath), nil
+}
+
 // toBillResponse 转换为账单响应
 func (s *BillService) toBillResponse(bill *model.Bill) *dto.BillResponse {
 	resp := &dto.BillResponse{
-		ID:          bill.ID,
-		UUID:        bill.UUID,
-		Amount:      bill.Amount,
-		BillType:    int(bill.BillType),
-		Platform:    bill.Platform,
-		Merchant:    bill.Merchant,
-		PayTime:     bill.PayTime,
-		PayMethod:   bill.PayMethod,
-		OrderNo:     bill.OrderNo,
-		Remark:      bill.Remark,
-		Confidence:  bill.Confidence,
-		IsConfirmed: bill.IsConfirmed,
-		CreatedAt:   bill.CreatedAt,
+		ID:            bill.ID,
+		UUID:          bill.UUID,
+		Amount:        bill.Amount,
+		BillType:      int(bill.BillType),
+		Platform:      bill.Platform,
+		Merchant:      bill.Merchant,
+		PayTime:       bill.PayTime,
+		PayMethod:     bill.PayMethod,
+		OrderNo:       bill.OrderNo,
+		InvoiceNo:     bill.InvoiceNo,
+		Remark:        bill.Remark,
+		HasImage:      bill.ImagePath != "",
+		Confidence:    bill.Confidence,
+		PromptVersion: bill.PromptVersion,
+		IsConfirmed:   bill.IsConfirmed,
+		Items:         make([]dto.BillItemResponse, len(bill.Items)),
+		CreatedAt:     bill.CreatedAt,
+	}
+	for i, item := range bill.Items {
+		resp.Items[i] = dto.BillItemResponse{
+			ID:       item.ID,
+			Name:     item.Name,
+			Price:    item.Price,
+			Quantity: item.Quantity,
+		}
 	}
 
 	if bill.Category != nil {
@@ -323,16 +868,51 @@ func (s *BillService) toBillResponse(bill *model.Bill) *dto.BillResponse {
 	return resp
 }
 
-func (s *BillService) ImportFromExcel(ctx context.Context, userID uint64, filePath, parserType string) (response *dto.BillImportResponse, err error) {
-	response = &dto.BillImportResponse{}
-	// 1. 创建解析器
-	parser, _ := importer.NewParser(importer.ParserTypeVivo)
-	parseResult, err := parser.Parse(filePath)
+// ListImportParsers 获取支持的账单导入来源
+func (s *BillService) ListImportParsers() []dto.ImportParserResponse {
+	parsers := importer.Parsers()
+	resp := make([]dto.ImportParserResponse, len(parsers))
+	for i, parser := range parsers {
+		resp[i] = dto.ImportParserResponse{
+			Type:    string(parser.Type),
+			Name:    parser.Name,
+			Formats: parser.Formats,
+		}
+	}
+	return resp
+}
+
+// Import 导入账单文件，parserType 为 auto 时根据表头自动识别账单来源
+func (s *BillService) Import(ctx context.Context, userID uint64, r io.Reader, filename, parserType string) (*dto.BillImportResponse, error) {
+	parseResult, err := importer.Parse(r, filename, importer.ParserType(parserType))
 	if err != nil {
-		return nil, err
+		if errors.Is(err, importer.ErrUnsupportedParser) || errors.Is(err, importer.ErrUnknownFormat) {
+			return nil, errcode.ErrImportUnsupported.WithMessage(err.Error())
+		}
+		return nil, errcode.ErrImportFileParse.WithMessage(err.Error())
+	}
+	return s.importRecords(ctx, userID, parseResult)
+}
+
+// ImportWithMapping 按用户自定义的列映射导入账单文件
+func (s *BillService) ImportWithMapping(ctx context.Context, userID uint64, r io.Reader, filename string, mapping importer.Mapping) (*dto.BillImportResponse, error) {
+	parseResult, err := importer.ParseWithMapping(r, filename, mapping)
+	if err != nil {
+		if errors.Is(err, importer.ErrInvalidMapping) {
+			return nil, errcode.ErrImportMappingInvalid.WithMessage(err.Error())
+		}
+		return nil, errcode.ErrImportFileParse.WithMessage(err.Error())
 	}
+	return s.importRecords(ctx, userID, parseResult)
+}
+
+// importRecords 将解析结果写入账单，有交易单号的记录按单号去重
+func (s *BillService) importRecords(ctx context.Context, userID uint64, parseResult *importer.ParseResult) (*dto.BillImportResponse, error) {
+	response := &dto.BillImportResponse{}
+	response.ParserType = string(parseResult.ParserType)
 	//获取解析成功的数据
 	records := parseResult.Records
+	response.Skipped = parseResult.Skipped
 	//处理解析阶段就失败的数据
 	for _, parseError := range parseResult.Errors {
 		response.Failed++
@@ -389,15 +969,31 @@ func (s *BillService) ImportFromExcel(ctx context.Context, userID uint64, filePa
 		if reocrd.BillType == 2 {
 			BillType = model.BillTypeIncome
 		}
+		//有交易单号时按单号去重，重复导入同一份账单不会产生重复记录
+		if reocrd.OrderNo != "" {
+			_, err := s.billRepo.FindDuplicate(ctx, &model.Bill{UserID: userID, BillType: BillType, OrderNo: reocrd.OrderNo})
+			if err == nil {
+				response.Duplicated++
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, pkgerrors.Wrap(err, "查询重复账单失败")
+			}
+		}
 		//创建账单
 		bill := &model.Bill{
-			UUID:       uuid.New().String(),
-			UserID:     userID,
-			Amount:     amount,
-			BillType:   BillType,
-			CategoryID: &categoryID,
-			PayTime:    reocrd.PayTime,
-			Merchant:   reocrd.Merchant,
+			UUID:        uuid.New().String(),
+			UserID:      userID,
+			Amount:      amount,
+			BillType:    BillType,
+			CategoryID:  &categoryID,
+			PayTime:     reocrd.PayTime,
+			Merchant:    reocrd.Merchant,
+			Platform:    reocrd.Platform,
+			PayMethod:   reocrd.PayMethod,
+			OrderNo:     reocrd.OrderNo,
+			Remark:      reocrd.Remark,
+			IsConfirmed: true, // 导入的账单来自用户自己的账单记录，无需复核
 		}
 		if err := s.billRepo.Create(ctx, bill); err != nil {
 			response.Failed++
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/storage"
+	"smart-ledger-server/pkg/errcode"
+)
+
+const testUserID = 1
+
+// testCategories 测试用户的分类，另有一个其他用户的同名分类
+func testCategories() []model.Category {
+	return []model.Category{
+		{BaseModel: model.BaseModel{ID: 1}, UserID: testUserID, Name: "餐饮", Type: model.CategoryTypeExpense},
+		{BaseModel: model.BaseModel{ID: 2}, UserID: testUserID, Name: "咖啡饮品", Type: model.CategoryTypeExpense, ParentID: 1},
+		{BaseModel: model.BaseModel{ID: 3}, UserID: testUserID, Name: "交通", Type: model.CategoryTypeExpense},
+		{BaseModel: model.BaseModel{ID: 4}, UserID: testUserID, Name: "其他", Type: model.CategoryTypeExpense},
+		{BaseModel: model.BaseModel{ID: 5}, UserID: testUserID, Name: "其他", Type: model.CategoryTypeIncome},
+		{BaseModel: model.BaseModel{ID: 6}, UserID: 2, Name: "交通", Type: model.CategoryTypeExpense},
+	}
+}
+
+func newTestBillService(corrections ...model.CategoryCorrection) (*BillService, *fakeBillRepo) {
+	categories := &fakeCategoryRepo{categories: testCategories()}
+	bills := newFakeBillRepo(categories)
+	users := &fakeUserRepo{users: map[uint64]*model.User{
+		testUserID: {BaseModel: model.BaseModel{ID: testUserID}, AutoConfirmThreshold: 0.9},
+	}}
+	return NewBillService(bills, categories, &fakeCorrectionRepo{corrections: corrections}, users, nil), bills
+}
+
+func TestBillService_CreateFromAI_CategoryResolution(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       dto.AIRecognizeResponse
+		wantCategory uint64 // 0 表示未分类
+		wantBillType int
+	}{
+		{
+			name:         "优先匹配二级分类",
+			result:       dto.AIRecognizeResponse{BillType: 1, Category: "餐饮", SubCategory: "咖啡饮品"},
+			wantCategory: 2,
+			wantBillType: 1,
+		},
+		{
+			name:         "二级分类不存在时匹配一级分类",
+			result:       dto.AIRecognizeResponse{BillType: 1, Category: "餐饮", SubCategory: "奶茶"},
+			wantCategory: 1,
+			wantBillType: 1,
+		},
+		{
+			name:         "按账单类型区分同名分类",
+			result:       dto.AIRecognizeResponse{BillType: 2, Category: "其他"},
+			wantCategory: 5,
+			wantBillType: 2,
+		},
+		{
+			name:         "类型不匹配时不使用其他类型的分类",
+			result:       dto.AIRecognizeResponse{BillType: 2, Category: "交通"},
+			wantBillType: 2,
+		},
+		{
+			name:         "分类不存在时未分类",
+			result:       dto.AIRecognizeResponse{BillType: 1, Category: "宠物"},
+			wantBillType: 1,
+		},
+		{
+			name:         "账单类型缺失时按支出处理",
+			result:       dto.AIRecognizeResponse{Category: "交通"},
+			wantCategory: 3,
+			wantBillType: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestBillService()
+			tt.result.Amount = decimal.NewFromInt(10)
+
+			bill, err := s.CreateFromAI(context.Background(), testUserID, &tt.result, "", "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBillType, bill.BillType)
+			if tt.wantCategory == 0 {
+				assert.Nil(t, bill.Category)
+				return
+			}
+			require.NotNil(t, bill.Category)
+			assert.Equal(t, tt.wantCategory, bill.Category.ID)
+		})
+	}
+}
+
+func TestBillService_CreateFromAI_LearnedCategory(t *testing.T) {
+	// 用户多次把“滴滴出行”改为交通，识别结果的分类被修正记录覆盖
+	s, _ := newTestBillService(
+		model.CategoryCorrection{UserID: testUserID, Merchant: "滴滴出行", CategoryID: 3, BillType: model.BillTypeExpense, HitCount: 3},
+	)
+	bill, err := s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(25), Merchant: "  滴滴出行 ", BillType: 1, Category: "其他",
+	}, "", "")
+	require.NoError(t, err)
+	require.NotNil(t, bill.Category)
+	assert.Equal(t, uint64(3), bill.Category.ID)
+
+	// 修正次数不足或没有占绝对多数时仍使用识别结果
+	s, _ = newTestBillService(
+		model.CategoryCorrection{UserID: testUserID, Merchant: "便利店", CategoryID: 1, HitCount: 2},
+		model.CategoryCorrection{UserID: testUserID, Merchant: "便利店", CategoryID: 4, HitCount: 2},
+	)
+	bill, err = s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(8), Merchant: "便利店", BillType: 1, Category: "其他",
+	}, "", "")
+	require.NoError(t, err)
+	require.NotNil(t, bill.Category)
+	assert.Equal(t, uint64(4), bill.Category.ID)
+
+	// 修正记录指向其他用户的分类时忽略
+	s, _ = newTestBillService(
+		model.CategoryCorrection{UserID: testUserID, Merchant: "地铁", CategoryID: 6, HitCount: 5},
+	)
+	bill, err = s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(4), Merchant: "地铁", BillType: 1,
+	}, "", "")
+	require.NoError(t, err)
+	assert.Nil(t, bill.Category)
+}
+
+func TestBillService_UpdateFromAI_LearnedCategory(t *testing.T) {
+	s, _ := newTestBillService(
+		model.CategoryCorrection{UserID: testUserID, Merchant: "滴滴出行", CategoryID: 3, BillType: model.BillTypeExpense, HitCount: 3},
+	)
+	ctx := context.Background()
+	bill, err := s.CreateFromAI(ctx, testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(25), Merchant: "出租车", BillType: 1, Category: "其他",
+	}, "", "")
+	require.NoError(t, err)
+	require.NotNil(t, bill.Category)
+	assert.Equal(t, uint64(4), bill.Category.ID)
+
+	// 重新识别出的商户有稳定的修正记录时，与新建账单一样以修正结果为准
+	resp, err := s.UpdateFromAI(ctx, testUserID, bill.ID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(25), Merchant: "滴滴出行", BillType: 1, Category: "其他",
+	}, true)
+	require.NoError(t, err)
+	assert.Contains(t, resp.Changes, dto.FieldChange{Field: "category", Old: "其他", New: "交通"})
+	require.NotNil(t, resp.Bill.Category)
+	assert.Equal(t, uint64(3), resp.Bill.Category.ID)
+}
+
+func TestBillService_CreateFromAI_Fields(t *testing.T) {
+	s, bills := newTestBillService()
+	bill, err := s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+		Amount:        decimal.RequireFromString("36.50"),
+		Merchant:      "瑞幸咖啡",
+		BillType:      1,
+		SubCategory:   "咖啡饮品",
+		PayTime:       "2025-03-07T08:30:00+08:00",
+		Confidence:    0.95,
+		Remark:        "可报销",
+		PromptVersion: "recognition-v2",
+		RawContent:    `{"merchant":"瑞幸咖啡"}`,
+		Items: []dto.AIRecognizeItem{
+			{Name: "拿铁", Price: decimal.RequireFromString("18.25"), Quantity: decimal.NewFromInt(2)},
+			{Name: " "},
+		},
+	}, "bills/1/a.png", "hash")
+	require.NoError(t, err)
+
+	assert.True(t, bill.IsConfirmed, "置信度不低于用户阈值时自动确认")
+	assert.True(t, bill.HasImage)
+	assert.Equal(t, "可报销", bill.Remark)
+	assert.Equal(t, "recognition-v2", bill.PromptVersion)
+	assert.Equal(t, "2025-03-07T08:30:00+08:00", bill.PayTime.Format("2006-01-02T15:04:05-07:00"))
+	require.Len(t, bill.Items, 1)
+	assert.Equal(t, "拿铁", bill.Items[0].Name)
+
+	saved, err := bills.GetByID(context.Background(), bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hash", saved.ImageHash)
+	assert.Equal(t, `{"merchant":"瑞幸咖啡"}`, saved.AIRawResponse)
+
+	// 低于阈值时需要复核
+	bill, err = s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(1), BillType: 1, Confidence: 0.5,
+	}, "", "")
+	require.NoError(t, err)
+	assert.False(t, bill.IsConfirmed)
+}
+
+func TestBillService_AutoConfirmThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  float64
+		confidence float64
+		want       bool
+	}{
+		{name: "置信度等于阈值时自动确认", threshold: 0.9, confidence: 0.9, want: true},
+		{name: "置信度略低于阈值时需要复核", threshold: 0.9, confidence: 0.89},
+		{name: "阈值为 0 时关闭自动确认", threshold: 0, confidence: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestBillService()
+			s.userRepo.(*fakeUserRepo).users[testUserID].AutoConfirmThreshold = tt.threshold
+
+			bill, err := s.CreateFromAI(context.Background(), testUserID, &dto.AIRecognizeResponse{
+				Amount: decimal.NewFromInt(10), BillType: 1, Confidence: tt.confidence,
+			}, "", "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, bill.IsConfirmed)
+		})
+	}
+}
+
+func TestBillService_ListReview(t *testing.T) {
+	s, bills := newTestBillService()
+	ctx := context.Background()
+	for _, confidence := range []float64{0.8, 0.95, 0.3, 0.6} {
+		_, err := s.CreateFromAI(ctx, testUserID, &dto.AIRecognizeResponse{
+			Amount: decimal.NewFromInt(10), BillType: 1, Confidence: confidence,
+		}, "", "")
+		require.NoError(t, err)
+	}
+	require.NoError(t, bills.Create(ctx, &model.Bill{UserID: 2, Amount: decimal.NewFromInt(1), Confidence: 0.1}))
+
+	// 只返回当前用户未确认的账单，置信度低的排在前面
+	resp, err := s.ListReview(ctx, testUserID, &dto.BillListRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.Total)
+	require.Len(t, resp.List, 3)
+	for i, want := range []float64{0.3, 0.6, 0.8} {
+		assert.Equal(t, want, resp.List[i].Confidence)
+		assert.False(t, resp.List[i].IsConfirmed)
+	}
+}
+
+func TestBillService_Review_Confirm(t *testing.T) {
+	s, bills := newTestBillService()
+	ctx := context.Background()
+	created := make([]uint64, 2)
+	for i := range created {
+		bill, err := s.CreateFromAI(ctx, testUserID, &dto.AIRecognizeResponse{
+			Amount: decimal.NewFromInt(10), Merchant: "滴滴出行", BillType: 1, Category: "其他", Confidence: 0.5,
+			RawContent: `{"merchant":"滴滴出行"}`,
+		}, "", "")
+		require.NoError(t, err)
+		created[i] = bill.ID
+	}
+	other := &model.Bill{UserID: 2, Amount: decimal.NewFromInt(1)}
+	require.NoError(t, bills.Create(ctx, other))
+
+	// 修改分类并确认，记录商户到分类的修正
+	transport := uint64(3)
+	confirmed := true
+	bill, err := s.Update(ctx, testUserID, created[0], &dto.UpdateBillRequest{CategoryID: &transport, IsConfirmed: &confirmed})
+	require.NoError(t, err)
+	assert.True(t, bill.IsConfirmed)
+	corrections := s.correctionRepo.(*fakeCorrectionRepo).corrections
+	require.Len(t, corrections, 1)
+	assert.Equal(t, "滴滴出行", corrections[0].Merchant)
+	assert.Equal(t, transport, corrections[0].CategoryID)
+
+	// 批量确认时跳过已确认和其他用户的账单
+	resp, err := s.Review(ctx, testUserID, &dto.ReviewBillsRequest{
+		IDs:    []uint64{created[0], created[1], created[1], other.ID, 999},
+		Action: "confirm",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Affected)
+	assert.Equal(t, []uint64{created[0], other.ID, 999}, resp.Skipped)
+	saved, err := bills.GetByID(ctx, created[1])
+	require.NoError(t, err)
+	assert.True(t, saved.IsConfirmed)
+
+	// 确认后不再修改修正记录
+	_, err = s.Update(ctx, testUserID, created[1], &dto.UpdateBillRequest{CategoryID: &transport})
+	require.NoError(t, err)
+	assert.Len(t, s.correctionRepo.(*fakeCorrectionRepo).corrections, 1)
+}
+
+func TestBillService_Review_RejectDeletesImage(t *testing.T) {
+	s, bills := newTestBillService()
+	ctx := context.Background()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	s.storage = store
+
+	imagePath := storage.BillImageKey(testUserID, "image/png")
+	require.NoError(t, store.Save(ctx, imagePath, []byte("png"), "image/png"))
+	bill, err := s.CreateFromAI(ctx, testUserID, &dto.AIRecognizeResponse{
+		Amount: decimal.NewFromInt(10), BillType: 1, Confidence: 0.5,
+	}, imagePath, "hash")
+	require.NoError(t, err)
+
+	resp, err := s.Review(ctx, testUserID, &dto.ReviewBillsRequest{IDs: []uint64{bill.ID}, Action: "reject"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Affected)
+	assert.Empty(t, resp.Skipped)
+
+	_, err = bills.GetByID(ctx, bill.ID)
+	assert.Error(t, err)
+	_, err = store.Open(ctx, imagePath)
+	assert.Error(t, err, "驳回后删除账单截图")
+}
+
+// importFile 打开测试文件并导入
+func importFile(t *testing.T, s *BillService, path, parserType string) (*dto.BillImportResponse, error) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	return s.Import(context.Background(), testUserID, file, filepath.Base(path), parserType)
+}
+
+func TestBillService_Import_WX(t *testing.T) {
+	s, bills := newTestBillService()
+	const file = "../pkg/importer/test_data/微信支付账单.csv"
+
+	resp, err := importFile(t, s, file, "wx")
+	require.NoError(t, err)
+	assert.Equal(t, "wx", resp.ParserType)
+	assert.Equal(t, 4, resp.Total)
+	assert.Equal(t, 1, resp.Failed)
+	assert.Equal(t, 5, resp.Skipped)
+	assert.Equal(t, 0, resp.Duplicated)
+
+	bill, err := bills.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "微信支付", bill.Platform)
+	assert.Equal(t, "零钱", bill.PayMethod)
+	assert.Equal(t, "4200001001202512150001", bill.OrderNo)
+	assert.Equal(t, "美团订单-外卖", bill.Remark)
+	require.NotNil(t, bill.Category)
+	assert.Equal(t, "未分类", bill.Category.Name)
+
+	// 再次导入同一份账单时按交易单号去重，自动识别账单来源
+	resp, err = importFile(t, s, file, "auto")
+	require.NoError(t, err)
+	assert.Equal(t, "wx", resp.ParserType)
+	assert.Equal(t, 0, resp.Total)
+	assert.Equal(t, 4, resp.Duplicated)
+}
+
+func TestBillService_Import_Errors(t *testing.T) {
+	s, _ := newTestBillService()
+
+	_, err := importFile(t, s, "../pkg/importer/test_data/微信支付账单.csv", "unknown")
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImportUnsupported.Code, err.(*errcode.ErrCode).Code)
+
+	_, err = s.Import(context.Background(), testUserID, strings.NewReader("日期,金额\n"), "账单.csv", "auto")
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImportUnsupported.Code, err.(*errcode.ErrCode).Code)
+
+	// 选择的来源与文件不符
+	_, err = importFile(t, s, "../pkg/importer/test_data/微信支付账单.csv", "ali")
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImportFileParse.Code, err.(*errcode.ErrCode).Code)
+	assert.Contains(t, err.(*errcode.ErrCode).Message, "未找到支付宝账单表头")
+}
+
+func TestBillService_ListImportParsers(t *testing.T) {
+	s, _ := newTestBillService()
+	parsers := s.ListImportParsers()
+	types := make([]string, len(parsers))
+	for i, parser := range parsers {
+		types[i] = parser.Type
+	}
+	assert.ElementsMatch(t, []string{"vivo", "wx", "ali"}, types)
+}
@@ -0,0 +1,751 @@
+package service
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/repository"
+)
+
+// 内存中的仓库替身，只实现测试用到的方法，调用未实现的方法会 panic
+
+func TestMain(m *testing.M) {
+	logger.Log = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeUserRepo 用户仓库替身
+type fakeUserRepo struct {
+	UserRepo
+	users map[uint64]*model.User
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// fakeCategoryRepo 分类仓库替身
+type fakeCategoryRepo struct {
+	CategoryRepo
+	categories []model.Category
+}
+
+func (r *fakeCategoryRepo) GetByID(ctx context.Context, id uint64) (*model.Category, error) {
+	for _, c := range r.categories {
+		if c.ID == id {
+			copied := c
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeCategoryRepo) GetByNameAndType(ctx context.Context, userID uint64, name string, categoryType model.CategoryType) (*model.Category, error) {
+	for _, c := range r.categories {
+		if c.UserID == userID && c.Name == name && c.Type == categoryType {
+			copied := c
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeCategoryRepo) GetAll(ctx context.Context, userID uint64) ([]model.Category, error) {
+	var categories []model.Category
+	for _, c := range r.categories {
+		if c.UserID == userID {
+			categories = append(categories, c)
+		}
+	}
+	return categories, nil
+}
+
+func (r *fakeCategoryRepo) Create(ctx context.Context, category *model.Category) error {
+	category.ID = uint64(len(r.categories) + 1)
+	r.categories = append(r.categories, *category)
+	return nil
+}
+
+func (r *fakeCategoryRepo) GetWithChildren(ctx context.Context, userID uint64) ([]model.Category, error) {
+	var parents []model.Category
+	for _, c := range r.categories {
+		if c.UserID != userID || c.ParentID != 0 {
+			continue
+		}
+		for _, child := range r.categories {
+			if child.UserID == userID && child.ParentID == c.ID {
+				c.Children = append(c.Children, child)
+			}
+		}
+		parents = append(parents, c)
+	}
+	return parents, nil
+}
+
+// fakeBillRepo 账单仓库替身，读取时按 categories 关联分类
+type fakeBillRepo struct {
+	BillRepo
+	categories *fakeCategoryRepo
+
+	mu     sync.Mutex
+	bills  map[uint64]*model.Bill
+	nextID uint64
+}
+
+func newFakeBillRepo(categories *fakeCategoryRepo) *fakeBillRepo {
+	return &fakeBillRepo{categories: categories, bills: make(map[uint64]*model.Bill)}
+}
+
+func (r *fakeBillRepo) Create(ctx context.Context, bill *model.Bill) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	bill.ID = r.nextID
+	bill.CreatedAt = time.Now()
+	copied := *bill
+	r.bills[bill.ID] = &copied
+	return nil
+}
+
+func (r *fakeBillRepo) GetByID(ctx context.Context, id uint64) (*model.Bill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bill, ok := r.bills[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *bill
+	if copied.CategoryID != nil {
+		copied.Category, _ = r.categories.GetByID(ctx, *copied.CategoryID)
+	}
+	return &copied, nil
+}
+
+func (r *fakeBillRepo) GetByImageHash(ctx context.Context, userID uint64, imageHash string) (*model.Bill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := uint64(1); id <= r.nextID; id++ {
+		if bill, ok := r.bills[id]; ok && bill.UserID == userID && bill.ImageHash == imageHash {
+			copied := *bill
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// FindDuplicate 有订单号时按订单号查找，否则按金额和支付时间前后一分钟查找
+func (r *fakeBillRepo) FindDuplicate(ctx context.Context, bill *model.Bill) (*model.Bill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := uint64(1); id <= r.nextID; id++ {
+		existing, ok := r.bills[id]
+		if !ok || existing.UserID != bill.UserID || existing.BillType != bill.BillType {
+			continue
+		}
+		if bill.OrderNo != "" && existing.OrderNo == bill.OrderNo ||
+			bill.OrderNo == "" && existing.Amount.Equal(bill.Amount) && existing.PayTime.Sub(bill.PayTime).Abs() <= time.Minute {
+			copied := *existing
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeBillRepo) Update(ctx context.Context, bill *model.Bill) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.bills[bill.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	copied := *bill
+	r.bills[bill.ID] = &copied
+	return nil
+}
+
+func (r *fakeBillRepo) ReplaceItems(ctx context.Context, billID uint64, items []model.BillItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bill, ok := r.bills[billID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	bill.Items = append([]model.BillItem(nil), items...)
+	return nil
+}
+
+// List 只支持按确认状态筛选，按置信度升序或支付时间倒序排列
+func (r *fakeBillRepo) List(ctx context.Context, query *repository.BillQuery) ([]model.Bill, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var bills []model.Bill
+	for id := uint64(1); id <= r.nextID; id++ {
+		bill, ok := r.bills[id]
+		if !ok || bill.UserID != query.UserID || query.IsConfirmed != nil && bill.IsConfirmed != *query.IsConfirmed {
+			continue
+		}
+		bills = append(bills, *bill)
+	}
+	sort.SliceStable(bills, func(i, j int) bool {
+		if query.OrderByConfidence && bills[i].Confidence != bills[j].Confidence {
+			return bills[i].Confidence < bills[j].Confidence
+		}
+		return bills[i].PayTime.After(bills[j].PayTime)
+	})
+	total := int64(len(bills))
+	offset := min((query.Page-1)*query.PageSize, len(bills))
+	return bills[offset:min(offset+query.PageSize, len(bills))], total, nil
+}
+
+func (r *fakeBillRepo) ListUnconfirmedByIDs(ctx context.Context, userID uint64, ids []uint64) ([]model.Bill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var bills []model.Bill
+	for _, id := range ids {
+		if bill, ok := r.bills[id]; ok && bill.UserID == userID && !bill.IsConfirmed {
+			bills = append(bills, *bill)
+		}
+	}
+	return bills, nil
+}
+
+func (r *fakeBillRepo) ConfirmByIDs(ctx context.Context, userID uint64, ids []uint64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var affected int64
+	for _, id := range ids {
+		if bill, ok := r.bills[id]; ok && bill.UserID == userID && !bill.IsConfirmed {
+			bill.IsConfirmed = true
+			affected++
+		}
+	}
+	return affected, nil
+}
+
+func (r *fakeBillRepo) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.bills, id)
+	}
+	return nil
+}
+
+// GetStatsSummary 汇总收支和账单数
+func (r *fakeBillRepo) GetStatsSummary(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) (*repository.StatsSummary, error) {
+	summary := &repository.StatsSummary{}
+	for _, bill := range r.matching(userID, startDate, endDate, confirmedOnly) {
+		if bill.BillType == model.BillTypeIncome {
+			summary.TotalIncome = summary.TotalIncome.Add(bill.Amount)
+		} else {
+			summary.TotalExpense = summary.TotalExpense.Add(bill.Amount)
+		}
+		summary.BillCount++
+	}
+	return summary, nil
+}
+
+// GetCategoryStats 按一级分类汇总，未分类账单的分类为空
+func (r *fakeBillRepo) GetCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, confirmedOnly bool) ([]repository.CategoryStats, error) {
+	return r.categoryStats(userID, billType, startDate, endDate, confirmedOnly, func(c *model.Category) (*model.Category, bool) {
+		if c != nil && c.ParentID != 0 {
+			c, _ = r.categories.GetByID(ctx, c.ParentID)
+		}
+		return c, true
+	}), nil
+}
+
+// GetSecondaryCategoryStats 按二级分类汇总指定一级分类及其二级分类的账单
+func (r *fakeBillRepo) GetSecondaryCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, categoryID uint64, confirmedOnly bool) ([]repository.CategoryStats, error) {
+	return r.categoryStats(userID, billType, startDate, endDate, confirmedOnly, func(c *model.Category) (*model.Category, bool) {
+		return c, c != nil && (c.ID == categoryID || c.ParentID == categoryID)
+	}), nil
+}
+
+// GetDailyStats 按日汇总
+func (r *fakeBillRepo) GetDailyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]repository.DailyStats, error) {
+	var stats []repository.DailyStats
+	for _, period := range r.periodStats(userID, startDate, endDate, confirmedOnly, "2006-01-02") {
+		date, _ := time.Parse("2006-01-02", period.Month)
+		stats = append(stats, repository.DailyStats{Date: date, Expense: period.Expense, Income: period.Income})
+	}
+	return stats, nil
+}
+
+// GetMonthlyStats 按月汇总
+func (r *fakeBillRepo) GetMonthlyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]repository.MonthlyStats, error) {
+	return r.periodStats(userID, startDate, endDate, confirmedOnly, "2006-01"), nil
+}
+
+// GetMerchantStats 按商户汇总，按金额降序排列
+func (r *fakeBillRepo) GetMerchantStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, limit int, confirmedOnly bool) ([]repository.MerchantStats, error) {
+	var stats []repository.MerchantStats
+	index := make(map[string]int)
+	for _, bill := range r.matching(userID, startDate, endDate, confirmedOnly) {
+		if bill.BillType != billType || bill.Merchant == "" {
+			continue
+		}
+		i, exists := index[bill.Merchant]
+		if !exists {
+			i = len(stats)
+			index[bill.Merchant] = i
+			stats = append(stats, repository.MerchantStats{Merchant: bill.Merchant})
+		}
+		stats[i].Amount = stats[i].Amount.Add(bill.Amount)
+		stats[i].Count++
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Amount.GreaterThan(stats[j].Amount) })
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// ListUserIDsWithBills 时间范围内有账单的用户，按ID升序排列
+func (r *fakeBillRepo) ListUserIDsWithBills(ctx context.Context, startDate, endDate time.Time) ([]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[uint64]bool)
+	var ids []uint64
+	for _, bill := range r.bills {
+		if !seen[bill.UserID] && !bill.PayTime.Before(startDate) && !bill.PayTime.After(endDate) {
+			seen[bill.UserID] = true
+			ids = append(ids, bill.UserID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// matching 获取用户在时间范围内的账单
+func (r *fakeBillRepo) matching(userID uint64, startDate, endDate time.Time, confirmedOnly bool) []model.Bill {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var bills []model.Bill
+	for id := uint64(1); id <= r.nextID; id++ {
+		bill, ok := r.bills[id]
+		if !ok || bill.UserID != userID || bill.PayTime.Before(startDate) || bill.PayTime.After(endDate) ||
+			(confirmedOnly && !bill.IsConfirmed) {
+			continue
+		}
+		bills = append(bills, *bill)
+	}
+	return bills
+}
+
+// categoryStats 按 group 返回的分类汇总金额，按金额降序排列
+func (r *fakeBillRepo) categoryStats(userID uint64, billType model.BillType, startDate, endDate time.Time, confirmedOnly bool, group func(*model.Category) (*model.Category, bool)) []repository.CategoryStats {
+	var stats []repository.CategoryStats
+	index := make(map[uint64]int)
+	for _, bill := range r.matching(userID, startDate, endDate, confirmedOnly) {
+		if bill.BillType != billType {
+			continue
+		}
+		var category *model.Category
+		if bill.CategoryID != nil {
+			category, _ = r.categories.GetByID(context.Background(), *bill.CategoryID)
+		}
+		category, ok := group(category)
+		if !ok {
+			continue
+		}
+		stat := repository.CategoryStats{}
+		if category != nil {
+			stat.CategoryID, stat.CategoryName = category.ID, category.Name
+		}
+		i, exists := index[stat.CategoryID]
+		if !exists {
+			i = len(stats)
+			index[stat.CategoryID] = i
+			stats = append(stats, stat)
+		}
+		stats[i].Amount = stats[i].Amount.Add(bill.Amount)
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Amount.GreaterThan(stats[j].Amount) })
+	return stats
+}
+
+// periodStats 按 layout 格式化的支付时间汇总收支，按时间升序排列
+func (r *fakeBillRepo) periodStats(userID uint64, startDate, endDate time.Time, confirmedOnly bool, layout string) []repository.MonthlyStats {
+	var stats []repository.MonthlyStats
+	index := make(map[string]int)
+	for _, bill := range r.matching(userID, startDate, endDate, confirmedOnly) {
+		label := bill.PayTime.Format(layout)
+		i, exists := index[label]
+		if !exists {
+			i = len(stats)
+			index[label] = i
+			stats = append(stats, repository.MonthlyStats{Month: label, Expense: decimal.Zero, Income: decimal.Zero})
+		}
+		if bill.BillType == model.BillTypeIncome {
+			stats[i].Income = stats[i].Income.Add(bill.Amount)
+		} else {
+			stats[i].Expense = stats[i].Expense.Add(bill.Amount)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Month < stats[j].Month })
+	return stats
+}
+
+// fakeCorrectionRepo 分类修正记录仓库替身，记录按修正次数降序排列
+type fakeCorrectionRepo struct {
+	CategoryCorrectionRepo
+	corrections []model.CategoryCorrection
+}
+
+func (r *fakeCorrectionRepo) Record(ctx context.Context, correction *model.CategoryCorrection) error {
+	r.corrections = append(r.corrections, *correction)
+	return nil
+}
+
+func (r *fakeCorrectionRepo) ListByMerchant(ctx context.Context, userID uint64, merchant string) ([]model.CategoryCorrection, error) {
+	var result []model.CategoryCorrection
+	for _, c := range r.corrections {
+		if c.UserID == userID && c.Merchant == merchant {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeCorrectionRepo) ListTop(ctx context.Context, userID uint64, limit int) ([]model.CategoryCorrection, error) {
+	var result []model.CategoryCorrection
+	for _, c := range r.corrections {
+		if c.UserID == userID && len(result) < limit {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// fakeUsageRepo AI用量仓库替身
+type fakeUsageRepo struct {
+	mu      sync.Mutex
+	records []model.AIUsageRecord
+}
+
+func (r *fakeUsageRepo) Create(ctx context.Context, record *model.AIUsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.create(record)
+	return nil
+}
+
+func (r *fakeUsageRepo) create(record *model.AIUsageRecord) {
+	record.ID = uint64(len(r.records) + 1)
+	record.CreatedAt = time.Now()
+	r.records = append(r.records, *record)
+}
+
+func (r *fakeUsageRepo) Reserve(ctx context.Context, userID uint64, n int, limits []repository.QuotaLimit) ([]model.AIUsageRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, limit := range limits {
+		used := r.summarize(userID, limit.Since).Calls
+		if used+int64(n) > int64(limit.Limit) {
+			return nil, &repository.QuotaExceededError{QuotaLimit: limit, Used: used}
+		}
+	}
+	records := make([]model.AIUsageRecord, n)
+	for i := range records {
+		records[i] = model.AIUsageRecord{UserID: userID, Status: model.UsageStatusReserved, Source: model.UsageSourceUser}
+		r.create(&records[i])
+	}
+	return records, nil
+}
+
+func (r *fakeUsageRepo) Finish(ctx context.Context, record *model.AIUsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.records {
+		if r.records[i].ID == record.ID {
+			r.records[i] = *record
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeUsageRepo) Release(ctx context.Context, ids []uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := r.records[:0]
+	for _, record := range r.records {
+		if record.Status != model.UsageStatusReserved || !slices.Contains(ids, record.ID) {
+			records = append(records, record)
+		}
+	}
+	r.records = records
+	return nil
+}
+
+func (r *fakeUsageRepo) Summarize(ctx context.Context, userID uint64, since time.Time) (*repository.UsageSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summarize(userID, since), nil
+}
+
+func (r *fakeUsageRepo) summarize(userID uint64, since time.Time) *repository.UsageSummary {
+	summary := &repository.UsageSummary{}
+	for _, record := range r.records {
+		if record.UserID != userID || record.Source == model.UsageSourceSystem || record.Cached || record.CreatedAt.Before(since) ||
+			record.Status == model.UsageStatusFailed {
+			continue
+		}
+		summary.Calls++
+		summary.PromptTokens += record.PromptTokens
+		summary.CompletionTokens += record.CompletionTokens
+	}
+	return summary
+}
+
+// fakeInsightRepo 月度消费洞察仓库替身
+type fakeInsightRepo struct {
+	mu       sync.Mutex
+	insights []model.MonthlyInsight
+}
+
+func (r *fakeInsightRepo) Save(ctx context.Context, insight *model.MonthlyInsight) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	insight.UpdatedAt = time.Now()
+	for i, existing := range r.insights {
+		if existing.UserID == insight.UserID && existing.Month == insight.Month {
+			r.insights[i] = *insight
+			return nil
+		}
+	}
+	insight.ID = uint64(len(r.insights) + 1)
+	r.insights = append(r.insights, *insight)
+	return nil
+}
+
+func (r *fakeInsightRepo) GetByUserAndMonth(ctx context.Context, userID uint64, month string) (*model.MonthlyInsight, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, insight := range r.insights {
+		if insight.UserID == userID && insight.Month == month {
+			copied := insight
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeInsightRepo) ListUserIDsByMonth(ctx context.Context, month string) ([]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint64
+	for _, insight := range r.insights {
+		if insight.Month == month {
+			ids = append(ids, insight.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// fakeImportProfileRepo 账单导入配置仓库替身
+type fakeImportProfileRepo struct {
+	profiles []model.ImportProfile
+	nextID   uint64
+}
+
+func (r *fakeImportProfileRepo) Create(ctx context.Context, profile *model.ImportProfile) error {
+	r.nextID++
+	profile.ID = r.nextID
+	r.profiles = append(r.profiles, *profile)
+	return nil
+}
+
+func (r *fakeImportProfileRepo) GetByID(ctx context.Context, id uint64) (*model.ImportProfile, error) {
+	for _, profile := range r.profiles {
+		if profile.ID == id {
+			copied := profile
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeImportProfileRepo) ListByUser(ctx context.Context, userID uint64) ([]model.ImportProfile, error) {
+	var profiles []model.ImportProfile
+	for _, profile := range r.profiles {
+		if profile.UserID == userID {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+func (r *fakeImportProfileRepo) ExistsByName(ctx context.Context, userID uint64, name string) (bool, error) {
+	for _, profile := range r.profiles {
+		if profile.UserID == userID && profile.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeImportProfileRepo) Update(ctx context.Context, profile *model.ImportProfile) error {
+	for i, existing := range r.profiles {
+		if existing.ID == profile.ID {
+			r.profiles[i] = *profile
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeImportProfileRepo) Delete(ctx context.Context, id uint64) error {
+	for i, profile := range r.profiles {
+		if profile.ID == id {
+			r.profiles = append(r.profiles[:i], r.profiles[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// fakeJobRepo 异步识别任务仓库替身，读写时复制任务和图片，模拟持久化
+type fakeJobRepo struct {
+	mu     sync.Mutex
+	jobs   map[uint64]*model.RecognitionJob
+	nextID uint64
+}
+
+func newFakeJobRepo() *fakeJobRepo {
+	return &fakeJobRepo{jobs: make(map[uint64]*model.RecognitionJob)}
+}
+
+func (r *fakeJobRepo) Create(ctx context.Context, job *model.RecognitionJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	job.ID = r.nextID
+	job.CreatedAt = time.Now()
+	for i := range job.Items {
+		r.nextID++
+		job.Items[i].ID = r.nextID
+		job.Items[i].JobID = job.ID
+	}
+	r.jobs[job.ID] = copyJob(job)
+	return nil
+}
+
+func (r *fakeJobRepo) GetByID(ctx context.Context, id uint64) (*model.RecognitionJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return copyJob(job), nil
+}
+
+func (r *fakeJobRepo) GetByUUID(ctx context.Context, uuid string) (*model.RecognitionJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.jobs {
+		if job.UUID == uuid {
+			return copyJob(job), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeJobRepo) ListIDsByStatus(ctx context.Context, statuses ...model.JobStatus) ([]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint64
+	for id, job := range r.jobs {
+		if slices.Contains(statuses, job.Status) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *fakeJobRepo) Claim(ctx context.Context, id uint64, owner string, now, staleBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return false, nil
+	}
+	stale := job.Status == model.JobStatusRunning && (job.HeartbeatAt == nil || job.HeartbeatAt.Before(staleBefore))
+	if job.Status != model.JobStatusPending && !stale {
+		return false, nil
+	}
+	job.Status = model.JobStatusRunning
+	job.ClaimedBy = owner
+	job.HeartbeatAt = &now
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	return true, nil
+}
+
+func (r *fakeJobRepo) Heartbeat(ctx context.Context, id uint64, owner string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.Status != model.JobStatusRunning || job.ClaimedBy != owner {
+		return false, nil
+	}
+	job.HeartbeatAt = &now
+	return true, nil
+}
+
+func (r *fakeJobRepo) Update(ctx context.Context, job *model.RecognitionJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.jobs[job.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	updated := copyJob(job)
+	updated.Items = existing.Items
+	updated.ClaimedBy = existing.ClaimedBy
+	updated.HeartbeatAt = existing.HeartbeatAt
+	r.jobs[job.ID] = updated
+	return nil
+}
+
+func (r *fakeJobRepo) UpdateItem(ctx context.Context, item *model.RecognitionJobItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[item.JobID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for i := range job.Items {
+		if job.Items[i].ID == item.ID {
+			job.Items[i] = *item
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// copyJob 复制任务及其图片
+func copyJob(job *model.RecognitionJob) *model.RecognitionJob {
+	copied := *job
+	copied.Items = slices.Clone(job.Items)
+	return &copied
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/importer"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// defaultPreviewLimit 导入预览默认返回的行数
+const defaultPreviewLimit = 10
+
+// ImportProfileService 账单导入配置服务
+// 用户为没有内置解析器的账单（银行流水、信用卡账单等）保存列映射，之后按配置导入
+type ImportProfileService struct {
+	profileRepo ImportProfileRepo
+	billService BillServiceInterface
+}
+
+// NewImportProfileService 创建账单导入配置服务
+func NewImportProfileService(profileRepo ImportProfileRepo, billService BillServiceInterface) *ImportProfileService {
+	return &ImportProfileService{
+		profileRepo: profileRepo,
+		billService: billService,
+	}
+}
+
+// List 获取用户的导入配置列表
+func (s *ImportProfileService) List(ctx context.Context, userID uint64) ([]dto.ImportProfileResponse, error) {
+	profiles, err := s.profileRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, errcode.ErrServer
+	}
+	result := make([]dto.ImportProfileResponse, 0, len(profiles))
+	for i := range profiles {
+		resp, err := toImportProfileResponse(&profiles[i])
+		if err != nil {
+			return nil, errcode.ErrServer
+		}
+		result = append(result, *resp)
+	}
+	return result, nil
+}
+
+// Create 创建导入配置
+func (s *ImportProfileService) Create(ctx context.Context, userID uint64, req *dto.CreateImportProfileRequest) (*dto.ImportProfileResponse, error) {
+	mapping, err := encodeImportMapping(&req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.profileRepo.ExistsByName(ctx, userID, req.Name)
+	if err != nil {
+		return nil, errcode.ErrServer
+	}
+	if exists {
+		return nil, errcode.ErrImportProfileExists
+	}
+
+	profile := &model.ImportProfile{
+		UserID:  userID,
+		Name:    req.Name,
+		Mapping: mapping,
+	}
+	if err := s.profileRepo.Create(ctx, profile); err != nil {
+		return nil, errcode.ErrServer
+	}
+	return toImportProfileResponse(profile)
+}
+
+// Update 更新导入配置
+func (s *ImportProfileService) Update(ctx context.Context, userID, id uint64, req *dto.UpdateImportProfileRequest) (*dto.ImportProfileResponse, error) {
+	profile, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查名称是否重复
+	if req.Name != "" && req.Name != profile.Name {
+		exists, err := s.profileRepo.ExistsByName(ctx, userID, req.Name)
+		if err != nil {
+			return nil, errcode.ErrServer
+		}
+		if exists {
+			return nil, errcode.ErrImportProfileExists
+		}
+		profile.Name = req.Name
+	}
+
+	if req.Mapping != nil {
+		mapping, err := encodeImportMapping(req.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		profile.Mapping = mapping
+	}
+
+	if err := s.profileRepo.Update(ctx, profile); err != nil {
+		return nil, errcode.ErrServer
+	}
+	return toImportProfileResponse(profile)
+}
+
+// Delete 删除导入配置
+func (s *ImportProfileService) Delete(ctx context.Context, userID, id uint64) error {
+	if _, err := s.getOwned(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.profileRepo.Delete(ctx, id); err != nil {
+		return errcode.ErrServer
+	}
+	return nil
+}
+
+// Preview 按列映射解析上传的文件，返回表头列名和前 limit 行的解析结果，用于保存配置前核对
+// 未设置列映射时只返回表头列名
+func (s *ImportProfileService) Preview(ctx context.Context, r io.Reader, filename string, mapping *dto.ImportMapping, limit int) (*dto.ImportPreviewResponse, error) {
+	if limit <= 0 {
+		limit = defaultPreviewLimit
+	}
+	rows, err := importer.ReadSheet(r, filename, mapping.Sheet)
+	if err != nil {
+		return nil, errcode.ErrImportFileParse.WithMessage(err.Error())
+	}
+
+	response := &dto.ImportPreviewResponse{
+		Columns: importer.HeaderColumns(rows, mapping.HeaderRow),
+		Rows:    []dto.ImportPreviewRow{},
+		Errors:  []dto.ImportError{},
+	}
+	if len(mapping.Columns) == 0 {
+		return response, nil
+	}
+
+	parser, err := importer.NewMappingParser(toImporterMapping(mapping))
+	if err != nil {
+		return nil, errcode.ErrImportMappingInvalid.WithMessage(err.Error())
+	}
+	result, err := parser.Parse(rows)
+	if err != nil {
+		return nil, errcode.ErrImportMappingInvalid.WithMessage(err.Error())
+	}
+
+	response.Total = len(result.Records)
+	response.Skipped = result.Skipped
+	for _, record := range result.Records[:min(limit, len(result.Records))] {
+		response.Rows = append(response.Rows, dto.ImportPreviewRow{
+			Row:       record.Row,
+			PayTime:   record.PayTime,
+			Amount:    record.Amount,
+			BillType:  record.BillType,
+			Merchant:  record.Merchant,
+			Category:  record.CategoryName,
+			Remark:    record.Remark,
+			OrderNo:   record.OrderNo,
+			PayMethod: record.PayMethod,
+		})
+	}
+	for _, parseError := range result.Errors[:min(limit, len(result.Errors))] {
+		response.Errors = append(response.Errors, dto.ImportError{
+			Row:     parseError.Row,
+			Column:  parseError.Column,
+			Message: parseError.Message,
+			RowData: parseError.RowData,
+		})
+	}
+	return response, nil
+}
+
+// Import 按导入配置导入账单文件
+func (s *ImportProfileService) Import(ctx context.Context, userID, id uint64, r io.Reader, filename string) (*dto.BillImportResponse, error) {
+	profile, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	var mapping dto.ImportMapping
+	if err := json.Unmarshal([]byte(profile.Mapping), &mapping); err != nil {
+		return nil, errcode.ErrServer
+	}
+	return s.billService.ImportWithMapping(ctx, userID, r, filename, toImporterMapping(&mapping))
+}
+
+// getOwned 获取导入配置并检查归属
+func (s *ImportProfileService) getOwned(ctx context.Context, userID, id uint64) (*model.ImportProfile, error) {
+	profile, err := s.profileRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.ErrImportProfileNotFound
+		}
+		return nil, errcode.ErrServer
+	}
+
+	// 检查权限
+	if profile.UserID != userID {
+		return nil, errcode.ErrForbidden
+	}
+	return profile, nil
+}
+
+// encodeImportMapping 校验列映射并序列化为 JSON
+func encodeImportMapping(mapping *dto.ImportMapping) (string, error) {
+	if _, err := importer.NewMappingParser(toImporterMapping(mapping)); err != nil {
+		return "", errcode.ErrImportMappingInvalid.WithMessage(err.Error())
+	}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return "", errcode.ErrServer
+	}
+	return string(data), nil
+}
+
+// toImporterMapping 转换为导入解析器使用的列映射
+func toImporterMapping(mapping *dto.ImportMapping) importer.Mapping {
+	return importer.Mapping{
+		Sheet:         mapping.Sheet,
+		HeaderRow:     mapping.HeaderRow,
+		Columns:       mapping.Columns,
+		DateFormat:    mapping.DateFormat,
+		TypeRule:      mapping.TypeRule,
+		AmountSign:    mapping.AmountSign,
+		IncomeValues:  mapping.IncomeValues,
+		ExpenseValues: mapping.ExpenseValues,
+		Platform:      mapping.Platform,
+	}
+}
+
+// toImportProfileResponse 转换为导入配置响应
+func toImportProfileResponse(profile *model.ImportProfile) (*dto.ImportProfileResponse, error) {
+	var mapping dto.ImportMapping
+	if err := json.Unmarshal([]byte(profile.Mapping), &mapping); err != nil {
+		return nil, err
+	}
+	return &dto.ImportProfileResponse{
+		ID:        profile.ID,
+		Name:      profile.Name,
+		Mapping:   mapping,
+		CreatedAt: profile.CreatedAt,
+		UpdatedAt: profile.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/pkg/errcode"
+)
+
+const cmbFile = "../pkg/importer/test_data/招商银行交易流水.csv"
+
+func newTestImportProfileService() (*ImportProfileService, *fakeBillRepo) {
+	billService, bills := newTestBillService()
+	return NewImportProfileService(&fakeImportProfileRepo{}, billService), bills
+}
+
+// cmbImportMapping 招商银行储蓄卡流水的列映射
+func cmbImportMapping() dto.ImportMapping {
+	return dto.ImportMapping{
+		HeaderRow: 3,
+		Columns: map[string]string{
+			"pay_time": "交易时间",
+			"amount":   "交易金额",
+			"merchant": "对手信息",
+			"remark":   "交易摘要",
+			"order_no": "流水号",
+		},
+		Platform: "招商银行",
+	}
+}
+
+func previewFile(t *testing.T, s *ImportProfileService, path string, mapping dto.ImportMapping, limit int) (*dto.ImportPreviewResponse, error) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	return s.Preview(context.Background(), file, filepath.Base(path), &mapping, limit)
+}
+
+func TestImportProfileService_CRUD(t *testing.T) {
+	s, _ := newTestImportProfileService()
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, testUserID, &dto.CreateImportProfileRequest{Name: "招商银行储蓄卡", Mapping: cmbImportMapping()})
+	require.NoError(t, err)
+	assert.Equal(t, "招商银行储蓄卡", created.Name)
+	assert.Equal(t, cmbImportMapping(), created.Mapping)
+
+	_, err = s.Create(ctx, testUserID, &dto.CreateImportProfileRequest{Name: "招商银行储蓄卡", Mapping: cmbImportMapping()})
+	assert.Equal(t, errcode.ErrImportProfileExists, err)
+
+	// 缺少金额列的映射不能保存
+	invalid := cmbImportMapping()
+	delete(invalid.Columns, "amount")
+	_, err = s.Create(ctx, testUserID, &dto.CreateImportProfileRequest{Name: "无效配置", Mapping: invalid})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImportMappingInvalid.Code, err.(*errcode.ErrCode).Code)
+
+	mapping := cmbImportMapping()
+	mapping.AmountSign = "expense_positive"
+	updated, err := s.Update(ctx, testUserID, created.ID, &dto.UpdateImportProfileRequest{Name: "招商银行信用卡", Mapping: &mapping})
+	require.NoError(t, err)
+	assert.Equal(t, "招商银行信用卡", updated.Name)
+	assert.Equal(t, "expense_positive", updated.Mapping.AmountSign)
+
+	profiles, err := s.List(ctx, testUserID)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "招商银行信用卡", profiles[0].Name)
+
+	// 其他用户的配置
+	_, err = s.Update(ctx, testUserID+1, created.ID, &dto.UpdateImportProfileRequest{Name: "x"})
+	assert.Equal(t, errcode.ErrForbidden, err)
+	assert.Equal(t, errcode.ErrForbidden, s.Delete(ctx, testUserID+1, created.ID))
+
+	require.NoError(t, s.Delete(ctx, testUserID, created.ID))
+	assert.Equal(t, errcode.ErrImportProfileNotFound, s.Delete(ctx, testUserID, created.ID))
+}
+
+func TestImportProfileService_Preview(t *testing.T) {
+	s, _ := newTestImportProfileService()
+
+	// 未设置列映射时只返回表头列名
+	resp, err := previewFile(t, s, cmbFile, dto.ImportMapping{HeaderRow: 3}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"交易时间", "交易金额", "联机余额", "交易摘要", "对手信息", "流水号"}, resp.Columns)
+	assert.Empty(t, resp.Rows)
+
+	resp, err = previewFile(t, s, cmbFile, cmbImportMapping(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, 2, resp.Skipped)
+	require.Len(t, resp.Rows, 1)
+	assert.Equal(t, 4, resp.Rows[0].Row)
+	assert.Equal(t, "38.5", resp.Rows[0].Amount)
+	assert.Equal(t, 1, resp.Rows[0].BillType)
+	assert.Equal(t, "美团", resp.Rows[0].Merchant)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, 7, resp.Errors[0].Row)
+
+	// 映射的列在表头中不存在
+	mapping := cmbImportMapping()
+	mapping.HeaderRow = 1
+	_, err = previewFile(t, s, cmbFile, mapping, 10)
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrImportMappingInvalid.Code, err.(*errcode.ErrCode).Code)
+}
+
+func TestImportProfileService_Import(t *testing.T) {
+	s, bills := newTestImportProfileService()
+	ctx := context.Background()
+
+	profile, err := s.Create(ctx, testUserID, &dto.CreateImportProfileRequest{Name: "招商银行储蓄卡", Mapping: cmbImportMapping()})
+	require.NoError(t, err)
+
+	importCMB := func(userID uint64) (*dto.BillImportResponse, error) {
+		file, err := os.Open(cmbFile)
+		require.NoError(t, err)
+		defer file.Close()
+		return s.Import(ctx, userID, profile.ID, file, filepath.Base(cmbFile))
+	}
+
+	resp, err := importCMB(testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", resp.ParserType)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, 1, resp.Failed)
+	assert.Equal(t, 2, resp.Skipped)
+
+	bill, err := bills.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "招商银行", bill.Platform)
+	assert.Equal(t, "CMB20251215001", bill.OrderNo)
+	assert.Equal(t, "美团", bill.Merchant)
+
+	// 再次导入时按流水号去重
+	resp, err = importCMB(testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, resp.Total)
+	assert.Equal(t, 2, resp.Duplicated)
+
+	_, err = importCMB(testUserID + 1)
+	assert.Equal(t, errcode.ErrForbidden, err)
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// 发送给模型的统计数据条数上限
+const (
+	insightCategoryLimit = 5
+	insightMerchantLimit = 5
+)
+
+// insightNumberRe 匹配洞察文字中的数字（不含符号）
+var insightNumberRe = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// insightThousandsRe 匹配千分位分隔符
+var insightThousandsRe = regexp.MustCompile(`(\d),(\d{3})`)
+
+// insightSentenceRe 按句末标点切分句子，标点保留在句子中
+var insightSentenceRe = regexp.MustCompile(`[^。！？；!?;]+[。！？；!?;]*`)
+
+// InsightWriter 撰写月度消费洞察的AI能力（由 AIService 实现）
+type InsightWriter interface {
+	WriteInsight(ctx context.Context, userID uint64, facts string, now time.Time) (*dto.AIInsightResponse, error)
+}
+
+// InsightService 月度消费洞察服务
+// 统计数据在服务端基于 StatsService 计算，模型只负责组织文字；洞察中出现统计数据以外的数字的句子会被去除
+type InsightService struct {
+	writer       InsightWriter // AI未启用时为 nil，只能查看已生成的洞察
+	statsService StatsServiceInterface
+	billRepo     BillRepo
+	insightRepo  MonthlyInsightRepo
+	cfg          config.InsightsConfig
+	location     *time.Location
+	now          func() time.Time
+}
+
+// NewInsightService 创建月度消费洞察服务，时区无效时使用 Asia/Shanghai
+func NewInsightService(writer InsightWriter, statsService StatsServiceInterface, billRepo BillRepo, insightRepo MonthlyInsightRepo, cfg config.InsightsConfig) *InsightService {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Log.Warn("月度消费洞察时区无效，使用默认时区", zap.String("timezone", cfg.Timezone), zap.Error(err))
+		location, _ = time.LoadLocation(defaultAskTimezone)
+	}
+	return &InsightService{
+		writer:       writer,
+		statsService: statsService,
+		billRepo:     billRepo,
+		insightRepo:  insightRepo,
+		cfg:          cfg,
+		location:     location,
+		now:          time.Now,
+	}
+}
+
+// Start 启动后台任务，按配置的间隔为上个月有账单且尚未生成洞察的用户生成报告，ctx 取消后停止
+// AI未启用或关闭自动生成时不启动，返回是否已启动
+func (s *InsightService) Start(ctx context.Context) bool {
+	if s.writer == nil || s.cfg.Disabled || s.cfg.Interval <= 0 {
+		return false
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			s.generateDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return true
+}
+
+// Get 获取用户某月的洞察，未指定月份时获取上个月
+func (s *InsightService) Get(ctx context.Context, userID uint64, req *dto.MonthlyInsightRequest) (*dto.MonthlyInsightResponse, error) {
+	month := req.Month
+	if month == "" {
+		month = s.lastMonth().Format("2006-01")
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, errcode.ErrParams.WithMessage("月份格式应为 2006-01")
+	}
+
+	insight, err := s.insightRepo.GetByUserAndMonth(ctx, userID, month)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errcode.ErrInsightNotFound
+	}
+	if err != nil {
+		logger.Log.Error("获取月度消费洞察失败", zap.Uint64("user_id", userID), zap.String("month", month), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	return toMonthlyInsightResponse(insight)
+}
+
+// generateDue 为上个月有账单且尚未生成洞察的用户生成报告
+// AI服务不可用时结束本轮，等待下次检查
+func (s *InsightService) generateDue(ctx context.Context) {
+	month := s.lastMonth()
+	monthKey := month.Format("2006-01")
+	start, end := insightMonthRange(month)
+
+	userIDs, err := s.billRepo.ListUserIDsWithBills(ctx, start, end)
+	if err != nil {
+		logger.Log.Error("查询有账单的用户失败", zap.String("month", monthKey), zap.Error(err))
+		return
+	}
+	doneIDs, err := s.insightRepo.ListUserIDsByMonth(ctx, monthKey)
+	if err != nil {
+		logger.Log.Error("查询已生成洞察的用户失败", zap.String("month", monthKey), zap.Error(err))
+		return
+	}
+	done := make(map[uint64]bool, len(doneIDs))
+	for _, id := range doneIDs {
+		done[id] = true
+	}
+
+	for _, userID := range userIDs {
+		if done[userID] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.generate(ctx, userID, month); err != nil {
+			logger.Log.Warn("生成月度消费洞察失败", zap.Uint64("user_id", userID), zap.String("month", monthKey), zap.Error(err))
+			var e *errcode.ErrCode
+			if errors.As(err, &e) && e.Code == errcode.ErrAIServiceUnavailable.Code {
+				return
+			}
+		}
+	}
+}
+
+// generate 计算统计数据并生成、保存用户某月的洞察
+func (s *InsightService) generate(ctx context.Context, userID uint64, month time.Time) error {
+	facts, err := s.buildFacts(ctx, userID, month)
+	if err != nil {
+		return err
+	}
+	factsJSON, err := json.Marshal(facts)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.writer.WriteInsight(ctx, userID, string(factsJSON), s.now().In(s.location))
+	if err != nil {
+		return err
+	}
+	if dropped := filterInsightNumbers(result, allowedInsightNumbers(string(factsJSON))); dropped > 0 {
+		logger.Log.Warn("月度消费洞察中包含统计数据以外的数字，已去除相关句子",
+			zap.Uint64("user_id", userID), zap.String("month", facts.Month), zap.Int("dropped", dropped))
+	}
+	if result.Summary == "" {
+		result.Summary = fmt.Sprintf("%s 共支出 %s 元，收入 %s 元，记账 %d 笔。",
+			facts.Month, facts.TotalExpense.StringFixed(2), facts.TotalIncome.StringFixed(2), facts.BillCount)
+	}
+
+	narrative, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.insightRepo.Save(ctx, &model.MonthlyInsight{
+		UserID:        userID,
+		Month:         facts.Month,
+		Facts:         string(factsJSON),
+		Narrative:     string(narrative),
+		PromptVersion: result.PromptVersion,
+	})
+}
+
+// buildFacts 基于本月和上月的统计摘要、商户统计计算洞察所需的数字
+func (s *InsightService) buildFacts(ctx context.Context, userID uint64, month time.Time) (*dto.InsightFacts, error) {
+	previousMonth := month.AddDate(0, -1, 0)
+	current, err := s.statsService.GetSummary(ctx, userID, &dto.StatsSummaryRequest{Period: "month", Date: month.Format("2006-01")})
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.statsService.GetSummary(ctx, userID, &dto.StatsSummaryRequest{Period: "month", Date: previousMonth.Format("2006-01")})
+	if err != nil {
+		return nil, err
+	}
+
+	facts := &dto.InsightFacts{
+		Month:                current.Period,
+		PreviousMonth:        previous.Period,
+		TotalExpense:         current.TotalExpense,
+		PreviousExpense:      previous.TotalExpense,
+		ExpenseChangePercent: changePercent(current.TotalExpense, previous.TotalExpense),
+		TotalIncome:          current.TotalIncome,
+		PreviousIncome:       previous.TotalIncome,
+		BillCount:            current.BillCount,
+		PreviousBillCount:    previous.BillCount,
+		DailyAverage:         current.DailyAverage,
+		CategoryChanges:      categoryChanges(current.TopCategories, previous.TopCategories),
+	}
+
+	facts.NewMerchants, facts.TopMerchants, err = s.merchantFacts(ctx, userID, month, previousMonth)
+	if err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+// merchantFacts 本月新出现的商户和消费最多的商户
+func (s *InsightService) merchantFacts(ctx context.Context, userID uint64, month, previousMonth time.Time) (newMerchants, topMerchants []dto.InsightMerchant, err error) {
+	start, end := insightMonthRange(month)
+	current, err := s.billRepo.GetMerchantStats(ctx, userID, model.BillTypeExpense, start, end, 0, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	start, end = insightMonthRange(previousMonth)
+	previous, err := s.billRepo.GetMerchantStats(ctx, userID, model.BillTypeExpense, start, end, 0, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	previousAmounts := make(map[string]decimal.Decimal, len(previous))
+	for _, stat := range previous {
+		previousAmounts[stat.Merchant] = stat.Amount
+	}
+
+	newMerchants, topMerchants = []dto.InsightMerchant{}, []dto.InsightMerchant{}
+	for _, stat := range current {
+		previousAmount, existed := previousAmounts[stat.Merchant]
+		merchant := dto.InsightMerchant{
+			Merchant:       stat.Merchant,
+			Amount:         stat.Amount.Round(2),
+			Count:          stat.Count,
+			PreviousAmount: previousAmount.Round(2),
+		}
+		if len(topMerchants) < insightMerchantLimit {
+			topMerchants = append(topMerchants, merchant)
+		}
+		if !existed && len(newMerchants) < insightMerchantLimit {
+			newMerchants = append(newMerchants, merchant)
+		}
+	}
+	return newMerchants, topMerchants, nil
+}
+
+// lastMonth 按配置的时区计算上个月，返回该月1日
+func (s *InsightService) lastMonth() time.Time {
+	now := s.now().In(s.location)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// insightMonthRange 月份的起止时间，与 StatsService 的月度统计范围一致
+func insightMonthRange(month time.Time) (time.Time, time.Time) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0).Add(-time.Second)
+}
+
+// categoryChanges 按一级分类计算支出环比变化，按变化金额的绝对值降序取前几项
+func categoryChanges(current, previous []dto.CategoryStatsItem) []dto.InsightCategoryChange {
+	type amounts struct{ current, previous decimal.Decimal }
+	var names []string
+	byName := make(map[string]*amounts)
+	add := func(items []dto.CategoryStatsItem, isCurrent bool) {
+		for _, item := range items {
+			name := categoryLabel(item.Name)
+			a, ok := byName[name]
+			if !ok {
+				a = &amounts{}
+				byName[name] = a
+				names = append(names, name)
+			}
+			if isCurrent {
+				a.current = a.current.Add(item.Amount)
+			} else {
+				a.previous = a.previous.Add(item.Amount)
+			}
+		}
+	}
+	add(current, true)
+	add(previous, false)
+
+	changes := make([]dto.InsightCategoryChange, 0, len(names))
+	for _, name := range names {
+		a := byName[name]
+		change := a.current.Sub(a.previous)
+		if change.IsZero() {
+			continue
+		}
+		changes = append(changes, dto.InsightCategoryChange{
+			Category:       name,
+			Amount:         a.current.Round(2),
+			PreviousAmount: a.previous.Round(2),
+			Change:         change.Round(2),
+			ChangePercent:  changePercent(a.current, a.previous),
+		})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Change.Abs().GreaterThan(changes[j].Change.Abs())
+	})
+	if len(changes) > insightCategoryLimit {
+		changes = changes[:insightCategoryLimit]
+	}
+	return changes
+}
+
+// changePercent 环比变化百分比，保留一位小数，上期为 0 时返回 nil
+func changePercent(current, previous decimal.Decimal) *decimal.Decimal {
+	if previous.IsZero() {
+		return nil
+	}
+	percent := current.Sub(previous).Div(previous).Mul(decimal.NewFromInt(100)).Round(1)
+	return &percent
+}
+
+// allowedInsightNumbers 洞察中允许出现的数字：统计数据中的数字及其取整到 0-2 位小数的写法，另外允许 0-10 的小整数
+func allowedInsightNumbers(factsJSON string) map[string]bool {
+	allowed := make(map[string]bool)
+	for i := 0; i <= 10; i++ {
+		allowed[strconv.Itoa(i)] = true
+	}
+	for _, match := range insightNumberRe.FindAllString(factsJSON, -1) {
+		d, err := decimal.NewFromString(match)
+		if err != nil {
+			continue
+		}
+		allowed[d.String()] = true
+		for places := int32(0); places <= 2; places++ {
+			allowed[d.Round(places).String()] = true
+		}
+	}
+	return allowed
+}
+
+// filterInsightNumbers 去除包含不允许数字的句子和条目，返回去除的数量
+func filterInsightNumbers(result *dto.AIInsightResponse, allowed map[string]bool) int {
+	dropped := 0
+	var summary strings.Builder
+	for _, sentence := range insightSentenceRe.FindAllString(result.Summary, -1) {
+		if numbersAllowed(sentence, allowed) {
+			summary.WriteString(sentence)
+		} else {
+			dropped++
+		}
+	}
+	result.Summary = strings.TrimSpace(summary.String())
+
+	filter := func(items []string) []string {
+		kept := make([]string, 0, len(items))
+		for _, item := range items {
+			if numbersAllowed(item, allowed) {
+				kept = append(kept, item)
+			} else {
+				dropped++
+			}
+		}
+		return kept
+	}
+	result.Changes = filter(result.Changes)
+	result.Merchants = filter(result.Merchants)
+	result.Suggestions = filter(result.Suggestions)
+	return dropped
+}
+
+// numbersAllowed 文本中的数字是否都是允许的数字
+func numbersAllowed(text string, allowed map[string]bool) bool {
+	for {
+		replaced := insightThousandsRe.ReplaceAllString(text, "$1$2")
+		if replaced == text {
+			break
+		}
+		text = replaced
+	}
+	for _, match := range insightNumberRe.FindAllString(text, -1) {
+		d, err := decimal.NewFromString(match)
+		if err != nil || !allowed[d.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// toMonthlyInsightResponse 转换为洞察响应
+func toMonthlyInsightResponse(insight *model.MonthlyInsight) (*dto.MonthlyInsightResponse, error) {
+	resp := &dto.MonthlyInsightResponse{
+		Month:         insight.Month,
+		PromptVersion: insight.PromptVersion,
+		GeneratedAt:   insight.UpdatedAt,
+	}
+	var narrative dto.AIInsightResponse
+	if err := json.Unmarshal([]byte(insight.Facts), &resp.Facts); err != nil {
+		logger.Log.Error("解析月度消费洞察统计数据失败", zap.Uint64("id", insight.ID), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	if err := json.Unmarshal([]byte(insight.Narrative), &narrative); err != nil {
+		logger.Log.Error("解析月度消费洞察内容失败", zap.Uint64("id", insight.ID), zap.Error(err))
+		return nil, errcode.ErrServer
+	}
+	resp.Summary = narrative.Summary
+	resp.Changes = nonNilStrings(narrative.Changes)
+	resp.Merchants = nonNilStrings(narrative.Merchants)
+	resp.Suggestions = nonNilStrings(narrative.Suggestions)
+	return resp, nil
+}
+
+// nonNilStrings 将 nil 转换为空切片，使 JSON 输出为 []
+func nonNilStrings(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"smart-ledger-server/internal/config"
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/ai"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// newTestInsightService 使用 AIService 撰写洞察的月度洞察服务，当前时间为 2025-03-05，并写入两个月的测试账单
+func newTestInsightService(t *testing.T) (*InsightService, *testAIService, *fakeInsightRepo) {
+	s := newTestAIService(t, config.QuotaConfig{})
+	seed := []struct {
+		date       string
+		amount     int64
+		billType   model.BillType
+		categoryID uint64
+		merchant   string
+		userID     uint64
+	}{
+		{"2025-01-10", 30, model.BillTypeExpense, 1, "食堂", testUserID},
+		{"2025-01-12", 40, model.BillTypeExpense, 3, "滴滴出行", testUserID},
+		{"2025-01-20", 300, model.BillTypeIncome, 5, "工资", testUserID},
+		{"2025-02-03", 30, model.BillTypeExpense, 1, "食堂", testUserID},
+		{"2025-02-10", 20, model.BillTypeExpense, 2, "瑞幸咖啡", testUserID},
+		{"2025-02-10", 15, model.BillTypeExpense, 3, "滴滴出行", testUserID},
+		{"2025-02-28", 8, model.BillTypeExpense, 0, "便利店", testUserID},
+		{"2025-02-10", 999, model.BillTypeExpense, 6, "地铁", 2},
+		{"2025-01-10", 12, model.BillTypeExpense, 0, "", 3},
+	}
+	for _, b := range seed {
+		payTime, err := time.Parse("2006-01-02 15:04", b.date+" 12:30")
+		require.NoError(t, err)
+		bill := &model.Bill{UserID: b.userID, Amount: decimal.NewFromInt(b.amount), BillType: b.billType, Merchant: b.merchant, PayTime: payTime}
+		if b.categoryID != 0 {
+			bill.CategoryID = &b.categoryID
+		}
+		require.NoError(t, s.bills.Create(context.Background(), bill))
+	}
+
+	insights := &fakeInsightRepo{}
+	svc := NewInsightService(s.AIService, NewStatsService(s.bills), s.bills, insights, config.InsightsConfig{Interval: time.Hour, Timezone: "Asia/Shanghai"})
+	svc.now = func() time.Time { return time.Date(2025, 3, 5, 9, 0, 0, 0, svc.location) }
+	return svc, s, insights
+}
+
+func TestInsightService_GenerateDue(t *testing.T) {
+	svc, s, insights := newTestInsightService(t)
+	// 用户2已生成过洞察，用户3上个月没有账单
+	require.NoError(t, insights.Save(context.Background(), &model.MonthlyInsight{UserID: 2, Month: "2025-02", Facts: "{}", Narrative: "{}"}))
+	s.client.Enqueue(ai.FakeReply{Insight: &dto.AIInsightResponse{
+		Summary:     "2月共支出 73.00 元，比上月增加 4.3%。预计3月支出将达到 120 元。",
+		Changes:     []string{"交通支出减少 25.00 元（-62.5%）", "餐饮多花了 200 元"},
+		Merchants:   []string{"新出现的商户瑞幸咖啡消费 20 元"},
+		Suggestions: []string{"咖啡可以自带，每月能省 1,000 元", "少打车，多坐地铁"},
+	}})
+
+	svc.generateDue(context.Background())
+
+	calls := s.client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "WriteInsight", calls[0].Method)
+	assert.Contains(t, calls[0].Prompt, "2025-03-05")
+	var facts dto.InsightFacts
+	require.NoError(t, json.Unmarshal([]byte(calls[0].Text), &facts))
+	assert.Equal(t, "2025-02", facts.Month)
+	assert.Equal(t, "2025-01", facts.PreviousMonth)
+	assert.True(t, facts.TotalExpense.Equal(decimal.NewFromInt(73)))
+	assert.True(t, facts.PreviousExpense.Equal(decimal.NewFromInt(70)))
+	require.NotNil(t, facts.ExpenseChangePercent)
+	assert.Equal(t, "4.3", facts.ExpenseChangePercent.String())
+	assert.Equal(t, int64(4), facts.BillCount)
+
+	// 分类变化按变化金额的绝对值排序，上月没有支出时不计算百分比
+	require.Len(t, facts.CategoryChanges, 3)
+	assert.Equal(t, "交通", facts.CategoryChanges[0].Category)
+	assert.Equal(t, "-25", facts.CategoryChanges[0].Change.String())
+	assert.Equal(t, "-62.5", facts.CategoryChanges[0].ChangePercent.String())
+	assert.Equal(t, "餐饮", facts.CategoryChanges[1].Category)
+	assert.Equal(t, "66.7", facts.CategoryChanges[1].ChangePercent.String())
+	assert.Equal(t, "未分类", facts.CategoryChanges[2].Category)
+	assert.Nil(t, facts.CategoryChanges[2].ChangePercent)
+
+	require.Len(t, facts.NewMerchants, 2)
+	assert.Equal(t, "瑞幸咖啡", facts.NewMerchants[0].Merchant)
+	assert.Equal(t, "便利店", facts.NewMerchants[1].Merchant)
+	require.Len(t, facts.TopMerchants, 4)
+	assert.Equal(t, "食堂", facts.TopMerchants[0].Merchant)
+	assert.True(t, facts.TopMerchants[0].PreviousAmount.Equal(decimal.NewFromInt(30)))
+
+	// 包含统计数据以外数字的句子和条目被去除
+	resp, err := svc.Get(context.Background(), testUserID, &dto.MonthlyInsightRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02", resp.Month)
+	assert.Equal(t, "2月共支出 73.00 元，比上月增加 4.3%。", resp.Summary)
+	assert.Equal(t, []string{"交通支出减少 25.00 元（-62.5%）"}, resp.Changes)
+	assert.Equal(t, []string{"新出现的商户瑞幸咖啡消费 20 元"}, resp.Merchants)
+	assert.Equal(t, []string{"少打车，多坐地铁"}, resp.Suggestions)
+	assert.Equal(t, "insight-v1", resp.PromptVersion)
+	assert.True(t, resp.Facts.TotalExpense.Equal(decimal.NewFromInt(73)))
+
+	// 生成洞察记录为系统用量，不占用用户配额
+	require.Len(t, s.usage.records, 1)
+	assert.Equal(t, model.UsageStatusSucceeded, s.usage.records[0].Status)
+	assert.Equal(t, model.UsageSourceSystem, s.usage.records[0].Source)
+	quota, err := s.Quota(context.Background(), testUserID)
+	require.NoError(t, err)
+	assert.Zero(t, quota.Daily.Used)
+	assert.Zero(t, quota.Monthly.Used)
+
+	// 已生成的用户不再重复生成
+	svc.generateDue(context.Background())
+	assert.Len(t, s.client.Calls(), 1)
+}
+
+func TestInsightService_GenerateDue_FallbackSummary(t *testing.T) {
+	svc, s, _ := newTestInsightService(t)
+	s.client.Handler = func(ctx context.Context, call ai.FakeCall) ai.FakeReply {
+		return ai.FakeReply{Insight: &dto.AIInsightResponse{Summary: "本月支出 9999 元。"}}
+	}
+
+	svc.generateDue(context.Background())
+	assert.Len(t, s.client.Calls(), 2)
+
+	// 摘要全部被去除时使用统计数据生成摘要
+	resp, err := svc.Get(context.Background(), testUserID, &dto.MonthlyInsightRequest{Month: "2025-02"})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02 共支出 73.00 元，收入 0.00 元，记账 4 笔。", resp.Summary)
+	assert.Equal(t, []string{}, resp.Changes)
+}
+
+func TestInsightService_GenerateDue_AIUnavailable(t *testing.T) {
+	svc, s, insights := newTestInsightService(t)
+	s.client.Enqueue(ai.FakeReply{Err: ai.ErrAllProvidersUnavailable})
+
+	// AI服务不可用时结束本轮，不再尝试其他用户
+	svc.generateDue(context.Background())
+	assert.Len(t, s.client.Calls(), 1)
+	assert.Empty(t, insights.insights)
+}
+
+func TestInsightService_Get_Errors(t *testing.T) {
+	svc, _, _ := newTestInsightService(t)
+
+	_, err := svc.Get(context.Background(), testUserID, &dto.MonthlyInsightRequest{Month: "2025-01"})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrInsightNotFound.Code, err.(*errcode.ErrCode).Code)
+
+	_, err = svc.Get(context.Background(), testUserID, &dto.MonthlyInsightRequest{Month: "2025/01"})
+	require.Error(t, err)
+	assert.Equal(t, errcode.ErrParams.Code, err.(*errcode.ErrCode).Code)
+}
+
+func TestInsightService_Start(t *testing.T) {
+	// AI未启用或关闭自动生成时不启动
+	svc := NewInsightService(nil, nil, nil, &fakeInsightRepo{}, config.InsightsConfig{Interval: time.Hour})
+	assert.False(t, svc.Start(context.Background()))
+
+	svc, _, _ = newTestInsightService(t)
+	svc.cfg.Disabled = true
+	assert.False(t, svc.Start(context.Background()))
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/notification"
+	"smart-ledger-server/internal/pkg/quickentry"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// 规则解析结果的置信度：金额和收支方向来自固定格式，较为可靠；分类只能按商户名推测，未匹配到时交给用户复核
+const (
+	notificationRuleConfidence          = 0.9
+	notificationRuleConfidenceUncertain = 0.6
+)
+
+// NotificationRecognizer 支付通知/短信的AI解析能力（由 AIService 实现）
+type NotificationRecognizer interface {
+	RecognizeNotification(ctx context.Context, userID uint64, text string, receivedAt time.Time) (*dto.AIRecognizeResponse, error)
+}
+
+// NotificationService 支付通知/短信记账服务
+// 优先使用注册的规则解析器，都无法解析时使用AI解析
+type NotificationService struct {
+	registry        *notification.Registry
+	recognizer      NotificationRecognizer // AI未启用时为 nil
+	billService     BillServiceInterface
+	categoryService CategoryServiceInterface
+}
+
+// NewNotificationService 创建支付通知/短信记账服务，recognizer 为 nil 时仅使用规则解析
+func NewNotificationService(registry *notification.Registry, recognizer NotificationRecognizer, billService BillServiceInterface, categoryService CategoryServiceInterface) *NotificationService {
+	return &NotificationService{
+		registry:        registry,
+		recognizer:      recognizer,
+		billService:     billService,
+		categoryService: categoryService,
+	}
+}
+
+// Ingest 解析支付通知/短信并创建待复核账单，重复推送的通知返回已有账单
+func (s *NotificationService) Ingest(ctx context.Context, userID uint64, req *dto.IngestNotificationRequest) (*dto.IngestNotificationResponse, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, errcode.ErrParams.WithMessage("通知内容不能为空")
+	}
+	local, _ := time.LoadLocation("Asia/Shanghai")
+	receivedAt := time.Now().In(local)
+	if req.ReceivedAt != nil && !req.ReceivedAt.IsZero() {
+		receivedAt = *req.ReceivedAt
+	}
+
+	resp := &dto.IngestNotificationResponse{}
+	msg := &notification.Message{Text: text, Sender: strings.TrimSpace(req.Sender), ReceivedAt: receivedAt}
+	aiResult, parser, ok := s.parseWithRules(ctx, userID, msg)
+	if ok {
+		resp.Source, resp.Parser = "rule", parser
+	} else {
+		if s.recognizer == nil {
+			return nil, errcode.ErrNotificationParseFailed
+		}
+		result, err := s.recognizer.RecognizeNotification(ctx, userID, text, receivedAt)
+		if err != nil {
+			logger.Log.Info("AI解析通知失败", zap.Uint64("user_id", userID), zap.Error(err))
+			if e, ok := err.(*errcode.ErrCode); ok && e.Code != errcode.ErrAIRecognizeFailed.Code {
+				return nil, err
+			}
+			return nil, errcode.ErrNotificationParseFailed
+		}
+		aiResult = result
+		resp.Source = "ai"
+	}
+
+	bill, err := s.billService.CreateFromNotification(ctx, userID, aiResult, notificationHash(msg))
+	if err != nil {
+		return nil, err
+	}
+	resp.Bill = bill
+	return resp, nil
+}
+
+// parseWithRules 使用规则解析器解析通知，并按商户名匹配分类
+func (s *NotificationService) parseWithRules(ctx context.Context, userID uint64, msg *notification.Message) (*dto.AIRecognizeResponse, string, bool) {
+	tx, parser, ok := s.registry.Parse(msg)
+	if !ok {
+		return nil, "", false
+	}
+
+	result := &dto.AIRecognizeResponse{
+		Platform:   tx.Platform,
+		Amount:     tx.Amount,
+		Merchant:   tx.Merchant,
+		PayTime:    tx.PayTime.Format(time.RFC3339),
+		PayMethod:  tx.PayMethod,
+		BillType:   int(tx.BillType),
+		Confidence: notificationRuleConfidenceUncertain,
+		RawContent: msg.Text,
+	}
+	categories, err := s.categoryService.GetCategoriesForAI(ctx, userID)
+	if err != nil {
+		logger.Log.Warn("获取分类失败", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	// 没有商户名时（如工资、转账短信）按正文匹配
+	keyword := tx.Merchant
+	if keyword == "" {
+		keyword = msg.Text
+	}
+	if category := quickentry.MatchCategory(keyword, tx.BillType, categories); category != nil {
+		result.SubCategory = category.Name
+		result.Confidence = notificationRuleConfidence
+	}
+	return result, parser, true
+}
+
+// notificationHash 计算通知摘要：同一来源在同一分钟收到的相同内容视为同一条通知
+func notificationHash(msg *notification.Message) string {
+	sum := sha256.Sum256([]byte(msg.Sender + "\n" + strconv.FormatInt(msg.ReceivedAt.Unix()/60, 10) + "\n" + msg.Text))
+	return hex.EncodeToString(sum[:])
+}
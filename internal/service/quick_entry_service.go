@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"smart-ledger-server/internal/model"
+	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/logger"
+	"smart-ledger-server/internal/pkg/quickentry"
+	"smart-ledger-server/pkg/errcode"
+)
+
+// defaultQuickEntryTimezone 未指定时区时使用的时区
+const defaultQuickEntryTimezone = "Asia/Shanghai"
+
+// QuickEntryRecognizer 一句话记账的AI解析能力（由 AIService 实现）
+type QuickEntryRecognizer interface {
+	RecognizeText(ctx context.Context, userID uint64, text string, now time.Time) (*dto.AIRecognizeResponse, error)
+}
+
+// QuickEntryService 一句话记账服务
+// 优先使用AI解析，AI未启用或解析失败时使用规则解析
+type QuickEntryService struct {
+	recognizer      QuickEntryRecognizer // AI未启用时为 nil
+	billService     BillServiceInterface
+	categoryService CategoryServiceInterface
+}
+
+// NewQuickEntryService 创建一句话记账服务，recognizer 为 nil 时仅使用规则解析
+func NewQuickEntryService(recognizer QuickEntryRecognizer, billService BillServiceInterface, categoryService CategoryServiceInterface) *QuickEntryService {
+	return &QuickEntryService{
+		recognizer:      recognizer,
+		billService:     billService,
+		categoryService: categoryService,
+	}
+}
+
+// Parse 解析一句话记账文本，返回账单草稿；req.Save 为 true 时同时保存为账单
+func (s *QuickEntryService) Parse(ctx context.Context, userID uint64, req *dto.QuickEntryRequest) (*dto.QuickEntryResponse, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, errcode.ErrParams.WithMessage("请输入记账内容")
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = defaultQuickEntryTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errcode.ErrParams.WithMessage("无效的时区：" + timezone)
+	}
+	now := time.Now().In(location)
+
+	categories, err := s.categoryService.GetCategoriesForAI(ctx, userID)
+	if err != nil {
+		logger.Log.Warn("获取分类失败", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+
+	resp, aiErr := s.parseWithAI(ctx, userID, text, now, categories)
+	if resp == nil {
+		draft, err := quickentry.Parse(text, now)
+		if err != nil {
+			// 规则也无法解析时，优先返回配额不足、服务不可用等AI错误
+			var e *errcode.ErrCode
+			if errors.As(aiErr, &e) && e.Code != errcode.ErrAIRecognizeFailed.Code {
+				return nil, aiErr
+			}
+			return nil, errcode.ErrQuickEntryParseFailed
+		}
+		resp = &dto.QuickEntryResponse{
+			Source: "rule",
+			Draft: dto.QuickEntryDraft{
+				Amount:    draft.Amount,
+				BillType:  int(draft.BillType),
+				Platform:  draft.Platform,
+				Category:  toQuickEntryCategory(quickentry.MatchCategory(draft.Keyword, draft.BillType, categories)),
+				PayTime:   draft.PayTime,
+				PayMethod: draft.PayMethod,
+			},
+		}
+	}
+	resp.Draft.Remark = text
+
+	if !req.Save {
+		return resp, nil
+	}
+	createReq := &dto.CreateBillRequest{
+		Amount:    resp.Draft.Amount,
+		BillType:  resp.Draft.BillType,
+		Platform:  resp.Draft.Platform,
+		Merchant:  resp.Draft.Merchant,
+		PayTime:   resp.Draft.PayTime,
+		PayMethod: resp.Draft.PayMethod,
+		Remark:    resp.Draft.Remark,
+	}
+	if resp.Draft.Category != nil {
+		createReq.CategoryID = &resp.Draft.Category.ID
+	}
+	bill, err := s.billService.Create(ctx, userID, createReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.Bill = bill
+	return resp, nil
+}
+
+// parseWithAI 使用AI解析，AI未启用或解析失败时返回 nil 和失败原因
+func (s *QuickEntryService) parseWithAI(ctx context.Context, userID uint64, text string, now time.Time, categories []model.Category) (*dto.QuickEntryResponse, error) {
+	if s.recognizer == nil {
+		return nil, nil
+	}
+	result, err := s.recognizer.RecognizeText(ctx, userID, text, now)
+	if err != nil {
+		logger.Log.Info("AI解析记账文本失败，使用规则解析", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	billType := model.BillTypeExpense
+	if result.BillType == int(model.BillTypeIncome) {
+		billType = model.BillTypeIncome
+	}
+	payTime, ok := parseAIPayTime(result.PayTime)
+	if !ok {
+		payTime = now
+	}
+
+	return &dto.QuickEntryResponse{
+		Source: "ai",
+		Draft: dto.QuickEntryDraft{
+			Amount:    result.Amount,
+			BillType:  int(billType),
+			Platform:  result.Platform,
+			Merchant:  truncateRunes(result.Merchant, 255),
+			Category:  toQuickEntryCategory(findCategoryByName(categories, billType, result.SubCategory, result.Category)),
+			PayTime:   payTime.In(now.Location()),
+			PayMethod: result.PayMethod,
+		},
+	}, nil
+}
+
+// findCategoryByName 在分类树中按名称依次查找指定类型的分类，二级分类优先
+func findCategoryByName(categories []model.Category, billType model.BillType, names ...string) *model.Category {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for i := range categories {
+			parent := &categories[i]
+			if model.BillType(parent.Type) != billType {
+				continue
+			}
+			for j := range parent.Children {
+				if parent.Children[j].Name == name {
+					return &parent.Children[j]
+				}
+			}
+			if parent.Name == name {
+				return parent
+			}
+		}
+	}
+	return nil
+}
+
+// toQuickEntryCategory 转换为草稿中的分类，分类为空时返回 nil
+func toQuickEntryCategory(category *model.Category) *dto.CategoryResponse {
+	if category == nil {
+		return nil
+	}
+	return &dto.CategoryResponse{
+		ID:        category.ID,
+		Name:      category.Name,
+		Type:      int(category.Type),
+		ParentID:  category.ParentID,
+		Icon:      category.Icon,
+		SortOrder: category.SortOrder,
+	}
+}
@@ -43,14 +43,71 @@ type CategoryTemplateRepo interface {
 type BillRepo interface {
 	Create(ctx context.Context, bill *model.Bill) error
 	GetByID(ctx context.Context, id uint64) (*model.Bill, error)
+	GetByImageHash(ctx context.Context, userID uint64, imageHash string) (*model.Bill, error)
+	GetBySourceHash(ctx context.Context, userID uint64, sourceHash string) (*model.Bill, error)
+	FindDuplicate(ctx context.Context, bill *model.Bill) (*model.Bill, error)
+	CreateBatch(ctx context.Context, bills []*model.Bill) ([]uint64, error)
 	List(ctx context.Context, query *repository.BillQuery) ([]model.Bill, int64, error)
 	Update(ctx context.Context, bill *model.Bill) error
+	ReplaceItems(ctx context.Context, billID uint64, items []model.BillItem) error
 	Delete(ctx context.Context, id uint64) error
 
-	// 统计相关
-	GetStatsSummary(ctx context.Context, userID uint64, startDate, endDate time.Time) (*repository.StatsSummary, error)
-	GetCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time) ([]repository.CategoryStats, error)
-	GetDailyStats(ctx context.Context, userID uint64, startDate, endDate time.Time) ([]repository.DailyStats, error)
-	GetMonthlyStats(ctx context.Context, userID uint64, startDate, endDate time.Time) ([]repository.MonthlyStats, error)
-	GetSecondaryCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, categoryID uint64) ([]repository.CategoryStats, error)
+	// 复核相关
+	ListUnconfirmedByIDs(ctx context.Context, userID uint64, ids []uint64) ([]model.Bill, error)
+	ConfirmByIDs(ctx context.Context, userID uint64, ids []uint64) (int64, error)
+	DeleteByIDs(ctx context.Context, ids []uint64) error
+
+	// 统计相关，confirmedOnly 为 true 时仅统计已确认账单
+	GetStatsSummary(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) (*repository.StatsSummary, error)
+	GetCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, confirmedOnly bool) ([]repository.CategoryStats, error)
+	GetDailyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]repository.DailyStats, error)
+	GetMonthlyStats(ctx context.Context, userID uint64, startDate, endDate time.Time, confirmedOnly bool) ([]repository.MonthlyStats, error)
+	GetSecondaryCategoryStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, categoryID uint64, confirmedOnly bool) ([]repository.CategoryStats, error)
+	GetMerchantStats(ctx context.Context, userID uint64, billType model.BillType, startDate, endDate time.Time, limit int, confirmedOnly bool) ([]repository.MerchantStats, error)
+	ListUserIDsWithBills(ctx context.Context, startDate, endDate time.Time) ([]uint64, error)
+}
+
+// CategoryCorrectionRepo 分类修正记录仓库接口
+type CategoryCorrectionRepo interface {
+	Record(ctx context.Context, correction *model.CategoryCorrection) error
+	ListByMerchant(ctx context.Context, userID uint64, merchant string) ([]model.CategoryCorrection, error)
+	ListTop(ctx context.Context, userID uint64, limit int) ([]model.CategoryCorrection, error)
+}
+
+// MonthlyInsightRepo 月度消费洞察仓库接口
+type MonthlyInsightRepo interface {
+	Save(ctx context.Context, insight *model.MonthlyInsight) error
+	GetByUserAndMonth(ctx context.Context, userID uint64, month string) (*model.MonthlyInsight, error)
+	ListUserIDsByMonth(ctx context.Context, month string) ([]uint64, error)
+}
+
+// ImportProfileRepo 账单导入配置仓库接口
+type ImportProfileRepo interface {
+	Create(ctx context.Context, profile *model.ImportProfile) error
+	GetByID(ctx context.Context, id uint64) (*model.ImportProfile, error)
+	ListByUser(ctx context.Context, userID uint64) ([]model.ImportProfile, error)
+	ExistsByName(ctx context.Context, userID uint64, name string) (bool, error)
+	Update(ctx context.Context, profile *model.ImportProfile) error
+	Delete(ctx context.Context, id uint64) error
+}
+
+// AIUsageRepo AI调用用量仓库接口
+type AIUsageRepo interface {
+	Create(ctx context.Context, record *model.AIUsageRecord) error
+	Reserve(ctx context.Context, userID uint64, n int, limits []repository.QuotaLimit) ([]model.AIUsageRecord, error)
+	Finish(ctx context.Context, record *model.AIUsageRecord) error
+	Release(ctx context.Context, ids []uint64) error
+	Summarize(ctx context.Context, userID uint64, since time.Time) (*repository.UsageSummary, error)
+}
+
+// RecognitionJobRepo 异步识别任务仓库接口
+type RecognitionJobRepo interface {
+	Create(ctx context.Context, job *model.RecognitionJob) error
+	GetByID(ctx context.Context, id uint64) (*model.RecognitionJob, error)
+	GetByUUID(ctx context.Context, uuid string) (*model.RecognitionJob, error)
+	ListIDsByStatus(ctx context.Context, statuses ...model.JobStatus) ([]uint64, error)
+	Claim(ctx context.Context, id uint64, owner string, now, staleBefore time.Time) (bool, error)
+	Heartbeat(ctx context.Context, id uint64, owner string, now time.Time) (bool, error)
+	Update(ctx context.Context, job *model.RecognitionJob) error
+	UpdateItem(ctx context.Context, item *model.RecognitionJobItem) error
 }
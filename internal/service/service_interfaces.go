@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
 
 	"smart-ledger-server/internal/model"
 	"smart-ledger-server/internal/model/dto"
+	"smart-ledger-server/internal/pkg/importer"
 )
 
 // 这里定义 handler 层依赖的最小服务接口，便于单测替身/Mock。
@@ -34,10 +36,31 @@ type BillServiceInterface interface {
 	Create(ctx context.Context, userID uint64, req *dto.CreateBillRequest) (*dto.BillResponse, error)
 	GetByID(ctx context.Context, userID, id uint64) (*dto.BillResponse, error)
 	List(ctx context.Context, userID uint64, req *dto.BillListRequest) (*dto.BillListResponse, error)
+	ListReview(ctx context.Context, userID uint64, req *dto.BillListRequest) (*dto.BillListResponse, error)
+	Review(ctx context.Context, userID uint64, req *dto.ReviewBillsRequest) (*dto.ReviewBillsResponse, error)
 	Update(ctx context.Context, userID, id uint64, req *dto.UpdateBillRequest) (*dto.BillResponse, error)
 	Delete(ctx context.Context, userID, id uint64) error
-	CreateFromAI(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, imagePath string) (*dto.BillResponse, error)
-	ImportFromExcel(ctx context.Context, userID uint64, filePath, parserType string) (*dto.BillImportResponse, error)
+	CreateFromAI(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, imagePath, imageHash string) (*dto.BillResponse, error)
+	CreateFromNotification(ctx context.Context, userID uint64, aiResult *dto.AIRecognizeResponse, sourceHash string) (*dto.BillResponse, error)
+	CreateBatchFromAI(ctx context.Context, userID uint64, aiResults []dto.AIRecognizeResponse) (*dto.SaveAITransactionsResponse, error)
+	MarkDuplicateTransactions(ctx context.Context, userID uint64, aiResults []dto.AIRecognizeResponse) error
+	FindByImageHash(ctx context.Context, userID uint64, imageHash string) (*dto.BillResponse, error)
+	ListCorrections(ctx context.Context, userID uint64, limit int) ([]model.CategoryCorrection, error)
+	UpdateFromAI(ctx context.Context, userID, id uint64, aiResult *dto.AIRecognizeResponse, apply bool) (*dto.ReRecognizeResponse, error)
+	Import(ctx context.Context, userID uint64, r io.Reader, filename, parserType string) (*dto.BillImportResponse, error)
+	ImportWithMapping(ctx context.Context, userID uint64, r io.Reader, filename string, mapping importer.Mapping) (*dto.BillImportResponse, error)
+	ListImportParsers() []dto.ImportParserResponse
+	GetImage(ctx context.Context, userID, id uint64) (io.ReadCloser, string, error)
+}
+
+// QuickEntryServiceInterface 一句话记账服务接口（供 Handler 依赖）
+type QuickEntryServiceInterface interface {
+	Parse(ctx context.Context, userID uint64, req *dto.QuickEntryRequest) (*dto.QuickEntryResponse, error)
+}
+
+// NotificationServiceInterface 支付通知/短信记账服务接口（供 Handler 依赖）
+type NotificationServiceInterface interface {
+	Ingest(ctx context.Context, userID uint64, req *dto.IngestNotificationRequest) (*dto.IngestNotificationResponse, error)
 }
 
 // StatsServiceInterface 统计服务接口（供 Handler 依赖）
@@ -47,8 +70,43 @@ type StatsServiceInterface interface {
 	GetSecondaryCategoryStats(ctx context.Context, userID uint64, req *dto.StatsSecondaryCategoryRequest) (*dto.CategoryStatsResponse, error)
 }
 
+// AskServiceInterface 自然语言查询账本服务接口（供 Handler 依赖）
+type AskServiceInterface interface {
+	Ask(ctx context.Context, userID uint64, req *dto.AskLedgerRequest) (*dto.AskLedgerResponse, error)
+}
+
+// InsightServiceInterface 月度消费洞察服务接口（供 Handler 依赖）
+type InsightServiceInterface interface {
+	Get(ctx context.Context, userID uint64, req *dto.MonthlyInsightRequest) (*dto.MonthlyInsightResponse, error)
+}
+
+// ImportProfileServiceInterface 账单导入配置服务接口（供 Handler 依赖）
+type ImportProfileServiceInterface interface {
+	List(ctx context.Context, userID uint64) ([]dto.ImportProfileResponse, error)
+	Create(ctx context.Context, userID uint64, req *dto.CreateImportProfileRequest) (*dto.ImportProfileResponse, error)
+	Update(ctx context.Context, userID, id uint64, req *dto.UpdateImportProfileRequest) (*dto.ImportProfileResponse, error)
+	Delete(ctx context.Context, userID, id uint64) error
+	Preview(ctx context.Context, r io.Reader, filename string, mapping *dto.ImportMapping, limit int) (*dto.ImportPreviewResponse, error)
+	Import(ctx context.Context, userID, id uint64, r io.Reader, filename string) (*dto.BillImportResponse, error)
+}
+
 // AIServiceInterface AI服务接口（供 Handler 依赖）
 type AIServiceInterface interface {
 	RecognizeImage(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.AIRecognizeResponse, error)
 	RecognizeAndCreateBill(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.BillResponse, error)
+	BatchRecognize(ctx context.Context, userID uint64, files []*multipart.FileHeader) (*dto.BatchRecognizeResponse, error)
+	BatchRecognizeAndCreateBill(ctx context.Context, userID uint64, files []*multipart.FileHeader) (*dto.BatchRecognizeResponse, error)
+	RecognizeTransactions(ctx context.Context, userID uint64, file *multipart.FileHeader) (*dto.AIRecognizeListResponse, error)
+	SaveTransactions(ctx context.Context, userID uint64, req *dto.SaveAITransactionsRequest) (*dto.SaveAITransactionsResponse, error)
+	ReRecognizeBill(ctx context.Context, userID, billID uint64, apply bool) (*dto.ReRecognizeResponse, error)
+	Health(ctx context.Context) *dto.AIHealthResponse
+	Quota(ctx context.Context, userID uint64) (*dto.AIQuotaResponse, error)
+}
+
+// AIJobServiceInterface 异步识别任务服务接口（供 Handler 依赖）
+type AIJobServiceInterface interface {
+	Submit(ctx context.Context, userID uint64, files []*multipart.FileHeader, save bool) (*dto.AIJobResponse, error)
+	Get(ctx context.Context, userID uint64, jobID string) (*dto.AIJobResponse, error)
+	Subscribe(ctx context.Context, userID uint64, jobID string) (*dto.AIJobResponse, <-chan dto.AIJobEvent, func(), error)
+	Poll(ctx context.Context, userID uint64, jobID string, seen map[int]string) ([]dto.AIJobEvent, error)
 }
@@ -33,7 +33,7 @@ func (s *StatsService) GetSummary(ctx context.Context, userID uint64, req *dto.S
 	}
 
 	// 获取基础统计
-	summary, err := s.billRepo.GetStatsSummary(ctx, userID, startDate, endDate)
+	summary, err := s.billRepo.GetStatsSummary(ctx, userID, startDate, endDate, req.ConfirmedOnly)
 	if err != nil {
 		logger.Log.Error("获取基础统计失败", zap.Error(err))
 		return nil, errcode.ErrServer
@@ -47,7 +47,7 @@ func (s *StatsService) GetSummary(ctx context.Context, userID uint64, req *dto.S
 	dailyAverage := summary.TotalExpense.Div(decimal.NewFromFloat(days))
 
 	// 获取分类统计
-	categoryStats, err := s.billRepo.GetCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate)
+	categoryStats, err := s.billRepo.GetCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate, req.ConfirmedOnly)
 	if err != nil {
 		logger.Log.Error("获取分类统计失败", zap.Error(err))
 		return nil, errcode.ErrServer
@@ -69,7 +69,7 @@ func (s *StatsService) GetSummary(ctx context.Context, userID uint64, req *dto.S
 	trend := make([]dto.TrendItem, 0)
 	if req.Period == "year" {
 		//年度统计趋势按月份返回
-		monthlyStats, err := s.billRepo.GetMonthlyStats(ctx, userID, startDate, endDate)
+		monthlyStats, err := s.billRepo.GetMonthlyStats(ctx, userID, startDate, endDate, req.ConfirmedOnly)
 		if err != nil {
 			logger.Log.Error("获取月度统计失败", zap.Error(err))
 			return nil, errcode.ErrServer
@@ -97,7 +97,7 @@ func (s *StatsService) GetSummary(ctx context.Context, userID uint64, req *dto.S
 	} else {
 		//其余的按照天返回
 		// 获取趋势数据
-		dailyStats, err := s.billRepo.GetDailyStats(ctx, userID, startDate, endDate)
+		dailyStats, err := s.billRepo.GetDailyStats(ctx, userID, startDate, endDate, req.ConfirmedOnly)
 		if err != nil {
 			logger.Log.Error("获取日度统计失败", zap.Error(err))
 			return nil, errcode.ErrServer
@@ -141,7 +141,7 @@ func (s *StatsService) GetCategoryStats(ctx context.Context, userID uint64, req
 	}
 
 	// 获取分类统计
-	categoryStats, err := s.billRepo.GetCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate)
+	categoryStats, err := s.billRepo.GetCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate, req.ConfirmedOnly)
 	if err != nil {
 		return nil, errcode.ErrServer
 	}
@@ -177,7 +177,7 @@ func (s *StatsService) GetSecondaryCategoryStats(ctx context.Context, userID uin
 	if err != nil {
 		return nil, errcode.ErrParams.WithMessage(err.Error())
 	}
-	categoryStats, err := s.billRepo.GetSecondaryCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate, req.CategoryID)
+	categoryStats, err := s.billRepo.GetSecondaryCategoryStats(ctx, userID, model.BillTypeExpense, startDate, endDate, req.CategoryID, req.ConfirmedOnly)
 	if err != nil {
 		logger.Log.Error("获取二级分类统计失败", zap.Error(err))
 		return nil, errcode.ErrServer
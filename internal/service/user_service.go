@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -124,6 +125,12 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID uint64, req *dto
 	if req.AvatarURL != "" {
 		user.AvatarURL = req.AvatarURL
 	}
+	if req.AutoConfirmThreshold != nil {
+		user.AutoConfirmThreshold = *req.AutoConfirmThreshold
+	}
+	if req.RecognitionInstructions != nil {
+		user.RecognitionInstructions = strings.TrimSpace(*req.RecognitionInstructions)
+	}
 
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, errcode.ErrServer
@@ -164,11 +171,13 @@ func (s *UserService) generateToken(userID uint64, expiresAt time.Time) (string,
 // toUserResponse 转换为用户响应
 func (s *UserService) toUserResponse(user *model.User) *dto.UserResponse {
 	return &dto.UserResponse{
-		ID:          user.ID,
-		Phone:       user.Phone,
-		Nickname:    user.Nickname,
-		AvatarURL:   user.AvatarURL,
-		LastLoginAt: user.LastLoginAt,
-		CreatedAt:   user.CreatedAt,
+		ID:                      user.ID,
+		Phone:                   user.Phone,
+		Nickname:                user.Nickname,
+		AvatarURL:               user.AvatarURL,
+		LastLoginAt:             user.LastLoginAt,
+		CreatedAt:               user.CreatedAt,
+		AutoConfirmThreshold:    user.AutoConfirmThreshold,
+		RecognitionInstructions: user.RecognitionInstructions,
 	}
 }
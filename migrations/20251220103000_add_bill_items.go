@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddBillItems, downAddBillItems)
+}
+
+func upAddBillItems(ctx context.Context, tx *sql.Tx) error {
+	// 创建账单明细表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS bill_items (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			bill_id BIGINT UNSIGNED NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			price DECIMAL(10,2) NOT NULL,
+			quantity DECIMAL(10,3) NOT NULL DEFAULT 1,
+			sort_order INT DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			INDEX idx_bill_id (bill_id),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddBillItems(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS bill_items`); err != nil {
+		return err
+	}
+	return nil
+}
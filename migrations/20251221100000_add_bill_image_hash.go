@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddBillImageHash, downAddBillImageHash)
+}
+
+func upAddBillImageHash(ctx context.Context, tx *sql.Tx) error {
+	// 账单截图内容摘要，用于识别同一截图的重复上传
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			ADD COLUMN image_hash VARCHAR(64) NOT NULL DEFAULT '' AFTER image_path,
+			ADD INDEX idx_user_image_hash (user_id, image_hash)
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddBillImageHash(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			DROP INDEX idx_user_image_hash,
+			DROP COLUMN image_hash
+	`); err != nil {
+		return err
+	}
+	return nil
+}
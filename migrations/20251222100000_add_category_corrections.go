@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddCategoryCorrections, downAddCategoryCorrections)
+}
+
+func upAddCategoryCorrections(ctx context.Context, tx *sql.Tx) error {
+	// 创建分类修正记录表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS category_corrections (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			merchant VARCHAR(255) NOT NULL,
+			category_id BIGINT UNSIGNED NOT NULL,
+			platform VARCHAR(50),
+			bill_type TINYINT DEFAULT 1,
+			hit_count INT DEFAULT 1,
+			last_corrected_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			UNIQUE KEY uk_user_merchant_category (user_id, merchant, category_id),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddCategoryCorrections(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS category_corrections`); err != nil {
+		return err
+	}
+	return nil
+}
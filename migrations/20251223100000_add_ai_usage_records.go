@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddAIUsageRecords, downAddAIUsageRecords)
+}
+
+func upAddAIUsageRecords(ctx context.Context, tx *sql.Tx) error {
+	// 创建AI调用用量记录表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS ai_usage_records (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			provider VARCHAR(50),
+			model VARCHAR(100),
+			prompt_tokens BIGINT DEFAULT 0,
+			completion_tokens BIGINT DEFAULT 0,
+			latency_ms BIGINT DEFAULT 0,
+			success TINYINT(1) DEFAULT 0,
+			cached TINYINT(1) DEFAULT 0,
+			error_message VARCHAR(500),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			INDEX idx_user_created (user_id, created_at),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddAIUsageRecords(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS ai_usage_records`); err != nil {
+		return err
+	}
+	return nil
+}
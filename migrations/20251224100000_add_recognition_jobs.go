@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRecognitionJobs, downAddRecognitionJobs)
+}
+
+func upAddRecognitionJobs(ctx context.Context, tx *sql.Tx) error {
+	// 创建异步识别任务表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS recognition_jobs (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			uuid VARCHAR(36) NOT NULL,
+			user_id BIGINT UNSIGNED NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			save TINYINT(1) DEFAULT 0,
+			total INT DEFAULT 0,
+			succeeded INT DEFAULT 0,
+			failed INT DEFAULT 0,
+			error VARCHAR(500),
+			started_at DATETIME,
+			finished_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			UNIQUE KEY uk_uuid (uuid),
+			INDEX idx_user_id (user_id),
+			INDEX idx_status (status),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+
+	// 创建异步识别任务图片表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS recognition_job_items (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			job_id BIGINT UNSIGNED NOT NULL,
+			item_index INT NOT NULL,
+			file_name VARCHAR(255),
+			image_path VARCHAR(255),
+			mime_type VARCHAR(50),
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			result TEXT,
+			bill_id BIGINT UNSIGNED,
+			error VARCHAR(500),
+			duration BIGINT DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			INDEX idx_job_id (job_id),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddRecognitionJobs(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS recognition_job_items`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS recognition_jobs`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddUserAutoConfirmThreshold, downAddUserAutoConfirmThreshold)
+}
+
+func upAddUserAutoConfirmThreshold(ctx context.Context, tx *sql.Tx) error {
+	// 用户自动确认阈值：AI识别置信度不低于该值的账单自动确认，0 表示关闭
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN auto_confirm_threshold DECIMAL(3,2) NOT NULL DEFAULT 0 AFTER last_login_at
+	`); err != nil {
+		return err
+	}
+
+	// 手动录入和导入的账单无需复核，历史数据统一标记为已确认
+	// 早期的AI账单不保存原始响应和截图，但记录了识别置信度：只处理置信度为 0 的账单，其余保留未确认由用户复核
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE bills SET is_confirmed = 1
+		WHERE is_confirmed = 0
+			AND (confidence IS NULL OR confidence = 0)
+			AND (ai_raw_response IS NULL OR ai_raw_response = '')
+			AND (image_path IS NULL OR image_path = '')
+	`); err != nil {
+		return err
+	}
+
+	// 复核队列按用户筛选未确认账单并按置信度排序
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			ADD INDEX idx_user_confirmed_confidence (user_id, is_confirmed, confidence)
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddUserAutoConfirmThreshold(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			DROP INDEX idx_user_confirmed_confidence
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			DROP COLUMN auto_confirm_threshold
+	`); err != nil {
+		return err
+	}
+	return nil
+}
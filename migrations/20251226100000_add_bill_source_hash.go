@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddBillSourceHash, downAddBillSourceHash)
+}
+
+func upAddBillSourceHash(ctx context.Context, tx *sql.Tx) error {
+	// 支付通知/短信原文摘要，用于识别同一条通知的重复推送
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			ADD COLUMN source_hash VARCHAR(64) NOT NULL DEFAULT '' AFTER image_hash,
+			ADD INDEX idx_user_source_hash (user_id, source_hash)
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddBillSourceHash(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			DROP INDEX idx_user_source_hash,
+			DROP COLUMN source_hash
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddBillInvoiceNo, downAddBillInvoiceNo)
+}
+
+func upAddBillInvoiceNo(ctx context.Context, tx *sql.Tx) error {
+	// 电子发票号码，识别PDF电子发票时写入
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			ADD COLUMN invoice_no VARCHAR(32) NOT NULL DEFAULT '' AFTER order_no
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddBillInvoiceNo(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			DROP COLUMN invoice_no
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddPromptVersionAndUserInstructions, downAddPromptVersionAndUserInstructions)
+}
+
+func upAddPromptVersionAndUserInstructions(ctx context.Context, tx *sql.Tx) error {
+	// 账单识别使用的提示词版本，用于对比不同版本提示词的识别效果
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			ADD COLUMN prompt_version VARCHAR(32) NOT NULL DEFAULT '' AFTER confidence
+	`); err != nil {
+		return err
+	}
+
+	// 用户自定义识别说明，追加在提示词末尾
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN recognition_instructions VARCHAR(500) NOT NULL DEFAULT '' AFTER auto_confirm_threshold
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddPromptVersionAndUserInstructions(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			DROP COLUMN recognition_instructions
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE bills
+			DROP COLUMN prompt_version
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddMonthlyInsights, downAddMonthlyInsights)
+}
+
+func upAddMonthlyInsights(ctx context.Context, tx *sql.Tx) error {
+	// 创建月度消费洞察表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS monthly_insights (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			month VARCHAR(7) NOT NULL,
+			facts TEXT,
+			narrative TEXT,
+			prompt_version VARCHAR(32),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			UNIQUE KEY uk_user_month (user_id, month),
+			INDEX idx_month (month),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddMonthlyInsights(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS monthly_insights`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddImportProfiles, downAddImportProfiles)
+}
+
+func upAddImportProfiles(ctx context.Context, tx *sql.Tx) error {
+	// 创建账单导入配置表
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS import_profiles (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			name VARCHAR(50) NOT NULL,
+			mapping TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at DATETIME,
+			INDEX idx_user_id (user_id),
+			INDEX idx_deleted_at (deleted_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddImportProfiles(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS import_profiles`); err != nil {
+		return err
+	}
+	return nil
+}
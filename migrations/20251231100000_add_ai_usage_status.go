@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddAIUsageStatus, downAddAIUsageStatus)
+}
+
+func upAddAIUsageStatus(ctx context.Context, tx *sql.Tx) error {
+	// 调用前预留配额：用量记录的 success 改为 reserved/succeeded/failed 状态，失败的调用不计入配额
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'reserved' AFTER latency_ms
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE ai_usage_records SET status = IF(success = 1, 'succeeded', 'failed')
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			DROP COLUMN success
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddAIUsageStatus(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			ADD COLUMN success TINYINT(1) DEFAULT 0 AFTER latency_ms
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE ai_usage_records SET success = IF(status = 'succeeded', 1, 0)
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			DROP COLUMN status
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRecognitionJobLease, downAddRecognitionJobLease)
+}
+
+func upAddRecognitionJobLease(ctx context.Context, tx *sql.Tx) error {
+	// 识别任务租约：记录处理实例和心跳时间，多实例部署时只接管心跳超时的任务
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE recognition_jobs
+			ADD COLUMN claimed_by VARCHAR(64) AFTER error,
+			ADD COLUMN heartbeat_at DATETIME AFTER claimed_by
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddRecognitionJobLease(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE recognition_jobs
+			DROP COLUMN claimed_by,
+			DROP COLUMN heartbeat_at
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddAIUsageSource, downAddAIUsageSource)
+}
+
+func upAddAIUsageSource(ctx context.Context, tx *sql.Tx) error {
+	// 用量记录区分调用来源：后台任务（月度消费洞察）的调用只统计费用，不计入用户配额
+	// 历史记录无法区分来源，统一视为用户调用
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			ADD COLUMN source VARCHAR(16) NOT NULL DEFAULT 'user' AFTER status
+	`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func downAddAIUsageSource(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE ai_usage_records
+			DROP COLUMN source
+	`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -104,10 +104,28 @@ var (
 	// ErrBillDeleteFailed 删除账单失败
 	ErrBillDeleteFailed = New(40004, "删除账单失败", http.StatusInternalServerError)
 
+	// ErrBillImageNotFound 账单图片不存在
+	ErrBillImageNotFound = New(40005, "账单图片不存在", http.StatusNotFound)
+
+	// ErrQuickEntryParseFailed 快速记账内容无法解析
+	ErrQuickEntryParseFailed = New(40006, "无法识别记账内容，请输入金额，如“午饭 35”", http.StatusBadRequest)
+
+	// ErrNotificationParseFailed 通知中没有可识别的交易
+	ErrNotificationParseFailed = New(40007, "未识别到交易信息", http.StatusBadRequest)
+
 	// 导入相关错误 (45000-45999)
 	ErrImportFileParse = New(45001, "文件解析失败", http.StatusInternalServerError)
 
 	ErrImportUnsupported = New(45002, "不支持的导入格式", http.StatusBadRequest)
+
+	// ErrImportProfileNotFound 导入配置不存在
+	ErrImportProfileNotFound = New(45003, "导入配置不存在", http.StatusNotFound)
+
+	// ErrImportProfileExists 导入配置名称重复
+	ErrImportProfileExists = New(45004, "导入配置已存在", http.StatusBadRequest)
+
+	// ErrImportMappingInvalid 列映射无效或与文件不符
+	ErrImportMappingInvalid = New(45005, "列映射无效", http.StatusBadRequest)
 )
 
 // =============== AI 错误码 (50000-59999) ===============
@@ -124,6 +142,27 @@ var (
 
 	// ErrAIServiceUnavailable AI服务不可用
 	ErrAIServiceUnavailable = New(50004, "AI服务暂时不可用", http.StatusServiceUnavailable)
+
+	// ErrTooManyImages 单次上传图片数量超限
+	ErrTooManyImages = New(50005, "单次上传图片数量超过限制", http.StatusBadRequest)
+
+	// ErrAIQuotaExceeded AI调用次数超出配额
+	ErrAIQuotaExceeded = New(50006, "AI识别次数已达上限", http.StatusTooManyRequests)
+
+	// ErrJobNotFound 识别任务不存在
+	ErrJobNotFound = New(50007, "识别任务不存在", http.StatusNotFound)
+
+	// ErrPDFUnreadable PDF中没有可识别的文本或图片
+	ErrPDFUnreadable = New(50008, "无法读取PDF内容，请上传截图", http.StatusBadRequest)
+
+	// ErrAskUnanswerable 问题无法转换为账本查询
+	ErrAskUnanswerable = New(50009, "暂时无法回答这个问题，可以换个问法，如“上个月餐饮花了多少”", http.StatusBadRequest)
+
+	// ErrInsightNotFound 月度消费洞察尚未生成
+	ErrInsightNotFound = New(50010, "该月的消费洞察尚未生成", http.StatusNotFound)
+
+	// ErrPDFRenderUnavailable PDF中没有可提取的文本或图片，且未配置渲染命令
+	ErrPDFRenderUnavailable = New(50011, "暂不支持识别该PDF（无可提取的文本或图片），请上传截图", http.StatusBadRequest)
 )
 
 // =============== 分类错误码 (60000-69999) ===============